@@ -0,0 +1,35 @@
+#!/bin/bash
+####
+# Bash Script that creates the hints templates of the docker provider from the hints templates of the kubernetes provider.
+# The script takes as an argument the path of the deploy directory
+# Eg. ./creator_docker_templates.sh deploy
+####
+
+KUBERNETES_TEMPLATES=kubernetes/elastic-agent-standalone/templates.d
+DOCKER_TEMPLATES=docker/elastic-agent-standalone/templates.d
+
+#Check if arguments provided
+((!$#)) && echo "No arguments provided!Please provide path of the deploy directory" && exit 1
+DEPLOY_PATH=$1
+
+#Check if the kubernetes templates exist
+if [ ! -d "$DEPLOY_PATH/$KUBERNETES_TEMPLATES" ]; then
+    echo "$DEPLOY_PATH/$KUBERNETES_TEMPLATES does not exists"
+    exit 1
+fi
+
+mkdir -p "$DEPLOY_PATH/$DOCKER_TEMPLATES"
+#Removing the generated templates, the ones of the removed kubernetes templates must not be left behind
+find "$DEPLOY_PATH/$DOCKER_TEMPLATES" -name '*.yml' ! -name 'container_logs.yml' -delete
+for TEMPLATE in "$DEPLOY_PATH"/$KUBERNETES_TEMPLATES/*.yml; do
+    NAME=$(basename "$TEMPLATE")
+    #The container logs templates are specific to each provider
+    if [ "$NAME" == "container_logs.yml" ]; then
+        continue
+    fi
+
+    #Replacing the kubernetes hints and the log paths of the kubernetes containers
+    sed -e 's|/var/log/containers/\*${kubernetes.hints.container_id}.log|/var/lib/docker/containers/${docker.hints.container_id}/*-json.log|g' \
+        -e 's/kubernetes\.hints\./docker.hints./g' \
+        "$TEMPLATE" > "$DEPLOY_PATH/$DOCKER_TEMPLATES/$NAME"
+done
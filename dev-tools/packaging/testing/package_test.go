@@ -52,16 +52,17 @@ const (
 )
 
 var (
-	excludedPathsPattern    = regexp.MustCompile(`node_modules`)
-	configFilePattern       = regexp.MustCompile(`.*beat\.spec.yml$|.*beat\.yml$|apm-server\.yml|elastic-agent\.yml$$`)
-	otelcolScriptPattern    = regexp.MustCompile(`/otelcol$`)
-	manifestFilePattern     = regexp.MustCompile(`manifest.yml`)
-	modulesDirPattern       = regexp.MustCompile(`module/.+`)
-	modulesDDirPattern      = regexp.MustCompile(`modules.d/$`)
-	modulesDFilePattern     = regexp.MustCompile(`modules.d/.+`)
-	monitorsDFilePattern    = regexp.MustCompile(`monitors.d/.+`)
-	systemdUnitFilePattern  = regexp.MustCompile(`/lib/systemd/system/.*\.service`)
-	hintsInputsDFilePattern = regexp.MustCompile(`usr/share/elastic-agent/hints.inputs.d/.*\.yml`)
+	excludedPathsPattern          = regexp.MustCompile(`node_modules`)
+	configFilePattern             = regexp.MustCompile(`.*beat\.spec.yml$|.*beat\.yml$|apm-server\.yml|elastic-agent\.yml$$`)
+	otelcolScriptPattern          = regexp.MustCompile(`/otelcol$`)
+	manifestFilePattern           = regexp.MustCompile(`manifest.yml`)
+	modulesDirPattern             = regexp.MustCompile(`module/.+`)
+	modulesDDirPattern            = regexp.MustCompile(`modules.d/$`)
+	modulesDFilePattern           = regexp.MustCompile(`modules.d/.+`)
+	monitorsDFilePattern          = regexp.MustCompile(`monitors.d/.+`)
+	systemdUnitFilePattern        = regexp.MustCompile(`/lib/systemd/system/.*\.service`)
+	hintsInputsDFilePattern       = regexp.MustCompile(`usr/share/elastic-agent/hints.inputs.d/.*\.yml`)
+	dockerHintsInputsDFilePattern = regexp.MustCompile(`usr/share/elastic-agent/docker.hints.inputs.d/.*\.yml`)
 
 	licenseFiles = []string{"LICENSE.txt", "NOTICE.txt"}
 )
@@ -433,6 +434,7 @@ func checkDocker(t *testing.T, file string, fipsPackage bool) (string, int64) {
 	checkModulesPresent(t, "", p)
 	checkModulesDPresent(t, "", p)
 	checkHintsInputsD(t, "hints.inputs.d", hintsInputsDFilePattern, p)
+	checkHintsInputsD(t, "docker.hints.inputs.d", dockerHintsInputsDFilePattern, p)
 	checkLicensesPresent(t, "licenses/", p)
 
 	if strings.Contains(file, "-complete") {
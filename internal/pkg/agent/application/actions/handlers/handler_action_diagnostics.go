@@ -69,19 +69,24 @@ type Diagnostics struct {
 	limiter      *rate.Limiter
 	uploader     Uploader
 	topPath      string
+	// redactionPolicy is the path of the redaction policy applied to the
+	// bundles, none is applied when empty.
+	redactionPolicy string
 }
 
-// NewDiagnostics returns a new Diagnostics handler.
-func NewDiagnostics(log abstractLogger, topPath string, coord diagnosticsProvider, cfg config.Limit, uploader Uploader) *Diagnostics {
+// NewDiagnostics returns a new Diagnostics handler. The redaction policy at
+// redactionPolicy, if set, is applied to every bundle.
+func NewDiagnostics(log abstractLogger, topPath string, coord diagnosticsProvider, cfg config.Limit, redactionPolicy string, uploader Uploader) *Diagnostics {
 	if topPath == "" {
 		topPath = paths.Top()
 	}
 	return &Diagnostics{
-		log:          log,
-		diagProvider: coord,
-		limiter:      rate.NewLimiter(rate.Every(cfg.Interval), cfg.Burst),
-		uploader:     uploader,
-		topPath:      topPath,
+		log:             log,
+		diagProvider:    coord,
+		limiter:         rate.NewLimiter(rate.Every(cfg.Interval), cfg.Burst),
+		uploader:        uploader,
+		topPath:         topPath,
+		redactionPolicy: redactionPolicy,
 	}
 }
 
@@ -94,7 +99,12 @@ func (h *Diagnostics) Handle(ctx context.Context, a fleetapi.Action, ack acker.A
 	if !ok {
 		return fmt.Errorf("invalid type, expected ActionDiagnostics and received %T", a)
 	}
-	go h.collectDiag(ctx, action, ack)
+	// a recurring action is handled again on every occurrence while the previous ones may still run,
+	// every occurrence reports its own result
+	occurrence := *action
+	occurrence.Err = nil
+	occurrence.UploadID = ""
+	go h.collectDiag(ctx, &occurrence, ack)
 	return nil
 }
 
@@ -132,6 +142,21 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 		return
 	}
 
+	var policy *diagnostics.RedactionPolicy
+	if h.redactionPolicy != "" {
+		// the policy is loaded on every action for its changes to apply without a restart,
+		// no bundle is uploaded when it can't be applied
+		var err error
+		policy, err = diagnostics.LoadRedactionPolicy(h.redactionPolicy)
+		if err != nil {
+			action.Err = err
+			h.log.Errorw("diagnostics action handler failed to load the redaction policy",
+				"error.message", err,
+				"action", action)
+			return
+		}
+	}
+
 	h.log.Debug("Gathering agent diagnostics.")
 	aDiag, err := h.runHooks(ctx, action)
 	if err != nil {
@@ -151,7 +176,7 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 	// attempt to create a temporary diagnostics file on disk in order to avoid
 	// loading a potentially large file in memory.
 	// if on-disk creation fails an in-memory buffer is used.
-	f, s, err := h.diagFile(aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog)
+	f, s, err := h.diagFile(aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog, policy)
 	if err != nil {
 		var b bytes.Buffer
 		h.log.Warnw("Diagnostics action unable to use temporary file, using buffer instead.", "error.message", err)
@@ -161,7 +186,7 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 				h.log.Warn(str)
 			}
 		}()
-		err := diagnostics.ZipArchive(&wBuf, &b, h.topPath, aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog)
+		err := diagnostics.ZipArchive(&wBuf, &b, h.topPath, aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog, policy)
 		if err != nil {
 			h.log.Errorw(
 				"diagnostics action handler failed generate zip archive",
@@ -336,7 +361,8 @@ func (h *Diagnostics) diagFile(
 	aDiag []client.DiagnosticFileResult,
 	uDiag []client.DiagnosticUnitResult,
 	cDiag []client.DiagnosticComponentResult,
-	excludeEvents bool) (*os.File, int64, error) {
+	excludeEvents bool,
+	policy *diagnostics.RedactionPolicy) (*os.File, int64, error) {
 
 	f, err := os.CreateTemp(paths.TempDir(), "elastic-agent-diagnostics")
 	if err != nil {
@@ -350,7 +376,7 @@ func (h *Diagnostics) diagFile(
 			h.log.Warn(str)
 		}
 	}()
-	if err := diagnostics.ZipArchive(&wBuf, f, h.topPath, aDiag, uDiag, cDiag, excludeEvents); err != nil {
+	if err := diagnostics.ZipArchive(&wBuf, f, h.topPath, aDiag, uDiag, cDiag, excludeEvents, policy); err != nil {
 		os.Remove(name)
 		return nil, 0, err
 	}
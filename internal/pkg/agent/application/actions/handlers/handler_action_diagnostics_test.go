@@ -5,7 +5,10 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"io"
 	"os"
 	"path"
 	"testing"
@@ -81,7 +84,7 @@ func TestDiagnosticHandlerHappyPathWithLogs(t *testing.T) {
 	mockDiagProvider := mockhandlers.NewDiagnosticsProvider(t)
 	mockUploader := mockhandlers.NewUploader(t)
 	testLogger, observedLogs := loggertest.New("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, "", mockUploader)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{hook1})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{mockUnitDiagnostic})
@@ -162,7 +165,7 @@ func TestDiagnosticHandlerUploaderErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mockhandlers.NewDiagnosticsProvider(t)
 	mockUploader := mockhandlers.NewUploader(t)
 	testLogger, observedLogs := loggertest.New("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, "", mockUploader)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
@@ -203,7 +206,7 @@ func TestDiagnosticHandlerZipArchiveErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mockhandlers.NewDiagnosticsProvider(t)
 	mockUploader := mockhandlers.NewUploader(t)
 	testLogger, observedLogs := loggertest.New("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, "", mockUploader)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
@@ -230,6 +233,77 @@ func TestDiagnosticHandlerZipArchiveErrorWithLogs(t *testing.T) {
 	// we could assert the logs for the hooks, but those will be the same as the happy path, so for brevity we won't
 }
 
+func TestDiagnosticHandlerRedactionPolicy(t *testing.T) {
+	tempAgentRoot := t.TempDir()
+	paths.SetTop(tempAgentRoot)
+	err := os.MkdirAll(path.Join(tempAgentRoot, "data"), 0755)
+	require.NoError(t, err)
+	policyPath := path.Join(t.TempDir(), "redaction.yml")
+	require.NoError(t, os.WriteFile(policyPath, []byte("values:\n  - name: ipv4\n"), 0600))
+
+	mockDiagProvider := mockhandlers.NewDiagnosticsProvider(t)
+	mockUploader := mockhandlers.NewUploader(t)
+	testLogger, _ := loggertest.New("diagnostic-handler-test")
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, policyPath, mockUploader)
+
+	profile := diagnostics.Hook{
+		Name:        "heap",
+		Filename:    "heap.pprof.gz",
+		ContentType: "application/octet-stream",
+		Hook: func(ctx context.Context) []byte {
+			return []byte("10.1.2.3")
+		},
+	}
+	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{hook1, profile})
+	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
+	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
+
+	var files []string
+	mockUploader.EXPECT().UploadDiagnostics(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).RunAndReturn(
+		func(ctx context.Context, actionID string, ts string, size int64, r io.Reader) (string, error) {
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			zr, err := zip.NewReader(bytes.NewReader(content), size)
+			require.NoError(t, err)
+			for _, f := range zr.File {
+				files = append(files, f.Name)
+			}
+			return "upload-id", nil
+		})
+
+	mockAcker := mockackers.NewAcker(t)
+	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).Return(nil)
+	mockAcker.EXPECT().Commit(mock.Anything).Return(nil)
+
+	diagAction := &fleetapi.ActionDiagnostics{}
+	handler.collectDiag(context.Background(), diagAction, mockAcker)
+
+	require.NoError(t, diagAction.Err)
+	assert.Contains(t, files, diagnostics.RedactionManifestFilename)
+	assert.Contains(t, files, hook1.Filename)
+	assert.NotContains(t, files, profile.Filename, "binary files can't be redacted")
+}
+
+func TestDiagnosticHandlerRedactionPolicyError(t *testing.T) {
+	tempAgentRoot := t.TempDir()
+	paths.SetTop(tempAgentRoot)
+
+	mockDiagProvider := mockhandlers.NewDiagnosticsProvider(t)
+	mockUploader := mockhandlers.NewUploader(t)
+	testLogger, _ := loggertest.New("diagnostic-handler-test")
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, path.Join(tempAgentRoot, "missing.yml"), mockUploader)
+
+	mockAcker := mockackers.NewAcker(t)
+	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).Return(nil)
+	mockAcker.EXPECT().Commit(mock.Anything).Return(nil)
+
+	diagAction := &fleetapi.ActionDiagnostics{}
+	handler.collectDiag(context.Background(), diagAction, mockAcker)
+
+	// nothing is collected nor uploaded when the policy can't be applied
+	assert.Error(t, diagAction.Err)
+}
+
 func TestDiagnosticHandlerAckErrorWithLogs(t *testing.T) {
 	tempAgentRoot := t.TempDir()
 	paths.SetTop(tempAgentRoot)
@@ -239,7 +313,7 @@ func TestDiagnosticHandlerAckErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mockhandlers.NewDiagnosticsProvider(t)
 	mockUploader := mockhandlers.NewUploader(t)
 	testLogger, observedLogs := loggertest.New("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, "", mockUploader)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
@@ -278,7 +352,7 @@ func TestDiagnosticHandlerCommitErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mockhandlers.NewDiagnosticsProvider(t)
 	mockUploader := mockhandlers.NewUploader(t)
 	testLogger, observedLogs := loggertest.New("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, "", mockUploader)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
@@ -318,7 +392,7 @@ func TestDiagnosticHandlerContexteExpiredErrorWithLogs(t *testing.T) {
 	mockDiagProvider := mockhandlers.NewDiagnosticsProvider(t)
 	mockUploader := mockhandlers.NewUploader(t)
 	testLogger, observedLogs := loggertest.New("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, "", mockUploader)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 
@@ -362,7 +436,7 @@ func TestDiagnosticHandlerWithCPUProfile(t *testing.T) {
 	mockDiagProvider := mockhandlers.NewDiagnosticsProvider(t)
 	mockUploader := mockhandlers.NewUploader(t)
 	testLogger, _ := loggertest.New("diagnostic-handler-test")
-	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, mockUploader)
+	handler := NewDiagnostics(testLogger, tempAgentRoot, mockDiagProvider, defaultRateLimit, "", mockUploader)
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{hook1})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{mockUnitDiagnostic})
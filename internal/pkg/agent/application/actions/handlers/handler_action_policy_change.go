@@ -41,6 +41,7 @@ type PolicyChangeHandler struct {
 	setters              []actions.ClientSetter
 	policyLogLevelSetter logLevelSetter
 	coordinator          *coordinator.Coordinator
+	configOverrider      clientConfigOverrider
 	// Disabled for 8.8.0 release in order to limit the surface
 	// https://github.com/elastic/security-team/issues/6501
 	// // Last known valid signature validation key
@@ -79,6 +80,28 @@ func (h *PolicyChangeHandler) AddSetter(cs actions.ClientSetter) {
 	h.setters = append(h.setters, cs)
 }
 
+// clientConfigOverrider overrides parts of the fleet client configuration
+// the fleet clients are created with. The overrides are not persisted.
+type clientConfigOverrider interface {
+	// ClientConfig returns the configuration with the overrides applied.
+	ClientConfig(cfg remote.Config) remote.Config
+	// SetClientConfig is called with the new configuration once applied.
+	SetClientConfig(cfg remote.Config)
+}
+
+// SetClientConfigOverrider sets the overrider of the fleet client configuration.
+func (h *PolicyChangeHandler) SetClientConfigOverrider(o clientConfigOverrider) {
+	h.configOverrider = o
+}
+
+// clientConfig returns the configuration the fleet clients are created with.
+func (h *PolicyChangeHandler) clientConfig(cfg remote.Config) remote.Config {
+	if h.configOverrider == nil {
+		return cfg
+	}
+	return h.configOverrider.ClientConfig(cfg)
+}
+
 // Handle handles policy change action.
 func (h *PolicyChangeHandler) Handle(ctx context.Context, a fleetapi.Action, acker acker.Acker) error {
 	h.log.Debugf("handlerPolicyChange: action '%+v' received", a)
@@ -146,7 +169,7 @@ func (h *PolicyChangeHandler) validateFleetServerHosts(ctx context.Context, cfg
 	updateFleetConfig(h.log, parsedConfig.Fleet.Client, &newFleetClientConfig)
 
 	// Test new config
-	err = testFleetConfig(ctx, h.log, newFleetClientConfig, h.config.Fleet.AccessAPIKey)
+	err = testFleetConfig(ctx, h.log, h.clientConfig(newFleetClientConfig), h.config.Fleet.AccessAPIKey)
 	if err != nil {
 		return nil, fmt.Errorf("validating fleet client config: %w", err)
 	}
@@ -356,13 +379,16 @@ func (h *PolicyChangeHandler) applyFleetClientConfig(validatedConfig *remote.Con
 
 	// the config has already been validated, no need for error handling
 	fleetClient, err := client.NewAuthWithConfig(
-		h.log, h.config.Fleet.AccessAPIKey, *validatedConfig)
+		h.log, h.config.Fleet.AccessAPIKey, h.clientConfig(*validatedConfig))
 	if err != nil {
 		return fmt.Errorf("creating new fleet client with updated config: %w", err)
 	}
 	for _, setter := range h.setters {
 		setter.SetClient(fleetClient)
 	}
+	if h.configOverrider != nil {
+		h.configOverrider.SetClientConfig(*validatedConfig)
+	}
 
 	return nil
 }
@@ -0,0 +1,72 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+type rollbackCoordinator interface {
+	Rollback(ctx context.Context, version string, action fleetapi.Action) error
+}
+
+// Rollback handles rollback requests coming from fleet.
+type Rollback struct {
+	log   *logger.Logger
+	coord rollbackCoordinator
+}
+
+// NewRollback creates a new Rollback handler.
+func NewRollback(log *logger.Logger, coord rollbackCoordinator) *Rollback {
+	return &Rollback{
+		log:   log,
+		coord: coord,
+	}
+}
+
+// Handle handles ROLLBACK action. The action is acked by the coordinator before the agent restarts into the
+// retained install, only failures are acked here.
+func (h *Rollback) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker) error {
+	h.log.Debugf("handlerRollback: action '%+v' received", a)
+
+	action, ok := a.(*fleetapi.ActionRollback)
+	if !ok {
+		return fmt.Errorf("invalid type, expected ActionRollback and received %T", a)
+	}
+
+	if action.Data.Version == "" {
+		err := fmt.Errorf("rollback action %s has no version", action.ActionID)
+		h.ackFailure(ctx, err, action, ack)
+		return err
+	}
+
+	if err := h.coord.Rollback(ctx, action.Data.Version, action); err != nil {
+		h.ackFailure(ctx, err, action, ack)
+		return fmt.Errorf("rollback to version %s failed: %w", action.Data.Version, err)
+	}
+
+	return nil
+}
+
+func (h *Rollback) ackFailure(ctx context.Context, err error, action *fleetapi.ActionRollback, acker acker.Acker) {
+	action.Err = err
+
+	if err := acker.Ack(ctx, action); err != nil {
+		h.log.Errorw("failed to ack rollback action",
+			"error.message", err,
+			"action", action)
+	}
+
+	if err := acker.Commit(ctx); err != nil {
+		h.log.Errorw("failed to commit rollback action",
+			"error.message", err,
+			"action", action)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestActionRollbackHandler(t *testing.T) {
+	log, _ := loggertest.New("")
+
+	t.Run("wrong action type", func(t *testing.T) {
+		action := &fleetapi.ActionSettings{}
+		ack := &fakeAcker{}
+		coord := &fakeRollbackCoordinator{}
+
+		h := NewRollback(log, coord)
+		require.Error(t, h.Handle(t.Context(), action, ack))
+		coord.AssertNotCalled(t, "Rollback", mock.Anything, mock.Anything, mock.Anything)
+		ack.AssertNotCalled(t, "Ack", mock.Anything, mock.Anything)
+	})
+
+	t.Run("missing version", func(t *testing.T) {
+		action := &fleetapi.ActionRollback{ActionID: "rollback-1", ActionType: fleetapi.ActionTypeRollback}
+		ack := &fakeAcker{}
+		ack.On("Ack", t.Context(), action).Return(nil)
+		ack.On("Commit", t.Context()).Return(nil)
+		coord := &fakeRollbackCoordinator{}
+
+		h := NewRollback(log, coord)
+		require.Error(t, h.Handle(t.Context(), action, ack))
+		coord.AssertNotCalled(t, "Rollback", mock.Anything, mock.Anything, mock.Anything)
+		ack.AssertCalled(t, "Ack", t.Context(), action)
+		assert.Error(t, action.Err)
+	})
+
+	t.Run("action propagated to coordinator", func(t *testing.T) {
+		action := &fleetapi.ActionRollback{
+			ActionID:   "rollback-1",
+			ActionType: fleetapi.ActionTypeRollback,
+			Data:       fleetapi.ActionRollbackData{Version: "1.2.3"},
+		}
+		ack := &fakeAcker{}
+		coord := &fakeRollbackCoordinator{}
+		coord.On("Rollback", t.Context(), "1.2.3", action).Return(nil)
+
+		h := NewRollback(log, coord)
+		require.NoError(t, h.Handle(t.Context(), action, ack))
+		coord.AssertNumberOfCalls(t, "Rollback", 1)
+		// the coordinator acks a successful rollback
+		ack.AssertNotCalled(t, "Ack", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failed rollback is acked", func(t *testing.T) {
+		action := &fleetapi.ActionRollback{
+			ActionID:   "rollback-1",
+			ActionType: fleetapi.ActionTypeRollback,
+			Data:       fleetapi.ActionRollbackData{Version: "1.2.3"},
+		}
+		rollbackErr := errors.New("no retained install")
+		ack := &fakeAcker{}
+		ack.On("Ack", t.Context(), action).Return(nil)
+		ack.On("Commit", t.Context()).Return(nil)
+		coord := &fakeRollbackCoordinator{}
+		coord.On("Rollback", t.Context(), "1.2.3", action).Return(rollbackErr)
+
+		h := NewRollback(log, coord)
+		err := h.Handle(t.Context(), action, ack)
+		require.ErrorIs(t, err, rollbackErr)
+		ack.AssertCalled(t, "Ack", t.Context(), action)
+		ack.AssertCalled(t, "Commit", t.Context())
+		assert.Equal(t, rollbackErr, action.Err)
+	})
+}
+
+type fakeRollbackCoordinator struct {
+	mock.Mock
+}
+
+func (f *fakeRollbackCoordinator) Rollback(ctx context.Context, version string, action fleetapi.Action) error {
+	args := f.Called(ctx, version, action)
+	return args.Error(0)
+}
@@ -0,0 +1,62 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+type rotateKeyCoordinator interface {
+	RotateKey(ctx context.Context) (uint32, error)
+}
+
+// RotateKey handles the rotation of the agent encryption key requested by fleet.
+type RotateKey struct {
+	log   *logger.Logger
+	coord rotateKeyCoordinator
+}
+
+// NewRotateKey creates a new RotateKey handler.
+func NewRotateKey(log *logger.Logger, coord rotateKeyCoordinator) *RotateKey {
+	return &RotateKey{
+		log:   log,
+		coord: coord,
+	}
+}
+
+// Handle handles ROTATE_KEY action.
+func (h *RotateKey) Handle(ctx context.Context, a fleetapi.Action, acker acker.Acker) error {
+	h.log.Debugf("handlerRotateKey: action '%+v' received", a)
+
+	action, ok := a.(*fleetapi.ActionRotateKey)
+	if !ok {
+		return fmt.Errorf("invalid type, expected ActionRotateKey and received %T", a)
+	}
+
+	_, err := h.coord.RotateKey(ctx)
+	if err != nil {
+		action.Err = err
+		err = fmt.Errorf("rotation of the agent key failed: %w", err)
+	}
+
+	if ackErr := acker.Ack(ctx, action); ackErr != nil {
+		h.log.Errorw("failed to ack rotate key action",
+			"error.message", ackErr,
+			"action", action)
+	}
+
+	if commitErr := acker.Commit(ctx); commitErr != nil {
+		h.log.Errorw("failed to commit rotate key action",
+			"error.message", commitErr,
+			"action", action)
+	}
+
+	return err
+}
@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestActionRotateKeyHandler(t *testing.T) {
+	log, _ := loggertest.New("")
+
+	t.Run("wrong action type", func(t *testing.T) {
+		action := &fleetapi.ActionSettings{}
+		ack := &fakeAcker{}
+		coord := &fakeRotateKeyCoordinator{}
+
+		h := NewRotateKey(log, coord)
+		require.Error(t, h.Handle(t.Context(), action, ack))
+		coord.AssertNotCalled(t, "RotateKey", mock.Anything)
+		ack.AssertNotCalled(t, "Ack", mock.Anything, mock.Anything)
+	})
+
+	t.Run("key rotated", func(t *testing.T) {
+		action := &fleetapi.ActionRotateKey{ActionID: "rotate-1", ActionType: fleetapi.ActionTypeRotateKey}
+		ack := &fakeAcker{}
+		ack.On("Ack", t.Context(), action).Return(nil)
+		ack.On("Commit", t.Context()).Return(nil)
+		coord := &fakeRotateKeyCoordinator{}
+		coord.On("RotateKey", t.Context()).Return(uint32(1), nil)
+
+		h := NewRotateKey(log, coord)
+		require.NoError(t, h.Handle(t.Context(), action, ack))
+		coord.AssertNumberOfCalls(t, "RotateKey", 1)
+		ack.AssertCalled(t, "Ack", t.Context(), action)
+		ack.AssertCalled(t, "Commit", t.Context())
+		assert.NoError(t, action.Err)
+	})
+
+	t.Run("failed rotation is acked", func(t *testing.T) {
+		action := &fleetapi.ActionRotateKey{ActionID: "rotate-1", ActionType: fleetapi.ActionTypeRotateKey}
+		rotateErr := errors.New("vault is readonly")
+		ack := &fakeAcker{}
+		ack.On("Ack", t.Context(), action).Return(nil)
+		ack.On("Commit", t.Context()).Return(nil)
+		coord := &fakeRotateKeyCoordinator{}
+		coord.On("RotateKey", t.Context()).Return(uint32(0), rotateErr)
+
+		h := NewRotateKey(log, coord)
+		err := h.Handle(t.Context(), action, ack)
+		require.ErrorIs(t, err, rotateErr)
+		ack.AssertCalled(t, "Ack", t.Context(), action)
+		ack.AssertCalled(t, "Commit", t.Context())
+		assert.Equal(t, rotateErr, action.Err)
+	})
+}
+
+type fakeRotateKeyCoordinator struct {
+	mock.Mock
+}
+
+func (f *fakeRotateKeyCoordinator) RotateKey(ctx context.Context) (uint32, error) {
+	args := f.Called(ctx)
+	return args.Get(0).(uint32), args.Error(1)
+}
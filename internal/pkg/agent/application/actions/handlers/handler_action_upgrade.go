@@ -9,19 +9,32 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/internal/pkg/queue"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/features"
 )
 
+type upgradeQueue interface {
+	Add(action fleetapi.ScheduledAction, priority int64)
+	AddWithSchedule(action fleetapi.ScheduledAction, priority int64, schedule queue.Schedule) error
+	Cancel(actionID string) int
+	Save() error
+}
+
 // Upgrade is a handler for UPGRADE action.
 // After running Upgrade agent should download its own version specified by action
 // from repository specified by fleet.
+// Upgrades received outside the upgrade maintenance windows are deferred to the
+// action queue until the next window opens.
 type Upgrade struct {
 	log        *logger.Logger
 	coord      upgradeCoordinator
+	queue      upgradeQueue
 	bkgActions []fleetapi.Action
 	bkgCancel  context.CancelFunc
 	bkgMutex   sync.Mutex
@@ -29,11 +42,12 @@ type Upgrade struct {
 	tamperProtectionFn func() bool // allows to inject the flag for tests, defaults to features.TamperProtection
 }
 
-// NewUpgrade creates a new Upgrade handler.
-func NewUpgrade(log *logger.Logger, coord upgradeCoordinator) *Upgrade {
+// NewUpgrade creates a new Upgrade handler that uses the passed queue to defer upgrades.
+func NewUpgrade(log *logger.Logger, coord upgradeCoordinator, queue upgradeQueue) *Upgrade {
 	return &Upgrade{
 		log:                log,
 		coord:              coord,
+		queue:              queue,
 		tamperProtectionFn: features.TamperProtection,
 	}
 }
@@ -50,6 +64,11 @@ func (h *Upgrade) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker
 		return fmt.Errorf("invalid type, expected ActionUpgrade and received %T", a)
 	}
 
+	if start, ok := h.coord.NextUpgradeMaintenanceWindow(time.Now()); !ok {
+		// the upgrade isn't acked, the actions depending on it keep waiting until it runs
+		return h.deferUpgrade(action, start)
+	}
+
 	asyncCtx, runAsync := h.getAsyncContext(ctx, a, ack)
 	if !runAsync {
 		return nil
@@ -80,6 +99,7 @@ func (h *Upgrade) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker
 			// If context is cancelled in getAsyncContext, the actions are acked there
 			if !errors.Is(asyncCtx.Err(), context.Canceled) {
 				h.bkgMutex.Lock()
+				action.Err = err
 				h.ackActions(asyncCtx, ack)
 				h.bkgMutex.Unlock()
 			}
@@ -88,6 +108,56 @@ func (h *Upgrade) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker
 	return nil
 }
 
+// deferUpgrade queues the upgrade action to start when the next maintenance
+// window opens and reports the upgrade as scheduled. The schedule of the
+// action is kept, a recurring upgrade recurs from its deferred occurrence.
+func (h *Upgrade) deferUpgrade(action *fleetapi.ActionUpgrade, start time.Time) error {
+	reason := fmt.Sprintf("upgrade deferred until the next maintenance window opens at %s",
+		start.UTC().Format(time.RFC3339))
+	h.log.Infof("Upgrade to version %s received outside of the maintenance windows, %s",
+		action.Data.Version, reason)
+
+	action.SetStartTime(start)
+	if err := h.queueDeferred(action, start); err != nil {
+		return err
+	}
+	if err := h.queue.Save(); err != nil {
+		h.log.Errorf("failed to persist deferred upgrade action id %s: %v", action.ID(), err)
+	}
+
+	det := details.NewDetails(action.Data.Version, details.StateScheduled, action.ID())
+	det.Metadata.ScheduledAt = &start
+	det.Metadata.Reason = reason
+	h.coord.SetUpgradeDetails(det)
+	return nil
+}
+
+// queueDeferred adds the deferred upgrade action to the queue with its schedule.
+// The next occurrence of a recurring upgrade, queued when the deferred one was
+// dequeued, is replaced by the deferred one.
+func (h *Upgrade) queueDeferred(action *fleetapi.ActionUpgrade, start time.Time) error {
+	h.queue.Cancel(action.ID())
+	if action.Schedule() == nil {
+		h.queue.Add(action, start.Unix())
+		return nil
+	}
+
+	schedule := queue.Schedule{
+		Recurrence: action.Schedule().Recurrence,
+		DependsOn:  action.Schedule().DependsOn,
+	}
+	err := h.queue.AddWithSchedule(action, start.Unix(), schedule)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, queue.ErrDependencyFailed) {
+		return fmt.Errorf("failed to defer upgrade action id %s: %w", action.ID(), err)
+	}
+	h.log.Warnf("Ignoring schedule of deferred upgrade action id %s: %v", action.ID(), err)
+	h.queue.Add(action, start.Unix())
+	return nil
+}
+
 // ackActions Acks all the actions in bkgActions, and deletes entries from bkgActions.
 // User is responsible for obtaining and releasing bkgMutex lock
 func (h *Upgrade) ackActions(ctx context.Context, ack acker.Acker) {
@@ -24,6 +24,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	noopacker "github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/noop"
+	"github.com/elastic/elastic-agent/internal/pkg/queue"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
@@ -38,6 +39,7 @@ type mockUpgradeManager struct {
 		skipVerifyOverride bool,
 		skipDefaultPgp bool,
 		pgpBytes ...string) (reexec.ShutdownCallbackFn, error)
+	NextMaintenanceWindowFn func(ts time.Time) (time.Time, bool)
 }
 
 func (u *mockUpgradeManager) Upgradeable() bool {
@@ -69,6 +71,13 @@ func (u *mockUpgradeManager) Upgrade(
 		pgpBytes...)
 }
 
+func (u *mockUpgradeManager) DryRun(_ context.Context, _ *upgrade.DryRunReport, _ string, _ string, _ bool, _ bool, _ ...string) {
+}
+
+func (u *mockUpgradeManager) RollbackTo(_ context.Context, _ string) (reexec.ShutdownCallbackFn, error) {
+	return nil, nil
+}
+
 func (u *mockUpgradeManager) Ack(_ context.Context, _ acker.Acker) error {
 	return nil
 }
@@ -81,6 +90,36 @@ func (u *mockUpgradeManager) MarkerWatcher() upgrade.MarkerWatcher {
 	return nil
 }
 
+func (u *mockUpgradeManager) NextMaintenanceWindow(ts time.Time) (time.Time, bool) {
+	if u.NextMaintenanceWindowFn == nil {
+		return ts, true
+	}
+	return u.NextMaintenanceWindowFn(ts)
+}
+
+type mockUpgradeQueue struct {
+	mock.Mock
+}
+
+func (q *mockUpgradeQueue) Add(action fleetapi.ScheduledAction, priority int64) {
+	q.Called(action, priority)
+}
+
+func (q *mockUpgradeQueue) AddWithSchedule(action fleetapi.ScheduledAction, priority int64, schedule queue.Schedule) error {
+	args := q.Called(action, priority, schedule)
+	return args.Error(0)
+}
+
+func (q *mockUpgradeQueue) Cancel(actionID string) int {
+	args := q.Called(actionID)
+	return args.Int(0)
+}
+
+func (q *mockUpgradeQueue) Save() error {
+	args := q.Called()
+	return args.Error(0)
+}
+
 func TestUpgradeHandler(t *testing.T) {
 	// Create a cancellable context that will shut down the coordinator after
 	// the test.
@@ -119,7 +158,7 @@ func TestUpgradeHandler(t *testing.T) {
 	//nolint:errcheck // We don't need the termination state of the Coordinator
 	go c.Run(ctx)
 
-	u := NewUpgrade(log, c)
+	u := NewUpgrade(log, c, nil)
 	a := fleetapi.ActionUpgrade{Data: fleetapi.ActionUpgradeData{
 		Version: "8.3.0", SourceURI: "http://localhost"}}
 	ack := noopacker.New()
@@ -178,7 +217,7 @@ func TestUpgradeHandlerSameVersion(t *testing.T) {
 	//nolint:errcheck // We don't need the termination state of the Coordinator
 	go c.Run(ctx)
 
-	u := NewUpgrade(log, c)
+	u := NewUpgrade(log, c, nil)
 	a := fleetapi.ActionUpgrade{Data: fleetapi.ActionUpgradeData{
 		Version: "8.3.0", SourceURI: "http://localhost"}}
 	ack := noopacker.New()
@@ -237,7 +276,7 @@ func TestDuplicateActionsHandled(t *testing.T) {
 	//nolint:errcheck // We don't need the termination state of the Coordinator
 	go c.Run(ctx)
 
-	u := NewUpgrade(log, c)
+	u := NewUpgrade(log, c, nil)
 	a1 := fleetapi.ActionUpgrade{
 		ActionID: "action-8.5-1",
 		Data: fleetapi.ActionUpgradeData{
@@ -331,7 +370,7 @@ func TestUpgradeHandlerNewVersion(t *testing.T) {
 	//nolint:errcheck // We don't need the termination state of the Coordinator
 	go c.Run(ctx)
 
-	u := NewUpgrade(log, c)
+	u := NewUpgrade(log, c, nil)
 	a1 := fleetapi.ActionUpgrade{
 		ActionID: "action-8.2",
 		Data: fleetapi.ActionUpgradeData{
@@ -381,3 +420,129 @@ func (f *fakeAcker) Commit(ctx context.Context) error {
 	args := f.Called(ctx)
 	return args.Error(0)
 }
+
+func TestUpgradeHandlerOutsideMaintenanceWindow(t *testing.T) {
+	// Create a cancellable context that will shut down the coordinator after
+	// the test.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log, _ := logger.New("", false)
+
+	agentInfo := &info.AgentInfo{}
+	windowStart := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	// Create and start the coordinator
+	c := coordinator.New(
+		log,
+		configuration.DefaultConfiguration(),
+		logger.DefaultLogLevel,
+		agentInfo,
+		component.RuntimeSpecs{},
+		nil,
+		&mockUpgradeManager{
+			UpgradeFn: func(
+				ctx context.Context,
+				version string,
+				sourceURI string,
+				action *fleetapi.ActionUpgrade,
+				details *details.Details,
+				skipVerifyOverride bool,
+				skipDefaultPgp bool,
+				pgpBytes ...string) (reexec.ShutdownCallbackFn, error) {
+
+				t.Error("upgrade must not run outside the maintenance windows")
+				return nil, nil
+			},
+			NextMaintenanceWindowFn: func(ts time.Time) (time.Time, bool) {
+				return windowStart, false
+			},
+		},
+		nil, nil, nil, nil, nil, false, nil, nil)
+	//nolint:errcheck // We don't need the termination state of the Coordinator
+	go c.Run(ctx)
+
+	a := &fleetapi.ActionUpgrade{
+		ActionID:   "upgrade-id",
+		ActionType: fleetapi.ActionTypeUpgrade,
+		Data: fleetapi.ActionUpgradeData{
+			Version: "8.3.0", SourceURI: "http://localhost"}}
+
+	q := &mockUpgradeQueue{}
+	q.On("Cancel", "upgrade-id").Return(0).Once()
+	q.On("Add", a, windowStart.Unix()).Once()
+	q.On("Save").Return(nil).Once()
+
+	u := NewUpgrade(log, c, q)
+	err := u.Handle(ctx, a, noopacker.New())
+	require.NoError(t, err)
+	q.AssertExpectations(t)
+
+	start, err := a.StartTime()
+	require.NoError(t, err)
+	require.True(t, windowStart.Equal(start), "action start time must be the start of the next maintenance window")
+
+	require.Eventually(t, func() bool {
+		upgradeDetails := c.State().UpgradeDetails
+		return upgradeDetails != nil &&
+			upgradeDetails.State == details.StateScheduled &&
+			upgradeDetails.ActionID == "upgrade-id" &&
+			upgradeDetails.Metadata.ScheduledAt != nil &&
+			upgradeDetails.Metadata.ScheduledAt.Equal(windowStart) &&
+			upgradeDetails.Metadata.Reason != ""
+	}, 5*time.Second, 10*time.Millisecond, "upgrade details must report the deferred upgrade")
+}
+
+// detailsRecorder records the upgrade details, the other methods of the coordinator must not be called.
+type detailsRecorder struct {
+	upgradeCoordinator
+	details *details.Details
+}
+
+func (r *detailsRecorder) SetUpgradeDetails(upgradeDetails *details.Details) {
+	r.details = upgradeDetails
+}
+
+func TestUpgradeHandlerDeferKeepsSchedule(t *testing.T) {
+	log, _ := logger.New("", false)
+	windowStart := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	a := &fleetapi.ActionUpgrade{
+		ActionID:   "upgrade-id",
+		ActionType: fleetapi.ActionTypeUpgrade,
+		ActionSchedule: &fleetapi.ActionSchedule{
+			Recurrence: "0 3 * * *",
+			DependsOn:  []string{"policy-id"},
+		},
+		Data: fleetapi.ActionUpgradeData{
+			Version: "8.3.0", SourceURI: "http://localhost"}}
+
+	t.Run("deferred occurrence replaces the next one", func(t *testing.T) {
+		q := &mockUpgradeQueue{}
+		q.On("Cancel", "upgrade-id").Return(1).Once()
+		q.On("AddWithSchedule", a, windowStart.Unix(), queue.Schedule{
+			Recurrence: "0 3 * * *",
+			DependsOn:  []string{"policy-id"},
+		}).Return(nil).Once()
+		q.On("Save").Return(nil).Once()
+
+		coord := &detailsRecorder{}
+		u := NewUpgrade(log, coord, q)
+		require.NoError(t, u.deferUpgrade(a, windowStart))
+		q.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+		q.AssertExpectations(t)
+		require.NotNil(t, coord.details)
+		require.Equal(t, details.StateScheduled, coord.details.State)
+	})
+
+	t.Run("failed dependency is returned", func(t *testing.T) {
+		q := &mockUpgradeQueue{}
+		q.On("Cancel", "upgrade-id").Return(0).Once()
+		q.On("AddWithSchedule", a, windowStart.Unix(), mock.Anything).Return(queue.ErrDependencyFailed).Once()
+
+		u := NewUpgrade(log, &detailsRecorder{}, q)
+		require.ErrorIs(t, u.deferUpgrade(a, windowStart), queue.ErrDependencyFailed)
+		q.AssertNotCalled(t, "Save")
+		q.AssertExpectations(t)
+	})
+}
@@ -24,14 +24,17 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	stateStore "github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/docker"
 	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/kubernetes"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/fleet"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/lazy"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/retrier"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/certrenewal"
 	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
 	otelmanager "github.com/elastic/elastic-agent/internal/pkg/otel/manager"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
@@ -68,6 +71,7 @@ func New(
 		log.With("error.message", err).Warnf("Error initializing version information: falling back to %s", release.Version())
 	}
 
+	modifiers = append(modifiers, component.WithUnprivileged(agentInfo.Unprivileged()))
 	platform, err := component.LoadPlatformDetail(modifiers...)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to gather system information: %w", err)
@@ -159,7 +163,7 @@ func New(
 			return nil, nil, nil, fmt.Errorf("failed to transform agent configuration into a map: %w", err)
 		}
 		discover := config.Discoverer(pathConfigFile, cfg.Settings.Path, paths.ExternalInputs(),
-			kubernetes.GetHintsInputConfigPath(log, rawCfgMap))
+			kubernetes.GetHintsInputConfigPath(log, rawCfgMap), docker.GetHintsInputConfigPath(log, rawCfgMap))
 		if !cfg.Settings.Reload.Enabled {
 			log.Debug("Reloading of configuration is off")
 			configMgr = newOnce(log, discover, loader)
@@ -191,7 +195,20 @@ func New(
 				InjectProxyEndpointModifier(),
 			)
 
-			client, err := fleetclient.NewAuthWithConfig(log, cfg.Fleet.AccessAPIKey, cfg.Fleet.Client)
+			fleetClientCfg := cfg.Fleet.Client
+			var certRenewer *certrenewal.Renewer
+			if cfg.Fleet.CertificateRenewal != nil && cfg.Fleet.CertificateRenewal.Enabled {
+				certRenewer, err = certrenewal.New(ctx, log, cfg.Fleet.CertificateRenewal, cfg.Fleet.AccessAPIKey, cfg.Fleet.Client,
+					vault.WithVaultPath(paths.AgentVaultPath()),
+					vault.WithUnprivileged(agentInfo.Unprivileged()))
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf("failed to create the fleet client certificate renewer: %w", err)
+				}
+				// use the certificate renewed by a previous run, if any
+				fleetClientCfg = certRenewer.ClientConfig(fleetClientCfg)
+			}
+
+			client, err := fleetclient.NewAuthWithConfig(log, cfg.Fleet.AccessAPIKey, fleetClientCfg)
 			if err != nil {
 				return nil, nil, nil, errors.New(err,
 					"fail to create API client",
@@ -208,12 +225,12 @@ func New(
 				return nil, nil, nil, fmt.Errorf("failed to create acker: %w", err)
 			}
 
-			retrier := retrier.New(fleetAcker, log)
+			retrier := retrier.New(fleetAcker, log, retrier.WithJournal(stateStorage))
 			batchedAcker := lazy.NewAcker(fleetAcker, log, lazy.WithRetrier(retrier))
 			actionAcker = stateStore.NewStateStoreActionAcker(batchedAcker, stateStorage)
 
 			// TODO: stop using global state
-			managed, err = newManagedConfigManager(ctx, log, agentInfo, cfg, store, runtime, fleetInitTimeout, paths.Top(), client, fleetAcker, actionAcker, retrier, stateStorage, upgrader)
+			managed, err = newManagedConfigManager(ctx, log, agentInfo, cfg, store, runtime, fleetInitTimeout, paths.Top(), client, fleetAcker, actionAcker, retrier, stateStorage, caps, certRenewer, upgrader)
 			if err != nil {
 				return nil, nil, nil, err
 			}
@@ -41,6 +41,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	fleetapiClient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/otel/translate"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -86,6 +87,12 @@ type UpgradeManager interface {
 	// Upgrade upgrades running agent.
 	Upgrade(ctx context.Context, version string, sourceURI string, action *fleetapi.ActionUpgrade, details *details.Details, skipVerifyOverride bool, skipDefaultPgp bool, pgpBytes ...string) (_ reexec.ShutdownCallbackFn, err error)
 
+	// DryRun runs the preconditions of an upgrade without upgrading, recording the outcome of each check in report.
+	DryRun(ctx context.Context, report *upgrade.DryRunReport, version string, sourceURI string, skipVerifyOverride bool, skipDefaultPgp bool, pgpBytes ...string)
+
+	// RollbackTo switches the agent to a retained install of version.
+	RollbackTo(ctx context.Context, version string) (_ reexec.ShutdownCallbackFn, err error)
+
 	// Ack is used on startup to check if the agent has upgraded and needs to send an ack for the action
 	Ack(ctx context.Context, acker acker.Acker) error
 
@@ -94,6 +101,10 @@ type UpgradeManager interface {
 
 	// MarkerWatcher returns a watcher for the upgrade marker.
 	MarkerWatcher() upgrade.MarkerWatcher
+
+	// NextMaintenanceWindow returns ts and true when upgrades are allowed at ts. Otherwise, it returns the time the
+	// next maintenance window opens and false.
+	NextMaintenanceWindow(ts time.Time) (time.Time, bool)
 }
 
 // MonitorManager provides an interface to perform the monitoring action for the agent.
@@ -247,6 +258,7 @@ type Coordinator struct {
 	monitorMgr MonitorManager
 
 	monitoringServerReloader configReloader
+	peerCacheServerReloader  configReloader
 
 	runtimeMgr RuntimeManager
 	configMgr  ConfigManager
@@ -341,6 +353,10 @@ type Coordinator struct {
 	// value that is sent to the runtime manager).
 	componentModel []component.Component
 
+	// Components configured to run in the otel runtime which can't be run there,
+	// keyed by component ID, with the reason they were kept on the process runtime.
+	otelUnsupportedComponents map[string]string
+
 	// Disabled for 8.8.0 release in order to limit the surface
 	// https://github.com/elastic/security-team/issues/6501
 
@@ -359,6 +375,10 @@ type Coordinator struct {
 	// run a ticker that checks to see if we have a new PID.
 	componentPIDTicker         *time.Ticker
 	componentPidRequiresUpdate *atomic.Bool
+
+	// timeDependentConditions is true when conditions of the inputs call time dependent functions,
+	// like now(), the component model is then regenerated on every tick of componentPIDTicker.
+	timeDependentConditions bool
 }
 
 // The channels Coordinator reads to receive updates from the various managers.
@@ -546,6 +566,11 @@ func (c *Coordinator) RegisterMonitoringServer(s configReloader) {
 	c.monitoringServerReloader = s
 }
 
+// RegisterPeerCacheServer registers the reloader of the server sharing the upgrade artifacts with other agents.
+func (c *Coordinator) RegisterPeerCacheServer(s configReloader) {
+	c.peerCacheServerReloader = s
+}
+
 // StateSubscribe returns a channel that reports changes in Coordinator state.
 //
 // bufferLen specifies how many state changes should be queued in addition to
@@ -755,6 +780,85 @@ func (c *Coordinator) Upgrade(ctx context.Context, version string, sourceURI str
 	return nil
 }
 
+// DryRunUpgrade runs the preconditions of the upgrade process without upgrading and returns the outcome of each
+// check. The running agent is left untouched.
+// Called from external goroutines.
+func (c *Coordinator) DryRunUpgrade(ctx context.Context, version string, sourceURI string, skipVerifyOverride bool, skipDefaultPgp bool, pgpBytes ...string) *upgrade.DryRunReport {
+	report := upgrade.NewDryRunReport(version)
+
+	if !c.upgradeMgr.Upgradeable() {
+		report.Fail(upgrade.DryRunCheckUpgradeable, ErrNotUpgradable)
+		return report
+	}
+	// the dry run shares the downloads directory with the upgrade
+	if c.State().State == agentclient.Upgrading {
+		report.Fail(upgrade.DryRunCheckUpgradeable, ErrUpgradeInProgress)
+		return report
+	}
+	report.Pass(upgrade.DryRunCheckUpgradeable, "")
+
+	if c.caps != nil && !c.caps.AllowUpgrade(version, sourceURI) {
+		report.Fail(upgrade.DryRunCheckCapabilities, ErrNotUpgradable)
+		return report
+	}
+	report.Pass(upgrade.DryRunCheckCapabilities, "")
+
+	c.upgradeMgr.DryRun(ctx, report, version, sourceURI, skipVerifyOverride, skipDefaultPgp, pgpBytes...)
+	return report
+}
+
+// Rollback switches the agent to a previously installed version retained in the data directory and restarts into
+// it. The action is acked before the restart as the retained install doesn't know about it.
+// Called from external goroutines.
+func (c *Coordinator) Rollback(ctx context.Context, version string, action fleetapi.Action) error {
+	if !c.upgradeMgr.Upgradeable() {
+		return ErrNotUpgradable
+	}
+	if c.caps != nil && !c.caps.AllowUpgrade(version, "") {
+		return ErrNotUpgradable
+	}
+	if c.State().State == agentclient.Upgrading {
+		return ErrUpgradeInProgress
+	}
+
+	cb, err := c.upgradeMgr.RollbackTo(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	if action != nil {
+		if err := c.upgradeMgr.AckAction(ctx, c.fleetAcker, action); err != nil {
+			c.logger.Warnf("failed to ack rollback action: %v", err)
+		}
+	}
+	if cb != nil {
+		c.ReExec(cb)
+	}
+	return nil
+}
+
+// RotateKey replaces the key the agent encrypts its fleet configuration and its state with and re-encrypts them
+// with the new key. It returns the version of the new key.
+// Called from external goroutines.
+func (c *Coordinator) RotateKey(ctx context.Context) (uint32, error) {
+	version, err := storage.RotateAgentKey(ctx)
+	if err != nil {
+		return 0, err
+	}
+	c.logger.Infof("Agent key rotated to version %d", version)
+	return version, nil
+}
+
+// NextUpgradeMaintenanceWindow returns ts and true when upgrades are allowed at ts. Otherwise, it returns the time
+// the next upgrade maintenance window opens and false.
+// Called from external goroutines.
+func (c *Coordinator) NextUpgradeMaintenanceWindow(ts time.Time) (time.Time, bool) {
+	if c.upgradeMgr == nil {
+		return ts, true
+	}
+	return c.upgradeMgr.NextMaintenanceWindow(ts)
+}
+
 func (c *Coordinator) logUpgradeDetails(details *details.Details) {
 	c.logger.Infow("updated upgrade details", "upgrade_details", details)
 }
@@ -1143,6 +1247,25 @@ func (c *Coordinator) DiagnosticHooks() diagnostics.Hooks {
 				return o
 			},
 		},
+		{
+			Name:        "runtime-facts",
+			Filename:    "runtime-facts.yaml",
+			Description: "host facts the runtime preventions of the component specifications are evaluated against, and the inputs they prevent",
+			ContentType: "application/yaml",
+			Hook: func(_ context.Context) []byte {
+				o, err := yaml.Marshal(struct {
+					Facts       map[string]interface{} `yaml:"facts"`
+					Preventions map[string]string      `yaml:"preventions,omitempty"`
+				}{
+					Facts:       component.RuntimeFacts(c.specs.Platform()),
+					Preventions: c.specs.Preventions(),
+				})
+				if err != nil {
+					return []byte(fmt.Sprintf("error: %q", err))
+				}
+				return o
+			},
+		},
 		{
 			Name:        "state",
 			Filename:    "state.yaml",
@@ -1235,6 +1358,23 @@ func (c *Coordinator) DiagnosticHooks() diagnostics.Hooks {
 				return o
 			},
 		},
+		{
+			Name:        "otel-unsupported-components",
+			Filename:    "otel-unsupported-components.yaml",
+			Description: "components configured for the otel runtime that run as processes instead, with the reason why",
+			ContentType: "application/yaml",
+			Hook: func(_ context.Context) []byte {
+				o, err := yaml.Marshal(struct {
+					Components map[string]string `yaml:"components"`
+				}{
+					Components: c.otelUnsupportedComponents,
+				})
+				if err != nil {
+					return []byte(fmt.Sprintf("error: %q", err))
+				}
+				return o
+			},
+		},
 		diagnostics.Hook{
 			Name:        "otel-merged",
 			Filename:    "otel-merged.yaml",
@@ -1393,12 +1533,12 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 	case c.heartbeatChan <- struct{}{}:
 
 	case <-c.componentPIDTicker.C:
-		// if we hit the ticker and we've got a new PID,
-		// reload the component model
-		if c.componentPidRequiresUpdate.Swap(false) {
+		// if we hit the ticker and we've got a new PID, or conditions
+		// whose result changes over time, reload the component model
+		if c.componentPidRequiresUpdate.Swap(false) || c.timeDependentConditions {
 			err := c.refreshComponentModel(ctx)
 			if err != nil {
-				err = fmt.Errorf("error refreshing component model for PID update: %w", err)
+				err = fmt.Errorf("error refreshing component model for PID update or time dependent conditions: %w", err)
 				c.setConfigManagerError(err)
 				c.logger.Errorf("%s", err)
 			}
@@ -1564,6 +1704,12 @@ func (c *Coordinator) generateAST(cfg *config.Config) (err error) {
 		}
 	}
 
+	if c.peerCacheServerReloader != nil {
+		if err := c.peerCacheServerReloader.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload peer cache server configuration: %w", err)
+		}
+	}
+
 	c.ast = rawAst
 	return nil
 }
@@ -1679,11 +1825,21 @@ func (c *Coordinator) updateManagersWithConfig(model *component.Model) {
 }
 
 // splitModelBetweenManager splits the model components between the runtime manager and the otel manager.
+// Components requesting the otel runtime that can't be run in the otel collector are sent to the runtime manager
+// instead, the reason is recorded for diagnostics.
 func (c *Coordinator) splitModelBetweenManagers(model *component.Model) (runtimeModel *component.Model, otelModel *component.Model) {
 	var otelComponents, runtimeComponents []component.Component
+	otelUnsupportedComponents := make(map[string]string)
 	for _, comp := range model.Components {
 		switch comp.RuntimeManager {
 		case component.OtelRuntimeManager:
+			if err := translate.VerifyComponentIsOtelSupported(&comp); err != nil {
+				c.logger.Warnf("component %s can't run in the otel runtime, running it as a process instead: %s", comp.ID, err)
+				otelUnsupportedComponents[comp.ID] = err.Error()
+				comp.RuntimeManager = component.ProcessRuntimeManager
+				runtimeComponents = append(runtimeComponents, comp)
+				continue
+			}
 			otelComponents = append(otelComponents, comp)
 		case component.ProcessRuntimeManager:
 			runtimeComponents = append(runtimeComponents, comp)
@@ -1700,6 +1856,7 @@ func (c *Coordinator) splitModelBetweenManagers(model *component.Model) (runtime
 		Components: runtimeComponents,
 		Signed:     model.Signed,
 	}
+	c.otelUnsupportedComponents = otelUnsupportedComponents
 	return
 }
 
@@ -1748,6 +1905,7 @@ func (c *Coordinator) generateComponentModel() (err error) {
 
 	// perform variable substitution for inputs
 	inputs, ok := transpiler.Lookup(ast, "inputs")
+	c.timeDependentConditions = ok && transpiler.HasTimeDependentConditions(inputs)
 	if ok {
 		renderedInputs, err := transpiler.RenderInputs(inputs, c.vars)
 		if err != nil {
@@ -1955,7 +2113,9 @@ func (c *Coordinator) checkAndLogUpdate(lastComponentModel []component.Component
 }
 
 // Filter any inputs and outputs in the generated component model
-// based on whether they're excluded by the capabilities config
+// based on whether they're excluded by the capabilities config.
+// Components whose binary is excluded are kept with an error, so the
+// denial is reported as a failure in the component state.
 func (c *Coordinator) filterByCapabilities(comps []component.Component) []component.Component {
 	if c.caps == nil {
 		// No active filters, return unchanged
@@ -1971,6 +2131,10 @@ func (c *Coordinator) filterByCapabilities(comps []component.Component) []compon
 			c.logger.Infof("Component '%v' with output type '%v' filtered by capabilities.yml", component.ID, component.OutputType)
 			continue
 		}
+		if component.InputSpec != nil && !c.caps.AllowComponent(component.InputSpec.BinaryName) {
+			c.logger.Infof("Component '%v' with binary '%v' denied by capabilities.yml", component.ID, component.InputSpec.BinaryName)
+			component.Err = fmt.Errorf("component binary '%s' is denied by capabilities.yml", component.InputSpec.BinaryName)
+		}
 		result = append(result, component)
 	}
 	return result
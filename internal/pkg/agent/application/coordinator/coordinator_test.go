@@ -1152,6 +1152,9 @@ type fakeUpgradeManager struct {
 	upgradeable   bool
 	upgradeErr    error // An error to return when Upgrade is called
 	upgradeCalled bool  // Set when Upgrade is called
+	dryRunCalled  bool  // Set when DryRun is called
+	rollbackErr   error // An error to return when RollbackTo is called
+	rollbackTo    string
 }
 
 func (f *fakeUpgradeManager) Upgradeable() bool {
@@ -1170,6 +1173,19 @@ func (f *fakeUpgradeManager) Upgrade(ctx context.Context, version string, source
 	return func() error { return nil }, nil
 }
 
+func (f *fakeUpgradeManager) DryRun(ctx context.Context, report *upgrade.DryRunReport, version string, sourceURI string, skipVerifyOverride bool, skipDefaultPgp bool, pgpBytes ...string) {
+	f.dryRunCalled = true
+	report.Pass(upgrade.DryRunCheckDownload, "")
+}
+
+func (f *fakeUpgradeManager) RollbackTo(ctx context.Context, version string) (_ reexec.ShutdownCallbackFn, err error) {
+	f.rollbackTo = version
+	if f.rollbackErr != nil {
+		return nil, f.rollbackErr
+	}
+	return func() error { return nil }, nil
+}
+
 func (f *fakeUpgradeManager) Ack(ctx context.Context, acker acker.Acker) error {
 	if acker != nil {
 		return acker.Ack(ctx, fleetapi.NewAction(fleetapi.ActionTypeUnknown))
@@ -1188,6 +1204,10 @@ func (f *fakeUpgradeManager) MarkerWatcher() upgrade.MarkerWatcher {
 	return nil
 }
 
+func (f *fakeUpgradeManager) NextMaintenanceWindow(ts time.Time) (time.Time, bool) {
+	return ts, true
+}
+
 type testMonitoringManager struct{}
 
 func newTestMonitoringMgr() *testMonitoringManager { return &testMonitoringManager{} }
@@ -53,6 +53,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/core/backoff"
 	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
@@ -1528,6 +1529,117 @@ func TestCoordinatorInitiatesUpgrade(t *testing.T) {
 	}
 }
 
+func TestCoordinatorDryRunUpgrade(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	overrideStateChan := make(chan *coordinatorOverrideState, 1)
+	newCoordinator := func(upgradeMgr *fakeUpgradeManager, state agentclient.State) *Coordinator {
+		return &Coordinator{
+			stateBroadcaster:  broadcaster.New(State{State: state}, 0, 0),
+			overrideStateChan: overrideStateChan,
+			upgradeMgr:        upgradeMgr,
+			logger:            logp.NewLogger("testing"),
+		}
+	}
+
+	t.Run("runs the upgrade manager dry run", func(t *testing.T) {
+		upgradeMgr := &fakeUpgradeManager{upgradeable: true}
+		report := newCoordinator(upgradeMgr, agentclient.Healthy).DryRunUpgrade(ctx, "1.2.3", "", false, false)
+
+		assert.True(t, upgradeMgr.dryRunCalled, "DryRunUpgrade should call upgrade manager DryRun")
+		assert.False(t, upgradeMgr.upgradeCalled, "DryRunUpgrade should not call upgrade manager Upgrade")
+		assert.True(t, report.Passed())
+		assert.Equal(t, []upgrade.DryRunCheck{
+			{Name: upgrade.DryRunCheckUpgradeable, Passed: true},
+			{Name: upgrade.DryRunCheckCapabilities, Passed: true},
+			{Name: upgrade.DryRunCheckDownload, Passed: true},
+		}, report.Checks)
+		assert.Empty(t, overrideStateChan, "DryRunUpgrade should not set an override state")
+	})
+
+	t.Run("not upgradeable", func(t *testing.T) {
+		upgradeMgr := &fakeUpgradeManager{upgradeable: false}
+		report := newCoordinator(upgradeMgr, agentclient.Healthy).DryRunUpgrade(ctx, "1.2.3", "", false, false)
+
+		assert.False(t, upgradeMgr.dryRunCalled)
+		assert.False(t, report.Passed())
+		assert.Equal(t, []upgrade.DryRunCheck{
+			{Name: upgrade.DryRunCheckUpgradeable, Passed: false, Message: ErrNotUpgradable.Error()},
+		}, report.Checks)
+	})
+
+	t.Run("upgrade in progress", func(t *testing.T) {
+		upgradeMgr := &fakeUpgradeManager{upgradeable: true}
+		report := newCoordinator(upgradeMgr, agentclient.Upgrading).DryRunUpgrade(ctx, "1.2.3", "", false, false)
+
+		assert.False(t, upgradeMgr.dryRunCalled)
+		assert.False(t, report.Passed())
+		assert.Equal(t, []upgrade.DryRunCheck{
+			{Name: upgrade.DryRunCheckUpgradeable, Passed: false, Message: ErrUpgradeInProgress.Error()},
+		}, report.Checks)
+	})
+}
+
+func TestCoordinatorRollback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	newCoordinator := func(upgradeMgr *fakeUpgradeManager, state agentclient.State, acker acker.Acker) *Coordinator {
+		return &Coordinator{
+			stateBroadcaster:  broadcaster.New(State{State: state}, 0, 0),
+			overrideStateChan: make(chan *coordinatorOverrideState, 1),
+			upgradeMgr:        upgradeMgr,
+			reexecMgr:         &fakeReExecManager{},
+			fleetAcker:        acker,
+			logger:            logp.NewLogger("testing"),
+		}
+	}
+
+	t.Run("acks the action and re-executes", func(t *testing.T) {
+		fleetAcker := &fakeActionAcker{}
+		fleetAcker.On("Ack", mock.Anything, mock.Anything).Return(nil).Once()
+		action := &fleetapi.ActionRollback{ActionID: "rollback-1", Data: fleetapi.ActionRollbackData{Version: "1.2.3"}}
+
+		upgradeMgr := &fakeUpgradeManager{upgradeable: true}
+		coord := newCoordinator(upgradeMgr, agentclient.Healthy, fleetAcker)
+		require.NoError(t, coord.Rollback(ctx, "1.2.3", action))
+
+		assert.Equal(t, "1.2.3", upgradeMgr.rollbackTo)
+		fleetAcker.AssertExpectations(t)
+		overrideState := <-coord.overrideStateChan
+		require.NotNil(t, overrideState, "Rollback should set an override state")
+		assert.Equal(t, agentclient.Stopping, overrideState.state)
+	})
+
+	t.Run("not upgradeable", func(t *testing.T) {
+		upgradeMgr := &fakeUpgradeManager{upgradeable: false}
+		err := newCoordinator(upgradeMgr, agentclient.Healthy, nil).Rollback(ctx, "1.2.3", nil)
+
+		assert.ErrorIs(t, err, ErrNotUpgradable)
+		assert.Empty(t, upgradeMgr.rollbackTo)
+	})
+
+	t.Run("upgrade in progress", func(t *testing.T) {
+		upgradeMgr := &fakeUpgradeManager{upgradeable: true}
+		err := newCoordinator(upgradeMgr, agentclient.Upgrading, nil).Rollback(ctx, "1.2.3", nil)
+
+		assert.ErrorIs(t, err, ErrUpgradeInProgress)
+		assert.Empty(t, upgradeMgr.rollbackTo)
+	})
+
+	t.Run("rollback fails", func(t *testing.T) {
+		fleetAcker := &fakeActionAcker{}
+		upgradeMgr := &fakeUpgradeManager{upgradeable: true, rollbackErr: upgrade.ErrNoRetainedInstall}
+		coord := newCoordinator(upgradeMgr, agentclient.Healthy, fleetAcker)
+		err := coord.Rollback(ctx, "1.2.3", &fleetapi.ActionRollback{ActionID: "rollback-1"})
+
+		assert.ErrorIs(t, err, upgrade.ErrNoRetainedInstall)
+		fleetAcker.AssertNotCalled(t, "Ack", mock.Anything, mock.Anything)
+		assert.Empty(t, coord.overrideStateChan, "a failed Rollback should not set an override state")
+	})
+}
+
 func TestCoordinator_UnmanagedAgent_SkipsMigrate(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
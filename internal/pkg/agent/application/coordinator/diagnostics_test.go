@@ -17,6 +17,7 @@ import (
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent-libs/logp"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
@@ -43,8 +44,10 @@ func TestCoordinatorExpectedDiagnosticHooks(t *testing.T) {
 		"computed-config",
 		"components-expected",
 		"components-actual",
+		"runtime-facts",
 		"state",
 		"otel",
+		"otel-unsupported-components",
 		"otel-merged",
 	}
 
@@ -459,6 +462,116 @@ components:
 	assert.YAMLEq(t, expected, string(result), "components-actual diagnostic returned unexpected value")
 }
 
+func TestDiagnosticOtelUnsupportedComponents(t *testing.T) {
+	// Split a model with components requesting the otel runtime and make sure
+	// the ones that can't run there are reported by the diagnostic
+	agentbeatSpec := func(beatName string) *component.InputRuntimeSpec {
+		return &component.InputRuntimeSpec{
+			BinaryName: "agentbeat",
+			Spec: component.InputSpec{
+				Command: &component.CommandSpec{
+					Args: []string{beatName},
+				},
+			},
+		}
+	}
+	model := &component.Model{
+		Components: []component.Component{
+			{
+				ID:             "filestream-default",
+				InputType:      "filestream",
+				OutputType:     "elasticsearch",
+				RuntimeManager: component.OtelRuntimeManager,
+				InputSpec:      agentbeatSpec("filebeat"),
+			},
+			{
+				ID:             "synthetics/http-default",
+				InputType:      "synthetics/http",
+				OutputType:     "elasticsearch",
+				RuntimeManager: component.OtelRuntimeManager,
+				InputSpec:      agentbeatSpec("heartbeat"),
+			},
+			{
+				ID:             "filestream-redis",
+				InputType:      "filestream",
+				OutputType:     "redis",
+				RuntimeManager: component.OtelRuntimeManager,
+				InputSpec:      agentbeatSpec("filebeat"),
+			},
+		},
+	}
+
+	expected := `
+components:
+  synthetics/http-default: "unsupported input type: synthetics/http, no receiver is available for heartbeat"
+  filestream-redis: "unsupported output type: redis"
+`
+
+	coord := &Coordinator{logger: logp.NewLogger("testing")}
+	runtimeModel, otelModel := coord.splitModelBetweenManagers(model)
+	require.Len(t, otelModel.Components, 1)
+	assert.Equal(t, "filestream-default", otelModel.Components[0].ID)
+	require.Len(t, runtimeModel.Components, 2)
+	for _, comp := range runtimeModel.Components {
+		assert.Equal(t, component.ProcessRuntimeManager, comp.RuntimeManager)
+	}
+
+	hook, ok := diagnosticHooksMap(coord)["otel-unsupported-components"]
+	require.True(t, ok, "diagnostic hooks should have an entry for otel-unsupported-components")
+
+	result := hook.Hook(context.Background())
+	assert.YAMLEq(t, expected, string(result), "otel-unsupported-components diagnostic returned unexpected value")
+}
+
+func TestDiagnosticRuntimeFacts(t *testing.T) {
+	// Create runtime specs with an input that is prevented by its runtime checks
+	// and make sure the runtime-facts hook reports both the facts and the prevention.
+	platform := component.PlatformDetail{
+		Platform: component.Platform{OS: component.Linux, Arch: component.AMD64, GOOS: component.Linux},
+		Family:   "redhat",
+		Major:    8,
+		Host: component.HostDetail{
+			KernelVersion: "4.18.0",
+			KernelMajor:   4,
+			KernelMinor:   18,
+			CgroupVersion: 1,
+		},
+		User: component.UserDetail{Root: true},
+	}
+	specs, err := component.NewRuntimeSpecs(platform, []component.InputRuntimeSpec{
+		{
+			InputType:  "filestream",
+			BinaryName: "filebeat",
+			Spec: component.InputSpec{
+				Name:      "filestream",
+				Platforms: []string{"linux/amd64"},
+				Runtime: component.RuntimeSpec{
+					Preventions: []component.RuntimePreventionSpec{
+						{Condition: "${runtime.cgroup_version} == 1", Message: "cgroup v2 is required"},
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	coord := &Coordinator{specs: specs}
+	hook, ok := diagnosticHooksMap(coord)["runtime-facts"]
+	require.True(t, ok, "diagnostic hooks should have an entry for runtime-facts")
+
+	var result struct {
+		Facts       map[string]map[string]interface{} `yaml:"facts"`
+		Preventions map[string]string                 `yaml:"preventions"`
+	}
+	require.NoError(t, yaml.Unmarshal(hook.Hook(context.Background()), &result))
+	assert.Equal(t, "redhat", result.Facts["runtime"]["family"])
+	assert.Equal(t, "4.18.0", result.Facts["runtime"]["kernel_version"])
+	assert.Equal(t, 1, result.Facts["runtime"]["cgroup_version"])
+	assert.Equal(t, true, result.Facts["user"]["root"])
+	assert.Equal(t, false, result.Facts["user"]["unprivileged"])
+	assert.Equal(t, map[string]string{"filestream": "cgroup v2 is required"}, result.Preventions)
+}
+
 // TestDiagnosticState creates a coordinator with a test state and verify that
 // the state diagnostic reports it.
 func TestDiagnosticState(t *testing.T) {
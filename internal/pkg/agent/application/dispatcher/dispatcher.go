@@ -16,8 +16,10 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/internal/pkg/queue"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -25,11 +27,18 @@ type actionHandlers map[reflect.Type]actions.Handler
 
 type priorityQueue interface {
 	Add(fleetapi.ScheduledAction, int64)
+	AddWithSchedule(fleetapi.ScheduledAction, int64, queue.Schedule) error
 	DequeueActions() []fleetapi.ScheduledAction
 	CancelType(string) int
 	Save() error
 }
 
+// dependencyTracker is implemented by the queues holding actions that depend on other actions.
+type dependencyTracker interface {
+	Complete(string)
+	Fail(string) []fleetapi.ScheduledAction
+}
+
 // Dispatcher processes actions coming from fleet api.
 type Dispatcher interface {
 	Dispatch(context.Context, details.Observer, acker.Acker, ...fleetapi.Action)
@@ -45,12 +54,14 @@ type ActionDispatcher struct {
 	rt       *retryConfig
 	errCh    chan error
 	topPath  string
+	caps     capabilities.Capabilities
 
 	lastUpgradeDetails *details.Details
 }
 
 // New creates a new action dispatcher.
-func New(log *logger.Logger, topPath string, def actions.Handler, queue priorityQueue) (*ActionDispatcher, error) {
+// Actions denied by caps are acknowledged as failed without being handled, caps may be nil.
+func New(log *logger.Logger, topPath string, def actions.Handler, queue priorityQueue, caps capabilities.Capabilities) (*ActionDispatcher, error) {
 	var err error
 	if log == nil {
 		log, err = logger.New("action_dispatcher", false)
@@ -71,6 +82,7 @@ func New(log *logger.Logger, topPath string, def actions.Handler, queue priority
 		rt:       defaultRetryConfig(),
 		errCh:    make(chan error),
 		topPath:  topPath,
+		caps:     caps,
 	}, nil
 }
 
@@ -114,19 +126,30 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 		span.End()
 	}()
 
+	// denied actions must not alter the queue nor the upgrade details
+	actions, reportedErr := ad.filterDeniedActions(ctx, actions, acker)
+
 	ad.removeQueuedUpgrades(actions)
 
 	// set scheduled action as soon as it's received
 	// report it before the scheduled actions go to the queue
 	ad.reportNextScheduledUpgrade(actions, detailsSetter, ad.log)
 
-	actions = ad.queueScheduledActions(actions)
+	actions = ad.queueScheduledActions(ctx, actions, acker)
 	actions = ad.dispatchCancelActions(ctx, actions, acker)
 	queued, expired := ad.gatherQueuedActions(time.Now().UTC())
 	ad.log.Debugf("Gathered %d actions from queue, %d actions expired", len(queued), len(expired))
 	ad.log.Debugf("Expired actions: %v", expired)
 
 	ad.handleExpired(expired, detailsSetter)
+	for _, e := range expired {
+		ad.failDependents(ctx, e.ID(), acker)
+	}
+	// the capabilities may have changed since the actions were queued
+	queued, queuedErr := ad.filterDeniedActions(ctx, queued, acker)
+	if queuedErr != nil {
+		reportedErr = queuedErr
+	}
 	actions = append(actions, queued...)
 
 	if err := ad.queue.Save(); err != nil {
@@ -135,6 +158,13 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 
 	if len(actions) == 0 {
 		ad.log.Debug("No action to dispatch")
+		// acknowledge the actions that failed without being dispatched
+		if err = acker.Commit(ctx); err != nil {
+			ad.log.Errorf("Failed to commit the failed actions: %v", err)
+		}
+		if reportedErr != nil {
+			ad.errCh <- reportedErr
+		}
 		return
 	}
 
@@ -144,7 +174,6 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 		strings.Join(detectTypes(actions), ", "),
 	)
 
-	var reportedErr error
 	for _, action := range actions {
 		if err = ctx.Err(); err != nil {
 			ad.errCh <- err
@@ -159,6 +188,7 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 				continue
 			}
 			ad.log.Errorf("Failed to dispatch action id %q of type %q, error: %+v", action.ID(), action.Type(), err)
+			ad.failDependents(ctx, action.ID(), acker)
 			reportedErr = err
 			continue
 		}
@@ -175,6 +205,7 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 }
 
 func (ad *ActionDispatcher) dispatchAction(ctx context.Context, a fleetapi.Action, acker acker.Acker) error {
+	acker = ad.Acker(acker)
 	handler, found := ad.handlers[ad.key(a)]
 	if !found {
 		return ad.def.Handle(ctx, a, acker)
@@ -183,6 +214,111 @@ func (ad *ActionDispatcher) dispatchAction(ctx context.Context, a fleetapi.Actio
 	return handler.Handle(ctx, a, acker)
 }
 
+// Acker returns an acker recording the outcome of the actions it acknowledges in the queue, releasing or failing
+// the queued actions depending on them. Handlers acknowledge the actions once they ran, which may be after Handle
+// returned or after a restart, the outcome is persisted right away so it isn't lost by a re-exec.
+func (ad *ActionDispatcher) Acker(ack acker.Acker) acker.Acker {
+	if _, ok := ad.queue.(dependencyTracker); !ok {
+		return ack
+	}
+	if _, ok := ack.(*trackingAcker); ok {
+		return ack
+	}
+	return &trackingAcker{Acker: ack, ad: ad}
+}
+
+// trackingAcker records the outcome of the acknowledged actions in the queue of the dispatcher.
+type trackingAcker struct {
+	acker.Acker
+	ad *ActionDispatcher
+}
+
+func (a *trackingAcker) Ack(ctx context.Context, action fleetapi.Action) error {
+	err := a.Acker.Ack(ctx, action)
+	a.ad.recordOutcome(ctx, action, a.Acker)
+	return err
+}
+
+// recordOutcome marks the acknowledged action as completed, or as failed when it's acknowledged with an error, and
+// persists the queue.
+func (ad *ActionDispatcher) recordOutcome(ctx context.Context, action fleetapi.Action, acker acker.Acker) {
+	tracker, ok := ad.queue.(dependencyTracker)
+	if !ok {
+		return
+	}
+	if event := action.AckEvent(); event.Error != "" {
+		ad.failDependents(ctx, action.ID(), acker)
+	} else {
+		tracker.Complete(action.ID())
+	}
+	if err := ad.queue.Save(); err != nil {
+		ad.log.Errorf("failed to persist the outcome of action id %s: %v", action.ID(), err)
+	}
+}
+
+// filterDeniedActions acknowledges the actions denied by the capabilities as failed and returns the allowed ones,
+// along with the error of the last denied action.
+func (ad *ActionDispatcher) filterDeniedActions(ctx context.Context, input []fleetapi.Action, acker acker.Acker) ([]fleetapi.Action, error) {
+	var deniedErr error
+	actions := make([]fleetapi.Action, 0, len(input))
+	for _, action := range input {
+		err := ad.checkCapabilities(action)
+		if err == nil {
+			actions = append(actions, action)
+			continue
+		}
+		ad.log.Errorf("Denied action id %q of type %q: %v", action.ID(), action.Type(), err)
+		if ackErr := acker.Ack(ctx, &failedAction{Action: action, err: err}); ackErr != nil {
+			ad.log.Errorf("Failed to acknowledge denied action id %q: %v", action.ID(), ackErr)
+		}
+		ad.failDependents(ctx, action.ID(), acker)
+		deniedErr = err
+	}
+	return actions, deniedErr
+}
+
+// failDependents marks the action as failed in the queue and acknowledges the queued actions depending on it as
+// failed, they won't run anymore.
+func (ad *ActionDispatcher) failDependents(ctx context.Context, actionID string, acker acker.Acker) {
+	tracker, ok := ad.queue.(dependencyTracker)
+	if !ok {
+		return
+	}
+	for _, dependent := range tracker.Fail(actionID) {
+		err := fmt.Errorf("action %s it depends on did not complete: %w", actionID, queue.ErrDependencyFailed)
+		ad.log.Errorf("Removed action id %q of type %q from the queue: %v", dependent.ID(), dependent.Type(), err)
+		if ackErr := acker.Ack(ctx, &failedAction{Action: dependent, err: err}); ackErr != nil {
+			ad.log.Errorf("Failed to acknowledge failed action id %q: %v", dependent.ID(), ackErr)
+		}
+	}
+}
+
+// checkCapabilities returns an error if the action is denied by the capabilities.
+func (ad *ActionDispatcher) checkCapabilities(a fleetapi.Action) error {
+	if ad.caps == nil {
+		return nil
+	}
+	if !ad.caps.AllowAction(a.Type()) {
+		return fmt.Errorf("action of type %s is denied by capabilities.yml", a.Type())
+	}
+	if settings, ok := a.(*fleetapi.ActionSettings); ok && settings.Data.LogLevel != "" && !ad.caps.AllowLogLevel(settings.Data.LogLevel) {
+		return fmt.Errorf("log level %s is denied by capabilities.yml", settings.Data.LogLevel)
+	}
+	return nil
+}
+
+// failedAction wraps an action that won't be handled, so it's acknowledged with an error.
+type failedAction struct {
+	fleetapi.Action
+	err error
+}
+
+func (a *failedAction) AckEvent() fleetapi.AckEvent {
+	event := a.Action.AckEvent()
+	event.Error = a.err.Error()
+	return event
+}
+
 func detectTypes(actions []fleetapi.Action) []string {
 	str := make([]string, len(actions))
 	for idx, action := range actions {
@@ -193,19 +329,28 @@ func detectTypes(actions []fleetapi.Action) []string {
 
 // queueScheduledActions will add any action in actions with a valid start time to the queue and return the rest.
 // start time to current time comparisons are purposefully not made in case of cancel actions.
-func (ad *ActionDispatcher) queueScheduledActions(input []fleetapi.Action) []fleetapi.Action {
+// Actions depending on a failed action are acknowledged as failed.
+func (ad *ActionDispatcher) queueScheduledActions(ctx context.Context, input []fleetapi.Action, acker acker.Acker) []fleetapi.Action {
 	actions := make([]fleetapi.Action, 0, len(input))
 	for _, action := range input {
 		sAction, ok := action.(fleetapi.ScheduledAction)
 		if ok {
 			start, err := sAction.StartTime()
 			if err != nil {
-				ad.log.Warnf("Skipping addition to action-queue, issue gathering start time from action id %s: %v", sAction.ID(), err)
+				if !errors.Is(err, fleetapi.ErrNoStartTime) {
+					ad.log.Warnf("Skipping addition to action-queue, issue gathering start time from action id %s: %v", sAction.ID(), err)
+				}
 				actions = append(actions, action)
 				continue
 			}
 			ad.log.Debugf("Adding action id: %s to queue.", sAction.ID())
-			ad.queue.Add(sAction, start.Unix())
+			if err := ad.queueAction(sAction, start); err != nil {
+				ad.log.Errorf("Failed to queue action id %q of type %q: %v", action.ID(), action.Type(), err)
+				if ackErr := acker.Ack(ctx, &failedAction{Action: action, err: err}); ackErr != nil {
+					ad.log.Errorf("Failed to acknowledge failed action id %q: %v", action.ID(), ackErr)
+				}
+				ad.failDependents(ctx, action.ID(), acker)
+			}
 			continue
 		}
 		actions = append(actions, action)
@@ -213,6 +358,24 @@ func (ad *ActionDispatcher) queueScheduledActions(input []fleetapi.Action) []fle
 	return actions
 }
 
+// queueAction adds the action to the queue, using the action schedule if it has one.
+// An error is returned if the action depends on a failed action, it's not queued then.
+func (ad *ActionDispatcher) queueAction(action fleetapi.ScheduledAction, start time.Time) error {
+	if sAction, ok := action.(fleetapi.SchedulableAction); ok && sAction.Schedule() != nil {
+		schedule := queue.Schedule{
+			Recurrence: sAction.Schedule().Recurrence,
+			DependsOn:  sAction.Schedule().DependsOn,
+		}
+		err := ad.queue.AddWithSchedule(action, start.Unix(), schedule)
+		if err == nil || errors.Is(err, queue.ErrDependencyFailed) {
+			return err
+		}
+		ad.log.Warnf("Ignoring schedule of action id %s: %v", action.ID(), err)
+	}
+	ad.queue.Add(action, start.Unix())
+	return nil
+}
+
 // dispatchCancelActions will separate and dispatch any cancel actions from the actions list and return the rest of the list.
 // cancel actions are dispatched seperatly as they may remove items from the queue.
 func (ad *ActionDispatcher) dispatchCancelActions(ctx context.Context, actions []fleetapi.Action, acker acker.Acker) []fleetapi.Action {
@@ -262,6 +425,7 @@ func (ad *ActionDispatcher) scheduleRetry(ctx context.Context, action fleetapi.R
 	if err != nil {
 		ad.log.Errorf("No more retries for action id %s: %v", action.ID(), err)
 		action.SetRetryAttempt(-1)
+		ad.failDependents(ctx, action.ID(), acker)
 		if err := acker.Ack(ctx, action); err != nil {
 			ad.log.Errorf("Unable to ack action failure (id %s) to fleet-server: %v", action.ID(), err)
 			return
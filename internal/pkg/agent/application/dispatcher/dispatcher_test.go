@@ -7,6 +7,8 @@ package dispatcher
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,9 +19,11 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/noop"
+	queuepkg "github.com/elastic/elastic-agent/internal/pkg/queue"
 	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
 )
 
@@ -96,6 +100,11 @@ func (m *mockQueue) Add(action fleetapi.ScheduledAction, n int64) {
 	m.Called(action, n)
 }
 
+func (m *mockQueue) AddWithSchedule(action fleetapi.ScheduledAction, n int64, schedule queuepkg.Schedule) error {
+	args := m.Called(action, n, schedule)
+	return args.Error(0)
+}
+
 func (m *mockQueue) DequeueActions() []fleetapi.ScheduledAction {
 	args := m.Called()
 	return args.Get(0).([]fleetapi.ScheduledAction)
@@ -111,6 +120,19 @@ func (m *mockQueue) Save() error {
 	return args.Error(0)
 }
 
+type mockTrackingQueue struct {
+	mockQueue
+}
+
+func (m *mockTrackingQueue) Complete(actionID string) {
+	m.Called(actionID)
+}
+
+func (m *mockTrackingQueue) Fail(actionID string) []fleetapi.ScheduledAction {
+	args := m.Called(actionID)
+	return args.Get(0).([]fleetapi.ScheduledAction)
+}
+
 func TestActionDispatcher(t *testing.T) {
 	detailsSetter := func(upgradeDetails *details.Details) {}
 	ack := noop.New()
@@ -121,7 +143,7 @@ func TestActionDispatcher(t *testing.T) {
 		queue := &mockQueue{}
 		queue.On("Save").Return(nil).Once()
 		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		success1 := &mockHandler{}
@@ -163,7 +185,7 @@ func TestActionDispatcher(t *testing.T) {
 		queue := &mockQueue{}
 		queue.On("Save").Return(nil).Once()
 		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		action := &mockOtherAction{}
@@ -187,7 +209,7 @@ func TestActionDispatcher(t *testing.T) {
 
 		def := &mockHandler{}
 		queue := &mockQueue{}
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		err = d.Register(&mockAction{}, success1)
@@ -207,7 +229,7 @@ func TestActionDispatcher(t *testing.T) {
 		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
 		queue.On("Add", mock.Anything, mock.Anything).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 		err = d.Register(&mockAction{}, def)
 		require.NoError(t, err)
@@ -230,6 +252,49 @@ func TestActionDispatcher(t *testing.T) {
 		queue.AssertExpectations(t)
 	})
 
+	t.Run("Dispatched action is queued with its schedule", func(t *testing.T) {
+		def := &mockHandler{}
+		start := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		action := &fleetapi.ActionUpgrade{
+			ActionID:        "upgrade",
+			ActionType:      fleetapi.ActionTypeUpgrade,
+			ActionStartTime: start.Format(time.RFC3339),
+			ActionSchedule: &fleetapi.ActionSchedule{
+				Recurrence: "0 2 * * 6",
+				DependsOn:  []string{"other"},
+			},
+		}
+
+		queue := &mockQueue{}
+		queue.On("Save").Return(nil).Once()
+		queue.On("CancelType", fleetapi.ActionTypeUpgrade).Return(0).Once()
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+		queue.On("AddWithSchedule", action, start.Unix(), queuepkg.Schedule{
+			Recurrence: "0 2 * * 6",
+			DependsOn:  []string{"other"},
+		}).Return(nil).Once()
+
+		d, err := New(nil, t.TempDir(), def, queue, nil)
+		require.NoError(t, err)
+
+		dispatchCtx, cancelFn := context.WithCancel(context.Background())
+		defer cancelFn()
+		dispatchCompleted := make(chan struct{})
+		go func() {
+			d.Dispatch(dispatchCtx, detailsSetter, ack, action)
+			dispatchCompleted <- struct{}{}
+		}()
+
+		select {
+		case err := <-d.Errors():
+			t.Fatalf("Unexpected error: %v", err)
+		case <-dispatchCompleted:
+			// OK, the action is queued so there is nothing to dispatch
+		}
+		def.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything, mock.Anything)
+		queue.AssertExpectations(t)
+	})
+
 	t.Run("Cancel queued action", func(t *testing.T) {
 		queue := &mockQueue{}
 		queue.On("Save").Return(nil).Once()
@@ -245,7 +310,7 @@ func TestActionDispatcher(t *testing.T) {
 		def := &mockHandler{}
 		def.On("Handle", dispatchCtx, action, ack).Return(nil).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		dispatchCompleted := make(chan struct{})
@@ -279,7 +344,7 @@ func TestActionDispatcher(t *testing.T) {
 		queue.On("Save").Return(nil).Once()
 		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{action1}).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 		err = d.Register(&mockAction{}, def)
 		require.NoError(t, err)
@@ -306,7 +371,7 @@ func TestActionDispatcher(t *testing.T) {
 		queue.On("Save").Return(nil).Once()
 		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 		err = d.Register(&mockAction{}, def)
 		require.NoError(t, err)
@@ -332,7 +397,7 @@ func TestActionDispatcher(t *testing.T) {
 		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
 		queue.On("Add", mock.Anything, mock.Anything).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 		err = d.Register(&mockRetryableAction{}, def)
 		require.NoError(t, err)
@@ -377,7 +442,7 @@ func TestActionDispatcher(t *testing.T) {
 		def.On("Handle", dispatchCtx, action1, ack).Return(errors.New("first error")).Once()
 		def.On("Handle", dispatchCtx, action2, ack).Return(errors.New("second error")).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		dispatchCompleted := make(chan struct{})
@@ -415,7 +480,7 @@ func TestActionDispatcher(t *testing.T) {
 		queue.On("Save").Return(nil).Times(2)
 		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Times(2)
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 		err = d.Register(&mockAction{}, def)
 		require.NoError(t, err)
@@ -467,7 +532,7 @@ func TestActionDispatcher(t *testing.T) {
 		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
 		queue.On("CancelType", mock.Anything).Return(1).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		var gotDetails *details.Details
@@ -517,7 +582,7 @@ func TestActionDispatcher(t *testing.T) {
 			Once()
 		queue.On("CancelType", mock.Anything).Return(1).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		var gotDetails *details.Details
@@ -559,7 +624,7 @@ func TestActionDispatcher(t *testing.T) {
 			Once()
 		queue.On("CancelType", mock.Anything).Return(1).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		wantDetail := &details.Details{
@@ -601,7 +666,7 @@ func TestActionDispatcher(t *testing.T) {
 			Once()
 		queue.On("CancelType", mock.Anything).Return(1).Once()
 
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		var gotDetails *details.Details
@@ -631,7 +696,7 @@ func Test_ActionDispatcher_scheduleRetry(t *testing.T) {
 
 	t.Run("no more attmpts", func(t *testing.T) {
 		queue := &mockQueue{}
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		action := &mockRetryableAction{}
@@ -648,7 +713,7 @@ func Test_ActionDispatcher_scheduleRetry(t *testing.T) {
 		queue := &mockQueue{}
 		queue.On("Save").Return(nil).Once()
 		queue.On("Add", mock.Anything, mock.Anything).Once()
-		d, err := New(nil, t.TempDir(), def, queue)
+		d, err := New(nil, t.TempDir(), def, queue, nil)
 		require.NoError(t, err)
 
 		action := &mockRetryableAction{}
@@ -747,7 +812,7 @@ func TestReportNextScheduledUpgrade(t *testing.T) {
 	def := &mockHandler{}
 
 	queue := &mockQueue{}
-	d, err := New(nil, t.TempDir(), def, queue)
+	d, err := New(nil, t.TempDir(), def, queue, nil)
 	require.NoError(t, err, "could not create dispatcher")
 
 	for name, test := range cases {
@@ -773,3 +838,294 @@ func TestReportNextScheduledUpgrade(t *testing.T) {
 		})
 	}
 }
+
+type recordingAcker struct {
+	acked []fleetapi.AckEvent
+}
+
+func (a *recordingAcker) Ack(_ context.Context, action fleetapi.Action) error {
+	a.acked = append(a.acked, action.AckEvent())
+	return nil
+}
+
+func (a *recordingAcker) Commit(_ context.Context) error {
+	return nil
+}
+
+func TestActionDispatcherCapabilities(t *testing.T) {
+	detailsSetter := func(upgradeDetails *details.Details) {}
+	caps, err := capabilities.Load(strings.NewReader(`
+version: 0.1.0
+capabilities:
+  - rule: deny
+    action: UNENROLL
+  - rule: deny
+    log_level: debug
+`), nil)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		action        fleetapi.Action
+		expectHandled bool
+		expectedErr   string
+	}{
+		"allowed action is dispatched": {
+			action:        &fleetapi.ActionPolicyChange{ActionID: "policy", ActionType: fleetapi.ActionTypePolicyChange},
+			expectHandled: true,
+		},
+		"denied action is acked with an error": {
+			action:      &fleetapi.ActionUnenroll{ActionID: "unenroll", ActionType: fleetapi.ActionTypeUnenroll},
+			expectedErr: "action of type UNENROLL is denied by capabilities.yml",
+		},
+		"allowed log level is dispatched": {
+			action: &fleetapi.ActionSettings{ActionID: "settings", ActionType: fleetapi.ActionTypeSettings,
+				Data: fleetapi.ActionSettingsData{LogLevel: "info"}},
+			expectHandled: true,
+		},
+		"denied log level is acked with an error": {
+			action: &fleetapi.ActionSettings{ActionID: "settings", ActionType: fleetapi.ActionTypeSettings,
+				Data: fleetapi.ActionSettingsData{LogLevel: "debug"}},
+			expectedErr: "log level debug is denied by capabilities.yml",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			def := &mockHandler{}
+			if tc.expectHandled {
+				def.On("Handle", mock.Anything, tc.action, mock.Anything).Return(nil).Once()
+			}
+			queue := &mockQueue{}
+			queue.On("Save").Return(nil).Once()
+			queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+			d, err := New(nil, t.TempDir(), def, queue, caps)
+			require.NoError(t, err)
+
+			ack := &recordingAcker{}
+			go d.Dispatch(context.Background(), detailsSetter, ack, tc.action)
+			err = <-d.Errors()
+
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+				assert.Empty(t, ack.acked)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+				require.Len(t, ack.acked, 1)
+				assert.Equal(t, tc.action.ID(), ack.acked[0].ActionID)
+				assert.Equal(t, tc.expectedErr, ack.acked[0].Error)
+				def.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything, mock.Anything)
+			}
+			def.AssertExpectations(t)
+		})
+	}
+}
+
+func TestActionDispatcherDeniedActionsDontAlterQueue(t *testing.T) {
+	caps, err := capabilities.Load(strings.NewReader(`
+version: 0.1.0
+capabilities:
+  - rule: deny
+    action: UPGRADE
+  - rule: deny
+    action: CANCEL
+`), nil)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		action      fleetapi.Action
+		expectedErr string
+	}{
+		"denied scheduled upgrade": {
+			action: &fleetapi.ActionUpgrade{
+				ActionID:         "upgrade",
+				ActionType:       fleetapi.ActionTypeUpgrade,
+				ActionStartTime:  time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+				ActionExpiration: time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339),
+				Data:             fleetapi.ActionUpgradeData{Version: "9.0.0"},
+			},
+			expectedErr: "action of type UPGRADE is denied by capabilities.yml",
+		},
+		"denied cancel": {
+			action: &fleetapi.ActionCancel{
+				ActionID:   "cancel",
+				ActionType: fleetapi.ActionTypeCancel,
+				Data:       fleetapi.ActionCancelData{TargetID: "upgrade"},
+			},
+			expectedErr: "action of type CANCEL is denied by capabilities.yml",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			def := &mockHandler{}
+			queue := &mockQueue{}
+			queue.On("Save").Return(nil).Once()
+			queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+			d, err := New(nil, t.TempDir(), def, queue, caps)
+			require.NoError(t, err)
+
+			var reportedDetails *details.Details
+			detailsSetter := func(upgradeDetails *details.Details) {
+				reportedDetails = upgradeDetails
+			}
+			ack := &recordingAcker{}
+			go d.Dispatch(context.Background(), detailsSetter, ack, tc.action)
+			err = <-d.Errors()
+
+			require.EqualError(t, err, tc.expectedErr)
+			require.Len(t, ack.acked, 1)
+			assert.Equal(t, tc.action.ID(), ack.acked[0].ActionID)
+			assert.Equal(t, tc.expectedErr, ack.acked[0].Error)
+			assert.Nil(t, reportedDetails, "a denied action must not report upgrade details")
+			def.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything, mock.Anything)
+			queue.AssertNotCalled(t, "CancelType", mock.Anything)
+			queue.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+			queue.AssertNotCalled(t, "AddWithSchedule", mock.Anything, mock.Anything, mock.Anything)
+			queue.AssertExpectations(t)
+		})
+	}
+}
+
+func TestActionDispatcherDependencies(t *testing.T) {
+	detailsSetter := func(upgradeDetails *details.Details) {}
+	dependent := &fleetapi.ActionDiagnostics{
+		ActionID:        "diagnostics",
+		ActionType:      fleetapi.ActionTypeDiagnostics,
+		ActionStartTime: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+		ActionSchedule:  &fleetapi.ActionSchedule{DependsOn: []string{"policy"}},
+	}
+
+	t.Run("acked action is completed", func(t *testing.T) {
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			ack := args.Get(2).(acker.Acker)
+			require.NoError(t, ack.Ack(args.Get(0).(context.Context), args.Get(1).(fleetapi.Action)))
+		}).Once()
+		queue := &mockTrackingQueue{}
+		queue.On("Save").Return(nil).Twice()
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+		queue.On("Complete", "policy").Once()
+		d, err := New(nil, t.TempDir(), def, queue, nil)
+		require.NoError(t, err)
+
+		go d.Dispatch(context.Background(), detailsSetter, &recordingAcker{}, &fleetapi.ActionPolicyChange{ActionID: "policy", ActionType: fleetapi.ActionTypePolicyChange})
+		require.NoError(t, <-d.Errors())
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("action not acked yet is not completed", func(t *testing.T) {
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		queue := &mockTrackingQueue{}
+		queue.On("Save").Return(nil).Once()
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+		d, err := New(nil, t.TempDir(), def, queue, nil)
+		require.NoError(t, err)
+
+		go d.Dispatch(context.Background(), detailsSetter, &recordingAcker{}, &fleetapi.ActionPolicyChange{ActionID: "policy", ActionType: fleetapi.ActionTypePolicyChange})
+		require.NoError(t, <-d.Errors())
+		queue.AssertNotCalled(t, "Complete", mock.Anything)
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("action acked later with an error fails its dependents", func(t *testing.T) {
+		queue := &mockTrackingQueue{}
+		queue.On("Save").Return(nil).Once()
+		queue.On("Fail", "diagnostics").Return([]fleetapi.ScheduledAction{}).Once()
+		d, err := New(nil, t.TempDir(), &mockHandler{}, queue, nil)
+		require.NoError(t, err)
+
+		ack := &recordingAcker{}
+		failed := &fleetapi.ActionDiagnostics{ActionID: "diagnostics", ActionType: fleetapi.ActionTypeDiagnostics, Err: errors.New("upload failed")}
+		require.NoError(t, d.Acker(ack).Ack(context.Background(), failed))
+
+		require.Len(t, ack.acked, 1)
+		assert.Equal(t, "upload failed", ack.acked[0].Error)
+		queue.AssertNotCalled(t, "Complete", mock.Anything)
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("queued dependents of a failed action are acked as failed", func(t *testing.T) {
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test error")).Once()
+		queue := &mockTrackingQueue{}
+		queue.On("Save").Return(nil).Once()
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+		queue.On("Fail", "policy").Return([]fleetapi.ScheduledAction{dependent}).Once()
+		d, err := New(nil, t.TempDir(), def, queue, nil)
+		require.NoError(t, err)
+
+		ack := &recordingAcker{}
+		go d.Dispatch(context.Background(), detailsSetter, ack, &fleetapi.ActionPolicyChange{ActionID: "policy", ActionType: fleetapi.ActionTypePolicyChange})
+		require.EqualError(t, <-d.Errors(), "test error")
+
+		require.Len(t, ack.acked, 1)
+		assert.Equal(t, "diagnostics", ack.acked[0].ActionID)
+		assert.Contains(t, ack.acked[0].Error, "action policy it depends on did not complete")
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("action depending on a failed action is acked as failed", func(t *testing.T) {
+		def := &mockHandler{}
+		queue := &mockTrackingQueue{}
+		queue.On("Save").Return(nil).Once()
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{}).Once()
+		queue.On("AddWithSchedule", dependent, mock.Anything, queuepkg.Schedule{DependsOn: []string{"policy"}}).
+			Return(fmt.Errorf("action diagnostics depends on action policy: %w", queuepkg.ErrDependencyFailed)).Once()
+		queue.On("Fail", "diagnostics").Return([]fleetapi.ScheduledAction{}).Once()
+		d, err := New(nil, t.TempDir(), def, queue, nil)
+		require.NoError(t, err)
+
+		ack := &recordingAcker{}
+		dispatchCompleted := make(chan struct{})
+		go func() {
+			d.Dispatch(context.Background(), detailsSetter, ack, dependent)
+			close(dispatchCompleted)
+		}()
+		select {
+		case err := <-d.Errors():
+			t.Fatalf("Unexpected error: %v", err)
+		case <-dispatchCompleted:
+		}
+
+		require.Len(t, ack.acked, 1)
+		assert.Equal(t, "diagnostics", ack.acked[0].ActionID)
+		assert.Contains(t, ack.acked[0].Error, "dependency failed")
+		def.AssertNotCalled(t, "Handle", mock.Anything, mock.Anything, mock.Anything)
+		queue.AssertNotCalled(t, "Add", mock.Anything, mock.Anything)
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("queued dependents of an expired action are acked as failed", func(t *testing.T) {
+		expired := &fleetapi.ActionUpgrade{
+			ActionID:         "upgrade",
+			ActionType:       fleetapi.ActionTypeUpgrade,
+			ActionStartTime:  time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			ActionExpiration: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339),
+		}
+		def := &mockHandler{}
+		queue := &mockTrackingQueue{}
+		queue.On("Save").Return(nil).Once()
+		queue.On("DequeueActions").Return([]fleetapi.ScheduledAction{expired}).Once()
+		queue.On("Fail", "upgrade").Return([]fleetapi.ScheduledAction{dependent}).Once()
+		d, err := New(nil, t.TempDir(), def, queue, nil)
+		require.NoError(t, err)
+
+		ack := &recordingAcker{}
+		dispatchCompleted := make(chan struct{})
+		go func() {
+			d.Dispatch(context.Background(), detailsSetter, ack)
+			close(dispatchCompleted)
+		}()
+		select {
+		case err := <-d.Errors():
+			t.Fatalf("Unexpected error: %v", err)
+		case <-dispatchCompleted:
+		}
+
+		require.Len(t, ack.acked, 1)
+		assert.Equal(t, "diagnostics", ack.acked[0].ActionID)
+		assert.Contains(t, ack.acked[0].Error, "action upgrade it depends on did not complete")
+		queue.AssertExpectations(t)
+	})
+}
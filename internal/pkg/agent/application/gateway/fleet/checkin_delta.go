@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleet
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+)
+
+// componentsDelta returns the components that changed from prev, the components
+// acknowledged by the last checkin, and the IDs of the removed components.
+// A changed component only contains the units that changed and the IDs of the
+// removed units.
+func componentsDelta(prev map[string]fleetapi.CheckinComponent, current []fleetapi.CheckinComponent) ([]fleetapi.CheckinComponent, []string) {
+	changed := make([]fleetapi.CheckinComponent, 0)
+	seen := make(map[string]struct{}, len(current))
+
+	for _, c := range current {
+		seen[c.ID] = struct{}{}
+		p, ok := prev[c.ID]
+		if !ok {
+			changed = append(changed, c)
+			continue
+		}
+
+		prevUnits := make(map[string]fleetapi.CheckinUnit, len(p.Units))
+		for _, u := range p.Units {
+			prevUnits[u.ID] = u
+		}
+
+		var units []fleetapi.CheckinUnit
+		for _, u := range c.Units {
+			pu, ok := prevUnits[u.ID]
+			delete(prevUnits, u.ID)
+			if ok && reflect.DeepEqual(u, pu) {
+				continue
+			}
+			units = append(units, u)
+		}
+
+		var removedUnits []string
+		for id := range prevUnits {
+			removedUnits = append(removedUnits, id)
+		}
+		sort.Strings(removedUnits)
+
+		if len(units) == 0 && len(removedUnits) == 0 &&
+			c.Type == p.Type && c.Status == p.Status && c.Message == p.Message {
+			continue
+		}
+
+		changed = append(changed, fleetapi.CheckinComponent{
+			ID:           c.ID,
+			Type:         c.Type,
+			Status:       c.Status,
+			Message:      c.Message,
+			Units:        units,
+			RemovedUnits: removedUnits,
+		})
+	}
+
+	var removed []string
+	for id := range prev {
+		if _, ok := seen[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+
+	return changed, removed
+}
+
+// indexComponents returns the components indexed by ID.
+func indexComponents(components []fleetapi.CheckinComponent) map[string]fleetapi.CheckinComponent {
+	index := make(map[string]fleetapi.CheckinComponent, len(components))
+	for _, c := range components {
+		index[c.ID] = c
+	}
+	return index
+}
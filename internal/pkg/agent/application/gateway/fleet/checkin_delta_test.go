@@ -0,0 +1,138 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+)
+
+func TestComponentsDelta(t *testing.T) {
+	healthyUnit := fleetapi.CheckinUnit{ID: "unit-1", Type: "input", Status: "HEALTHY", Message: "Healthy"}
+	failedUnit := fleetapi.CheckinUnit{ID: "unit-1", Type: "input", Status: "FAILED", Message: "Failed"}
+	otherUnit := fleetapi.CheckinUnit{ID: "unit-2", Type: "output", Status: "HEALTHY", Message: "Healthy"}
+	component := fleetapi.CheckinComponent{
+		ID:      "filestream-default",
+		Type:    "filestream",
+		Status:  "HEALTHY",
+		Message: "Healthy",
+		Units:   []fleetapi.CheckinUnit{healthyUnit, otherUnit},
+	}
+
+	tests := []struct {
+		name        string
+		prev        []fleetapi.CheckinComponent
+		current     []fleetapi.CheckinComponent
+		wantChanged []fleetapi.CheckinComponent
+		wantRemoved []string
+	}{
+		{
+			name:        "nothing changed",
+			prev:        []fleetapi.CheckinComponent{component},
+			current:     []fleetapi.CheckinComponent{component},
+			wantChanged: []fleetapi.CheckinComponent{},
+		},
+		{
+			name:        "new component",
+			prev:        nil,
+			current:     []fleetapi.CheckinComponent{component},
+			wantChanged: []fleetapi.CheckinComponent{component},
+		},
+		{
+			name:        "removed component",
+			prev:        []fleetapi.CheckinComponent{component},
+			current:     nil,
+			wantChanged: []fleetapi.CheckinComponent{},
+			wantRemoved: []string{"filestream-default"},
+		},
+		{
+			name: "changed unit",
+			prev: []fleetapi.CheckinComponent{component},
+			current: []fleetapi.CheckinComponent{{
+				ID:      "filestream-default",
+				Type:    "filestream",
+				Status:  "HEALTHY",
+				Message: "Healthy",
+				Units:   []fleetapi.CheckinUnit{otherUnit, failedUnit},
+			}},
+			wantChanged: []fleetapi.CheckinComponent{{
+				ID:      "filestream-default",
+				Type:    "filestream",
+				Status:  "HEALTHY",
+				Message: "Healthy",
+				Units:   []fleetapi.CheckinUnit{failedUnit},
+			}},
+		},
+		{
+			name: "changed unit payload",
+			prev: []fleetapi.CheckinComponent{component},
+			current: []fleetapi.CheckinComponent{{
+				ID:      "filestream-default",
+				Type:    "filestream",
+				Status:  "HEALTHY",
+				Message: "Healthy",
+				Units: []fleetapi.CheckinUnit{
+					healthyUnit,
+					{ID: "unit-2", Type: "output", Status: "HEALTHY", Message: "Healthy", Payload: map[string]interface{}{"events": 10}},
+				},
+			}},
+			wantChanged: []fleetapi.CheckinComponent{{
+				ID:      "filestream-default",
+				Type:    "filestream",
+				Status:  "HEALTHY",
+				Message: "Healthy",
+				Units: []fleetapi.CheckinUnit{
+					{ID: "unit-2", Type: "output", Status: "HEALTHY", Message: "Healthy", Payload: map[string]interface{}{"events": 10}},
+				},
+			}},
+		},
+		{
+			name: "removed unit",
+			prev: []fleetapi.CheckinComponent{component},
+			current: []fleetapi.CheckinComponent{{
+				ID:      "filestream-default",
+				Type:    "filestream",
+				Status:  "HEALTHY",
+				Message: "Healthy",
+				Units:   []fleetapi.CheckinUnit{healthyUnit},
+			}},
+			wantChanged: []fleetapi.CheckinComponent{{
+				ID:           "filestream-default",
+				Type:         "filestream",
+				Status:       "HEALTHY",
+				Message:      "Healthy",
+				RemovedUnits: []string{"unit-2"},
+			}},
+		},
+		{
+			name: "changed component status only",
+			prev: []fleetapi.CheckinComponent{component},
+			current: []fleetapi.CheckinComponent{{
+				ID:      "filestream-default",
+				Type:    "filestream",
+				Status:  "DEGRADED",
+				Message: "Degraded",
+				Units:   []fleetapi.CheckinUnit{healthyUnit, otherUnit},
+			}},
+			wantChanged: []fleetapi.CheckinComponent{{
+				ID:      "filestream-default",
+				Type:    "filestream",
+				Status:  "DEGRADED",
+				Message: "Degraded",
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			changed, removed := componentsDelta(indexComponents(tc.prev), tc.current)
+			assert.Equal(t, tc.wantChanged, changed)
+			assert.Equal(t, tc.wantRemoved, removed)
+		})
+	}
+}
@@ -6,6 +6,7 @@ package fleet
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/status"
@@ -29,6 +30,11 @@ import (
 // Max number of times an invalid API Key is checked
 const maxUnauthCounter int = 6
 
+// statusJournalSaveInterval is the minimum time between two saves of the
+// status journal while fleet can't be reached, the transitions in between are
+// kept in memory until the next save.
+const statusJournalSaveInterval = time.Minute
+
 // Consts for states at fleet checkin
 const (
 	fleetStateDegraded = "DEGRADED"
@@ -49,6 +55,7 @@ var defaultGatewaySettings = &fleetGatewaySettings{
 	Jitter:                       500 * time.Millisecond, // used as a jitter for duration
 	ErrConsecutiveUnauthDuration: 1 * time.Hour,          // time between calls when the agent exceeds unauthorized response limit
 	Backoff:                      &defaultFleetBackoffSettings,
+	ComponentsSnapshot:           30 * time.Minute, // time between full component snapshots when fleet accepts delta checkins
 }
 
 type fleetGatewaySettings struct {
@@ -56,6 +63,10 @@ type fleetGatewaySettings struct {
 	Jitter                       time.Duration    `config:"jitter"`
 	Backoff                      *backoffSettings `config:"backoff"`
 	ErrConsecutiveUnauthDuration time.Duration
+	// ComponentsSnapshot is the time between checkins sending all the
+	// components when fleet accepts delta checkins. Delta checkins are
+	// disabled when it's zero.
+	ComponentsSnapshot time.Duration `config:"components_snapshot"`
 }
 
 type backoffSettings struct {
@@ -70,6 +81,11 @@ type agentInfo interface {
 type stateStore interface {
 	AckToken() string
 	SetAckToken(ackToken string)
+	AppendStatusTransition(t fleetapi.CheckinStatusTransition) bool
+	StatusJournal() []fleetapi.CheckinStatusTransition
+	TrimStatusJournal(n int)
+	StatusHistorySupported() bool
+	SetStatusHistorySupported(supported bool)
 	Save() error
 }
 
@@ -86,6 +102,20 @@ type FleetGateway struct {
 	stateStore         stateStore
 	errCh              chan error
 	actionCh           chan []fleetapi.Action
+
+	// components acknowledged by the last checkin, delta checkins are
+	// computed against them
+	lastComponents map[string]fleetapi.CheckinComponent
+	lastSnapshot   time.Time
+	deltaSupported bool
+
+	// historySupported is set once fleet accepts the status history, the
+	// journaled status transitions are only sent then
+	historySupported bool
+	// journalSaved is the last time the status journal was saved,
+	// journalUnsaved is set when it has transitions not saved yet
+	journalSaved   time.Time
+	journalUnsaved bool
 }
 
 // New creates a new fleet gateway
@@ -131,6 +161,8 @@ func newFleetGatewayWithScheduler(
 		stateStore:   stateStore,
 		errCh:        make(chan error),
 		actionCh:     make(chan []fleetapi.Action, 1),
+		// the journal is only sent on the first checkin if fleet accepted it before
+		historySupported: stateStore.StatusHistorySupported(),
 	}, nil
 }
 
@@ -356,6 +388,12 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 	// Fix loglevel with the current log level used by coordinator
 	ecsMeta.Elastic.Agent.LogLevel = state.LogLevel.String()
 
+	// status transitions journaled while fleet could not be reached
+	var history []fleetapi.CheckinStatusTransition
+	if f.historySupported {
+		history = f.stateStore.StatusJournal()
+	}
+
 	// checkin
 	cmd := fleetapi.NewCheckinCmd(f.agentInfo, f.client)
 	req := &fleetapi.CheckinRequest{
@@ -365,9 +403,19 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 		Message:        state.Message,
 		Components:     components,
 		UpgradeDetails: state.UpgradeDetails,
+		StatusHistory:  history,
+	}
+
+	delta := f.useComponentsDelta()
+	if delta {
+		req.ComponentsDelta = true
+		req.Components, req.RemovedComponents = componentsDelta(f.lastComponents, components)
 	}
 
 	resp, took, err := cmd.Execute(ctx, req)
+	if err != nil {
+		f.journalStatus(req.Status, req.Message, components)
+	}
 	if isUnauth(err) {
 		f.unauthCounter++
 		if f.shouldUseLongSched() {
@@ -386,18 +434,103 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 		return nil, took, err
 	}
 
+	f.lastComponents = indexComponents(components)
+	if !delta {
+		f.lastSnapshot = time.Now()
+	}
+	if resp.ComponentsDelta != f.deltaSupported {
+		f.log.Infof("fleet-server accepts delta checkins: %t", resp.ComponentsDelta)
+		f.deltaSupported = resp.ComponentsDelta
+	}
+
+	historyChanged := resp.StatusHistory != f.historySupported
+	if historyChanged {
+		f.log.Infof("fleet-server accepts the status history: %t", resp.StatusHistory)
+		f.historySupported = resp.StatusHistory
+		f.stateStore.SetStatusHistorySupported(resp.StatusHistory)
+	}
+
+	// the journal is sent on the next checkin when fleet just advertised it
+	// accepts it, and dropped when fleet doesn't accept it
+	trim := len(history)
+	if !f.historySupported {
+		trim = len(f.stateStore.StatusJournal())
+	}
+	if len(history) > 0 {
+		f.log.Infof("sent %d status transitions journaled while fleet could not be reached", len(history))
+	} else if trim > 0 {
+		f.log.Infof("dropped %d status transitions journaled while fleet could not be reached, fleet-server doesn't accept them", trim)
+	}
+	if trim > 0 {
+		f.stateStore.TrimStatusJournal(trim)
+	}
+
 	// Save the latest ackToken
 	if resp.AckToken != "" {
 		f.stateStore.SetAckToken(resp.AckToken)
+	}
+	if trim > 0 || resp.AckToken != "" || historyChanged || f.journalUnsaved {
 		serr := f.stateStore.Save()
 		if serr != nil {
-			f.log.Errorf("failed to save the ack token, err: %v", serr)
+			f.log.Errorf("failed to save the ack token and status journal, err: %v", serr)
+		} else {
+			f.journalUnsaved = false
 		}
 	}
 
 	return resp, took, nil
 }
 
+// useComponentsDelta returns true when the next checkin should only send the
+// components that changed since the last acknowledged checkin.
+func (f *FleetGateway) useComponentsDelta() bool {
+	return f.deltaSupported &&
+		f.lastComponents != nil &&
+		f.settings.ComponentsSnapshot > 0 &&
+		time.Since(f.lastSnapshot) < f.settings.ComponentsSnapshot
+}
+
+// journalStatus records the status of the checkin that could not be sent, so
+// the transition is reported once fleet can be reached again. The journal is
+// saved when the status changed, at most once per statusJournalSaveInterval.
+func (f *FleetGateway) journalStatus(status, message string, checkinComponents []fleetapi.CheckinComponent) {
+	components := make([]fleetapi.CheckinComponent, 0, len(checkinComponents))
+	for _, c := range checkinComponents {
+		units := make([]fleetapi.CheckinUnit, 0, len(c.Units))
+		for _, u := range c.Units {
+			// payloads are dropped, they can be large and aren't part of the status
+			u.Payload = nil
+			units = append(units, u)
+		}
+		// units are sorted so identical states compare equal
+		sort.Slice(units, func(i, j int) bool {
+			return units[i].ID < units[j].ID
+		})
+		c.Units = units
+		components = append(components, c)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].ID < components[j].ID
+	})
+
+	appended := f.stateStore.AppendStatusTransition(fleetapi.CheckinStatusTransition{
+		Timestamp:  time.Now().UTC(),
+		Status:     status,
+		Message:    message,
+		Components: components,
+	})
+	f.journalUnsaved = f.journalUnsaved || appended
+	if !f.journalUnsaved || time.Since(f.journalSaved) < statusJournalSaveInterval {
+		return
+	}
+	if err := f.stateStore.Save(); err != nil {
+		f.log.Errorf("failed to save the status journal, err: %v", err)
+		return
+	}
+	f.journalSaved = time.Now()
+	f.journalUnsaved = false
+}
+
 // shouldUseLongSched checks if the max number of trying an invalid key is reached
 func (f *FleetGateway) shouldUseLongSched() bool {
 	return f.unauthCounter > maxUnauthCounter
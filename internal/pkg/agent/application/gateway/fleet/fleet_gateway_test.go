@@ -464,6 +464,207 @@ func TestRetriesOnFailures(t *testing.T) {
 		}))
 }
 
+func TestStatusJournal(t *testing.T) {
+	testCases := []struct {
+		name            string
+		checkinResponse string
+		expectedHistory int
+	}{
+		{
+			name:            "fleet accepts the status history",
+			checkinResponse: `{ "actions": [], "status_history": true }`,
+			expectedHistory: 1,
+		},
+		{
+			name:            "fleet does not accept the status history",
+			checkinResponse: `{ "actions": [] }`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			scheduler := scheduler.NewStepper()
+			client := newTestingClient()
+
+			log, _ := loggertest.New("fleet_gateway")
+			stateStore := newStateStore(t, log)
+
+			gateway, err := newFleetGatewayWithScheduler(
+				log,
+				&fleetGatewaySettings{
+					Duration: 5 * time.Second,
+					Backoff:  &backoffSettings{Init: 10 * time.Millisecond, Max: 50 * time.Millisecond},
+				},
+				&testAgentInfo{},
+				client,
+				scheduler,
+				noop.New(),
+				emptyStateFetcher,
+				stateStore,
+			)
+			require.NoError(t, err)
+
+			var history []fleetapi.CheckinStatusTransition
+			respond := func() func() {
+				return ackSeq(
+					client.Answer(func(_ http.Header, body io.Reader) (*http.Response, error) {
+						var checkinRequest fleetapi.CheckinRequest
+						err := json.NewDecoder(body).Decode(&checkinRequest)
+						require.NoError(t, err)
+						history = checkinRequest.StatusHistory
+
+						return wrapStrToResp(http.StatusOK, tc.checkinResponse), nil
+					}),
+				)
+			}
+
+			errCh := runFleetGateway(ctx, gateway)
+
+			// fleet advertises whether it accepts the status history
+			waitFn := respond()
+			scheduler.Next()
+			waitFn()
+			require.Empty(t, history)
+
+			fail := func(_ http.Header, _ io.Reader) (*http.Response, error) {
+				return wrapStrToResp(http.StatusInternalServerError, "something is bad"), nil
+			}
+			clientWaitFn := client.Answer(fail)
+			scheduler.Next()
+
+			// Fleet cannot be reached for the next 3 calls.
+			<-clientWaitFn
+			<-clientWaitFn
+			<-clientWaitFn
+
+			waitFn = respond()
+			waitFn()
+
+			cancel()
+			err = <-errCh
+			require.NoError(t, err)
+
+			// the identical status of the failed checkins is journaled once
+			require.Len(t, history, tc.expectedHistory)
+			for _, transition := range history {
+				assert.Equal(t, fleetStateStarting, transition.Status)
+				assert.False(t, transition.Timestamp.IsZero())
+			}
+			assert.Empty(t, stateStore.StatusJournal(), "journal should be trimmed once sent or dropped")
+			assert.Equal(t, tc.expectedHistory > 0, stateStore.StatusHistorySupported(), "fleet support for the status history should be saved")
+		})
+	}
+}
+
+// countingStateStore counts the saves of the state store.
+type countingStateStore struct {
+	*store.StateStore
+	saves int
+}
+
+func (s *countingStateStore) Save() error {
+	s.saves++
+	return s.StateStore.Save()
+}
+
+func TestStatusJournalSaves(t *testing.T) {
+	log, _ := loggertest.New("fleet_gateway")
+	stateStore := &countingStateStore{StateStore: newStateStore(t, log)}
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		defaultGatewaySettings,
+		&testAgentInfo{},
+		newTestingClient(),
+		scheduler.NewStepper(),
+		noop.New(),
+		emptyStateFetcher,
+		stateStore,
+	)
+	require.NoError(t, err)
+
+	gateway.journalStatus(fleetStateDegraded, "failed", nil)
+	assert.Equal(t, 1, stateStore.saves, "the first transition should be saved")
+
+	gateway.journalStatus(fleetStateDegraded, "failed", nil)
+	gateway.journalStatus(fleetStateOnline, "running", nil)
+	assert.Equal(t, 1, stateStore.saves, "transitions should be kept in memory until the save interval elapsed")
+	assert.Len(t, stateStore.StatusJournal(), 2)
+
+	gateway.journalSaved = time.Now().Add(-statusJournalSaveInterval)
+	gateway.journalStatus(fleetStateOnline, "running", nil)
+	assert.Equal(t, 2, stateStore.saves, "unsaved transitions should be saved once the save interval elapsed")
+
+	gateway.journalSaved = time.Now().Add(-statusJournalSaveInterval)
+	gateway.journalStatus(fleetStateOnline, "running", nil)
+	assert.Equal(t, 2, stateStore.saves, "the journal should not be saved when the status didn't change")
+}
+
+func TestComponentsDeltaNegotiation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler := scheduler.NewStepper()
+	client := newTestingClient()
+
+	log, _ := loggertest.New("fleet_gateway")
+	stateStore := newStateStore(t, log)
+
+	gateway, err := newFleetGatewayWithScheduler(
+		log,
+		&fleetGatewaySettings{
+			Duration:           5 * time.Second,
+			Backoff:            &backoffSettings{Init: 1 * time.Second, Max: 5 * time.Second},
+			ComponentsSnapshot: time.Hour,
+		},
+		&testAgentInfo{},
+		client,
+		scheduler,
+		noop.New(),
+		emptyStateFetcher,
+		stateStore,
+	)
+	require.NoError(t, err)
+
+	var deltas []bool
+	respond := func(body string) func() {
+		return ackSeq(
+			client.Answer(func(_ http.Header, reqBody io.Reader) (*http.Response, error) {
+				var checkinRequest fleetapi.CheckinRequest
+				err := json.NewDecoder(reqBody).Decode(&checkinRequest)
+				require.NoError(t, err)
+				deltas = append(deltas, checkinRequest.ComponentsDelta)
+
+				return wrapStrToResp(http.StatusOK, body), nil
+			}),
+		)
+	}
+
+	errCh := runFleetGateway(ctx, gateway)
+
+	// fleet-server accepts delta checkins
+	waitFn := respond(`{ "actions": [], "components_delta": true }`)
+	scheduler.Next()
+	waitFn()
+
+	// fleet-server does not accept delta checkins anymore
+	waitFn = respond(`{ "actions": [] }`)
+	scheduler.Next()
+	waitFn()
+
+	waitFn = respond(`{ "actions": [] }`)
+	scheduler.Next()
+	waitFn()
+
+	cancel()
+	err = <-errCh
+	require.NoError(t, err)
+
+	assert.Equal(t, []bool{false, true, false}, deltas)
+}
+
 type testAgentInfo struct{}
 
 func (testAgentInfo) AgentID() string { return "agent-secret" }
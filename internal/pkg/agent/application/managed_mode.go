@@ -22,10 +22,12 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/fleet"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/retrier"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/certrenewal"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/uploader"
 	"github.com/elastic/elastic-agent/internal/pkg/queue"
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
@@ -53,6 +55,7 @@ type managedConfigManager struct {
 	fleetAcker           *fleet.Acker
 	actionAcker          acker.Acker
 	retrier              *retrier.Retrier
+	certRenewer          *certrenewal.Renewer
 
 	ch    chan coordinator.ConfigChange
 	errCh chan error
@@ -72,14 +75,16 @@ func newManagedConfigManager(
 	actionAcker acker.Acker,
 	retrier *retrier.Retrier,
 	stateStore *store.StateStore,
+	caps capabilities.Capabilities,
+	certRenewer *certrenewal.Renewer,
 	clientSetters ...actions.ClientSetter,
 ) (*managedConfigManager, error) {
-	actionQueue, err := queue.NewActionQueue(stateStore.Queue(), stateStore)
+	actionQueue, err := queue.NewActionQueue(stateStore.Queue(), stateStore.QueueScheduling(), stateStore)
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize action queue: %w", err)
 	}
 
-	actionDispatcher, err := dispatcher.New(log, topPath, handlers.NewDefault(log), actionQueue)
+	actionDispatcher, err := dispatcher.New(log, topPath, handlers.NewDefault(log), actionQueue, caps)
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize action dispatcher: %w", err)
 	}
@@ -101,6 +106,7 @@ func newManagedConfigManager(
 		fleetAcker:           fleetAcker,
 		actionAcker:          actionAcker,
 		retrier:              retrier,
+		certRenewer:          certRenewer,
 	}, nil
 }
 
@@ -129,7 +135,8 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 	policyChanger := m.initDispatcher(gatewayCancel)
 
 	// Create ackers to enqueue/retry failed acks
-	if err := m.coord.AckUpgrade(ctx, m.actionAcker); err != nil {
+	// the upgrade is acked once the new version runs, the actions depending on it are released then
+	if err := m.coord.AckUpgrade(ctx, m.dispatcher.Acker(m.actionAcker)); err != nil {
 		m.log.Warnf("Failed to ack upgrade: %v", err)
 	}
 
@@ -192,6 +199,28 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 		for _, cs := range m.initialClientSetters {
 			policyChanger.AddSetter(cs)
 		}
+
+		if m.certRenewer != nil {
+			// the renewed certificate must be kept when the policy changes
+			// the fleet client configuration
+			policyChanger.SetClientConfigOverrider(m.certRenewer)
+			m.certRenewer.AddSetter(gateway)
+			m.certRenewer.AddSetter(m.fleetAcker)
+			for _, cs := range m.initialClientSetters {
+				m.certRenewer.AddSetter(cs)
+			}
+
+			renewerRun := make(chan struct{})
+			renewerCtx, renewerCancel := context.WithCancel(ctx)
+			defer func() {
+				renewerCancel()
+				<-renewerRun
+			}()
+			go func() {
+				m.certRenewer.Run(renewerCtx)
+				close(renewerRun)
+			}()
+		}
 	} else {
 		// locally managed fleet server
 		// init with local address
@@ -356,7 +385,7 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 
 	m.dispatcher.MustRegister(
 		&fleetapi.ActionUpgrade{},
-		handlers.NewUpgrade(m.log, m.coord),
+		handlers.NewUpgrade(m.log, m.coord, m.actionQueue),
 	)
 
 	m.dispatcher.MustRegister(
@@ -379,6 +408,7 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 			paths.Top(), // TODO: stop using global state
 			m.coord,
 			m.cfg.Settings.MonitoringConfig.Diagnostics.Limit,
+			m.cfg.Settings.MonitoringConfig.Diagnostics.RedactionPolicy,
 			uploader.New(m.agentInfo.AgentID(), m.client, m.cfg.Settings.MonitoringConfig.Diagnostics.Uploader),
 		),
 	)
@@ -393,6 +423,16 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 		handlers.NewMigrate(m.log, m.agentInfo, m.coord),
 	)
 
+	m.dispatcher.MustRegister(
+		&fleetapi.ActionRollback{},
+		handlers.NewRollback(m.log, m.coord),
+	)
+
+	m.dispatcher.MustRegister(
+		&fleetapi.ActionRotateKey{},
+		handlers.NewRotateKey(m.log, m.coord),
+	)
+
 	m.dispatcher.MustRegister(
 		&fleetapi.ActionUnknown{},
 		handlers.NewUnknown(m.log),
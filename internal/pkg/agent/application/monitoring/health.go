@@ -0,0 +1,179 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+)
+
+type unitHealth struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	State   string `json:"state"`
+	Message string `json:"message"`
+}
+
+type componentHealth struct {
+	ID      string       `json:"id"`
+	State   string       `json:"state"`
+	Message string       `json:"message"`
+	Healthy bool         `json:"healthy"`
+	Ready   bool         `json:"ready"`
+	Units   []unitHealth `json:"units,omitempty"`
+}
+
+type readinessStatus struct {
+	Ready    bool     `json:"ready"`
+	NotReady []string `json:"not_ready,omitempty"`
+}
+
+// isComponentLive returns false when the component is unhealthy, a degraded
+// component is considered unhealthy only when failOnDegraded is set.
+func isComponentLive(state client.UnitState, failOnDegraded bool) bool {
+	switch state {
+	case client.UnitStateFailed, client.UnitStateStopping, client.UnitStateStopped:
+		return false
+	case client.UnitStateDegraded:
+		return !failOnDegraded
+	default:
+		return true
+	}
+}
+
+// isComponentReady returns true when the component is running and can serve
+// traffic, a component that is still starting or being configured is live but
+// not ready.
+func isComponentReady(state client.UnitState, failOnDegraded bool) bool {
+	switch state {
+	case client.UnitStateHealthy:
+		return true
+	case client.UnitStateDegraded:
+		return !failOnDegraded
+	default:
+		return false
+	}
+}
+
+// failOnDegraded parses the failon form value shared with the liveness endpoint,
+// only the degraded mode changes the outcome of the health endpoints.
+func failOnDegraded(r *http.Request) (bool, error) {
+	failConfig, err := handleFormValues(r)
+	if err != nil {
+		return false, errorWithStatus(http.StatusBadRequest, err)
+	}
+	return failConfig.Degraded, nil
+}
+
+// readinessHandler reports whether the agent is ready: the coordinator is
+// active and all the components are running. Unlike liveness a component that
+// is still starting makes the agent not ready.
+func readinessHandler(coord CoordinatorState) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		degraded, err := failOnDegraded(r)
+		if err != nil {
+			return err
+		}
+
+		if !coord.IsActive(time.Second * 10) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			writeResponse(w, readinessStatus{Ready: false, NotReady: []string{"coordinator"}})
+			return nil
+		}
+
+		status := readinessStatus{Ready: true}
+		for _, comp := range coord.State().Components {
+			if !isComponentReady(comp.State.State, degraded) {
+				status.Ready = false
+				status.NotReady = append(status.NotReady, comp.Component.ID)
+			}
+		}
+
+		if !status.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		writeResponse(w, status)
+		return nil
+	}
+}
+
+// componentHealthHandler reports the health of a single component. It answers
+// with 503 when the component is unhealthy, or when it's not ready if readiness
+// is set, so probes can target a single input.
+func componentHealthHandler(coord CoordinatorState, readiness bool) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		vars := mux.Vars(r)
+		componentID, found := vars[componentIDKey]
+		if !found || componentID == "" {
+			return errorfWithStatus(http.StatusNotFound, "component with specified ID not found")
+		}
+		componentID = cloudComponentIDToAgentInputType(componentID)
+
+		degraded, err := failOnDegraded(r)
+		if err != nil {
+			return err
+		}
+
+		state := coord.State()
+		for iter, c := range state.Components {
+			// access the components array manually to avoid a memory aliasing error. This is fixed in go 1.22
+			if !matchesCloudProcessID(&state.Components[iter].Component, componentID) {
+				continue
+			}
+
+			health := newComponentHealth(c, degraded)
+			if (readiness && !health.Ready) || (!readiness && !health.Healthy) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+
+			bytes, err := json.Marshal(health)
+			if err != nil {
+				return fmt.Errorf("failed to marshal health of component %s: %w", componentID, err)
+			}
+			_, err = w.Write(bytes)
+			return err
+		}
+
+		return errorWithStatus(http.StatusNotFound, fmt.Errorf("matching component %v not found", componentID))
+	}
+}
+
+func newComponentHealth(c runtime.ComponentComponentState, failOnDegraded bool) componentHealth {
+	health := componentHealth{
+		ID:      c.Component.ID,
+		State:   c.State.State.String(),
+		Message: c.State.Message,
+		Healthy: isComponentLive(c.State.State, failOnDegraded),
+		Ready:   isComponentReady(c.State.State, failOnDegraded),
+	}
+	for key, unit := range c.State.Units {
+		health.Units = append(health.Units, unitHealth{
+			ID:      key.UnitID,
+			Type:    key.UnitType.String(),
+			State:   unit.State.String(),
+			Message: unit.Message,
+		})
+	}
+	slices.SortFunc(health.Units, func(a, b unitHealth) int {
+		if a.Type != b.Type {
+			return strings.Compare(a.Type, b.Type)
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return health
+}
@@ -0,0 +1,221 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+)
+
+func healthTestCoordinator(isUp bool, states map[string]client.UnitState) mockCoordinator {
+	coord := mockCoordinator{isUp: isUp}
+	for _, id := range []string{"apm-default", "filestream-default", "fleet-server-default"} {
+		state, ok := states[id]
+		if !ok {
+			continue
+		}
+		binary := "filebeat"
+		switch id {
+		case "apm-default":
+			binary = "apm-server"
+		case "fleet-server-default":
+			binary = "fleet-server"
+		}
+		coord.state.Components = append(coord.state.Components, runtime.ComponentComponentState{
+			Component: component.Component{
+				ID:        id,
+				InputSpec: &component.InputRuntimeSpec{BinaryName: binary},
+			},
+			State: runtime.ComponentState{
+				State: state,
+				Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+					{UnitType: client.UnitTypeInput, UnitID: id + "-unit"}: {State: state, Message: "unit message"},
+				},
+			},
+		})
+	}
+	return coord
+}
+
+func healthTestServer(coord mockCoordinator) *httptest.Server {
+	r := mux.NewRouter()
+	r.Handle("/readiness", createHandler(readinessHandler(coord)))
+	r.Handle("/health/components/{componentID}", createHandler(componentHealthHandler(coord, false)))
+	r.Handle("/health/components/{componentID}/ready", createHandler(componentHealthHandler(coord, true)))
+	return httptest.NewServer(r)
+}
+
+func TestComponentHealthHandler(t *testing.T) {
+	testCases := []struct {
+		name         string
+		states       map[string]client.UnitState
+		path         string
+		expectedCode int
+	}{
+		{
+			name:         "healthy",
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateHealthy, "fleet-server-default": client.UnitStateFailed},
+			path:         "/health/components/filestream-default",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "other-component-failed",
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateHealthy, "fleet-server-default": client.UnitStateFailed},
+			path:         "/health/components/fleet-server-default",
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "degraded-is-healthy-by-default",
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateDegraded},
+			path:         "/health/components/filestream-default",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "degraded-fails-on-degraded",
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateDegraded},
+			path:         "/health/components/filestream-default?failon=degraded",
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "starting-is-live",
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateStarting},
+			path:         "/health/components/filestream-default",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "starting-is-not-ready",
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateStarting},
+			path:         "/health/components/filestream-default/ready",
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "healthy-is-ready",
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateHealthy},
+			path:         "/health/components/filestream-default/ready",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "apm-server-cloud-id",
+			states:       map[string]client.UnitState{"apm-default": client.UnitStateHealthy},
+			path:         "/health/components/apm-server-default",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not-found",
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateHealthy},
+			path:         "/health/components/missing",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid-failon",
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateHealthy},
+			path:         "/health/components/filestream-default?failon=bogus",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := healthTestServer(healthTestCoordinator(true, tc.states))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + tc.path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, tc.expectedCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestComponentHealthHandlerBody(t *testing.T) {
+	srv := healthTestServer(healthTestCoordinator(true, map[string]client.UnitState{"filestream-default": client.UnitStateStarting}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/health/components/filestream-default")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var health componentHealth
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	assert.Equal(t, componentHealth{
+		ID:      "filestream-default",
+		State:   "STARTING",
+		Healthy: true,
+		Ready:   false,
+		Units: []unitHealth{
+			{ID: "filestream-default-unit", Type: "input", State: "STARTING", Message: "unit message"},
+		},
+	}, health)
+}
+
+func TestReadinessHandler(t *testing.T) {
+	testCases := []struct {
+		name             string
+		isUp             bool
+		states           map[string]client.UnitState
+		path             string
+		expectedCode     int
+		expectedNotReady []string
+	}{
+		{
+			name:         "all-healthy",
+			isUp:         true,
+			states:       map[string]client.UnitState{"filestream-default": client.UnitStateHealthy, "fleet-server-default": client.UnitStateDegraded},
+			path:         "/readiness",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:             "coordinator-down",
+			isUp:             false,
+			states:           map[string]client.UnitState{"filestream-default": client.UnitStateHealthy},
+			path:             "/readiness",
+			expectedCode:     http.StatusServiceUnavailable,
+			expectedNotReady: []string{"coordinator"},
+		},
+		{
+			name:             "component-starting",
+			isUp:             true,
+			states:           map[string]client.UnitState{"filestream-default": client.UnitStateHealthy, "fleet-server-default": client.UnitStateStarting},
+			path:             "/readiness",
+			expectedCode:     http.StatusServiceUnavailable,
+			expectedNotReady: []string{"fleet-server-default"},
+		},
+		{
+			name:             "degraded-fails-on-degraded",
+			isUp:             true,
+			states:           map[string]client.UnitState{"filestream-default": client.UnitStateHealthy, "fleet-server-default": client.UnitStateDegraded},
+			path:             "/readiness?failon=degraded",
+			expectedCode:     http.StatusServiceUnavailable,
+			expectedNotReady: []string{"fleet-server-default"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := healthTestServer(healthTestCoordinator(tc.isUp, tc.states))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL + tc.path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, tc.expectedCode, resp.StatusCode)
+
+			var status readinessStatus
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+			assert.Equal(t, tc.expectedCode == http.StatusOK, status.Ready)
+			assert.Equal(t, tc.expectedNotReady, status.NotReady)
+		})
+	}
+}
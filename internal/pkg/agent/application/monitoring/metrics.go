@@ -0,0 +1,403 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+const (
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	metricsPrefix          = "elastic_agent_"
+
+	metricTypeCounter = "counter"
+	metricTypeGauge   = "gauge"
+	metricTypeUnknown = "unknown"
+
+	componentStateMetric    = "component_state"
+	componentRestartsMetric = "component_restarts"
+	unitStateMetric         = "unit_state"
+
+	// componentStatsTimeout bounds the time spent fetching the stats of each
+	// component, a component not answering doesn't hold the scrape.
+	componentStatsTimeout = 2 * time.Second
+)
+
+// componentStatsFetcher returns the stats of a running component as exposed
+// by its monitoring endpoint.
+type componentStatsFetcher func(ctx context.Context, componentID string) ([]byte, error)
+
+// beatStatsMetric maps a value of the beats stats endpoint to a metric.
+type beatStatsMetric struct {
+	path  []string
+	name  string
+	typ   string
+	help  string
+	scale float64
+}
+
+var beatStatsMetrics = []beatStatsMetric{
+	{
+		path: []string{"libbeat", "pipeline", "events", "published"},
+		name: "component_events_published",
+		typ:  metricTypeCounter,
+		help: "Events published to the pipeline of the component.",
+	},
+	{
+		path: []string{"libbeat", "pipeline", "events", "failed"},
+		name: "component_events_failed",
+		typ:  metricTypeCounter,
+		help: "Events that failed to be published to the pipeline of the component.",
+	},
+	{
+		path: []string{"libbeat", "pipeline", "events", "dropped"},
+		name: "component_events_dropped",
+		typ:  metricTypeCounter,
+		help: "Events dropped by the pipeline of the component.",
+	},
+	{
+		path: []string{"libbeat", "output", "events", "acked"},
+		name: "component_events_acked",
+		typ:  metricTypeCounter,
+		help: "Events acknowledged by the output of the component.",
+	},
+	{
+		path: []string{"libbeat", "pipeline", "queue", "filled", "events"},
+		name: "component_queue_filled_events",
+		typ:  metricTypeGauge,
+		help: "Events in the queue of the component.",
+	},
+	{
+		path: []string{"libbeat", "pipeline", "queue", "filled", "pct"},
+		name: "component_queue_filled_ratio",
+		typ:  metricTypeGauge,
+		help: "Fill ratio of the queue of the component.",
+	},
+	{
+		path: []string{"beat", "memstats", "rss"},
+		name: "component_memory_rss_bytes",
+		typ:  metricTypeGauge,
+		help: "Resident memory of the component process.",
+	},
+	{
+		path: []string{"beat", "memstats", "memory_alloc"},
+		name: "component_memory_alloc_bytes",
+		typ:  metricTypeGauge,
+		help: "Heap memory allocated by the component process.",
+	},
+	{
+		path:  []string{"beat", "cpu", "total", "time", "ms"},
+		name:  "component_cpu_seconds",
+		typ:   metricTypeCounter,
+		help:  "CPU time consumed by the component process.",
+		scale: 0.001,
+	},
+}
+
+// FetchComponentStats fetches the stats of a running component over its monitoring socket.
+func FetchComponentStats(ctx context.Context, componentID string) ([]byte, error) {
+	endpoint := prefixedEndpoint(utils.SocketURLWithFallback(componentID, paths.TempDir()))
+	data, statusCode, err := processMetrics(ctx, endpoint, "stats")
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d fetching stats of %s", statusCode, componentID)
+	}
+	return data, nil
+}
+
+// metricsHandler exposes the agent, component and unit metrics in the
+// OpenMetrics text format.
+func metricsHandler(coord CoordinatorState, ns *monitoring.Namespace, fetchStats componentStatsFetcher) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		state := coord.State()
+		componentStats := collectComponentStats(r.Context(), state.Components, fetchStats)
+
+		var buf bytes.Buffer
+		om := &openMetricsWriter{w: &buf}
+		writeAgentMetrics(om, ns)
+		writeComponentMetrics(om, state.Components, componentStats)
+		om.eof()
+
+		w.Header().Set("Content-Type", openMetricsContentType)
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+}
+
+func writeAgentMetrics(om *openMetricsWriter, ns *monitoring.Namespace) {
+	snapshot := monitoring.CollectFlatSnapshot(ns.GetRegistry(), monitoring.Full, false)
+
+	values := make(map[string]float64, len(snapshot.Ints)+len(snapshot.Floats)+len(snapshot.Bools))
+	for k, v := range snapshot.Ints {
+		values[k] = float64(v)
+	}
+	for k, v := range snapshot.Floats {
+		values[k] = v
+	}
+	for k, v := range snapshot.Bools {
+		values[k] = boolToFloat(v)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	// different keys may be sanitized to the same name or to the name of a component metric, a numeric suffix keeps
+	// the names unique, the keys being sorted the same key always gets the same name
+	taken := componentMetricNames()
+	for _, k := range keys {
+		name := uniqueMetricName(metricsPrefix+sanitizeMetricName(k), taken)
+		om.family(name, metricTypeUnknown, fmt.Sprintf("Agent metric %s.", k))
+		om.sample(name, nil, values[k])
+	}
+}
+
+func writeComponentMetrics(om *openMetricsWriter, components []runtime.ComponentComponentState, componentStats map[string]map[string]any) {
+	if len(components) == 0 {
+		return
+	}
+
+	componentLabels := func(c runtime.ComponentComponentState) []label {
+		return []label{
+			{"component_id", c.Component.ID},
+			{"binary", c.Component.BinaryName()},
+		}
+	}
+
+	name := metricsPrefix + componentStateMetric
+	om.family(name, metricTypeGauge, "State of the component (0=starting, 1=configuring, 2=healthy, 3=degraded, 4=failed, 5=stopping, 6=stopped).")
+	for _, c := range components {
+		om.sample(name, componentLabels(c), float64(c.State.State))
+	}
+
+	name = metricsPrefix + componentRestartsMetric
+	om.family(name, metricTypeCounter, "Times the component process exited while expected to be running.")
+	for _, c := range components {
+		om.sample(name+"_total", componentLabels(c), float64(c.State.Restarts))
+	}
+
+	name = metricsPrefix + unitStateMetric
+	om.family(name, metricTypeGauge, "State of the unit (0=starting, 1=configuring, 2=healthy, 3=degraded, 4=failed, 5=stopping, 6=stopped).")
+	for _, c := range components {
+		units := make([]runtime.ComponentUnitKey, 0, len(c.State.Units))
+		for k := range c.State.Units {
+			units = append(units, k)
+		}
+		slices.SortFunc(units, func(a, b runtime.ComponentUnitKey) int {
+			if a.UnitType != b.UnitType {
+				return int(a.UnitType) - int(b.UnitType)
+			}
+			return strings.Compare(a.UnitID, b.UnitID)
+		})
+		for _, k := range units {
+			labels := append(componentLabels(c),
+				label{"unit_id", k.UnitID},
+				label{"unit_type", k.UnitType.String()},
+			)
+			om.sample(name, labels, float64(c.State.Units[k].State))
+		}
+	}
+
+	if len(componentStats) == 0 {
+		return
+	}
+	for _, m := range beatStatsMetrics {
+		name := metricsPrefix + m.name
+		sampleName := name
+		if m.typ == metricTypeCounter {
+			sampleName += "_total"
+		}
+
+		familyWritten := false
+		for _, c := range components {
+			stats, ok := componentStats[c.Component.ID]
+			if !ok {
+				continue
+			}
+			v, ok := lookupNumber(stats, m.path)
+			if !ok {
+				continue
+			}
+			if m.scale != 0 {
+				v *= m.scale
+			}
+			if !familyWritten {
+				om.family(name, m.typ, m.help)
+				familyWritten = true
+			}
+			om.sample(sampleName, componentLabels(c), v)
+		}
+	}
+}
+
+// componentMetricNames returns the metric and sample names used by writeComponentMetrics.
+func componentMetricNames() map[string]struct{} {
+	names := map[string]struct{}{
+		metricsPrefix + componentStateMetric:               {},
+		metricsPrefix + componentRestartsMetric:            {},
+		metricsPrefix + componentRestartsMetric + "_total": {},
+		metricsPrefix + unitStateMetric:                    {},
+	}
+	for _, m := range beatStatsMetrics {
+		names[metricsPrefix+m.name] = struct{}{}
+		if m.typ == metricTypeCounter {
+			names[metricsPrefix+m.name+"_total"] = struct{}{}
+		}
+	}
+	return names
+}
+
+// uniqueMetricName returns name, or name with the first numeric suffix that isn't taken yet, and marks it as taken.
+func uniqueMetricName(name string, taken map[string]struct{}) string {
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := taken[unique]; !ok {
+			break
+		}
+		unique = name + "_" + strconv.Itoa(i)
+	}
+	taken[unique] = struct{}{}
+	return unique
+}
+
+// collectComponentStats fetches the stats of all the beats components
+// concurrently, components whose stats can't be fetched within
+// componentStatsTimeout are left out.
+func collectComponentStats(ctx context.Context, components []runtime.ComponentComponentState, fetchStats componentStatsFetcher) map[string]map[string]any {
+	if fetchStats == nil {
+		return nil
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mx    sync.Mutex
+		stats = make(map[string]map[string]any)
+	)
+	for _, c := range components {
+		if !IsSupportedBeatsBinary(c.Component.BinaryName()) {
+			continue
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			fetchCtx, cancel := context.WithTimeout(ctx, componentStatsTimeout)
+			defer cancel()
+			data, err := fetchStats(fetchCtx, id)
+			if err != nil {
+				return
+			}
+			var decoded map[string]any
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				return
+			}
+			mx.Lock()
+			stats[id] = decoded
+			mx.Unlock()
+		}(c.Component.ID)
+	}
+	wg.Wait()
+
+	return stats
+}
+
+func lookupNumber(m map[string]any, path []string) (float64, bool) {
+	var current any = m
+	for _, p := range path {
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return 0, false
+		}
+		current, ok = obj[p]
+		if !ok {
+			return 0, false
+		}
+	}
+	v, ok := current.(float64)
+	return v, ok
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+// openMetricsWriter writes metric families in the OpenMetrics text format.
+type openMetricsWriter struct {
+	w *bytes.Buffer
+}
+
+func (o *openMetricsWriter) family(name, typ, help string) {
+	fmt.Fprintf(o.w, "# TYPE %s %s\n", name, typ)
+	fmt.Fprintf(o.w, "# HELP %s %s\n", name, escapeHelp(help))
+}
+
+func (o *openMetricsWriter) sample(name string, labels []label, value float64) {
+	o.w.WriteString(name)
+	if len(labels) > 0 {
+		o.w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				o.w.WriteByte(',')
+			}
+			fmt.Fprintf(o.w, "%s=\"%s\"", l.name, escapeLabelValue(l.value))
+		}
+		o.w.WriteByte('}')
+	}
+	o.w.WriteByte(' ')
+	o.w.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	o.w.WriteByte('\n')
+}
+
+func (o *openMetricsWriter) eof() {
+	o.w.WriteString("# EOF\n")
+}
+
+// sanitizeMetricName replaces the characters not allowed in a metric name with underscores.
+func sanitizeMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+var (
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+func escapeHelp(v string) string {
+	return helpReplacer.Replace(v)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,183 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	monitoring.NewInt(reg, "beat.memstats.rss").Set(1024)
+	monitoring.NewFloat(reg, "system.load.1").Set(0.5)
+	ns := &monitoring.Namespace{}
+	ns.SetRegistry(reg)
+
+	coord := mockCoordinator{
+		isUp: true,
+		state: coordinator.State{
+			Components: []runtime.ComponentComponentState{
+				{
+					Component: component.Component{
+						ID:        "filestream-default",
+						InputSpec: &component.InputRuntimeSpec{BinaryName: "filebeat"},
+					},
+					State: runtime.ComponentState{
+						State:    client.UnitStateHealthy,
+						Restarts: 2,
+						Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+							{UnitType: client.UnitTypeOutput, UnitID: "filestream-default"}:           {State: client.UnitStateHealthy},
+							{UnitType: client.UnitTypeInput, UnitID: "filestream-default-filestream"}: {State: client.UnitStateDegraded},
+						},
+					},
+				},
+				{
+					Component: component.Component{
+						ID:        "endpoint-default",
+						InputSpec: &component.InputRuntimeSpec{BinaryName: "endpoint-security"},
+					},
+					State: runtime.ComponentState{State: client.UnitStateFailed},
+				},
+			},
+		},
+	}
+
+	var fetched []string
+	fetchStats := func(_ context.Context, componentID string) ([]byte, error) {
+		fetched = append(fetched, componentID)
+		if componentID != "filestream-default" {
+			return nil, errors.New("not found")
+		}
+		return []byte(`{
+			"beat": {"cpu": {"total": {"time": {"ms": 1500}}}, "memstats": {"rss": 2048, "memory_alloc": 512}},
+			"libbeat": {
+				"pipeline": {"events": {"published": 10, "failed": 1, "dropped": 0}, "queue": {"filled": {"events": 3, "pct": 0.25}}},
+				"output": {"events": {"acked": 9}}
+			}
+		}`), nil
+	}
+
+	srv := httptest.NewServer(createHandler(metricsHandler(coord, ns, fetchStats)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, openMetricsContentType, resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	out := string(body)
+
+	// only beats components have their stats fetched
+	assert.Equal(t, []string{"filestream-default"}, fetched)
+
+	expected := []string{
+		`# TYPE elastic_agent_beat_memstats_rss unknown`,
+		`elastic_agent_beat_memstats_rss 1024`,
+		`elastic_agent_system_load_1 0.5`,
+		`# TYPE elastic_agent_component_state gauge`,
+		`elastic_agent_component_state{component_id="filestream-default",binary="filebeat"} 2`,
+		`elastic_agent_component_state{component_id="endpoint-default",binary="endpoint-security"} 4`,
+		`# TYPE elastic_agent_component_restarts counter`,
+		`elastic_agent_component_restarts_total{component_id="filestream-default",binary="filebeat"} 2`,
+		`elastic_agent_component_restarts_total{component_id="endpoint-default",binary="endpoint-security"} 0`,
+		`elastic_agent_unit_state{component_id="filestream-default",binary="filebeat",unit_id="filestream-default-filestream",unit_type="input"} 3`,
+		`elastic_agent_unit_state{component_id="filestream-default",binary="filebeat",unit_id="filestream-default",unit_type="output"} 2`,
+		`elastic_agent_component_events_published_total{component_id="filestream-default",binary="filebeat"} 10`,
+		`elastic_agent_component_events_acked_total{component_id="filestream-default",binary="filebeat"} 9`,
+		`elastic_agent_component_queue_filled_events{component_id="filestream-default",binary="filebeat"} 3`,
+		`elastic_agent_component_queue_filled_ratio{component_id="filestream-default",binary="filebeat"} 0.25`,
+		`elastic_agent_component_memory_rss_bytes{component_id="filestream-default",binary="filebeat"} 2048`,
+		`elastic_agent_component_cpu_seconds_total{component_id="filestream-default",binary="filebeat"} 1.5`,
+	}
+	for _, line := range expected {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.NotContains(t, out, `elastic_agent_component_events_published_total{component_id="endpoint-default"`)
+	assert.True(t, strings.HasSuffix(out, "# EOF\n"), "output must be terminated by # EOF")
+}
+
+func TestCollectComponentStatsTimeout(t *testing.T) {
+	components := []runtime.ComponentComponentState{
+		{Component: component.Component{ID: "filestream-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "filebeat"}}},
+		{Component: component.Component{ID: "system/metrics-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "metricbeat"}}},
+	}
+	fetchStats := func(ctx context.Context, componentID string) ([]byte, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok || time.Until(deadline) > componentStatsTimeout {
+			return nil, errors.New("no deadline bounded by componentStatsTimeout")
+		}
+		if componentID == "system/metrics-default" {
+			// the component doesn't answer
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []byte(`{"libbeat": {"output": {"events": {"acked": 1}}}}`), nil
+	}
+
+	stats := collectComponentStats(context.Background(), components, fetchStats)
+	require.Len(t, stats, 1)
+	assert.Contains(t, stats, "filestream-default")
+}
+
+func TestOpenMetricsWriterEscaping(t *testing.T) {
+	var buf bytes.Buffer
+	om := &openMetricsWriter{w: &buf}
+	om.family("elastic_agent_test", metricTypeGauge, "line\nbreak")
+	om.sample("elastic_agent_test", []label{{"id", `a"b\c` + "\n"}}, 1)
+
+	assert.Equal(t,
+		"# TYPE elastic_agent_test gauge\n"+
+			"# HELP elastic_agent_test line\\nbreak\n"+
+			`elastic_agent_test{id="a\"b\\c\n"} 1`+"\n",
+		buf.String())
+	assert.Equal(t, "a_b_c_1", sanitizeMetricName("a.b-c/1"))
+}
+
+func TestWriteAgentMetricsNameCollisions(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	monitoring.NewInt(reg, "a.b").Set(1)
+	monitoring.NewInt(reg, "a_b").Set(2)
+	monitoring.NewInt(reg, "a-b").Set(3)
+	monitoring.NewInt(reg, "component.state").Set(4)
+	monitoring.NewInt(reg, "component.restarts.total").Set(5)
+	ns := &monitoring.Namespace{}
+	ns.SetRegistry(reg)
+
+	var buf bytes.Buffer
+	writeAgentMetrics(&openMetricsWriter{w: &buf}, ns)
+	out := buf.String()
+
+	for _, line := range []string{
+		"elastic_agent_a_b 3",
+		"elastic_agent_a_b_2 1",
+		"elastic_agent_a_b_3 2",
+		"elastic_agent_component_state_2 4",
+		"elastic_agent_component_restarts_total_2 5",
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.NotContains(t, out, "elastic_agent_component_state ")
+	assert.NotContains(t, out, "elastic_agent_component_restarts_total ")
+}
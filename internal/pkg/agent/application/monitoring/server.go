@@ -66,6 +66,7 @@ func exposeMetricsEndpoint(
 
 		statsHandler := statsHandler(statNs)
 		r.Handle("/stats", createHandler(statsHandler))
+		r.Handle("/metrics", createHandler(metricsHandler(coord, statNs, FetchComponentStats)))
 
 		if isProcessStatsEnabled(cfg) {
 			log.Infof("process monitoring is enabled, creating monitoring endpoints")
@@ -75,6 +76,9 @@ func exposeMetricsEndpoint(
 			r.Handle("/processes/{componentID}/{metricsPath}", createHandler(processHandler(coord, statsHandler, operatingSystem)))
 
 			r.Handle("/liveness", createHandler(livenessHandler(coord)))
+			r.Handle("/readiness", createHandler(readinessHandler(coord)))
+			r.Handle("/health/components/{componentID}", createHandler(componentHealthHandler(coord, false)))
+			r.Handle("/health/components/{componentID}/ready", createHandler(componentHealthHandler(coord, true)))
 		}
 
 		if isPprofEnabled(cfg) {
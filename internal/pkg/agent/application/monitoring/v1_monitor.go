@@ -272,7 +272,7 @@ func (b *BeatsMonitor) ComponentMonitoringConfig(unitID, binary string) map[stri
 	}
 
 	// only beats understand these flags
-	if !isSupportedBeatsBinary(binary) {
+	if !IsSupportedBeatsBinary(binary) {
 		return nil
 	}
 
@@ -742,7 +742,7 @@ func (b *BeatsMonitor) getBeatsStreams(
 
 	for _, compInfo := range componentInfos {
 		binaryName := compInfo.BinaryName
-		if !isSupportedBeatsBinary(binaryName) {
+		if !IsSupportedBeatsBinary(binaryName) {
 			continue
 		}
 
@@ -1321,7 +1321,8 @@ func isSupportedMetricsBinary(binaryName string) bool {
 	return false
 }
 
-func isSupportedBeatsBinary(binaryName string) bool {
+// IsSupportedBeatsBinary returns true when the binary is a beat reporting its metrics on its monitoring endpoint.
+func IsSupportedBeatsBinary(binaryName string) bool {
 	for _, supportedBinary := range supportedBeatsComponents {
 		if strings.EqualFold(supportedBinary, binaryName) {
 			return true
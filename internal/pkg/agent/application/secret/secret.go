@@ -18,13 +18,44 @@ import (
 
 const AgentSecretKey = "secret"
 
+// AgentSecretNextKey is the key of the agent secret created by a rotation in progress, it replaces the
+// agent secret once the data encrypted with the agent secret is re-encrypted.
+const AgentSecretNextKey = "secret.next"
+
 // mutex for secret create calls
 var mxCreate sync.Mutex
 
 // Secret is the structure that is JSON serialized and stored
 type Secret struct {
-	Value     []byte    `json:"v"` // binary value
-	CreatedOn time.Time `json:"t"` // date/time the secret was created on
+	Value     []byte    `json:"v"`             // binary value
+	CreatedOn time.Time `json:"t"`             // date/time the secret was created on
+	Version   uint32    `json:"ver,omitempty"` // version of the secret, incremented by every rotation
+}
+
+// Keyring holds the agent secrets data can be encrypted with.
+type Keyring struct {
+	Current Secret
+	// Next is the secret created by the rotation in progress, nil when no rotation is in progress.
+	Next *Secret
+}
+
+// Active returns the secret new data must be encrypted with.
+func (k Keyring) Active() Secret {
+	if k.Next != nil {
+		return *k.Next
+	}
+	return k.Current
+}
+
+// ByVersion returns the secret of the keyring with the given version.
+func (k Keyring) ByVersion(version uint32) (Secret, bool) {
+	if k.Next != nil && k.Next.Version == version {
+		return *k.Next, true
+	}
+	if k.Current.Version == version {
+		return k.Current, true
+	}
+	return Secret{}, false
 }
 
 // CreateAgentSecret creates agent secret key if it doesn't exist
@@ -78,6 +109,121 @@ func SetAgentSecret(ctx context.Context, secret Secret, opts ...vault.OptionFunc
 	return Set(ctx, AgentSecretKey, secret, opts...)
 }
 
+// GetAgentKeyring reads the agent secret and the secret of the rotation in progress from the vault
+func GetAgentKeyring(ctx context.Context, opts ...vault.OptionFunc) (keyring Keyring, err error) {
+	// open vault readonly, will not create the vault directory or the seed it was not created before
+	opts = append(opts, vault.WithReadonly(true))
+	v, err := vault.New(ctx, opts...)
+	if err != nil {
+		return keyring, err
+	}
+	defer v.Close()
+
+	keyring.Current, err = get(ctx, v, AgentSecretKey)
+	if err != nil {
+		return keyring, err
+	}
+	keyring.Next, err = getNext(ctx, v, keyring.Current)
+	return keyring, err
+}
+
+// BeginAgentSecretRotation creates the agent secret replacing the current one and returns the keyring
+// holding both. The current secret is kept until CommitAgentSecretRotation is called, an interrupted
+// rotation is resumed with the secret it created.
+func BeginAgentSecretRotation(ctx context.Context, opts ...vault.OptionFunc) (keyring Keyring, err error) {
+	v, err := vault.New(ctx, opts...)
+	if err != nil {
+		return keyring, fmt.Errorf("could not create new vault: %w", err)
+	}
+	defer v.Close()
+
+	mxCreate.Lock()
+	defer mxCreate.Unlock()
+
+	keyring.Current, err = get(ctx, v, AgentSecretKey)
+	if err != nil {
+		return keyring, fmt.Errorf("could not read agent secret: %w", err)
+	}
+	keyring.Next, err = getNext(ctx, v, keyring.Current)
+	if err != nil {
+		return keyring, fmt.Errorf("could not read agent secret of the rotation in progress: %w", err)
+	}
+
+	if keyring.Next == nil {
+		k, err := aesgcm.NewKey(aesgcm.AES256)
+		if err != nil {
+			return keyring, err
+		}
+		next := Secret{
+			Value:     k,
+			CreatedOn: time.Now().UTC(),
+			Version:   keyring.Current.Version + 1,
+		}
+		if err := set(ctx, v, AgentSecretNextKey, next); err != nil {
+			return keyring, fmt.Errorf("could not save the new agent secret: %w", err)
+		}
+		keyring.Next = &next
+	}
+
+	return keyring, nil
+}
+
+// CommitAgentSecretRotation replaces the agent secret with the secret created by
+// BeginAgentSecretRotation, the previous secret is discarded. The data encrypted with the previous
+// secret must be re-encrypted before.
+func CommitAgentSecretRotation(ctx context.Context, opts ...vault.OptionFunc) error {
+	v, err := vault.New(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("could not create new vault: %w", err)
+	}
+	defer v.Close()
+
+	mxCreate.Lock()
+	defer mxCreate.Unlock()
+
+	current, err := get(ctx, v, AgentSecretKey)
+	if err != nil {
+		return fmt.Errorf("could not read agent secret: %w", err)
+	}
+	next, err := getNext(ctx, v, current)
+	if err != nil {
+		return fmt.Errorf("could not read agent secret of the rotation in progress: %w", err)
+	}
+	if next != nil {
+		if err := set(ctx, v, AgentSecretKey, *next); err != nil {
+			return fmt.Errorf("could not replace the agent secret: %w", err)
+		}
+	}
+
+	// the next secret is the agent secret at this point, if it cannot be removed getNext handles it
+	// as the secret of a committed rotation
+	exists, err := v.Exists(ctx, AgentSecretNextKey)
+	if err != nil || !exists {
+		return err
+	}
+	if err := v.Remove(ctx, AgentSecretNextKey); err != nil {
+		return fmt.Errorf("could not remove the agent secret of the rotation: %w", err)
+	}
+	return nil
+}
+
+// getNext returns the secret of the rotation in progress, nil when no rotation is in progress or when
+// the rotation was committed before the secret could be removed.
+func getNext(ctx context.Context, v vault.Vault, current Secret) (*Secret, error) {
+	exists, err := v.Exists(ctx, AgentSecretNextKey)
+	if err != nil || !exists {
+		return nil, err
+	}
+	next, err := get(ctx, v, AgentSecretNextKey)
+	if err != nil {
+		return nil, err
+	}
+	if next.Version <= current.Version {
+		return nil, nil
+	}
+	return &next, nil
+}
+
 // Get reads the secret key from the vault
 func Get(ctx context.Context, key string, opts ...vault.OptionFunc) (secret Secret, err error) {
 	// open vault readonly, will not create the vault directory or the seed it was not created before
@@ -88,6 +234,10 @@ func Get(ctx context.Context, key string, opts ...vault.OptionFunc) (secret Secr
 	}
 	defer v.Close()
 
+	return get(ctx, v, key)
+}
+
+func get(ctx context.Context, v vault.Vault, key string) (secret Secret, err error) {
 	b, err := v.Get(ctx, key)
 	if err != nil {
 		return secret, err
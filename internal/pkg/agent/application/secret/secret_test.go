@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
@@ -69,3 +71,65 @@ func TestCreate(t *testing.T) {
 		}
 	}
 }
+
+func TestAgentSecretRotation(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "secret storage does not use NewGCMWithRandomNonce.")
+	opts := getTestOptions(t)
+
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	require.NoError(t, CreateAgentSecret(ctx, opts...))
+	initial, err := GetAgentKeyring(ctx, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), initial.Current.Version)
+	assert.Nil(t, initial.Next)
+	assert.Equal(t, initial.Current, initial.Active())
+
+	keyring, err := BeginAgentSecretRotation(ctx, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, initial.Current, keyring.Current, "the current secret is kept during the rotation")
+	require.NotNil(t, keyring.Next)
+	assert.Equal(t, uint32(1), keyring.Next.Version)
+	assert.NotEqual(t, initial.Current.Value, keyring.Next.Value)
+	assert.Equal(t, *keyring.Next, keyring.Active())
+
+	current, ok := keyring.ByVersion(0)
+	assert.True(t, ok)
+	assert.Equal(t, initial.Current, current)
+	next, ok := keyring.ByVersion(1)
+	assert.True(t, ok)
+	assert.Equal(t, *keyring.Next, next)
+	_, ok = keyring.ByVersion(2)
+	assert.False(t, ok)
+
+	// an interrupted rotation is resumed with the same secret
+	resumed, err := BeginAgentSecretRotation(ctx, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, keyring, resumed)
+	read, err := GetAgentKeyring(ctx, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, keyring, read)
+
+	require.NoError(t, CommitAgentSecretRotation(ctx, opts...))
+	committed, err := GetAgentKeyring(ctx, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, *keyring.Next, committed.Current)
+	assert.Nil(t, committed.Next)
+
+	// committing without a rotation in progress does nothing
+	require.NoError(t, CommitAgentSecretRotation(ctx, opts...))
+	read, err = GetAgentKeyring(ctx, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, committed, read)
+
+	// the secret of a rotation committed before it was removed is not used
+	require.NoError(t, Set(ctx, AgentSecretNextKey, committed.Current, opts...))
+	read, err = GetAgentKeyring(ctx, opts...)
+	require.NoError(t, err)
+	assert.Nil(t, read.Next)
+	keyring, err = BeginAgentSecretRotation(ctx, opts...)
+	require.NoError(t, err)
+	require.NotNil(t, keyring.Next)
+	assert.Equal(t, uint32(2), keyring.Next.Version)
+}
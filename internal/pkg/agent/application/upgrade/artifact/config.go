@@ -61,6 +61,9 @@ type configWithoutHTTPTransportSettings struct {
 	// will increase for subsequent retry attempts in a randomized exponential backoff manner.
 	// This key is, for some reason, problematic
 	RetrySleepInitDuration time.Duration `yaml:"retry_sleep_init_duration" config:"retry_sleep_init_duration"`
+
+	// PeerCache: distribution of the artifacts between agents, see PeerCacheConfig.
+	PeerCache PeerCacheConfig `yaml:"peer_cache" config:"peer_cache" json:"peerCache"`
 }
 
 // Config is a configuration used for verifier and downloader
@@ -91,9 +94,39 @@ type Config struct {
 	// will increase for subsequent retry attempts in a randomized exponential backoff manner.
 	RetrySleepInitDuration time.Duration `yaml:"retry_sleep_init_duration" config:"retry_sleep_init_duration"`
 
+	// PeerCache: distribution of the artifacts between agents, see PeerCacheConfig.
+	PeerCache PeerCacheConfig `yaml:"peer_cache" config:"peer_cache" json:"peerCache"`
+
 	httpcommon.HTTPTransportSettings `config:",inline" yaml:",inline"` // Note: use anonymous struct for json inline
 }
 
+// PeerCacheConfig configures the distribution of the artifacts between agents,
+// so a single agent or a designated cache node downloads them from the SourceURI
+// and the other agents of the network download them from it.
+type PeerCacheConfig struct {
+	// Sources: URIs of the agents or cache nodes serving artifacts, e.g http://10.0.0.5:6792/.
+	// They are tried in order before the SourceURI. The artifacts downloaded from
+	// them are verified against the SHA512 and the PGP signature of the SourceURI.
+	Sources []string `yaml:"sources" config:"sources" json:"sources"`
+
+	// Serve: serving the verified artifacts of this agent to the other agents.
+	Serve PeerCacheServeConfig `yaml:"serve" config:"serve" json:"serve"`
+}
+
+// PeerCacheServeConfig configures the HTTP endpoint serving the verified artifacts
+// of this agent to the other agents.
+type PeerCacheServeConfig struct {
+	// Enabled: serve the verified artifacts.
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+
+	// Host: address the endpoint listens on, e.g 10.0.0.5:6792. It must be set
+	// when the endpoint is enabled.
+	Host string `yaml:"host" config:"host" json:"host"`
+
+	// Path: directory containing the served artifacts, defaults to TargetDirectory.
+	Path string `yaml:"path" config:"path" json:"path"`
+}
+
 type Reloader struct {
 	log       *logger.Logger
 	cfg       *Config
@@ -144,6 +177,7 @@ func (r *Reloader) reloadConfig(rawConfig *config.Config) error {
 		TargetDirectory:       tmp.C.TargetDirectory,
 		InstallPath:           tmp.C.InstallPath,
 		DropPath:              tmp.C.DropPath,
+		PeerCache:             tmp.C.PeerCache,
 		HTTPTransportSettings: tmp.C.HTTPTransportSettings,
 	}
 
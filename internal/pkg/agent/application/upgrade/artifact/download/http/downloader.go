@@ -41,6 +41,13 @@ const (
 	// warningProgressIntervalPercentage defines how often to log messages as a warning once the amount of time
 	// passed is this percentage or more of the total allotted time to download.
 	warningProgressIntervalPercentage = 0.75
+
+	// partialSuffix is appended to the path of a file while it's being downloaded.
+	partialSuffix = ".part"
+
+	// validatorSuffix is appended to the path of a partial download to store the ETag or
+	// Last-Modified value of the file, used to resume the download with If-Range.
+	validatorSuffix = ".validator"
 )
 
 // Downloader is a downloader able to fetch artifacts from elastic.co web page.
@@ -100,7 +107,7 @@ func (e *Downloader) Download(ctx context.Context, a artifact.Artifact, version
 	defer func() {
 		if err != nil {
 			for _, path := range downloadedFiles {
-				if err := os.Remove(path); err != nil {
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 					e.log.Warnf("failed to cleanup %s: %v", path, err)
 				}
 			}
@@ -167,59 +174,186 @@ func (e *Downloader) downloadHash(ctx context.Context, remoteArtifact string, op
 	return e.downloadFile(ctx, remoteArtifact, filename, fullPath)
 }
 
-func (e *Downloader) downloadFile(ctx context.Context, artifactName, filename, fullPath string) (string, error) {
+func (e *Downloader) downloadFile(ctx context.Context, artifactName, filename, fullPath string) (_ string, err error) {
 	sourceURI, err := e.composeURI(artifactName, filename)
 	if err != nil {
 		return "", err
 	}
 
-	req, err := http.NewRequest("GET", sourceURI, nil)
-	if err != nil {
-		return "", errors.New(err, "fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
-	}
-
 	if destinationDir := filepath.Dir(fullPath); destinationDir != "" && destinationDir != "." {
 		if err := os.MkdirAll(destinationDir, 0o755); err != nil {
 			return "", err
 		}
 	}
 
-	destinationFile, err := os.OpenFile(fullPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, packagePermissions)
+	// the artifact is downloaded next to its destination and only moved in place
+	// once complete. A download interrupted by a network error is kept to be resumed
+	// by the next attempt, it's removed on any other error or when ctx is cancelled.
+	partialPath := fullPath + partialSuffix
+	keepPartial := false
+	defer func() {
+		if err != nil && !keepPartial {
+			removePartialDownload(partialPath)
+		}
+	}()
+
+	resp, offset, err := e.requestFile(ctx, sourceURI, partialPath)
 	if err != nil {
-		return "", errors.New(err, "creating package file failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, fullPath))
+		// the server couldn't be reached, unlike an unsuccessful status code
+		var urlErr *url.Error
+		keepPartial = ctx.Err() == nil && errors.As(err, &urlErr)
+		return fullPath, err
 	}
-	defer destinationFile.Close()
+	defer resp.Body.Close()
 
-	resp, err := e.client.Do(req.WithContext(ctx))
+	flags := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	if offset > 0 {
+		flags = os.O_CREATE | os.O_APPEND | os.O_WRONLY
+	}
+	destinationFile, err := os.OpenFile(partialPath, flags, packagePermissions)
 	if err != nil {
-		// return path, file already exists and needs to be cleaned up
-		return fullPath, errors.New(err, "fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+		return fullPath, errors.New(err, "creating package file failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, partialPath))
 	}
-	defer resp.Body.Close()
+	defer destinationFile.Close()
 
-	if resp.StatusCode != 200 {
-		// return path, file already exists and needs to be cleaned up
-		return fullPath, errors.New(fmt.Sprintf("call to '%s' returned unsuccessful status code: %d", sourceURI, resp.StatusCode), errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+	if offset == 0 {
+		e.storeValidator(partialPath, resp)
 	}
 
 	fileSize := -1
 	if contentLength := resp.Header.Get("Content-Length"); contentLength != "" {
 		if length, err := strconv.Atoi(contentLength); err == nil {
-			fileSize = length
+			// the content length of a partial response is the length of the remaining content
+			fileSize = int(offset) + length
 		}
 	}
 
 	loggingObserver := newLoggingProgressObserver(e.log, e.config.HTTPTransportSettings.Timeout)
 	detailsObserver := newDetailsProgressObserver(e.upgradeDetails)
 	dp := newDownloadProgressReporter(sourceURI, e.config.HTTPTransportSettings.Timeout, fileSize, loggingObserver, detailsObserver)
+	if offset > 0 {
+		dp.ReportResumed(offset)
+	}
 	dp.Report(ctx)
 	_, err = io.Copy(destinationFile, io.TeeReader(resp.Body, dp))
+	if err == nil {
+		// the file must be closed before being moved on Windows
+		err = destinationFile.Close()
+	}
 	if err != nil {
 		dp.ReportFailed(err)
-		// return path, file already exists and needs to be cleaned up
+		keepPartial = ctx.Err() == nil
+		// return path, a complete file may already exist and needs to be cleaned up
 		return fullPath, errors.New(err, "copying fetched package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
 	}
+
+	if err := os.Rename(partialPath, fullPath); err != nil {
+		dp.ReportFailed(err)
+		return fullPath, errors.New(err, "moving downloaded package failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, fullPath))
+	}
+	_ = os.Remove(partialPath + validatorSuffix)
 	dp.ReportComplete()
 
 	return fullPath, nil
 }
+
+// requestFile requests the file at sourceURI, resuming the partial download at
+// partialPath with a range request when possible. It returns the response and
+// the offset of the response body in the file, zero when the whole file is sent.
+func (e *Downloader) requestFile(ctx context.Context, sourceURI, partialPath string) (*http.Response, int64, error) {
+	offset, validator := partialDownload(partialPath)
+	for {
+		req, err := http.NewRequestWithContext(ctx, "GET", sourceURI, nil)
+		if err != nil {
+			return nil, 0, errors.New(err, "fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			// the server sends the whole file when it changed since the partial download
+			req.Header.Set("If-Range", validator)
+		}
+
+		resp, err := e.client.Do(req)
+		if err != nil {
+			return nil, 0, errors.New(err, "fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			if offset > 0 {
+				e.log.Infof("download from %s cannot be resumed, downloading the whole file", sourceURI)
+			}
+			return resp, 0, nil
+		case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+			if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); ok && start == offset {
+				return resp, offset, nil
+			}
+			e.log.Warnf("download from %s returned unexpected range %q, downloading the whole file", sourceURI, resp.Header.Get("Content-Range"))
+		case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+			e.log.Infof("partial download from %s doesn't match the file anymore, downloading the whole file", sourceURI)
+		default:
+			resp.Body.Close()
+			return nil, 0, errors.New(fmt.Sprintf("call to '%s' returned unsuccessful status code: %d", sourceURI, resp.StatusCode), errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+		}
+
+		// retry without range
+		resp.Body.Close()
+		offset = 0
+	}
+}
+
+// storeValidator stores the validator the server sent for the file next to the
+// partial download, so an interrupted download can be resumed with If-Range.
+// Only strong ETags and Last-Modified dates can be used with If-Range.
+func (e *Downloader) storeValidator(partialPath string, resp *http.Response) {
+	validatorPath := partialPath + validatorSuffix
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = resp.Header.Get("Last-Modified")
+	}
+	if validator == "" || resp.Header.Get("Accept-Ranges") == "none" {
+		// the download cannot be resumed
+		_ = os.Remove(validatorPath)
+		return
+	}
+	if err := os.WriteFile(validatorPath, []byte(validator), packagePermissions); err != nil {
+		e.log.Warnf("failed to store download validator, an interrupted download won't be resumed: %v", err)
+	}
+}
+
+// removePartialDownload removes the partial download at partialPath and its validator.
+func removePartialDownload(partialPath string) {
+	_ = os.Remove(partialPath)
+	_ = os.Remove(partialPath + validatorSuffix)
+}
+
+// partialDownload returns the size and the validator of the partial download
+// at partialPath, a zero size means there is nothing to resume.
+func partialDownload(partialPath string) (int64, string) {
+	info, err := os.Stat(partialPath)
+	if err != nil || info.Size() == 0 {
+		return 0, ""
+	}
+	validator, err := os.ReadFile(partialPath + validatorSuffix)
+	if err != nil || len(validator) == 0 {
+		return 0, ""
+	}
+	return info.Size(), string(validator)
+}
+
+// contentRangeStart returns the first byte position of a "bytes <start>-<end>/<size>" Content-Range header.
+func contentRangeStart(contentRange string) (int64, bool) {
+	byteRange, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
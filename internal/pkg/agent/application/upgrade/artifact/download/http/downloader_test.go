@@ -528,3 +528,231 @@ func TestDownloadVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	const etag = `"artifact-etag"`
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	serveContent := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", modTime, bytes.NewReader(content))
+	}
+	ignoreRange := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write(content)
+	}
+
+	testCases := []struct {
+		name            string
+		handler         http.HandlerFunc
+		partial         []byte
+		validator       string
+		expectedRange   string
+		expectedResumed bool
+	}{
+		{
+			name:            "resumes partial download",
+			handler:         serveContent,
+			partial:         content[:4000],
+			validator:       etag,
+			expectedRange:   "bytes=4000-",
+			expectedResumed: true,
+		},
+		{
+			name:          "artifact changed",
+			handler:       serveContent,
+			partial:       []byte("stale content"),
+			validator:     `"old-etag"`,
+			expectedRange: "bytes=13-",
+		},
+		{
+			name:          "ranges not supported",
+			handler:       ignoreRange,
+			partial:       content[:4000],
+			validator:     etag,
+			expectedRange: "bytes=4000-",
+		},
+		{
+			name:          "range not satisfiable",
+			handler:       serveContent,
+			partial:       append(bytes.Clone(content), []byte("trailing garbage")...),
+			validator:     etag,
+			expectedRange: fmt.Sprintf("bytes=%d-", len(content)+16),
+		},
+		{
+			name:    "no validator",
+			handler: serveContent,
+			partial: content[:4000],
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ranges []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if filepath.Ext(r.URL.Path) == ".gz" {
+					ranges = append(ranges, r.Header.Get("Range"))
+				}
+				tc.handler(w, r)
+			}))
+			defer srv.Close()
+
+			targetDir := t.TempDir()
+			config := &artifact.Config{
+				SourceURI:       srv.URL,
+				TargetDirectory: targetDir,
+				OperatingSystem: "linux",
+				Architecture:    "64",
+			}
+			fullPath, err := artifact.GetArtifactPath(beatSpec, *version, config.OS(), config.Arch(), targetDir)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(fullPath+partialSuffix, tc.partial, packagePermissions))
+			if tc.validator != "" {
+				require.NoError(t, os.WriteFile(fullPath+partialSuffix+validatorSuffix, []byte(tc.validator), packagePermissions))
+			}
+
+			log, obs := loggertest.New("downloader")
+			upgradeDetails := details.NewDetails("8.12.0", details.StateRequested, "")
+			testClient := NewDownloaderWithClient(log, config, *srv.Client(), upgradeDetails)
+			artifactPath, err := testClient.Download(context.Background(), beatSpec, version)
+			require.NoError(t, err)
+			assert.Equal(t, fullPath, artifactPath)
+
+			downloaded, err := os.ReadFile(artifactPath)
+			require.NoError(t, err)
+			assert.Equal(t, content, downloaded)
+			assert.NoFileExists(t, fullPath+partialSuffix)
+			assert.NoFileExists(t, fullPath+partialSuffix+validatorSuffix)
+
+			require.NotEmpty(t, ranges)
+			assert.Equal(t, tc.expectedRange, ranges[0])
+			resumed := obs.FilterMessageSnippet("resuming download").Len() > 0
+			assert.Equal(t, tc.expectedResumed, resumed)
+		})
+	}
+}
+
+func TestDownloadKeepsPartialFile(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	const etag = `"artifact-etag"`
+
+	// the first request fails half way through the artifact
+	var requests int
+	type connKey struct{}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if filepath.Ext(r.URL.Path) != ".gz" {
+			_, _ = w.Write(content)
+			return
+		}
+		requests++
+		if requests > 1 {
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(content[:4000])
+		w.(http.Flusher).Flush()
+		if conn, ok := r.Context().Value(connKey{}).(net.Conn); ok {
+			_ = conn.Close()
+		}
+	}))
+	srv.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		return context.WithValue(ctx, connKey{}, c)
+	}
+	srv.Start()
+	defer srv.Close()
+
+	targetDir := t.TempDir()
+	config := &artifact.Config{
+		SourceURI:       srv.URL,
+		TargetDirectory: targetDir,
+		OperatingSystem: "linux",
+		Architecture:    "64",
+	}
+	fullPath, err := artifact.GetArtifactPath(beatSpec, *version, config.OS(), config.Arch(), targetDir)
+	require.NoError(t, err)
+	log, _ := loggertest.New("downloader")
+	upgradeDetails := details.NewDetails("8.12.0", details.StateRequested, "")
+
+	testClient := NewDownloaderWithClient(log, config, *srv.Client(), upgradeDetails)
+	_, err = testClient.Download(context.Background(), beatSpec, version)
+	require.Error(t, err)
+	assert.NoFileExists(t, fullPath)
+	partial, err := os.ReadFile(fullPath + partialSuffix)
+	require.NoError(t, err)
+	assert.Equal(t, content[:4000], partial)
+
+	testClient = NewDownloaderWithClient(log, config, *srv.Client(), upgradeDetails)
+	artifactPath, err := testClient.Download(context.Background(), beatSpec, version)
+	require.NoError(t, err)
+	downloaded, err := os.ReadFile(artifactPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.Equal(t, 2, requests)
+}
+
+func TestDownloadRemovesPartialFile(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	const etag = `"artifact-etag"`
+
+	testCases := []struct {
+		name    string
+		handler func(cancel context.CancelFunc) http.HandlerFunc
+	}{
+		{
+			name: "unsuccessful status code",
+			handler: func(context.CancelFunc) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+		},
+		{
+			name: "cancelled during the download",
+			handler: func(cancel context.CancelFunc) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("ETag", etag)
+					w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+					w.WriteHeader(http.StatusOK)
+					_, _ = w.Write(content[:4000])
+					w.(http.Flusher).Flush()
+					cancel()
+					<-r.Context().Done()
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			srv := httptest.NewServer(tc.handler(cancel))
+			defer srv.Close()
+
+			targetDir := t.TempDir()
+			config := &artifact.Config{
+				SourceURI:       srv.URL,
+				TargetDirectory: targetDir,
+				OperatingSystem: "linux",
+				Architecture:    "64",
+			}
+			fullPath, err := artifact.GetArtifactPath(beatSpec, *version, config.OS(), config.Arch(), targetDir)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(fullPath+partialSuffix, content[:2000], packagePermissions))
+			require.NoError(t, os.WriteFile(fullPath+partialSuffix+validatorSuffix, []byte(etag), packagePermissions))
+
+			log, _ := loggertest.New("downloader")
+			upgradeDetails := details.NewDetails("8.12.0", details.StateRequested, "")
+			testClient := NewDownloaderWithClient(log, config, *srv.Client(), upgradeDetails)
+			_, err = testClient.Download(ctx, beatSpec, version)
+			require.Error(t, err)
+			assert.NoFileExists(t, fullPath)
+			assert.NoFileExists(t, fullPath+partialSuffix)
+			assert.NoFileExists(t, fullPath+partialSuffix+validatorSuffix)
+		})
+	}
+}
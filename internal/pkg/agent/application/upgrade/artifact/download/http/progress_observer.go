@@ -18,6 +18,9 @@ type progressObserver interface {
 	// Report is called on a periodic basis with information about the download's progress so far.
 	Report(sourceURI string, timePast time.Duration, downloadedBytes, totalBytes, percentComplete, downloadRate float64)
 
+	// ReportResumed is called once before the periodic reports when the download resumes a partial download.
+	ReportResumed(sourceURI string, resumedBytes, totalBytes float64)
+
 	// ReportCompleted is called when the download completes successfully.
 	ReportCompleted(sourceURI string, timePast time.Duration, downloadRate float64)
 
@@ -63,6 +66,15 @@ func (lpObs *loggingProgressObserver) Report(sourceURI string, timePast time.Dur
 	}
 }
 
+func (lpObs *loggingProgressObserver) ReportResumed(sourceURI string, resumedBytes, totalBytes float64) {
+	if totalBytes > 0 {
+		lpObs.log.Infof("resuming download from %s at %s/%s (%.2f%% complete)",
+			sourceURI, units.HumanSize(resumedBytes), units.HumanSize(totalBytes), resumedBytes/totalBytes*100.0)
+		return
+	}
+	lpObs.log.Infof("resuming download from %s at %s", sourceURI, units.HumanSize(resumedBytes))
+}
+
 func (lpObs *loggingProgressObserver) ReportCompleted(sourceURI string, timePast time.Duration, downloadRate float64) {
 	msg := "download from %s completed in %s @ %sps"
 	args := []interface{}{
@@ -117,6 +129,17 @@ func (dpObs *detailsProgressObserver) Report(sourceURI string, timePast time.Dur
 	dpObs.upgradeDetails.SetDownloadProgress(percentComplete, downloadRateBytesPerSecond)
 }
 
+func (dpObs *detailsProgressObserver) ReportResumed(sourceURI string, resumedBytes, totalBytes float64) {
+	if totalBytes <= 0 {
+		return
+	}
+
+	dpObs.mu.Lock()
+	defer dpObs.mu.Unlock()
+
+	dpObs.upgradeDetails.SetDownloadProgress(resumedBytes/totalBytes*100.0, 0)
+}
+
 func (dpObs *detailsProgressObserver) ReportCompleted(sourceURI string, timePast time.Duration, downloadRateBytesPerSecond float64) {
 	dpObs.mu.Lock()
 	defer dpObs.mu.Unlock()
@@ -17,6 +17,7 @@ type downloadProgressReporter struct {
 	length      float64
 
 	downloaded atomic.Int64
+	resumed    float64
 	started    time.Time
 
 	progressObservers []progressObserver
@@ -45,6 +46,16 @@ func (dp *downloadProgressReporter) Write(b []byte) (int, error) {
 	return n, nil
 }
 
+// ReportResumed reports to registered observers that the download resumes a partial
+// download of resumedBytes. It must be called before Report, the resumed bytes count
+// towards the progress of the download but not towards its rate.
+func (dp *downloadProgressReporter) ReportResumed(resumedBytes int64) {
+	dp.resumed = float64(resumedBytes)
+	for _, obs := range dp.progressObservers {
+		obs.ReportResumed(dp.sourceURI, dp.resumed, dp.length)
+	}
+}
+
 // Report periodically reports download progress to registered observers. Callers MUST either
 // cancel the context provided to this method OR call either ReportComplete or ReportFailed when
 // they no longer need the downloadProgressReporter to avoid resource leaks.
@@ -53,6 +64,7 @@ func (dp *downloadProgressReporter) Report(ctx context.Context) {
 	dp.started = started
 	sourceURI := dp.sourceURI
 	length := dp.length
+	resumed := dp.resumed
 	interval := dp.interval
 
 	// If there are no observers to report progress to, there is nothing to do!
@@ -76,11 +88,11 @@ func (dp *downloadProgressReporter) Report(ctx context.Context) {
 				bytesPerSecond := downloaded / float64(timePast/time.Second)
 				var percentComplete float64
 				if length > 0 {
-					percentComplete = downloaded / length * 100.0
+					percentComplete = (resumed + downloaded) / length * 100.0
 				}
 
 				for _, obs := range dp.progressObservers {
-					obs.Report(sourceURI, timePast, downloaded, length, percentComplete, bytesPerSecond)
+					obs.Report(sourceURI, timePast, resumed+downloaded, length, percentComplete, bytesPerSecond)
 				}
 			}
 		}
@@ -125,10 +137,10 @@ func (dp *downloadProgressReporter) ReportFailed(err error) {
 	bytesPerSecond := downloaded / float64(timePast/time.Second)
 	var percentComplete float64
 	if dp.length > 0 {
-		percentComplete = downloaded / dp.length * 100.0
+		percentComplete = (dp.resumed + downloaded) / dp.length * 100.0
 	}
 
 	for _, obs := range dp.progressObservers {
-		obs.ReportFailed(dp.sourceURI, timePast, downloaded, dp.length, percentComplete, bytesPerSecond, err)
+		obs.ReportFailed(dp.sourceURI, timePast, dp.resumed+downloaded, dp.length, percentComplete, bytesPerSecond, err)
 	}
 }
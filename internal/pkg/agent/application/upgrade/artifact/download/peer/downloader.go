@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peer
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"sync"
+
+	"go.elastic.co/apm/v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+// ErrNoSources is returned when no peer cache source is configured.
+var ErrNoSources = goerrors.New("no peer cache sources configured")
+
+// Downloader fetches artifacts from the agents or cache nodes configured as
+// peer cache sources, trying them in order. The downloaded artifacts are
+// verified as usual by the verifier of the SourceURI.
+type Downloader struct {
+	log            *logger.Logger
+	upgradeDetails *details.Details
+
+	mu sync.RWMutex
+	dd []*sourceDownloader
+}
+
+type sourceDownloader struct {
+	source     string
+	downloader *http.Downloader
+}
+
+// NewDownloader creates a downloader fetching artifacts from the peer cache sources of the config.
+func NewDownloader(log *logger.Logger, config *artifact.Config, upgradeDetails *details.Details) (*Downloader, error) {
+	d := &Downloader{
+		log:            log,
+		upgradeDetails: upgradeDetails,
+	}
+	if err := d.Reload(config); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Download fetches the package from the first peer cache source having it.
+// Returns absolute path to downloaded package and an error.
+func (d *Downloader) Download(ctx context.Context, a artifact.Artifact, version *agtversion.ParsedSemVer) (string, error) {
+	span, ctx := apm.StartSpan(ctx, "download", "app.internal")
+	defer span.End()
+
+	d.mu.RLock()
+	dd := d.dd
+	d.mu.RUnlock()
+
+	if len(dd) == 0 {
+		return "", ErrNoSources
+	}
+
+	var errs []error
+	for _, sd := range dd {
+		path, err := sd.downloader.Download(ctx, a, version)
+		if err == nil {
+			d.log.Infof("Downloaded %s from peer cache %s", a.Name, sd.source)
+			return path, nil
+		}
+		d.log.Debugf("Failed to download %s from peer cache %s: %v", a.Name, sd.source, err)
+		errs = append(errs, fmt.Errorf("peer cache %s: %w", sd.source, err))
+	}
+
+	return "", goerrors.Join(errs...)
+}
+
+// Reload recreates the downloaders of the peer cache sources out of the config.
+func (d *Downloader) Reload(c *artifact.Config) error {
+	dd := make([]*sourceDownloader, 0, len(c.PeerCache.Sources))
+	for _, source := range c.PeerCache.Sources {
+		sourceConfig := *c
+		sourceConfig.SourceURI = source
+
+		downloader, err := http.NewDownloader(d.log, &sourceConfig, d.upgradeDetails)
+		if err != nil {
+			return errors.New(err, fmt.Sprintf("failed to create downloader for peer cache %s", source))
+		}
+		dd = append(dd, &sourceDownloader{source: source, downloader: downloader})
+	}
+
+	d.mu.Lock()
+	d.dd = dd
+	d.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+var agentArtifact = artifact.Artifact{
+	Name:     "Elastic Agent",
+	Cmd:      "elastic-agent",
+	Artifact: "beats/elastic-agent",
+}
+
+func TestDownloader(t *testing.T) {
+	content := []byte("verified elastic agent package")
+	cacheDir := t.TempDir()
+	writeArtifact(t, cacheDir, packageName, content, false)
+
+	log, _ := loggertest.New("peer")
+	cache := httptest.NewServer(NewServer(log, &artifact.Config{TargetDirectory: cacheDir}))
+	defer cache.Close()
+	empty := httptest.NewServer(http.NotFoundHandler())
+	defer empty.Close()
+
+	targetDir := t.TempDir()
+	config := &artifact.Config{
+		SourceURI:       "https://artifacts.elastic.co/downloads/",
+		TargetDirectory: targetDir,
+		OperatingSystem: "linux",
+		Architecture:    "64",
+		PeerCache: artifact.PeerCacheConfig{
+			// the first peer doesn't have the artifact
+			Sources: []string{empty.URL, cache.URL},
+		},
+	}
+	upgradeDetails := details.NewDetails("9.1.0", details.StateRequested, "")
+	d, err := NewDownloader(log, config, upgradeDetails)
+	require.NoError(t, err)
+
+	path, err := d.Download(context.Background(), agentArtifact, agtversion.NewParsedSemVer(9, 1, 0, "", ""))
+	require.NoError(t, err)
+
+	downloaded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.NoError(t, download.VerifySHA512Hash(path))
+
+	_, err = d.Download(context.Background(), agentArtifact, agtversion.NewParsedSemVer(9, 2, 0, "", ""))
+	assert.ErrorContains(t, err, "peer cache "+cache.URL)
+}
+
+func TestDownloaderReload(t *testing.T) {
+	log, _ := loggertest.New("peer")
+	config := &artifact.Config{TargetDirectory: t.TempDir()}
+	upgradeDetails := details.NewDetails("9.1.0", details.StateRequested, "")
+	d, err := NewDownloader(log, config, upgradeDetails)
+	require.NoError(t, err)
+
+	_, err = d.Download(context.Background(), agentArtifact, agtversion.NewParsedSemVer(9, 1, 0, "", ""))
+	assert.ErrorIs(t, err, ErrNoSources)
+
+	reloaded := *config
+	reloaded.PeerCache.Sources = []string{"http://10.0.0.5:6792/"}
+	require.NoError(t, d.Reload(&reloaded))
+	assert.Len(t, d.dd, 1)
+	assert.Equal(t, "http://10.0.0.5:6792/", d.dd[0].source)
+}
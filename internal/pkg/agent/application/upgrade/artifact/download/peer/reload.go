@@ -0,0 +1,132 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peer
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// ServerReloader starts, stops or restarts the server sharing the artifacts
+// with other agents when its settings change.
+type ServerReloader struct {
+	log *logger.Logger
+
+	mx              sync.Mutex
+	srv             *Server
+	serve           artifact.PeerCacheServeConfig
+	targetDirectory string
+}
+
+// NewServerReloader creates the reloader of the server sharing the artifacts
+// of the config, Apply starts the server when it's enabled.
+func NewServerReloader(log *logger.Logger, config *artifact.Config) *ServerReloader {
+	return &ServerReloader{
+		log:             log,
+		serve:           config.PeerCache.Serve,
+		targetDirectory: config.TargetDirectory,
+	}
+}
+
+// Apply starts the server when it's enabled and not running yet.
+func (r *ServerReloader) Apply() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.apply(r.serve, r.targetDirectory)
+}
+
+// Reload applies the peer cache settings of the configuration. The settings
+// of the server are kept when the configuration doesn't have them, the
+// policies coming from Fleet don't always define them.
+func (r *ServerReloader) Reload(rawConfig *config.Config) error {
+	var reloaded struct {
+		Agent struct {
+			Download struct {
+				TargetDirectory string `config:"target_directory"`
+				PeerCache       struct {
+					Serve *artifact.PeerCacheServeConfig `config:"serve"`
+				} `config:"peer_cache"`
+			} `config:"download"`
+		} `config:"agent"`
+	}
+	if err := rawConfig.UnpackTo(&reloaded); err != nil {
+		return errors.New(err, "failed to unpack peer cache config during reload")
+	}
+
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	serve := r.serve
+	if reloaded.Agent.Download.PeerCache.Serve != nil {
+		serve = *reloaded.Agent.Download.PeerCache.Serve
+	}
+	targetDirectory := r.targetDirectory
+	if reloaded.Agent.Download.TargetDirectory != "" {
+		targetDirectory = reloaded.Agent.Download.TargetDirectory
+	}
+	return r.apply(serve, targetDirectory)
+}
+
+// apply stops the running server when its settings changed and starts it
+// again when it's enabled. It must be called with the lock held.
+func (r *ServerReloader) apply(serve artifact.PeerCacheServeConfig, targetDirectory string) error {
+	unchanged := serve == r.serve && targetDirectory == r.targetDirectory
+	r.serve, r.targetDirectory = serve, targetDirectory
+	if r.srv != nil && unchanged {
+		return nil
+	}
+	if err := r.stop(); err != nil {
+		return err
+	}
+	if !serve.Enabled {
+		return nil
+	}
+
+	srv := NewServer(r.log, &artifact.Config{
+		TargetDirectory: targetDirectory,
+		PeerCache:       artifact.PeerCacheConfig{Serve: serve},
+	})
+	if err := srv.Start(); err != nil {
+		return fmt.Errorf("failed to start the peer cache server: %w", err)
+	}
+	r.srv = srv
+	return nil
+}
+
+// Stop stops the server when it's running.
+func (r *ServerReloader) Stop() error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.stop()
+}
+
+func (r *ServerReloader) stop() error {
+	if r.srv == nil {
+		return nil
+	}
+	r.log.Info("Stopping the peer cache server")
+	srv := r.srv
+	r.srv = nil
+	if err := srv.Stop(); err != nil {
+		return fmt.Errorf("failed to stop the peer cache server: %w", err)
+	}
+	return nil
+}
+
+// Addr returns the address the server listens on, nil when it's not running.
+func (r *ServerReloader) Addr() net.Addr {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.srv == nil {
+		return nil
+	}
+	return r.srv.Addr()
+}
@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestServerReloader(t *testing.T) {
+	log, _ := loggertest.New("peer")
+	dir := t.TempDir()
+
+	r := NewServerReloader(log, &artifact.Config{TargetDirectory: dir})
+	t.Cleanup(func() {
+		_ = r.Stop()
+	})
+	require.NoError(t, r.Apply())
+	assert.Nil(t, r.Addr(), "the server is disabled by default")
+
+	serve := func(serve string) *config.Config {
+		return config.MustNewConfigFrom(`
+agent.download.peer_cache.serve:
+` + serve)
+	}
+
+	err := r.Reload(serve("  enabled: true\n"))
+	assert.ErrorContains(t, err, "host must be set")
+	assert.Nil(t, r.Addr(), "the server doesn't listen on every interface by default")
+
+	require.NoError(t, r.Reload(serve("  enabled: true\n  host: 127.0.0.1:0\n")))
+	addr := r.Addr()
+	require.NotNil(t, addr, "the server is started once enabled")
+
+	require.NoError(t, r.Reload(config.MustNewConfigFrom(`agent.download.sourceURI: "https://example.com/"`)))
+	assert.Equal(t, addr, r.Addr(), "the server keeps running when the configuration doesn't have its settings")
+
+	require.NoError(t, r.Reload(serve("  enabled: true\n  host: 127.0.0.1:0\n  path: "+t.TempDir()+"\n")))
+	require.NotNil(t, r.Addr())
+	assert.NotEqual(t, addr, r.Addr(), "the server is restarted when its settings change")
+
+	require.NoError(t, r.Reload(serve("  enabled: false\n")))
+	assert.Nil(t, r.Addr(), "the server is stopped once disabled")
+}
@@ -0,0 +1,181 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	hashSuffix = ".sha512"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Server serves the artifacts of a directory to other agents. Only the
+// artifacts matching their SHA512 sidecar file are served, so an agent never
+// shares an artifact it could not verify itself.
+type Server struct {
+	log  *logger.Logger
+	host string
+	dir  string
+
+	srv      *http.Server
+	listener net.Listener
+
+	mu       sync.Mutex
+	verified map[string]fileStamp
+}
+
+// fileStamp identifies the content of a verified file without hashing it again.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewServer creates the server sharing the artifacts of the config with other agents.
+func NewServer(log *logger.Logger, config *artifact.Config) *Server {
+	host := config.PeerCache.Serve.Host
+	dir := config.PeerCache.Serve.Path
+	if dir == "" {
+		dir = config.TargetDirectory
+	}
+
+	s := &Server{
+		log:      log,
+		host:     host,
+		dir:      dir,
+		verified: make(map[string]fileStamp),
+	}
+	s.srv = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Start starts listening and serving the artifacts in the background. The
+// address to listen on must be set explicitly, the artifacts are not served on
+// every interface of the host by default.
+func (s *Server) Start() error {
+	if s.host == "" {
+		return errors.New("agent.download.peer_cache.serve.host must be set to serve the artifacts")
+	}
+	listener, err := net.Listen("tcp", s.host)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.host, err)
+	}
+	s.listener = listener
+
+	s.log.Infof("Serving verified artifacts of %s to other agents on %s", s.dir, listener.Addr())
+	go func() {
+		if err := s.srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Errorf("Peer cache server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the server listens on, nil before Start.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Stop stops the server.
+func (s *Server) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return s.srv.Shutdown(ctx)
+}
+
+// ServeHTTP serves an artifact or its SHA512 sidecar file. Artifacts are looked up
+// by file name so any path layout of the SourceURI, e.g. beats/elastic-agent/, works.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := path.Base(r.URL.Path)
+	if name == "." || name == "/" || strings.HasPrefix(name, ".") {
+		http.NotFound(w, r)
+		return
+	}
+
+	packageName := strings.TrimSuffix(name, hashSuffix)
+	if err := s.verify(filepath.Join(s.dir, packageName)); err != nil {
+		s.log.Debugf("Not serving %s to %s: %v", name, r.RemoteAddr, err)
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.log.Debugf("Serving %s to %s", name, r.RemoteAddr)
+	// ServeContent supports range requests, interrupted downloads can be resumed
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// verify checks the package matches its SHA512 sidecar file. The result is
+// kept as long as the package and its sidecar file don't change.
+func (s *Server) verify(packagePath string) error {
+	stamp, err := stampOf(packagePath)
+	if err != nil {
+		return err
+	}
+	hashStamp, err := stampOf(packagePath + hashSuffix)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.verified[packagePath] == stamp && s.verified[packagePath+hashSuffix] == hashStamp {
+		return nil
+	}
+	if err := download.VerifySHA512Hash(packagePath); err != nil {
+		return err
+	}
+	s.verified[packagePath] = stamp
+	s.verified[packagePath+hashSuffix] = hashStamp
+	return nil
+}
+
+func stampOf(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{size: info.Size(), modTime: info.ModTime()}, nil
+}
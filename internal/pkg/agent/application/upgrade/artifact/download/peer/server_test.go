@@ -0,0 +1,148 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peer
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+const packageName = "elastic-agent-9.1.0-linux-x86_64.tar.gz"
+
+// writeArtifact writes a package and its SHA512 sidecar file to dir, the
+// sidecar file doesn't match the package when corrupt is set.
+func writeArtifact(t *testing.T, dir, name string, content []byte, corrupt bool) {
+	hash := sha512.Sum512(content)
+	if corrupt {
+		hash = sha512.Sum512([]byte("something else"))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+hashSuffix), []byte(fmt.Sprintf("%x  %s\n", hash, name)), 0o600))
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("verified elastic agent package")
+	writeArtifact(t, dir, packageName, content, false)
+	writeArtifact(t, dir, "elastic-agent-9.2.0-linux-x86_64.tar.gz", []byte("tampered package"), true)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "elastic-agent-9.3.0-linux-x86_64.tar.gz"), []byte("no hash"), 0o600))
+
+	log, _ := loggertest.New("peer")
+	s := NewServer(log, &artifact.Config{TargetDirectory: dir})
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	testCases := []struct {
+		name         string
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "verified package",
+			path:         "/beats/elastic-agent/" + packageName,
+			expectedCode: http.StatusOK,
+			expectedBody: string(content),
+		},
+		{
+			name:         "hash of verified package",
+			path:         "/beats/elastic-agent/" + packageName + hashSuffix,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "package not matching its hash",
+			path:         "/beats/elastic-agent/elastic-agent-9.2.0-linux-x86_64.tar.gz",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "hash of package not matching it",
+			path:         "/beats/elastic-agent/elastic-agent-9.2.0-linux-x86_64.tar.gz" + hashSuffix,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "package without hash",
+			path:         "/beats/elastic-agent/elastic-agent-9.3.0-linux-x86_64.tar.gz",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "missing package",
+			path:         "/beats/elastic-agent/elastic-agent-9.4.0-linux-x86_64.tar.gz",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "path traversal stays in the directory",
+			path:         "/../../" + packageName,
+			expectedCode: http.StatusOK,
+			expectedBody: string(content),
+		},
+		{
+			name:         "root",
+			path:         "/",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tc.path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tc.expectedCode, resp.StatusCode)
+
+			if tc.expectedBody != "" {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedBody, string(body))
+			}
+		})
+	}
+}
+
+func TestServerRangeRequest(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("verified elastic agent package")
+	writeArtifact(t, dir, packageName, content, false)
+
+	log, _ := loggertest.New("peer")
+	srv := httptest.NewServer(NewServer(log, &artifact.Config{TargetDirectory: dir}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/"+packageName, nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=9-")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, string(content[9:]), string(body))
+}
+
+func TestServerReverifiesChangedPackage(t *testing.T) {
+	dir := t.TempDir()
+	writeArtifact(t, dir, packageName, []byte("verified elastic agent package"), false)
+
+	log, _ := loggertest.New("peer")
+	s := NewServer(log, &artifact.Config{PeerCache: artifact.PeerCacheConfig{Serve: artifact.PeerCacheServeConfig{Path: dir}}})
+	require.NoError(t, s.verify(filepath.Join(dir, packageName)))
+
+	// the package is replaced by a package of another size
+	require.NoError(t, os.WriteFile(filepath.Join(dir, packageName), []byte("replaced package"), 0o600))
+	assert.Error(t, s.verify(filepath.Join(dir, packageName)))
+}
@@ -59,6 +59,10 @@ type Metadata struct {
 	// Reason is a string that may give out more information about transitioning to the current state. It has been
 	// introduced initially to distinguish between manual and automatic rollbacks
 	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+
+	// FailedHealthGate is the name of the health gate of the upgrade watcher that
+	// failed and triggered the rollback of the upgrade.
+	FailedHealthGate string `json:"failed_health_gate,omitempty" yaml:"failed_health_gate,omitempty"`
 }
 
 func NewDetails(targetVersion string, initialState State, actionID string) *Details {
@@ -112,6 +116,21 @@ func (d *Details) SetStateWithReason(s State, reason string) {
 	d.notifyObservers()
 }
 
+// SetRollbackForHealthGate is a convenience method to set the state of the upgrade
+// to StateRollback because the health gate failed, and notify all observers.
+func (d *Details) SetRollbackForHealthGate(gate string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.State = StateRollback
+	d.Metadata.Reason = ReasonHealthGateFailed
+	d.Metadata.FailedHealthGate = gate
+	d.Metadata.ErrorMsg = ""
+	d.Metadata.FailedState = ""
+
+	d.notifyObservers()
+}
+
 // SetDownloadProgress is a convenience method to set the download percent
 // and download rate when the upgrade is in UPG_DOWNLOADING state.
 func (d *Details) SetDownloadProgress(percent, rateBytesPerSecond float64) {
@@ -231,7 +250,8 @@ func (m Metadata) Equals(otherM Metadata) bool {
 		m.DownloadPercent == otherM.DownloadPercent &&
 		m.DownloadRate == otherM.DownloadRate &&
 		equalTimePointers(m.RetryUntil, otherM.RetryUntil) &&
-		m.RetryErrorMsg == otherM.RetryErrorMsg
+		m.RetryErrorMsg == otherM.RetryErrorMsg &&
+		m.FailedHealthGate == otherM.FailedHealthGate
 }
 
 func equalTimePointers(t, otherT *time.Time) bool {
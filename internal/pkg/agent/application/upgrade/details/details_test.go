@@ -40,6 +40,21 @@ func TestDetailsSetStateWithReason(t *testing.T) {
 	assert.Equal(t, ReasonWatchFailed, det.Metadata.Reason)
 }
 
+func TestDetailsSetRollbackForHealthGate(t *testing.T) {
+	det := NewDetails("99.999.9999", StateWatching, "test_action_id")
+
+	var observed Metadata
+	det.RegisterObserver(func(d *Details) {
+		observed = d.Metadata
+	})
+
+	det.SetRollbackForHealthGate("filestream-healthy")
+	assert.Equal(t, StateRollback, det.State)
+	assert.Equal(t, ReasonHealthGateFailed, det.Metadata.Reason)
+	assert.Equal(t, "filestream-healthy", det.Metadata.FailedHealthGate)
+	assert.Equal(t, "filestream-healthy", observed.FailedHealthGate)
+}
+
 func TestDetailsFail(t *testing.T) {
 	det := NewDetails("99.999.9999", StateRequested, "test_action_id")
 	require.Equal(t, StateRequested, det.State)
@@ -0,0 +1,26 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package upgrade
+
+import (
+	winsys "golang.org/x/sys/windows"
+)
+
+// freeDiskSpace returns the disk space available to the agent on the volume
+// holding path.
+func freeDiskSpace(path string) (uint64, error) {
+	p, err := winsys.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var available, total, free uint64
+	if err := winsys.GetDiskFreeSpaceEx(p, &available, &total, &free); err != nil {
+		return 0, err
+	}
+	return available, nil
+}
@@ -0,0 +1,235 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+// Names of the checks of an upgrade dry run, in the order they run.
+const (
+	DryRunCheckUpgradeable  = "upgradeable"
+	DryRunCheckCapabilities = "capabilities"
+	DryRunCheckDownload     = "download"
+	DryRunCheckVerification = "verification"
+	DryRunCheckVersion      = "version"
+	DryRunCheckDiskSpace    = "disk_space"
+	DryRunCheckUnpack       = "unpack"
+)
+
+// DryRunCheck is the outcome of a single upgrade precondition.
+type DryRunCheck struct {
+	Name    string `json:"name" yaml:"name"`
+	Passed  bool   `json:"passed" yaml:"passed"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// DryRunReport is the outcome of an upgrade dry run. The checks stop at the
+// first failure, the checks after it are not in the report.
+type DryRunReport struct {
+	Version string        `json:"version" yaml:"version"`
+	Checks  []DryRunCheck `json:"checks" yaml:"checks"`
+}
+
+// NewDryRunReport creates an empty report for an upgrade to version.
+func NewDryRunReport(version string) *DryRunReport {
+	return &DryRunReport{Version: version}
+}
+
+// Pass records the check as passed.
+func (r *DryRunReport) Pass(name string, message string) {
+	r.Checks = append(r.Checks, DryRunCheck{Name: name, Passed: true, Message: message})
+}
+
+// Fail records the check as failed with err.
+func (r *DryRunReport) Fail(name string, err error) {
+	r.Checks = append(r.Checks, DryRunCheck{Name: name, Passed: false, Message: err.Error()})
+}
+
+// Passed returns true when no check failed.
+func (r *DryRunReport) Passed() bool {
+	for _, c := range r.Checks {
+		if !c.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// DryRun runs the preconditions of an upgrade to version without replacing the
+// running agent, recording the outcome of each check in report. The package is
+// unpacked in a temporary directory removed afterwards, the downloaded package
+// is kept so the upgrade doesn't download it again.
+func (u *Upgrader) DryRun(ctx context.Context, report *DryRunReport, version string, sourceURI string, skipVerifyOverride bool, skipDefaultPgp bool, pgpBytes ...string) {
+	u.log.Infow("Running upgrade dry run", "version", version, "source_uri", sourceURI)
+
+	currentVersion := agentVersion{
+		version:  release.Version(),
+		snapshot: release.Snapshot(),
+		hash:     release.Commit(),
+		fips:     release.FIPSDistribution(),
+	}
+	if isSameReleaseVersion(u.log, currentVersion, version) {
+		report.Fail(DryRunCheckVersion, ErrUpgradeSameVersion)
+		return
+	}
+
+	parsedVersion, err := agtversion.ParseVersion(version)
+	if err != nil {
+		report.Fail(DryRunCheckVersion, fmt.Errorf("error parsing version %q: %w", version, err))
+		return
+	}
+
+	// details are not reported, a dry run is not an upgrade in progress
+	det := details.NewDetails(version, details.StateDownloading, "")
+	archivePath, settings, verifier, err := u.fetchArtifact(ctx, parsedVersion, u.sourceURI(sourceURI), det)
+	if err != nil {
+		report.Fail(DryRunCheckDownload, err)
+		return
+	}
+	report.Pass(DryRunCheckDownload, archivePath)
+
+	if skipVerifyOverride {
+		report.Pass(DryRunCheckVerification, "verification skipped")
+	} else {
+		if err := u.verifyArtifact(ctx, parsedVersion, settings, verifier, skipDefaultPgp, pgpBytes...); err != nil {
+			report.Fail(DryRunCheckVerification, err)
+			return
+		}
+		report.Pass(DryRunCheckVerification, "")
+	}
+
+	metadata, err := u.getPackageMetadata(archivePath)
+	if err != nil {
+		report.Fail(DryRunCheckVersion, fmt.Errorf("reading metadata for elastic agent version %s package %q: %w", version, archivePath, err))
+		return
+	}
+	newVersion := extractAgentVersion(metadata, version)
+	if err := checkUpgrade(u.log, currentVersion, newVersion, metadata); err != nil {
+		report.Fail(DryRunCheckVersion, err)
+		return
+	}
+	report.Pass(DryRunCheckVersion, fmt.Sprintf("upgrading from %s to %s", currentVersion, newVersion))
+
+	required, err := unpackedSize(archivePath)
+	if err != nil {
+		report.Fail(DryRunCheckDiskSpace, fmt.Errorf("failed to compute the unpacked size of %q: %w", archivePath, err))
+		return
+	}
+	available, err := freeDiskSpace(paths.Data())
+	if err != nil {
+		report.Fail(DryRunCheckDiskSpace, fmt.Errorf("failed to get the free disk space of %q: %w", paths.Data(), err))
+		return
+	}
+	if available < required {
+		report.Fail(DryRunCheckDiskSpace, fmt.Errorf("%d bytes available in %q, %d bytes required", available, paths.Data(), required))
+		return
+	}
+	report.Pass(DryRunCheckDiskSpace, fmt.Sprintf("%d bytes available, %d bytes required", available, required))
+
+	unpackRes, err := u.dryRunUnpack(version, archivePath)
+	if err != nil {
+		report.Fail(DryRunCheckUnpack, err)
+		return
+	}
+	report.Pass(DryRunCheckUnpack, unpackRes.VersionedHome)
+}
+
+// dryRunUnpack unpacks the package in a temporary directory next to the
+// installed versions and removes it.
+func (u *Upgrader) dryRunUnpack(version, archivePath string) (UnpackResult, error) {
+	tmpDir, err := os.MkdirTemp(paths.Data(), "upgrade-dry-run-")
+	if err != nil {
+		return UnpackResult{}, errors.New(err, "failed to create the unpack directory", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, paths.Data()))
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			u.log.Errorw("Unable to remove the upgrade dry run unpack directory", "error.message", err, "file.path", tmpDir)
+		}
+	}()
+
+	detectedFlavor, err := install.UsedFlavor(paths.Top(), "")
+	if err != nil {
+		u.log.Warnf("error encountered when detecting used flavor with top path %q: %v", paths.Top(), err)
+	}
+	unpackRes, err := u.unpack(version, archivePath, tmpDir, detectedFlavor)
+	if err != nil {
+		return UnpackResult{}, err
+	}
+	if unpackRes.Hash == "" {
+		return UnpackResult{}, errors.New("unknown hash")
+	}
+	if unpackRes.VersionedHome == "" {
+		return UnpackResult{}, fmt.Errorf("versionedhome is empty: %v", unpackRes)
+	}
+	return unpackRes, nil
+}
+
+// unpackedSize returns the size of the files of the package unpacked in the
+// data directory.
+func unpackedSize(archivePath string) (uint64, error) {
+	// entries are prefixed by elastic-agent-{version}-{os}-{arch}/
+	inData := func(name string) bool {
+		_, rel, _ := strings.Cut(name, "/")
+		return strings.HasPrefix(rel, "data/")
+	}
+
+	if filepath.Ext(archivePath) == ".zip" {
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+
+		var size uint64
+		for _, f := range r.File {
+			if f.Mode().IsRegular() && inData(f.Name) {
+				size += f.UncompressedSize64
+			}
+		}
+		return size, nil
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, errors.New("requires gzip-compressed body", err, errors.TypeFilesystem)
+	}
+
+	var size uint64
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return size, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if hdr.Typeflag == tar.TypeReg && inData(hdr.Name) && hdr.Size > 0 {
+			size += uint64(hdr.Size)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunReport(t *testing.T) {
+	report := NewDryRunReport("9.1.0")
+	report.Pass(DryRunCheckUpgradeable, "")
+	report.Pass(DryRunCheckDownload, "elastic-agent-9.1.0-linux-x86_64.tar.gz")
+	assert.True(t, report.Passed())
+
+	report.Fail(DryRunCheckVerification, errors.New("invalid signature"))
+	assert.False(t, report.Passed())
+	assert.Equal(t, &DryRunReport{
+		Version: "9.1.0",
+		Checks: []DryRunCheck{
+			{Name: DryRunCheckUpgradeable, Passed: true},
+			{Name: DryRunCheckDownload, Passed: true, Message: "elastic-agent-9.1.0-linux-x86_64.tar.gz"},
+			{Name: DryRunCheckVerification, Passed: false, Message: "invalid signature"},
+		},
+	}, report)
+}
+
+func TestUnpackedSize(t *testing.T) {
+	var expected uint64
+	for _, f := range archiveFilesWithManifestNoSymlink {
+		if f.fType == REGULAR && strings.Contains(f.path, "/data/") {
+			expected += uint64(len(f.content))
+		}
+	}
+
+	tests := map[string]func(t *testing.T, archiveFiles []files) (string, error){
+		"tar.gz": func(t *testing.T, archiveFiles []files) (string, error) {
+			return createTarArchive(t, "elastic-agent-1.2.3-SNAPSHOT-someos-x86_64.tar.gz", archiveFiles)
+		},
+		"zip": func(t *testing.T, archiveFiles []files) (string, error) {
+			return createZipArchive(t, "elastic-agent-1.2.3-SNAPSHOT-someos-x86_64.zip", archiveFiles)
+		},
+	}
+	for name, createArchive := range tests {
+		t.Run(name, func(t *testing.T) {
+			archivePath, err := createArchive(t, archiveFilesWithManifestNoSymlink)
+			require.NoError(t, err)
+
+			size, err := unpackedSize(archivePath)
+			require.NoError(t, err)
+			assert.Equal(t, expected, size)
+		})
+	}
+}
+
+func TestFreeDiskSpace(t *testing.T) {
+	available, err := freeDiskSpace(t.TempDir())
+	require.NoError(t, err)
+	assert.NotZero(t, available)
+}
@@ -0,0 +1,319 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/eql"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+// errNoState is returned by the health gates until the state of the agent is received.
+var errNoState = errors.New("no state received from the agent")
+
+// HealthGateError is returned when a health gate fails the upgrade.
+type HealthGateError struct {
+	Gate string
+	Err  error
+}
+
+func (e *HealthGateError) Error() string {
+	return fmt.Sprintf("health gate %q failed: %s", e.Gate, e.Err)
+}
+
+func (e *HealthGateError) Unwrap() error {
+	return e.Err
+}
+
+// ComponentStatsFetcher returns the stats of a running component as exposed on its monitoring socket.
+type ComponentStatsFetcher func(ctx context.Context, componentID string) ([]byte, error)
+
+// ComponentFilter returns true for the components the gate applies to.
+type ComponentFilter func(comp client.ComponentState) bool
+
+// HealthGates evaluates the health gates the upgraded agent must pass during the grace period
+// against the latest state of the agent.
+type HealthGates struct {
+	gates          []*healthGate
+	fetchStats     ComponentStatsFetcher
+	reportsMetrics ComponentFilter
+
+	mu    sync.Mutex
+	state *client.AgentState
+}
+
+type healthGate struct {
+	cfg       configuration.HealthGateConfig
+	condition *eql.Expression
+
+	// failingSince is when the gate started failing, zero when it passes
+	failingSince time.Time
+}
+
+// NewHealthGates creates the health gates out of their configuration. The events published gates
+// without a component only apply to the components reportsMetrics returns true for, all the
+// components with inputs when it's nil.
+func NewHealthGates(cfgs []configuration.HealthGateConfig, fetchStats ComponentStatsFetcher, reportsMetrics ComponentFilter) (*HealthGates, error) {
+	h := &HealthGates{fetchStats: fetchStats, reportsMetrics: reportsMetrics}
+	for _, cfg := range cfgs {
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+		gate := &healthGate{cfg: cfg}
+		if cfg.Type == configuration.HealthGateCondition {
+			condition, err := eql.New(cfg.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("health gate %q has an invalid condition: %w", cfg.Name, err)
+			}
+			gate.condition = condition
+		}
+		h.gates = append(h.gates, gate)
+	}
+	return h, nil
+}
+
+// Len returns the number of health gates.
+func (h *HealthGates) Len() int {
+	return len(h.gates)
+}
+
+// Observe records the latest state of the agent the gates are evaluated against.
+func (h *HealthGates) Observe(state *client.AgentState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = state
+}
+
+// Check evaluates the gates during the grace period. It returns a *HealthGateError for
+// the first gate failing for longer than its timeout.
+func (h *HealthGates) Check(ctx context.Context, now time.Time) error {
+	state := h.latestState()
+	for _, gate := range h.gates {
+		err := h.evaluate(ctx, gate, state)
+		if err == nil {
+			gate.failingSince = time.Time{}
+			continue
+		}
+		if gate.failingSince.IsZero() {
+			gate.failingSince = now
+		}
+		if gate.cfg.Timeout > 0 && now.Sub(gate.failingSince) > gate.cfg.Timeout {
+			return gateTimeoutError(gate, err)
+		}
+	}
+	return nil
+}
+
+// Final evaluates the gates when the grace period ends. It returns a *HealthGateError
+// for the first gate without a timeout not passing, or failing for longer than its timeout.
+// Otherwise it returns how long to wait for the gates still failing within their timeout,
+// zero when all the gates pass.
+func (h *HealthGates) Final(ctx context.Context, now time.Time) (time.Duration, error) {
+	state := h.latestState()
+	var wait time.Duration
+	for _, gate := range h.gates {
+		err := h.evaluate(ctx, gate, state)
+		if err == nil {
+			gate.failingSince = time.Time{}
+			continue
+		}
+		if gate.cfg.Timeout == 0 {
+			return 0, &HealthGateError{Gate: gate.cfg.Name, Err: err}
+		}
+		if gate.failingSince.IsZero() {
+			gate.failingSince = now
+		}
+		remaining := gate.cfg.Timeout - now.Sub(gate.failingSince)
+		if remaining < 0 {
+			return 0, gateTimeoutError(gate, err)
+		}
+		wait = max(wait, remaining)
+	}
+	return wait, nil
+}
+
+func gateTimeoutError(gate *healthGate, err error) *HealthGateError {
+	return &HealthGateError{
+		Gate: gate.cfg.Name,
+		Err:  fmt.Errorf("failing for more than %s: %w", gate.cfg.Timeout, err),
+	}
+}
+
+func (h *HealthGates) latestState() *client.AgentState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.state
+}
+
+// evaluate returns nil when the gate passes, or the reason it doesn't.
+func (h *HealthGates) evaluate(ctx context.Context, gate *healthGate, state *client.AgentState) error {
+	if state == nil {
+		return errNoState
+	}
+
+	switch gate.cfg.Type {
+	case configuration.HealthGateComponentHealthy:
+		comp, ok := findComponent(state, gate.cfg.Component)
+		if !ok {
+			return fmt.Errorf("component %s is not running", gate.cfg.Component)
+		}
+		if comp.State != client.Healthy {
+			return fmt.Errorf("component %s is %s: %s", comp.ID, comp.State, comp.Message)
+		}
+	case configuration.HealthGateNoDegradedUnits:
+		var errs []error
+		for _, comp := range gateComponents(state, gate.cfg.Component) {
+			for _, unit := range comp.Units {
+				if unit.State == client.Degraded || unit.State == client.Failed {
+					errs = append(errs, fmt.Errorf("unit %s of component %s is %s: %s", unit.UnitID, comp.ID, unit.State, unit.Message))
+				}
+			}
+		}
+		return errors.Join(errs...)
+	case configuration.HealthGateEventsPublished:
+		return h.eventsPublished(ctx, h.publishingComponents(state, gate.cfg.Component))
+	case configuration.HealthGateCondition:
+		vars, err := transpiler.NewAST(stateVars(state))
+		if err != nil {
+			return fmt.Errorf("failed to create variables out of the agent state: %w", err)
+		}
+		ok, err := gate.condition.Eval(vars, true)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate condition %q: %w", gate.cfg.Condition, err)
+		}
+		if !ok {
+			return fmt.Errorf("condition %q is false", gate.cfg.Condition)
+		}
+	}
+	return nil
+}
+
+// eventsPublished returns nil when all the components published events.
+func (h *HealthGates) eventsPublished(ctx context.Context, components []client.ComponentState) error {
+	if len(components) == 0 {
+		return errors.New("no component publishing events is running")
+	}
+	var errs []error
+	for _, comp := range components {
+		published, err := h.componentEventsPublished(ctx, comp.ID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if published == 0 {
+			errs = append(errs, fmt.Errorf("component %s published no events", comp.ID))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *HealthGates) componentEventsPublished(ctx context.Context, componentID string) (uint64, error) {
+	if h.fetchStats == nil {
+		return 0, errors.New("component stats are not available")
+	}
+	data, err := h.fetchStats(ctx, componentID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch stats of component %s: %w", componentID, err)
+	}
+	var stats struct {
+		Libbeat struct {
+			Pipeline struct {
+				Events struct {
+					Published uint64 `json:"published"`
+				} `json:"events"`
+			} `json:"pipeline"`
+		} `json:"libbeat"`
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return 0, fmt.Errorf("failed to parse stats of component %s: %w", componentID, err)
+	}
+	return stats.Libbeat.Pipeline.Events.Published, nil
+}
+
+func findComponent(state *client.AgentState, id string) (client.ComponentState, bool) {
+	for _, comp := range state.Components {
+		if comp.ID == id {
+			return comp, true
+		}
+	}
+	return client.ComponentState{}, false
+}
+
+// publishingComponents returns the component with the ID, or when id is empty the components with
+// inputs that report their metrics.
+func (h *HealthGates) publishingComponents(state *client.AgentState, id string) []client.ComponentState {
+	if id != "" {
+		return gateComponents(state, id)
+	}
+	var components []client.ComponentState
+	for _, comp := range state.Components {
+		if !hasInputUnits(comp) {
+			continue
+		}
+		if h.reportsMetrics != nil && !h.reportsMetrics(comp) {
+			continue
+		}
+		components = append(components, comp)
+	}
+	return components
+}
+
+func hasInputUnits(comp client.ComponentState) bool {
+	for _, unit := range comp.Units {
+		if unit.UnitType == client.UnitTypeInput {
+			return true
+		}
+	}
+	return false
+}
+
+// gateComponents returns the component with the ID, or all the components when id is empty.
+func gateComponents(state *client.AgentState, id string) []client.ComponentState {
+	if id == "" {
+		return state.Components
+	}
+	if comp, ok := findComponent(state, id); ok {
+		return []client.ComponentState{comp}
+	}
+	return nil
+}
+
+// stateVars returns the variables the condition gates are evaluated with, e.g.
+// ${state}, ${components.filestream-default.state} or
+// ${components.filestream-default.units.filestream-default-unit.state}.
+func stateVars(state *client.AgentState) map[string]interface{} {
+	components := make(map[string]interface{}, len(state.Components))
+	for _, comp := range state.Components {
+		units := make(map[string]interface{}, len(comp.Units))
+		for _, unit := range comp.Units {
+			units[unit.UnitID] = map[string]interface{}{
+				"type":    unit.UnitType.String(),
+				"state":   unit.State.String(),
+				"message": unit.Message,
+			}
+		}
+		components[comp.ID] = map[string]interface{}{
+			"name":    comp.Name,
+			"state":   comp.State.String(),
+			"message": comp.Message,
+			"units":   units,
+		}
+	}
+	return map[string]interface{}{
+		"version":     state.Info.Version,
+		"state":       state.State.String(),
+		"message":     state.Message,
+		"fleet_state": state.FleetState.String(),
+		"components":  components,
+	}
+}
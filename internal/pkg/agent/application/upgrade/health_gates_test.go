@@ -0,0 +1,268 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func healthGatesTestState(filestream, unit client.State) *client.AgentState {
+	return &client.AgentState{
+		State: client.Healthy,
+		Components: []client.ComponentState{
+			{
+				ID:    "filestream-default",
+				Name:  "filestream",
+				State: filestream,
+				Units: []client.ComponentUnitState{
+					{UnitID: "filestream-default-unit", UnitType: client.UnitTypeInput, State: unit, Message: "unit message"},
+				},
+			},
+			{
+				ID:    "system/metrics-default",
+				Name:  "system/metrics",
+				State: client.Healthy,
+				Units: []client.ComponentUnitState{
+					{UnitID: "system/metrics-default-unit", UnitType: client.UnitTypeInput, State: client.Healthy},
+				},
+			},
+			{
+				// doesn't report metrics
+				ID:    "endpoint-default",
+				Name:  "endpoint",
+				State: client.Healthy,
+				Units: []client.ComponentUnitState{
+					{UnitID: "endpoint-default-unit", UnitType: client.UnitTypeInput, State: client.Healthy},
+				},
+			},
+			{
+				// no input configured
+				ID:    "log-default",
+				Name:  "log",
+				State: client.Healthy,
+				Units: []client.ComponentUnitState{
+					{UnitID: "log-default", UnitType: client.UnitTypeOutput, State: client.Healthy},
+				},
+			},
+		},
+	}
+}
+
+// reportsMetrics returns true for all the components but endpoint.
+func reportsMetrics(comp client.ComponentState) bool {
+	return comp.Name != "endpoint"
+}
+
+func fakeStatsFetcher(published map[string]int) ComponentStatsFetcher {
+	return func(_ context.Context, componentID string) ([]byte, error) {
+		n, ok := published[componentID]
+		if !ok {
+			return nil, errors.New("no stats")
+		}
+		return []byte(fmt.Sprintf(`{"libbeat":{"pipeline":{"events":{"published":%d}}}}`, n)), nil
+	}
+}
+
+func TestHealthGatesFinal(t *testing.T) {
+	testCases := []struct {
+		name      string
+		gate      configuration.HealthGateConfig
+		state     *client.AgentState
+		published map[string]int
+		expectErr string
+	}{
+		{
+			name:  "component healthy",
+			gate:  configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateComponentHealthy, Component: "filestream-default"},
+			state: healthGatesTestState(client.Healthy, client.Healthy),
+		},
+		{
+			name:      "component degraded",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateComponentHealthy, Component: "filestream-default"},
+			state:     healthGatesTestState(client.Degraded, client.Degraded),
+			expectErr: `health gate "gate" failed: component filestream-default is DEGRADED`,
+		},
+		{
+			name:      "component not running",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateComponentHealthy, Component: "missing"},
+			state:     healthGatesTestState(client.Healthy, client.Healthy),
+			expectErr: "component missing is not running",
+		},
+		{
+			name:      "no state",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateComponentHealthy, Component: "filestream-default"},
+			expectErr: errNoState.Error(),
+		},
+		{
+			name:  "no degraded units",
+			gate:  configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateNoDegradedUnits},
+			state: healthGatesTestState(client.Healthy, client.Healthy),
+		},
+		{
+			name:      "degraded unit",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateNoDegradedUnits},
+			state:     healthGatesTestState(client.Degraded, client.Degraded),
+			expectErr: "unit filestream-default-unit of component filestream-default is DEGRADED: unit message",
+		},
+		{
+			name:  "degraded unit of another component",
+			gate:  configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateNoDegradedUnits, Component: "system/metrics-default"},
+			state: healthGatesTestState(client.Degraded, client.Degraded),
+		},
+		{
+			name:      "events published",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateEventsPublished},
+			state:     healthGatesTestState(client.Healthy, client.Healthy),
+			published: map[string]int{"filestream-default": 3, "system/metrics-default": 12},
+		},
+		{
+			name:      "no events published by one of the components",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateEventsPublished},
+			state:     healthGatesTestState(client.Healthy, client.Healthy),
+			published: map[string]int{"filestream-default": 0, "system/metrics-default": 12},
+			expectErr: "component filestream-default published no events",
+		},
+		{
+			name:      "no component publishing events",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateEventsPublished},
+			state:     &client.AgentState{State: client.Healthy},
+			published: map[string]int{},
+			expectErr: "no component publishing events is running",
+		},
+		{
+			name:      "events published by component",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateEventsPublished, Component: "system/metrics-default"},
+			state:     healthGatesTestState(client.Healthy, client.Healthy),
+			published: map[string]int{"filestream-default": 0, "system/metrics-default": 12},
+		},
+		{
+			name:      "no events published by component",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateEventsPublished, Component: "filestream-default"},
+			state:     healthGatesTestState(client.Healthy, client.Healthy),
+			published: map[string]int{"filestream-default": 0, "system/metrics-default": 12},
+			expectErr: "component filestream-default published no events",
+		},
+		{
+			name:      "stats not available",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateEventsPublished},
+			state:     healthGatesTestState(client.Healthy, client.Healthy),
+			published: map[string]int{},
+			expectErr: "failed to fetch stats of component filestream-default: no stats",
+		},
+		{
+			name:  "condition true",
+			gate:  configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateCondition, Condition: "${components.filestream-default.units.filestream-default-unit.state} == 'HEALTHY' and ${state} == 'HEALTHY'"},
+			state: healthGatesTestState(client.Healthy, client.Healthy),
+		},
+		{
+			name:      "condition false",
+			gate:      configuration.HealthGateConfig{Name: "gate", Type: configuration.HealthGateCondition, Condition: "${components.filestream-default.state} == 'HEALTHY'"},
+			state:     healthGatesTestState(client.Starting, client.Starting),
+			expectErr: `condition "${components.filestream-default.state} == 'HEALTHY'" is false`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gates, err := NewHealthGates([]configuration.HealthGateConfig{tc.gate}, fakeStatsFetcher(tc.published), reportsMetrics)
+			require.NoError(t, err)
+			if tc.state != nil {
+				gates.Observe(tc.state)
+			}
+
+			wait, err := gates.Final(context.Background(), time.Now())
+			assert.Zero(t, wait)
+			if tc.expectErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var gateErr *HealthGateError
+			require.ErrorAs(t, err, &gateErr)
+			assert.Equal(t, "gate", gateErr.Gate)
+			assert.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+}
+
+func TestHealthGatesCheckTimeout(t *testing.T) {
+	gates, err := NewHealthGates([]configuration.HealthGateConfig{
+		{Name: "no-degraded", Type: configuration.HealthGateNoDegradedUnits, Timeout: 5 * time.Minute},
+		// without timeout the gate is only checked at the end of the grace period
+		{Name: "filestream-healthy", Type: configuration.HealthGateComponentHealthy, Component: "filestream-default"},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	gates.Observe(healthGatesTestState(client.Degraded, client.Degraded))
+	require.NoError(t, gates.Check(context.Background(), start))
+	require.NoError(t, gates.Check(context.Background(), start.Add(4*time.Minute)))
+
+	// recovering resets the timeout
+	gates.Observe(healthGatesTestState(client.Healthy, client.Healthy))
+	require.NoError(t, gates.Check(context.Background(), start.Add(5*time.Minute)))
+
+	gates.Observe(healthGatesTestState(client.Degraded, client.Degraded))
+	require.NoError(t, gates.Check(context.Background(), start.Add(6*time.Minute)))
+	require.NoError(t, gates.Check(context.Background(), start.Add(11*time.Minute)))
+
+	err = gates.Check(context.Background(), start.Add(12*time.Minute))
+	var gateErr *HealthGateError
+	require.ErrorAs(t, err, &gateErr)
+	assert.Equal(t, "no-degraded", gateErr.Gate)
+	assert.ErrorContains(t, err, "failing for more than 5m0s")
+}
+
+func TestHealthGatesFinalTimeout(t *testing.T) {
+	gates, err := NewHealthGates([]configuration.HealthGateConfig{
+		{Name: "no-degraded", Type: configuration.HealthGateNoDegradedUnits, Timeout: 5 * time.Minute},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	gates.Observe(healthGatesTestState(client.Degraded, client.Degraded))
+	require.NoError(t, gates.Check(context.Background(), start))
+
+	// the gate failing within its timeout at the end of the grace period is waited for
+	wait, err := gates.Final(context.Background(), start.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 3*time.Minute, wait)
+
+	// and passes once it recovers
+	gates.Observe(healthGatesTestState(client.Healthy, client.Healthy))
+	wait, err = gates.Final(context.Background(), start.Add(4*time.Minute))
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// or fails once its timeout expires
+	gates.Observe(healthGatesTestState(client.Degraded, client.Degraded))
+	wait, err = gates.Final(context.Background(), start.Add(5*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, wait)
+	_, err = gates.Final(context.Background(), start.Add(11*time.Minute))
+	var gateErr *HealthGateError
+	require.ErrorAs(t, err, &gateErr)
+	assert.Equal(t, "no-degraded", gateErr.Gate)
+	assert.ErrorContains(t, err, "failing for more than 5m0s")
+}
+
+func TestNewHealthGatesInvalid(t *testing.T) {
+	_, err := NewHealthGates([]configuration.HealthGateConfig{
+		{Name: "bad", Type: configuration.HealthGateCondition, Condition: "${state} =="},
+	}, nil, nil)
+	assert.ErrorContains(t, err, `health gate "bad" has an invalid condition`)
+
+	_, err = NewHealthGates([]configuration.HealthGateConfig{{Name: "unknown", Type: "bogus"}}, nil, nil)
+	assert.ErrorContains(t, err, `unknown type "bogus"`)
+}
@@ -0,0 +1,214 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	currentagtversion "github.com/elastic/elastic-agent/version"
+)
+
+var (
+	ErrNoRetainedInstall     = errors.New("no retained install of the requested version")
+	ErrRollbackDuringWatch   = errors.New("cannot roll back while an upgrade is being watched")
+	ErrRollbackToSameVersion = errors.New("cannot roll back to the running version")
+)
+
+// RetainedInstall is an agent install kept in the data directory besides the running one.
+type RetainedInstall struct {
+	// Version is the package version of the install, empty when unknown.
+	Version string `json:"version" yaml:"version"`
+	// Hash is the short commit hash of the install.
+	Hash string `json:"hash" yaml:"hash"`
+	// VersionedHome is the path of the install relative to the top directory.
+	VersionedHome string `json:"versioned_home" yaml:"versioned_home"`
+	// InstalledOn is the time the install was unpacked.
+	InstalledOn time.Time `json:"installed_on" yaml:"installed_on"`
+}
+
+// RetainedInstalls returns the agent installs kept in topDirPath other than the current one and the one rolled back
+// from, most recently installed first.
+func RetainedInstalls(topDirPath, currentVersionedHome, currentHash string) ([]RetainedInstall, error) {
+	currentDir, err := versionedHomeDir(currentVersionedHome, currentHash)
+	if err != nil {
+		return nil, err
+	}
+	dataDirPath := paths.DataFrom(topDirPath)
+	rolledBack, err := rolledBackDir(dataDirPath, currentDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the upgrade marker: %w", err)
+	}
+	return retainedInstalls(dataDirPath, currentDir, rolledBack)
+}
+
+// versionedHomeDir returns the name of the versioned home directory in the data directory.
+func versionedHomeDir(versionedHome, hash string) (string, error) {
+	if versionedHome == "" {
+		return fmt.Sprintf("%s-%s", agentName, hash), nil
+	}
+
+	dir, err := filepath.Rel("data", versionedHome)
+	if err != nil {
+		return "", fmt.Errorf("extracting elastic-agent path relative to data directory from %s: %w", versionedHome, err)
+	}
+	return dir, nil
+}
+
+// rolledBackDir returns the directory, relative to the data directory, of the install rolled back from when the
+// upgrade marker shows the upgrade was rolled back to currentDir. It returns an empty string otherwise.
+func rolledBackDir(dataDirPath, currentDir string) (string, error) {
+	marker, err := LoadMarker(dataDirPath)
+	if err != nil {
+		return "", err
+	}
+	if marker == nil || marker.Details == nil || marker.Details.State != details.StateRollback {
+		return "", nil
+	}
+	prevDir, err := versionedHomeDir(marker.PrevVersionedHome, marker.PrevHash)
+	if err != nil || prevDir != currentDir {
+		return "", nil
+	}
+	dir, err := versionedHomeDir(marker.VersionedHome, marker.Hash)
+	if err != nil || dir == currentDir {
+		return "", nil
+	}
+	return dir, nil
+}
+
+// retainedInstalls lists the installs in dataDirPath other than currentDir and rolledBackDir. The install rolled back
+// from is removed by the cleanup after the rollback, so it doesn't count as a retained install.
+func retainedInstalls(dataDirPath, currentDir, rolledBackDir string) ([]RetainedInstall, error) {
+	entries, err := os.ReadDir(dataDirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	dirPrefix := fmt.Sprintf("%s-", agentName)
+	var installs []RetainedInstall
+	for _, entry := range entries {
+		dir := entry.Name()
+		if !entry.IsDir() || dir == currentDir || dir == rolledBackDir || !strings.HasPrefix(dir, dirPrefix) {
+			continue
+		}
+
+		home := filepath.Join(dataDirPath, dir)
+		install := RetainedInstall{
+			// elastic-agent-{hash} or elastic-agent-{version}-{hash}
+			Hash:          dir[strings.LastIndex(dir, "-")+1:],
+			VersionedHome: filepath.Join("data", dir),
+		}
+
+		// package.version is written when the install is unpacked
+		versionFile := filepath.Join(home, currentagtversion.PackageVersionFileName)
+		if fi, err := os.Stat(versionFile); err == nil {
+			install.InstalledOn = fi.ModTime()
+			if content, err := os.ReadFile(versionFile); err == nil {
+				install.Version = strings.TrimSpace(string(content))
+			}
+		} else if info, err := entry.Info(); err == nil {
+			install.InstalledOn = info.ModTime()
+		}
+
+		installs = append(installs, install)
+	}
+
+	sort.SliceStable(installs, func(i, j int) bool {
+		return installs[i].InstalledOn.After(installs[j].InstalledOn)
+	})
+	return installs, nil
+}
+
+// RollbackTo switches the agent to the most recently installed retained install of version. The action store and the
+// run directory are copied to the retained install. The returned callback must be called by reexec to restart into
+// the retained install.
+func (u *Upgrader) RollbackTo(ctx context.Context, version string) (reexec.ShutdownCallbackFn, error) {
+	u.log.Infow("Rolling back agent", "version", version)
+
+	marker, err := LoadMarker(paths.Data())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the upgrade marker: %w", err)
+	}
+	if marker != nil && !markerInTerminalState(marker) {
+		return nil, ErrRollbackDuringWatch
+	}
+
+	if version == release.VersionWithSnapshot() {
+		return nil, ErrRollbackToSameVersion
+	}
+
+	currentVersionedHome, err := filepath.Rel(paths.Top(), paths.Home())
+	if err != nil {
+		return nil, fmt.Errorf("calculating home path relative to top, home: %q top: %q : %w", paths.Home(), paths.Top(), err)
+	}
+	installs, err := RetainedInstalls(paths.Top(), currentVersionedHome, release.ShortCommit())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the retained installs: %w", err)
+	}
+
+	var target *RetainedInstall
+	for i := range installs {
+		if installs[i].Version == version {
+			target = &installs[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("%w %s", ErrNoRetainedInstall, version)
+	}
+	u.log.Infow("Found retained install", "install", target)
+
+	targetHome := filepath.Join(paths.Top(), target.VersionedHome)
+	if err := copyActionStore(u.log, targetHome); err != nil {
+		return nil, errors.New(err, "failed to copy action store")
+	}
+	if err := copyRunDirectory(u.log, paths.Run(), filepath.Join(targetHome, "run")); err != nil {
+		return nil, errors.New(err, "failed to copy run directory")
+	}
+
+	symlinkPath := filepath.Join(paths.Top(), agentName)
+	if err := changeSymlink(u.log, paths.Top(), symlinkPath, paths.BinaryPath(targetHome, agentName)); err != nil {
+		return nil, err
+	}
+	if err := UpdateActiveCommit(u.log, paths.Top(), target.Hash); err != nil {
+		u.log.Errorw("Rolling back: updating active commit failed", "error.message", err)
+		restoreErr := changeSymlink(u.log, paths.Top(), symlinkPath, paths.BinaryPath(paths.Home(), agentName))
+		return nil, goerrors.Join(err, restoreErr)
+	}
+
+	// the marker describes an upgrade to the install we're leaving
+	if marker != nil {
+		if err := CleanMarker(u.log, paths.Data()); err != nil {
+			u.log.Warnw("Failed to remove the upgrade marker", "error.message", err)
+		}
+	}
+
+	return shutdownCallback(u.log, paths.Home(), release.Version(), target.Version, targetHome), nil
+}
+
+// markerInTerminalState returns true when the upgrade of the marker is over.
+func markerInTerminalState(marker *UpdateMarker) bool {
+	if marker.Details == nil {
+		return false
+	}
+
+	switch marker.Details.State {
+	case details.StateCompleted, details.StateRollback, details.StateFailed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	currentagtversion "github.com/elastic/elastic-agent/version"
+)
+
+// createRetainedInstall creates a fake agent install with its package.version file written at installedOn.
+func createRetainedInstall(t *testing.T, topDir, version, hash string, installedOn time.Time) string {
+	versionedHome := createFakeAgentInstall(t, topDir, version, hash, true)
+	versionFile := filepath.Join(topDir, versionedHome, currentagtversion.PackageVersionFileName)
+	require.NoError(t, os.WriteFile(versionFile, []byte(version), 0o600))
+	require.NoError(t, os.Chtimes(versionFile, installedOn, installedOn))
+	return versionedHome
+}
+
+func TestRetainedInstalls(t *testing.T) {
+	topDir := t.TempDir()
+	now := time.Now().Truncate(time.Second)
+
+	createRetainedInstall(t, topDir, "1.1.1", "aaabbb", now.Add(-2*time.Hour))
+	createRetainedInstall(t, topDir, "1.2.3-SNAPSHOT", "abcdef", now.Add(-time.Hour))
+	createRetainedInstall(t, topDir, "4.5.6-SNAPSHOT", "ghijkl", now)
+	// legacy install without package.version
+	legacyHome := createFakeAgentInstall(t, topDir, "0.9.9", "aaaaaa", false)
+	require.NoError(t, os.Chtimes(filepath.Join(topDir, legacyHome), now.Add(-3*time.Hour), now.Add(-3*time.Hour)))
+	// not an install
+	require.NoError(t, os.MkdirAll(filepath.Join(topDir, "data", "downloads"), 0o750))
+
+	installs, err := RetainedInstalls(topDir, filepath.Join("data", "elastic-agent-4.5.6-SNAPSHOT-ghijkl"), "ghijkl")
+	require.NoError(t, err)
+	assert.Equal(t, []RetainedInstall{
+		{
+			Version:       "1.2.3-SNAPSHOT",
+			Hash:          "abcdef",
+			VersionedHome: filepath.Join("data", "elastic-agent-1.2.3-SNAPSHOT-abcdef"),
+			InstalledOn:   installs[0].InstalledOn,
+		},
+		{
+			Version:       "1.1.1",
+			Hash:          "aaabbb",
+			VersionedHome: filepath.Join("data", "elastic-agent-1.1.1-aaabbb"),
+			InstalledOn:   installs[1].InstalledOn,
+		},
+		{
+			Hash:          "aaaaaa",
+			VersionedHome: filepath.Join("data", "elastic-agent-aaaaaa"),
+			InstalledOn:   installs[2].InstalledOn,
+		},
+	}, installs)
+	assert.True(t, installs[0].InstalledOn.Equal(now.Add(-time.Hour)))
+	assert.True(t, installs[2].InstalledOn.Equal(now.Add(-3*time.Hour)))
+
+	// the legacy current install is excluded by hash
+	installs, err = RetainedInstalls(topDir, "", "aaaaaa")
+	require.NoError(t, err)
+	assert.Len(t, installs, 3)
+	for _, install := range installs {
+		assert.NotEqual(t, "aaaaaa", install.Hash)
+	}
+
+	// the install rolled back from is excluded
+	marker := &UpdateMarker{
+		Version:           "4.5.6-SNAPSHOT",
+		Hash:              "ghijkl",
+		VersionedHome:     filepath.Join("data", "elastic-agent-4.5.6-SNAPSHOT-ghijkl"),
+		PrevVersion:       "1.2.3-SNAPSHOT",
+		PrevHash:          "abcdef",
+		PrevVersionedHome: filepath.Join("data", "elastic-agent-1.2.3-SNAPSHOT-abcdef"),
+		Details:           details.NewDetails("4.5.6-SNAPSHOT", details.StateRollback, ""),
+	}
+	require.NoError(t, SaveMarker(paths.DataFrom(topDir), marker, true))
+	installs, err = RetainedInstalls(topDir, filepath.Join("data", "elastic-agent-1.2.3-SNAPSHOT-abcdef"), "abcdef")
+	require.NoError(t, err)
+	require.Len(t, installs, 2)
+	assert.Equal(t, "1.1.1", installs[0].Version)
+	assert.Equal(t, "aaaaaa", installs[1].Hash)
+}
+
+func TestRollbackTo(t *testing.T) {
+	setup := func(t *testing.T) string {
+		topDir := t.TempDir()
+		prevTop := paths.Top()
+		paths.SetTop(topDir)
+		t.Cleanup(func() { paths.SetTop(prevTop) })
+
+		now := time.Now()
+		createRetainedInstall(t, topDir, release.VersionWithSnapshot(), release.ShortCommit(), now)
+		createRetainedInstall(t, topDir, "1.1.1", "aaabbb", now.Add(-48*time.Hour))
+		createLink(t, topDir, filepath.Join("data", "elastic-agent-"+release.VersionWithSnapshot()+"-"+release.ShortCommit()))
+		require.NoError(t, os.WriteFile(filepath.Join(paths.Run(), "state.sock"), []byte("placeholder"), 0o600))
+		return topDir
+	}
+
+	newUpgrader := func(t *testing.T) *Upgrader {
+		log, _ := loggertest.New(t.Name())
+		return &Upgrader{log: log}
+	}
+
+	t.Run("relinks the retained install", func(t *testing.T) {
+		topDir := setup(t)
+
+		cb, err := newUpgrader(t).RollbackTo(context.Background(), "1.1.1")
+		require.NoError(t, err)
+		assert.NotNil(t, cb)
+
+		targetHome := filepath.Join(topDir, "data", "elastic-agent-1.1.1-aaabbb")
+		agentExecutable := agentName
+		if runtime.GOOS == "windows" {
+			agentExecutable += ".exe"
+		}
+		linkTarget, err := os.Readlink(filepath.Join(topDir, agentExecutable))
+		require.NoError(t, err)
+		assert.Equal(t, paths.BinaryPath(targetHome, agentExecutable), linkTarget)
+
+		activeCommit, err := os.ReadFile(filepath.Join(topDir, agentCommitFile))
+		require.NoError(t, err)
+		assert.Equal(t, "aaabbb", string(activeCommit))
+		assert.FileExists(t, filepath.Join(targetHome, "run", "state.sock"))
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		setup(t)
+
+		_, err := newUpgrader(t).RollbackTo(context.Background(), "2.2.2")
+		assert.ErrorIs(t, err, ErrNoRetainedInstall)
+	})
+
+	t.Run("running version", func(t *testing.T) {
+		setup(t)
+
+		_, err := newUpgrader(t).RollbackTo(context.Background(), release.VersionWithSnapshot())
+		assert.ErrorIs(t, err, ErrRollbackToSameVersion)
+	})
+
+	t.Run("upgrade being watched", func(t *testing.T) {
+		topDir := setup(t)
+		marker := &UpdateMarker{
+			Version: "1.1.1",
+			Hash:    "aaabbb",
+			Details: details.NewDetails("1.1.1", details.StateWatching, ""),
+		}
+		require.NoError(t, SaveMarker(paths.DataFrom(topDir), marker, true))
+
+		_, err := newUpgrader(t).RollbackTo(context.Background(), "1.1.1")
+		assert.ErrorIs(t, err, ErrRollbackDuringWatch)
+	})
+
+	t.Run("completed upgrade marker is removed", func(t *testing.T) {
+		topDir := setup(t)
+		marker := &UpdateMarker{
+			Version: release.VersionWithSnapshot(),
+			Hash:    release.Commit(),
+			Details: details.NewDetails(release.VersionWithSnapshot(), details.StateCompleted, ""),
+		}
+		require.NoError(t, SaveMarker(paths.DataFrom(topDir), marker, true))
+
+		_, err := newUpgrader(t).RollbackTo(context.Background(), "1.1.1")
+		require.NoError(t, err)
+		assert.NoFileExists(t, markerFilePath(paths.DataFrom(topDir)))
+	})
+}
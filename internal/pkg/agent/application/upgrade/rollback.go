@@ -34,7 +34,8 @@ const (
 )
 
 // Rollback rollbacks to previous version which was functioning before upgrade.
-func Rollback(ctx context.Context, log *logger.Logger, c client.Client, topDirPath, prevVersionedHome, prevHash string) error {
+// The retained most recently installed versions, other than the one we're rolling back into, are kept.
+func Rollback(ctx context.Context, log *logger.Logger, c client.Client, topDirPath, prevVersionedHome, prevHash string, retained int) error {
 	symlinkPath := filepath.Join(topDirPath, agentName)
 
 	var symlinkTarget string
@@ -62,22 +63,33 @@ func Rollback(ctx context.Context, log *logger.Logger, c client.Client, topDirPa
 		return err
 	}
 
-	// cleanup everything except version we're rolling back into
-	return Cleanup(log, topDirPath, prevVersionedHome, prevHash, false, true)
+	// cleanup everything except version we're rolling back into and the retained versions
+	return Cleanup(log, topDirPath, prevVersionedHome, prevHash, false, true, retained)
 }
 
-// Cleanup removes all artifacts and files related to a specified version.
-func Cleanup(log *logger.Logger, topDirPath, currentVersionedHome, currentHash string, removeMarker, keepLogs bool) error {
-	return cleanup(log, topDirPath, currentVersionedHome, currentHash, removeMarker, keepLogs, afterRestartDelay)
+// Cleanup removes all artifacts and files related to versions other than the specified version,
+// except the retained most recently installed versions.
+func Cleanup(log *logger.Logger, topDirPath, currentVersionedHome, currentHash string, removeMarker, keepLogs bool, retained int) error {
+	return cleanup(log, topDirPath, currentVersionedHome, currentHash, removeMarker, keepLogs, retained, afterRestartDelay)
 }
 
-func cleanup(log *logger.Logger, topDirPath, currentVersionedHome, currentHash string, removeMarker, keepLogs bool, delay time.Duration) error {
-	log.Infow("Cleaning up upgrade", "hash", currentHash, "remove_marker", removeMarker)
+func cleanup(log *logger.Logger, topDirPath, currentVersionedHome, currentHash string, removeMarker, keepLogs bool, retained int, delay time.Duration) error {
+	log.Infow("Cleaning up upgrade", "hash", currentHash, "remove_marker", removeMarker, "retained", retained)
 	<-time.After(delay)
 
 	// data directory path
 	dataDirPath := paths.DataFrom(topDirPath)
 
+	currentDir, err := versionedHomeDir(currentVersionedHome, currentHash)
+	if err != nil {
+		return err
+	}
+	// read before the marker is removed
+	rolledBack, err := rolledBackDir(dataDirPath, currentDir)
+	if err != nil {
+		log.Warnw("Failed to load the update marker, the install rolled back from, if any, is counted as a retained install", "error.message", err)
+	}
+
 	// remove upgrade marker
 	if removeMarker {
 		if err := CleanMarker(log, dataDirPath); err != nil {
@@ -108,19 +120,21 @@ func cleanup(log *logger.Logger, topDirPath, currentVersionedHome, currentHash s
 	_ = os.Remove(prevSymlink)
 
 	dirPrefix := fmt.Sprintf("%s-", agentName)
-	var currentDir string
-	if currentVersionedHome != "" {
-		currentDir, err = filepath.Rel("data", currentVersionedHome)
+	keep := map[string]bool{currentDir: true}
+	if retained > 0 {
+		installs, err := retainedInstalls(dataDirPath, currentDir, rolledBack)
 		if err != nil {
-			return fmt.Errorf("extracting elastic-agent path relative to data directory from %s: %w", currentVersionedHome, err)
+			return fmt.Errorf("listing retained installs: %w", err)
+		}
+		for _, install := range installs[:min(retained, len(installs))] {
+			log.Infow("Retaining agent install", "version", install.Version, "versioned_home", install.VersionedHome)
+			keep[filepath.Base(install.VersionedHome)] = true
 		}
-	} else {
-		currentDir = fmt.Sprintf("%s-%s", agentName, currentHash)
 	}
 
 	var errs []error
 	for _, dir := range subdirs {
-		if dir == currentDir {
+		if keep[dir] {
 			continue
 		}
 
@@ -11,12 +11,14 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
 	mocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
@@ -58,6 +60,7 @@ func TestCleanup(t *testing.T) {
 		currentHash          string
 		removeMarker         bool
 		keepLogs             bool
+		retained             int
 	}
 
 	tests := map[string]struct {
@@ -202,6 +205,59 @@ func TestCleanup(t *testing.T) {
 				checkFilesAfterCleanup(t, topDir, newAgentHome, oldAgentHomes...)
 			},
 		},
+		"cleanup keeps the retained installs": {
+			args: args{
+				currentVersionedHome: "data/elastic-agent-4.5.6-SNAPSHOT-ghijkl",
+				currentHash:          "ghijkl",
+				removeMarker:         true,
+				keepLogs:             false,
+				retained:             2,
+			},
+			agentInstallsSetup: setupAgentInstallations{
+				installedAgents: []testAgentInstall{
+					{
+						version: testAgentVersion{
+							version: "0.9.9",
+							hash:    "aaaaaa",
+						},
+						useVersionInPath: true,
+					},
+					{
+						version: testAgentVersion{
+							version: "1.1.1",
+							hash:    "aaabbb",
+						},
+						useVersionInPath: true,
+					},
+					{
+						version:          version123Snapshot,
+						useVersionInPath: true,
+					},
+					{
+						version:          version456Snapshot,
+						useVersionInPath: true,
+					},
+				},
+				upgradeFrom:  version123Snapshot,
+				upgradeTo:    version456Snapshot,
+				currentAgent: version456Snapshot,
+			},
+			additionalSetup: func(t *testing.T, topDir string) {
+				// installs are ordered by install time
+				installedOn := time.Now().Add(-time.Hour)
+				for _, dir := range []string{"elastic-agent-0.9.9-aaaaaa", "elastic-agent-1.1.1-aaabbb", "elastic-agent-1.2.3-SNAPSHOT-abcdef"} {
+					installedOn = installedOn.Add(time.Minute)
+					require.NoError(t, os.Chtimes(filepath.Join(topDir, "data", dir), installedOn, installedOn))
+				}
+			},
+			wantErr: assert.NoError,
+			checkAfterCleanup: func(t *testing.T, topDir string) {
+				newAgentHome := filepath.Join("data", "elastic-agent-4.5.6-SNAPSHOT-ghijkl")
+				checkFilesAfterCleanup(t, topDir, newAgentHome, filepath.Join("data", "elastic-agent-0.9.9-aaaaaa"))
+				assert.DirExists(t, filepath.Join(topDir, "data", "elastic-agent-1.1.1-aaabbb"), "retained agent directory should exist after cleanup")
+				assert.DirExists(t, filepath.Join(topDir, "data", "elastic-agent-1.2.3-SNAPSHOT-abcdef"), "retained agent directory should exist after cleanup")
+			},
+		},
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -215,7 +271,7 @@ func TestCleanup(t *testing.T) {
 			require.NoError(t, err, "error loading update marker")
 			require.NotNil(t, marker, "loaded marker must not be nil")
 			t.Logf("Loaded update marker %+v", marker)
-			tt.wantErr(t, cleanup(testLogger, testTop, marker.VersionedHome, marker.Hash, tt.args.removeMarker, tt.args.keepLogs, 0), fmt.Sprintf("Cleanup(%v, %v, %v, %v)", marker.VersionedHome, marker.Hash, tt.args.removeMarker, tt.args.keepLogs))
+			tt.wantErr(t, cleanup(testLogger, testTop, marker.VersionedHome, marker.Hash, tt.args.removeMarker, tt.args.keepLogs, tt.args.retained, 0), fmt.Sprintf("Cleanup(%v, %v, %v, %v, %v)", marker.VersionedHome, marker.Hash, tt.args.removeMarker, tt.args.keepLogs, tt.args.retained))
 			tt.checkAfterCleanup(t, testTop)
 		})
 	}
@@ -225,6 +281,7 @@ func TestRollback(t *testing.T) {
 	tests := map[string]struct {
 		agentInstallsSetup setupAgentInstallations
 		additionalSetup    hookFunc
+		retained           int
 		wantErr            assert.ErrorAssertionFunc
 		checkAfterRollback hookFunc
 	}{
@@ -297,6 +354,61 @@ func TestRollback(t *testing.T) {
 				checkFilesAfterRollback(t, topDir, oldAgentHome, newAgentHome)
 			},
 		},
+		"rollback keeps the retained installs other than the one rolled back from": {
+			agentInstallsSetup: setupAgentInstallations{
+				installedAgents: []testAgentInstall{
+					{
+						version: testAgentVersion{
+							version: "0.9.9",
+							hash:    "aaaaaa",
+						},
+						useVersionInPath: true,
+					},
+					{
+						version: testAgentVersion{
+							version: "1.1.1",
+							hash:    "aaabbb",
+						},
+						useVersionInPath: true,
+					},
+					{
+						version:          version123Snapshot,
+						useVersionInPath: true,
+					},
+					{
+						version:          version456Snapshot,
+						useVersionInPath: true,
+					},
+				},
+				upgradeFrom:  version123Snapshot,
+				upgradeTo:    version456Snapshot,
+				currentAgent: version456Snapshot,
+			},
+			additionalSetup: func(t *testing.T, topDir string) {
+				// installs are ordered by install time, the install rolled back from is the most recent one
+				installedOn := time.Now().Add(-time.Hour)
+				for _, dir := range []string{"elastic-agent-0.9.9-aaaaaa", "elastic-agent-1.1.1-aaabbb", "elastic-agent-1.2.3-SNAPSHOT-abcdef", "elastic-agent-4.5.6-SNAPSHOT-ghijkl"} {
+					installedOn = installedOn.Add(time.Minute)
+					require.NoError(t, os.Chtimes(filepath.Join(topDir, "data", dir), installedOn, installedOn))
+				}
+
+				// the watcher records the rollback in the marker before rolling back
+				dataDir := paths.DataFrom(topDir)
+				marker, err := LoadMarker(dataDir)
+				require.NoError(t, err)
+				marker.Details = details.NewDetails("4.5.6-SNAPSHOT", details.StateRollback, "")
+				require.NoError(t, SaveMarker(dataDir, marker, true))
+			},
+			retained: 1,
+			wantErr:  assert.NoError,
+			checkAfterRollback: func(t *testing.T, topDir string) {
+				oldAgentHome := filepath.Join("data", "elastic-agent-1.2.3-SNAPSHOT-abcdef")
+				newAgentHome := filepath.Join("data", "elastic-agent-4.5.6-SNAPSHOT-ghijkl")
+				checkFilesAfterRollback(t, topDir, oldAgentHome, newAgentHome)
+				assert.FileExists(t, paths.BinaryPath(filepath.Join(topDir, "data", "elastic-agent-1.1.1-aaabbb"), agentName), "retained agent install should be untouched")
+				assert.NoFileExists(t, paths.BinaryPath(filepath.Join(topDir, "data", "elastic-agent-0.9.9-aaaaaa"), agentName), "agent install beyond the retained ones should be cleaned up")
+			},
+		},
 	}
 	for name, tt := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -322,7 +434,7 @@ func TestRollback(t *testing.T) {
 			mockClient.EXPECT().Restart(mock.Anything).Return(nil).Once()
 
 			ctx := context.TODO()
-			tt.wantErr(t, Rollback(ctx, testLogger, mockClient, testTop, marker.PrevVersionedHome, marker.PrevHash), fmt.Sprintf("Rollback(%v, %v, %v, %v, %v, %v)", ctx, testLogger, mockClient, testTop, marker.PrevVersionedHome, marker.PrevHash))
+			tt.wantErr(t, Rollback(ctx, testLogger, mockClient, testTop, marker.PrevVersionedHome, marker.PrevHash, tt.retained), fmt.Sprintf("Rollback(%v, %v, %v, %v, %v, %v)", ctx, testLogger, mockClient, testTop, marker.PrevVersionedHome, marker.PrevHash))
 			tt.checkAfterRollback(t, testTop)
 		})
 	}
@@ -23,6 +23,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/fs"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/localremote"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/peer"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/snapshot"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
@@ -47,13 +48,31 @@ func (u *Upgrader) downloadArtifact(ctx context.Context, parsedVersion *agtversi
 		span.End()
 	}()
 
-	pgpBytes = u.appendFallbackPGP(parsedVersion, pgpBytes)
+	path, settings, verifier, err := u.fetchArtifact(ctx, parsedVersion, sourceURI, upgradeDetails)
+	if err != nil {
+		return "", err
+	}
+
+	if skipVerifyOverride {
+		return path, nil
+	}
+
+	if err := u.verifyArtifact(ctx, parsedVersion, settings, verifier, skipDefaultPgp, pgpBytes...); err != nil {
+		return "", err
+	}
+	return path, nil
+}
 
+// fetchArtifact downloads the artifact without verifying it. It returns the
+// path of the artifact, the settings used to download it and the verifier to
+// use for a local artifact, nil otherwise.
+func (u *Upgrader) fetchArtifact(ctx context.Context, parsedVersion *agtversion.ParsedSemVer, sourceURI string, upgradeDetails *details.Details) (string, *artifact.Config, download.Verifier, error) {
 	// do not update source config
 	settings := *u.settings
 	var downloaderFunc downloader
 	var factory downloaderFactory
 	var verifier download.Verifier
+	var err error
 	if sourceURI != "" {
 		if strings.HasPrefix(sourceURI, "file://") {
 			// update the DropPath so the fs.Downloader can download from this
@@ -73,7 +92,7 @@ func (u *Upgrader) downloadArtifact(ctx context.Context, parsedVersion *agtversi
 			// set specific verifier, local file verifies locally only
 			verifier, err = fs.NewVerifier(u.log, &settings, release.PGP())
 			if err != nil {
-				return "", errors.New(err, "initiating verifier")
+				return "", nil, nil, errors.New(err, "initiating verifier")
 			}
 
 			// log that a local upgrade artifact is being used
@@ -97,29 +116,34 @@ func (u *Upgrader) downloadArtifact(ctx context.Context, parsedVersion *agtversi
 	}
 
 	if err := os.MkdirAll(paths.Downloads(), 0750); err != nil {
-		return "", errors.New(err, fmt.Sprintf("failed to create download directory at %s", paths.Downloads()))
+		return "", nil, nil, errors.New(err, fmt.Sprintf("failed to create download directory at %s", paths.Downloads()))
 	}
 
 	path, err := downloaderFunc(ctx, factory, parsedVersion, &settings, upgradeDetails)
 	if err != nil {
-		return "", errors.New(err, "failed download of agent binary")
+		return "", nil, nil, errors.New(err, "failed download of agent binary")
 	}
+	return path, &settings, verifier, nil
+}
 
-	if skipVerifyOverride {
-		return path, nil
-	}
+// verifyArtifact verifies the hash and the signature of the artifact
+// downloaded with settings. verifier is used when set, otherwise the verifier
+// matching the settings is created.
+func (u *Upgrader) verifyArtifact(ctx context.Context, parsedVersion *agtversion.ParsedSemVer, settings *artifact.Config, verifier download.Verifier, skipDefaultPgp bool, pgpBytes ...string) error {
+	pgpBytes = u.appendFallbackPGP(parsedVersion, pgpBytes)
 
 	if verifier == nil {
-		verifier, err = newVerifier(parsedVersion, u.log, &settings)
+		var err error
+		verifier, err = newVerifier(parsedVersion, u.log, settings)
 		if err != nil {
-			return "", errors.New(err, "initiating verifier")
+			return errors.New(err, "initiating verifier")
 		}
 	}
 
 	if err := verifier.Verify(ctx, agentArtifact, *parsedVersion, skipDefaultPgp, pgpBytes...); err != nil {
-		return "", errors.New(err, "failed verification of agent binary")
+		return errors.New(err, "failed verification of agent binary")
 	}
-	return path, nil
+	return nil
 }
 
 func (u *Upgrader) appendFallbackPGP(targetVersion *agtversion.ParsedSemVer, pgpBytes []string) []string {
@@ -166,6 +190,14 @@ func newDownloader(version *agtversion.ParsedSemVer, log *logger.Logger, setting
 		return nil, err
 	}
 
+	if len(settings.PeerCache.Sources) > 0 {
+		peerDownloader, err := peer.NewDownloader(log, settings, upgradeDetails)
+		if err != nil {
+			return nil, err
+		}
+		return composed.NewDownloader(fs.NewDownloader(settings), peerDownloader, snapDownloader, httpDownloader), nil
+	}
+
 	return composed.NewDownloader(fs.NewDownloader(settings), snapDownloader, httpDownloader), nil
 }
 
@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/otiai10/copy"
@@ -76,6 +77,9 @@ type Upgrader struct {
 	upgradeable    bool
 	fleetServerURI string
 	markerWatcher  MarkerWatcher
+
+	windowsMx          sync.RWMutex
+	maintenanceWindows configuration.MaintenanceWindows
 }
 
 // IsUpgradeable when agent is installed and running as a service or flag was provided.
@@ -144,9 +148,25 @@ func (u *Upgrader) Reload(rawConfig *config.Config) error {
 	}
 
 	u.settings = cfg.Settings.DownloadConfig
+
+	var windows configuration.MaintenanceWindows
+	if cfg.Settings.Upgrade != nil {
+		windows = cfg.Settings.Upgrade.MaintenanceWindows
+	}
+	u.windowsMx.Lock()
+	u.maintenanceWindows = windows
+	u.windowsMx.Unlock()
 	return nil
 }
 
+// NextMaintenanceWindow returns ts and true when upgrades are allowed at ts. Otherwise, it returns the time the next
+// maintenance window opens and false.
+func (u *Upgrader) NextMaintenanceWindow(ts time.Time) (time.Time, bool) {
+	u.windowsMx.RLock()
+	defer u.windowsMx.RUnlock()
+	return u.maintenanceWindows.Next(ts)
+}
+
 // Upgradeable returns true if the Elastic Agent can be upgraded.
 func (u *Upgrader) Upgradeable() bool {
 	return u.upgradeable
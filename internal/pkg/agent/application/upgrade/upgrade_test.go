@@ -281,6 +281,37 @@ func TestUpgraderReload(t *testing.T) {
 	assert.Equal(t, &want, u.settings)
 }
 
+func TestUpgraderReload_maintenanceWindows(t *testing.T) {
+	log, _ := loggertest.New("")
+	u := Upgrader{
+		log:      log,
+		settings: artifact.DefaultConfig(),
+	}
+	now := time.Now()
+
+	next, ok := u.NextMaintenanceWindow(now)
+	assert.True(t, ok, "upgrades are allowed at any time without maintenance windows")
+	assert.Equal(t, now, next)
+
+	err := u.Reload(config.MustNewConfigFrom(`
+agent.upgrade.maintenance_windows:
+  - start: "01:00"
+    end: "01:01"
+    timezone: UTC
+`))
+	require.NoError(t, err, "error reloading config")
+
+	ts := time.Date(2025, time.June, 4, 12, 0, 0, 0, time.UTC)
+	next, ok = u.NextMaintenanceWindow(ts)
+	assert.False(t, ok)
+	assert.Equal(t, time.Date(2025, time.June, 5, 1, 0, 0, 0, time.UTC), next.UTC())
+
+	err = u.Reload(config.MustNewConfigFrom(``))
+	require.NoError(t, err, "error reloading config")
+	_, ok = u.NextMaintenanceWindow(ts)
+	assert.True(t, ok, "removing the maintenance windows allows upgrades at any time")
+}
+
 func TestUpgraderAckAction(t *testing.T) {
 	log, _ := loggertest.New("")
 	u := Upgrader{
@@ -47,6 +47,7 @@ type AgentWatcher struct {
 	log           *logger.Logger
 	agentClient   client.Client
 	checkInterval time.Duration
+	healthGates   *HealthGates
 }
 
 // NewAgentWatcher creates a new agent watcher.
@@ -66,6 +67,12 @@ func NewAgentWatcher(ch chan error, log *logger.Logger, checkInterval time.Durat
 	return ec
 }
 
+// WithHealthGates makes the watcher record the states of the agent the health gates are evaluated against.
+func (ch *AgentWatcher) WithHealthGates(gates *HealthGates) *AgentWatcher {
+	ch.healthGates = gates
+	return ch
+}
+
 // Run runs the checking loop.
 func (ch *AgentWatcher) Run(ctx context.Context) {
 	ch.log.Info("Agent watcher started")
@@ -200,6 +207,9 @@ LOOP:
 				}
 				ch.log.Debugf("received state: %s:%s",
 					state.State, state.Message)
+				if ch.healthGates != nil {
+					ch.healthGates.Observe(state)
+				}
 
 				// gRPC is good at hiding the fact that connection was lost
 				// to ensure that we don't miss a restart a changed PID means
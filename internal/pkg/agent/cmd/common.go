@@ -83,6 +83,7 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.AddCommand(newInstallCommandWithArgs(args, streams))
 	cmd.AddCommand(newUninstallCommandWithArgs(args, streams))
 	cmd.AddCommand(newUpgradeCommandWithArgs(args, streams))
+	cmd.AddCommand(newRollbackCommandWithArgs(args, streams))
 	cmd.AddCommand(newEnrollCommandWithArgs(args, streams))
 	cmd.AddCommand(newInspectCommandWithArgs(args, streams))
 	cmd.AddCommand(newPrivilegedCommandWithArgs(args, streams))
@@ -95,6 +96,7 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.AddCommand(newLogsCommandWithArgs(args, streams))
 	cmd.AddCommand(newOtelCommandWithArgs(args, streams))
 	cmd.AddCommand(newApplyFlavorCommandWithArgs(args, streams))
+	cmd.AddCommand(newVaultCommandWithArgs(args, streams))
 
 	// windows special hidden sub-command (only added on Windows)
 	reexec := newReExecWindowsCommand(args, streams)
@@ -38,6 +38,7 @@ func newDiagnosticsCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.Flags().BoolP("cpu-profile", "p", false, "wait to collect a CPU profile")
 	cmd.Flags().BoolP("skip-conn", "", false, "Skip connection request diagnostics")
 	cmd.Flags().Bool("exclude-events", false, "do not collect events log file")
+	cmd.Flags().String("redaction-policy", "", "path to a redaction policy file applied to every file of the archive, logs included")
 
 	return cmd
 }
@@ -54,6 +55,14 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		return fmt.Errorf("cannot get 'exclude-events' flag: %w", err)
 	}
 
+	var policy *diagnostics.RedactionPolicy
+	if policyPath, _ := cmd.Flags().GetString("redaction-policy"); policyPath != "" {
+		policy, err = diagnostics.LoadRedactionPolicy(policyPath)
+		if err != nil {
+			return err
+		}
+	}
+
 	ctx := handleSignal(context.Background())
 
 	// 1st create the file to store the diagnostics, if it fails, anything else
@@ -71,10 +80,13 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		return fmt.Errorf("failed collecting diagnostics: %w", err)
 	}
 
-	if err := diagnostics.ZipArchive(streams.Err, f, paths.Top(), agentDiag, unitDiags, compDiags, excludeEvents); err != nil {
+	if err := diagnostics.ZipArchive(streams.Err, f, paths.Top(), agentDiag, unitDiags, compDiags, excludeEvents, policy); err != nil {
 		return fmt.Errorf("unable to create archive %q: %w", filepath, err)
 	}
 	fmt.Fprintf(streams.Out, "Created diagnostics archive %q\n", filepath)
+	if policy != nil {
+		fmt.Fprintf(streams.Out, "Redactions applied by the policy are listed in %q in the archive\n", diagnostics.RedactionManifestFilename)
+	}
 	fmt.Fprintln(streams.Out, "***** WARNING *****\nCreated archive may contain plain text credentials.\nEnsure that files in archive are redacted before sharing.\n*******************")
 	return nil
 }
@@ -17,4 +17,6 @@ import (
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/local"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/localdynamic"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/path"
+	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/process"
+	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/secrets"
 )
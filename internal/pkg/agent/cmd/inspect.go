@@ -7,7 +7,9 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -22,6 +24,8 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vars"
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
@@ -29,6 +33,8 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/config/operations"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/queue"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/utils"
@@ -69,6 +75,7 @@ wait that amount of time before using the variables for the configuration.
 	cmd.Flags().Duration("variables-wait", time.Duration(0), "wait this amount of time for variables before performing substitution (implies --variables)")
 
 	cmd.AddCommand(newInspectComponentsCommandWithArgs(s, streams))
+	cmd.AddCommand(newInspectQueueCommandWithArgs(s, streams))
 
 	return cmd
 }
@@ -124,6 +131,30 @@ variables for the configuration.
 	return cmd
 }
 
+func newInspectQueueCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Displays the scheduled actions queue",
+		Long: `Displays the actions waiting in the scheduled actions queue of a Fleet managed Elastic Agent.
+
+For each action the time it's due to run next is shown, along with its recurrence and the actions it depends on. The
+dependencies that are not completed yet are listed under waiting_for.
+`,
+		Args: cobra.ExactArgs(0),
+		Run: func(c *cobra.Command, args []string) {
+			ctx, cancel := context.WithCancel(context.Background())
+			service.HandleSignals(func() {}, cancel)
+
+			if err := inspectQueue(ctx, streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	}
+
+	return cmd
+}
+
 type inspectConfigOpts struct {
 	variables         bool
 	includeMonitoring bool
@@ -263,7 +294,8 @@ type inspectComponentsOpts struct {
 
 // returns true if the given Capabilities config blocks the given component.
 func blockedByCaps(c component.Component, caps capabilities.Capabilities) bool {
-	return !caps.AllowInput(c.InputType) || !caps.AllowOutput(c.OutputType)
+	return !caps.AllowInput(c.InputType) || !caps.AllowOutput(c.OutputType) ||
+		(c.InputSpec != nil && !caps.AllowComponent(c.InputSpec.BinaryName))
 }
 
 func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponentsOpts, streams *cli.IOStreams) error {
@@ -456,6 +488,98 @@ func getLogLevel(rawCfg *config.Config, cfgPath string) (logp.Level, error) {
 	return logger.DefaultLogLevel, nil
 }
 
+// queuedAction is the representation of a queued action shown by inspect queue.
+type queuedAction struct {
+	ID         string    `yaml:"id"`
+	Type       string    `yaml:"type"`
+	NextRun    time.Time `yaml:"next_run,omitempty"`
+	Expiration time.Time `yaml:"expiration,omitempty"`
+	Recurrence string    `yaml:"recurrence,omitempty"`
+	DependsOn  []string  `yaml:"depends_on,omitempty"`
+	WaitingFor []string  `yaml:"waiting_for,omitempty"`
+}
+
+func inspectQueue(ctx context.Context, streams *cli.IOStreams) error {
+	l, err := newErrorLogger()
+	if err != nil {
+		return fmt.Errorf("error creating logger: %w", err)
+	}
+
+	isAdmin, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("error checking for root/Administrator privileges: %w", err)
+	}
+
+	// the state store belongs to the running agent, it's neither migrated nor saved here
+	diskStore, err := storage.NewEncryptedDiskStore(ctx, paths.AgentStateStoreFile(), storage.WithUnprivileged(!isAdmin))
+	if err != nil {
+		return fmt.Errorf("could not open the state store: %w", err)
+	}
+	stateStore, err := store.NewStateStore(l, readOnlyStore{Storage: diskStore})
+	if err != nil {
+		return fmt.Errorf("could not load the state store: %w", err)
+	}
+
+	return printQueue(queuedActions(stateStore.Queue(), stateStore.QueueScheduling()), streams)
+}
+
+// queuedActions returns the queued actions with their scheduling state, ordered by the time they are due to run.
+func queuedActions(actions []fleetapi.ScheduledAction, scheduling queue.Scheduling) []queuedAction {
+	result := make([]queuedAction, 0, len(actions))
+	for _, action := range actions {
+		qa := queuedAction{
+			ID:   action.ID(),
+			Type: action.Type(),
+		}
+		if start, err := action.StartTime(); err == nil {
+			qa.NextRun = start
+		}
+		if exp, err := action.Expiration(); err == nil {
+			qa.Expiration = exp
+		}
+		if schedule, ok := scheduling.Schedules[action.ID()]; ok {
+			if !schedule.Next.IsZero() {
+				qa.NextRun = schedule.Next
+			}
+			qa.Recurrence = schedule.Recurrence
+			qa.DependsOn = schedule.DependsOn
+			for _, id := range schedule.DependsOn {
+				if _, ok := scheduling.Completed[id]; !ok {
+					qa.WaitingFor = append(qa.WaitingFor, id)
+				}
+			}
+		}
+		result = append(result, qa)
+	}
+	slices.SortStableFunc(result, func(a, b queuedAction) int {
+		return a.NextRun.Compare(b.NextRun)
+	})
+	return result
+}
+
+// readOnlyStore is a storage.Storage refusing to be saved.
+type readOnlyStore struct {
+	storage.Storage
+}
+
+func (readOnlyStore) Save(io.Reader) error {
+	return errors.New("the state store is opened read-only")
+}
+
+func printQueue(actions []queuedAction, streams *cli.IOStreams) error {
+	topLevel := struct {
+		Actions []queuedAction `yaml:"actions"`
+	}{
+		Actions: actions,
+	}
+	data, err := yaml.Marshal(topLevel)
+	if err != nil {
+		return errors.New(err, "could not marshal to YAML")
+	}
+	_, err = streams.Out.Write(data)
+	return err
+}
+
 func printComponents(
 	components []component.Component,
 	blocked []component.Component,
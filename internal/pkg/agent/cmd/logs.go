@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -20,6 +21,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
@@ -33,6 +35,9 @@ const (
 	logBufferSize = 1024
 	// when follow logs, on each interval we check log file updates and if a new file appeared
 	watchInterval = 500 * time.Millisecond
+
+	outputJSON = "json"
+	outputText = "text"
 )
 
 var (
@@ -56,17 +61,133 @@ type logEntry struct {
 	LogLevel string `json:"log.level"`
 }
 
-// createComponentFilter creates a new log entry filter that
-// lets print only the log lines that contain the given component ID.
-func createComponentFilter(id string) filterFunc {
-	return func(entry []byte) bool {
-		var e logEntry
-		err := json.Unmarshal(entry, &e)
+// logFilter selects log entries, an entry is printed only if it matches
+// every criteria that is set.
+type logFilter struct {
+	component string
+	unit      string
+	inputType string
+	// minLevel is only used when hasLevel is set
+	minLevel zapcore.Level
+	hasLevel bool
+	since    time.Time
+	until    time.Time
+	// fields are the values required for the given field names,
+	// nested and dotted field names are both supported
+	fields map[string]string
+}
+
+// isEmpty returns true when the filter has no criteria.
+func (f logFilter) isEmpty() bool {
+	return f.component == "" && f.unit == "" && f.inputType == "" && !f.hasLevel &&
+		f.since.IsZero() && f.until.IsZero() && len(f.fields) == 0
+}
+
+// match returns true if the log entry matches every criteria of the filter.
+// Lines that are not valid JSON never match.
+func (f logFilter) match(entry []byte) bool {
+	var e map[string]interface{}
+	err := json.Unmarshal(entry, &e)
+	if err != nil {
+		return false
+	}
+
+	if f.component != "" && fieldString(e, "component.id") != f.component {
+		return false
+	}
+	if f.unit != "" && fieldString(e, "unit.id") != f.unit {
+		return false
+	}
+	if f.inputType != "" && fieldString(e, "component.type") != f.inputType {
+		return false
+	}
+	if f.hasLevel {
+		level, err := parseLogLevel(fieldString(e, "log.level"))
+		if err != nil || level < f.minLevel {
+			return false
+		}
+	}
+	if !f.since.IsZero() || !f.until.IsZero() {
+		ts, err := time.Parse(time.RFC3339Nano, fieldString(e, "@timestamp"))
 		if err != nil {
 			return false
 		}
-		return e.Component.ID == id
+		if !f.since.IsZero() && ts.Before(f.since) {
+			return false
+		}
+		if !f.until.IsZero() && ts.After(f.until) {
+			return false
+		}
 	}
+	for name, value := range f.fields {
+		v, ok := lookupField(e, name)
+		if !ok || fmt.Sprint(v) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupField returns the value of the field `name` in the log entry, the name
+// can refer to nested objects (`{"log":{"level":"info"}}`), dotted keys
+// (`{"log.level":"info"}`) or a mix of both.
+func lookupField(e map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := e[name]; ok {
+		return v, true
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		nested, ok := e[name[:i]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if v, ok := lookupField(nested, name[i+1:]); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// fieldString returns the value of the field `name` if it's a string.
+func fieldString(e map[string]interface{}, name string) string {
+	v, _ := lookupField(e, name)
+	s, _ := v.(string)
+	return s
+}
+
+// parseLogLevel parses both the level names used in the configuration
+// (e.g. `warning`, `critical`) and the ones written in the log files (e.g. `warn`, `fatal`).
+func parseLogLevel(name string) (zapcore.Level, error) {
+	var level logp.Level
+	if err := level.Unpack(name); err == nil {
+		return level.ZapLevel(), nil
+	}
+	var zapLevel zapcore.Level
+	if err := zapLevel.UnmarshalText([]byte(strings.ToLower(name))); err != nil {
+		return zapLevel, fmt.Errorf("invalid log level %q", name)
+	}
+	return zapLevel, nil
+}
+
+// parseLogTime parses either a RFC3339 timestamp or a duration that is
+// subtracted from `now`, e.g. `15m` for the last 15 minutes.
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a RFC3339 timestamp nor a duration", value)
+	}
+	return t, nil
+}
+
+// createComponentFilter creates a new log entry filter that
+// lets print only the log lines that contain the given component ID.
+func createComponentFilter(id string) filterFunc {
+	return logFilter{component: id}.match
 }
 
 func addColorModifier(entry []byte) []byte {
@@ -75,21 +196,63 @@ func addColorModifier(entry []byte) []byte {
 	if err != nil {
 		return entry
 	}
-	switch strings.ToLower(e.LogLevel) {
+	return colorize(e.LogLevel, entry)
+}
 
-	case logp.InfoLevel.String():
+// colorize applies the color of the given log level to the entry.
+func colorize(levelName string, entry []byte) []byte {
+	level, err := parseLogLevel(levelName)
+	if err != nil {
+		return entry
+	}
+	switch {
+	case level == zapcore.InfoLevel:
 		return []byte(color.CyanString(string(entry)))
-	case logp.WarnLevel.String():
+	case level == zapcore.WarnLevel:
 		return []byte(color.YellowString(string(entry)))
-	case logp.ErrorLevel.String():
+	case level == zapcore.ErrorLevel:
 		return []byte(color.RedString(string(entry)))
-	case logp.CriticalLevel.String():
+	case level > zapcore.ErrorLevel:
 		return []byte(color.HiRedString(string(entry)))
 	default:
 		return entry
 	}
 }
 
+// createTextModifier creates a modifier formatting the JSON log entries as
+// human-readable lines: timestamp, level, component and message.
+// Lines that are not valid JSON are left as is.
+func createTextModifier(withColor bool) modifierFunc {
+	return func(entry []byte) []byte {
+		var e map[string]interface{}
+		err := json.Unmarshal(entry, &e)
+		if err != nil {
+			return entry
+		}
+
+		level := fieldString(e, "log.level")
+		var b strings.Builder
+		b.WriteString(fieldString(e, "@timestamp"))
+		fmt.Fprintf(&b, "\t%-5s", strings.ToUpper(level))
+		if id := fieldString(e, "component.id"); id != "" {
+			if unit := fieldString(e, "unit.id"); unit != "" {
+				id = unit
+			}
+			fmt.Fprintf(&b, "\t[%s]", id)
+		}
+		fmt.Fprintf(&b, "\t%s", fieldString(e, "message"))
+		if errMsg := fieldString(e, "error.message"); errMsg != "" {
+			fmt.Fprintf(&b, "\terror: %s", errMsg)
+		}
+
+		line := []byte(b.String())
+		if withColor {
+			return colorize(level, line)
+		}
+		return line
+	}
+}
+
 // stackWriter collects written byte slices and then pops them in
 // the reversed (LIFO) order.
 // Supports filtering and modification of each written byte slice.
@@ -182,28 +345,99 @@ func newLogsCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.Flags().Bool("exclude-events", false, "Excludes events log files")
 
 	cmd.Flags().StringP("component", "C", "", "Filter logs and output only logs for the given component ID.")
+	cmd.Flags().StringP("unit", "U", "", "Filter logs and output only logs for the given unit ID.")
+	cmd.Flags().String("input-type", "", "Filter logs and output only logs for components of the given input type.")
+	cmd.Flags().StringP("level", "l", "", "Filter logs and output only logs of the given level or above (debug, info, warning, error, critical).")
+	cmd.Flags().String("since", "", "Output only logs written after the given RFC3339 timestamp or duration ago (e.g. 2024-05-30T12:00:00Z or 1h). All matching lines are printed unless --number is set.")
+	cmd.Flags().String("until", "", "Output only logs written before the given RFC3339 timestamp or duration ago.")
+	cmd.Flags().StringArray("match", nil, "Filter logs and output only logs where the given field has the given value, formatted as field=value. Can be repeated.")
+	cmd.Flags().StringP("output", "o", outputJSON, "Output format, json or text.")
 
 	return cmd
 }
 
+// newLogFilter creates the log filter from the command flags.
+func newLogFilter(cmd *cobra.Command, now time.Time) (logFilter, error) {
+	var f logFilter
+	f.component, _ = cmd.Flags().GetString("component")
+	f.unit, _ = cmd.Flags().GetString("unit")
+	f.inputType, _ = cmd.Flags().GetString("input-type")
+
+	if level, _ := cmd.Flags().GetString("level"); level != "" {
+		minLevel, err := parseLogLevel(level)
+		if err != nil {
+			return f, err
+		}
+		f.minLevel, f.hasLevel = minLevel, true
+	}
+
+	if since, _ := cmd.Flags().GetString("since"); since != "" {
+		t, err := parseLogTime(since, now)
+		if err != nil {
+			return f, fmt.Errorf("invalid --since: %w", err)
+		}
+		f.since = t
+	}
+	if until, _ := cmd.Flags().GetString("until"); until != "" {
+		t, err := parseLogTime(until, now)
+		if err != nil {
+			return f, fmt.Errorf("invalid --until: %w", err)
+		}
+		f.until = t
+	}
+	if !f.since.IsZero() && !f.until.IsZero() && f.until.Before(f.since) {
+		return f, fmt.Errorf("--until %s is before --since %s", f.until.Format(time.RFC3339), f.since.Format(time.RFC3339))
+	}
+
+	matches, _ := cmd.Flags().GetStringArray("match")
+	for _, m := range matches {
+		name, value, ok := strings.Cut(m, "=")
+		if !ok || name == "" {
+			return f, fmt.Errorf("invalid --match %q, expected field=value", m)
+		}
+		if f.fields == nil {
+			f.fields = make(map[string]string, len(matches))
+		}
+		f.fields[name] = value
+	}
+
+	return f, nil
+}
+
 func logsCmd(streams *cli.IOStreams, cmd *cobra.Command, logsDir, eventLogsDir string) error {
-	component, _ := cmd.Flags().GetString("component")
 	lines, _ := cmd.Flags().GetInt("number")
 	follow, _ := cmd.Flags().GetBool("follow")
 	noColor, _ := cmd.Flags().GetBool("no-color")
 	excludeEvents, _ := cmd.Flags().GetBool("exclude-events")
+	output, _ := cmd.Flags().GetString("output")
 
 	var (
 		filter   filterFunc
 		modifier modifierFunc
 	)
 
-	if component != "" {
-		filter = createComponentFilter(component)
+	entryFilter, err := newLogFilter(cmd, time.Now())
+	if err != nil {
+		return err
+	}
+	if !entryFilter.isEmpty() {
+		filter = entryFilter.match
+	}
+	// when looking for logs in a time range print all of them,
+	// unless a number of lines was explicitly requested
+	if !entryFilter.since.IsZero() && !cmd.Flags().Changed("number") {
+		lines = math.MaxInt
 	}
 
-	if !noColor {
-		modifier = addColorModifier
+	switch output {
+	case outputJSON:
+		if !noColor {
+			modifier = addColorModifier
+		}
+	case outputText:
+		modifier = createTextModifier(!noColor)
+	default:
+		return fmt.Errorf("invalid output %q, must be %s or %s", output, outputJSON, outputText)
 	}
 
 	// uncomment for debugging
@@ -212,7 +446,7 @@ func logsCmd(streams *cli.IOStreams, cmd *cobra.Command, logsDir, eventLogsDir s
 	errChan := make(chan error)
 
 	go func() {
-		err := printLogs(cmd.Context(), streams.Out, logsDir, lines, follow, filter, modifier)
+		err := printLogs(cmd.Context(), streams.Out, logsDir, lines, follow, entryFilter.since, filter, modifier)
 		if err != nil {
 			errChan <- fmt.Errorf("failed to get logs: %w", err)
 			return
@@ -225,7 +459,7 @@ func logsCmd(streams *cli.IOStreams, cmd *cobra.Command, logsDir, eventLogsDir s
 			done := false
 			// The event log folder might not exist, so we keep trying every five seconds
 			for !done {
-				err := printLogs(cmd.Context(), streams.Out, eventLogsDir, lines, follow, filter, modifier)
+				err := printLogs(cmd.Context(), streams.Out, eventLogsDir, lines, follow, entryFilter.since, filter, modifier)
 				if err != nil {
 					if !strings.Contains(err.Error(), "logs/events: no such file or directory") {
 						errChan <- fmt.Errorf("failed to get event logs: %w", err)
@@ -248,8 +482,9 @@ func logsCmd(streams *cli.IOStreams, cmd *cobra.Command, logsDir, eventLogsDir s
 
 // printLogs prints the last `lines` number of log lines from the log files in `dir`
 // applying the `filter` and printing all the log lines to `w`.
+// Rotated files last modified before `since`, when set, are not read.
 // if `follow` is true it will keep printing all the log updates afterwards.
-func printLogs(ctx context.Context, w io.Writer, dir string, lines int, follow bool, filter filterFunc, modifier modifierFunc) error {
+func printLogs(ctx context.Context, w io.Writer, dir string, lines int, follow bool, since time.Time, filter filterFunc, modifier modifierFunc) error {
 	files, err := getLogFilenames(dir)
 	if err != nil {
 		return fmt.Errorf("failed to fetch log filenames: %w", err)
@@ -297,6 +532,14 @@ func printLogs(ctx context.Context, w io.Writer, dir string, lines int, follow b
 		if fileIndex < 0 {
 			break
 		}
+		// files are in the rotation order, if this one was last written before `since`
+		// it and all the previous ones only contain older entries
+		if !since.IsZero() {
+			info, err := os.Stat(files[fileIndex])
+			if err == nil && info.ModTime().Before(since) {
+				break
+			}
+		}
 	}
 
 	// all log lines written above were written in LIFO order, we need to invert that
@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,6 +19,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 )
@@ -254,7 +256,7 @@ func TestPrintLogs(t *testing.T) {
 				createFileContent(t, dir, f.name, bytes.NewBuffer([]byte(f.content)))
 			}
 			result := bytes.NewBuffer(nil)
-			err := printLogs(context.Background(), result, dir, tc.lines, false, nil, nil)
+			err := printLogs(context.Background(), result, dir, tc.lines, false, time.Time{}, nil, nil)
 			require.NoError(t, err)
 
 			require.Equal(t, tc.expected, result.String())
@@ -270,7 +272,7 @@ func TestPrintLogs(t *testing.T) {
 		logResult := newChanWriter()
 		errChan := make(chan error)
 		go func() {
-			errChan <- printLogs(ctx, logResult, dir, 5, true, nil, nil)
+			errChan <- printLogs(ctx, logResult, dir, 5, true, time.Time{}, nil, nil)
 		}()
 
 		var expected string
@@ -331,7 +333,7 @@ func TestPrintLogs(t *testing.T) {
 		logResult := newChanWriter()
 		errChan := make(chan error)
 		go func() {
-			errChan <- printLogs(ctx, logResult, dir, 3, true, createComponentFilter("match"), exclamationModifier)
+			errChan <- printLogs(ctx, logResult, dir, 3, true, time.Time{}, createComponentFilter("match"), exclamationModifier)
 		}()
 
 		var expected string
@@ -719,3 +721,207 @@ func TestCobraCmd(t *testing.T) {
 		t.Logf("Log lines:\n%s", strings.Join(lines, "\n"))
 	}
 }
+
+func TestLogFilter(t *testing.T) {
+	entry := []byte(`{"@timestamp":"2024-05-30T12:00:00.000Z","log.level":"warn","message":"test",` +
+		`"component":{"id":"filestream-default","type":"filestream"},"unit":{"id":"filestream-default-logs"},` +
+		`"log.origin":{"file.line":42},"service":{"name":"filebeat"}}`)
+	ts := time.Date(2024, 5, 30, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		filter logFilter
+		exp    bool
+	}{
+		{
+			name:   "matches component, unit and input type",
+			filter: logFilter{component: "filestream-default", unit: "filestream-default-logs", inputType: "filestream"},
+			exp:    true,
+		},
+		{
+			name:   "does not match another unit",
+			filter: logFilter{unit: "filestream-default-other"},
+			exp:    false,
+		},
+		{
+			name:   "does not match another input type",
+			filter: logFilter{inputType: "system/metrics"},
+			exp:    false,
+		},
+		{
+			name:   "matches a lower minimum level",
+			filter: logFilter{minLevel: zapcore.InfoLevel, hasLevel: true},
+			exp:    true,
+		},
+		{
+			name:   "does not match a higher minimum level",
+			filter: logFilter{minLevel: zapcore.ErrorLevel, hasLevel: true},
+			exp:    false,
+		},
+		{
+			name:   "matches a time range",
+			filter: logFilter{since: ts.Add(-time.Minute), until: ts.Add(time.Minute)},
+			exp:    true,
+		},
+		{
+			name:   "does not match entries before since",
+			filter: logFilter{since: ts.Add(time.Second)},
+			exp:    false,
+		},
+		{
+			name:   "does not match entries after until",
+			filter: logFilter{until: ts.Add(-time.Second)},
+			exp:    false,
+		},
+		{
+			name:   "matches nested and dotted fields",
+			filter: logFilter{fields: map[string]string{"service.name": "filebeat", "log.origin.file.line": "42"}},
+			exp:    true,
+		},
+		{
+			name:   "does not match a missing field",
+			filter: logFilter{fields: map[string]string{"error.message": ""}},
+			exp:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, tc.filter.match(entry))
+		})
+	}
+
+	t.Run("does not match invalid JSON", func(t *testing.T) {
+		assert.False(t, logFilter{hasLevel: true}.match([]byte("not json")))
+	})
+}
+
+func TestNewLogFilter(t *testing.T) {
+	now := time.Date(2024, 5, 30, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		flags  map[string][]string
+		exp    logFilter
+		errMsg string
+	}{
+		{
+			name: "no filter",
+			exp:  logFilter{},
+		},
+		{
+			name: "all filters",
+			flags: map[string][]string{
+				"component":  {"filestream-default"},
+				"unit":       {"filestream-default-logs"},
+				"input-type": {"filestream"},
+				"level":      {"warning"},
+				"since":      {"1h"},
+				"until":      {"2024-05-30T11:30:00Z"},
+				"match":      {"service.name=filebeat", "message=a=b"},
+			},
+			exp: logFilter{
+				component: "filestream-default",
+				unit:      "filestream-default-logs",
+				inputType: "filestream",
+				minLevel:  zapcore.WarnLevel,
+				hasLevel:  true,
+				since:     now.Add(-time.Hour),
+				until:     time.Date(2024, 5, 30, 11, 30, 0, 0, time.UTC),
+				fields:    map[string]string{"service.name": "filebeat", "message": "a=b"},
+			},
+		},
+		{
+			name:   "invalid level",
+			flags:  map[string][]string{"level": {"verbose"}},
+			errMsg: `invalid log level "verbose"`,
+		},
+		{
+			name:   "invalid since",
+			flags:  map[string][]string{"since": {"yesterday"}},
+			errMsg: "invalid --since",
+		},
+		{
+			name:   "until before since",
+			flags:  map[string][]string{"since": {"1h"}, "until": {"2h"}},
+			errMsg: "is before --since",
+		},
+		{
+			name:   "invalid match",
+			flags:  map[string][]string{"match": {"service.name"}},
+			errMsg: `invalid --match "service.name"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			streams, _, _, _ := cli.NewTestingIOStreams()
+			cmd := newLogsCommandWithArgs(nil, streams)
+			for name, values := range tc.flags {
+				for _, v := range values {
+					require.NoError(t, cmd.Flags().Set(name, v))
+				}
+			}
+
+			f, err := newLogFilter(cmd, now)
+			if tc.errMsg != "" {
+				assert.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, f)
+		})
+	}
+}
+
+func TestTextModifier(t *testing.T) {
+	modifier := createTextModifier(false)
+
+	cases := []struct {
+		name  string
+		entry string
+		exp   string
+	}{
+		{
+			name:  "agent entry",
+			entry: `{"@timestamp":"2024-05-30T12:00:00.000Z","log.level":"info","message":"Unit state changed"}`,
+			exp:   "2024-05-30T12:00:00.000Z\tINFO \tUnit state changed",
+		},
+		{
+			name:  "component entry with unit and error",
+			entry: `{"@timestamp":"2024-05-30T12:00:00.000Z","log.level":"error","message":"failed","component":{"id":"filestream-default"},"unit":{"id":"filestream-default-logs"},"error":{"message":"boom"}}`,
+			exp:   "2024-05-30T12:00:00.000Z\tERROR\t[filestream-default-logs]\tfailed\terror: boom",
+		},
+		{
+			name:  "not JSON",
+			entry: "plain line",
+			exp:   "plain line",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, string(modifier([]byte(tc.entry))))
+		})
+	}
+}
+
+func TestPrintLogsSince(t *testing.T) {
+	dir := t.TempDir()
+	since := time.Date(2024, 5, 30, 12, 0, 0, 0, time.UTC)
+	entry := func(ts time.Time, msg string) string {
+		return fmt.Sprintf(`{"@timestamp":%q,"message":%q}`+"\n", ts.Format(time.RFC3339Nano), msg)
+	}
+
+	// the oldest rotated file was last written before `since`, it must not be read
+	// even though its content would match
+	createFileContent(t, dir, file1, bytes.NewBufferString(entry(since.Add(time.Hour), "skipped")))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, file1), since.Add(-2*time.Hour), since.Add(-2*time.Hour)))
+	createFileContent(t, dir, file2, bytes.NewBufferString(entry(since.Add(-time.Minute), "before")+entry(since.Add(time.Minute), "rotated")))
+	createFileContent(t, dir, file3, bytes.NewBufferString(entry(since.Add(2*time.Minute), "current")))
+
+	result := bytes.NewBuffer(nil)
+	err := printLogs(context.Background(), result, dir, math.MaxInt, false, since, logFilter{since: since}.match, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, entry(since.Add(time.Minute), "rotated")+entry(since.Add(2*time.Minute), "current"), result.String())
+}
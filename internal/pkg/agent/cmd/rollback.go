@@ -0,0 +1,173 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+const flagRollbackTo = "to"
+
+var (
+	unsupportedRollbackError    = errors.New("this agent is fleet managed and must be rolled back using Fleet")
+	nonRootRollbackError        = errors.New("rollback command needs to be executed as root for fleet managed agents")
+	rollbackDuringUpgradeError  = errors.New("an upgrade is in progress; please try again later.")
+	rollbackVersionMissingError = errors.New("no retained install found, set agent.upgrade.rollback.retained_installs to keep previous versions")
+)
+
+func newRollbackCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back the currently installed Elastic Agent to a previous version",
+		Long: `This command lists the previous versions of Elastic Agent kept on disk, or, when --to is set, rolls back
+the currently installed Elastic Agent to one of them and restarts it.
+
+The number of previous versions kept on disk is set by agent.upgrade.rollback.retained_installs.`,
+		Args: cobra.NoArgs,
+		Run: func(c *cobra.Command, _ []string) {
+			c.SetContext(context.Background())
+			if err := rollbackCmd(streams, c); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().String(flagRollbackTo, "", "Version of a previous install to roll back to")
+	cmd.Flags().BoolP(flagForce, "", false, "Advanced option to force a rollback on a fleet managed agent")
+	err := cmd.Flags().MarkHidden(flagForce)
+	if err != nil {
+		fmt.Fprintf(streams.Err, "error while setting rollback force flag attributes: %s", err.Error())
+		os.Exit(1)
+	}
+
+	return cmd
+}
+
+type rollbackInput struct {
+	streams   *cli.IOStreams
+	cmd       *cobra.Command
+	c         client.Client
+	agentInfo client.AgentStateInfo
+	isRoot    bool
+}
+
+func rollbackCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
+	version, _ := cmd.Flags().GetString(flagRollbackTo)
+	if version == "" {
+		return listRetainedInstalls(streams, paths.Top())
+	}
+
+	c := client.New()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err := c.Connect(ctx)
+	if err != nil {
+		return errors.New(err, "failed communicating to running daemon", errors.TypeNetwork, errors.M("socket", control.Address()))
+	}
+	defer c.Disconnect()
+	state, err := c.State(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("error while trying to get agent state: %w", err)
+	}
+
+	isRoot, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("error while retrieving user permission: %w", err)
+	}
+
+	return rollbackCmdWithClient(&rollbackInput{
+		streams,
+		cmd,
+		c,
+		state.Info,
+		isRoot,
+	})
+}
+
+func rollbackCmdWithClient(input *rollbackInput) error {
+	cmd := input.cmd
+	version, _ := cmd.Flags().GetString(flagRollbackTo)
+
+	force, err := cmd.Flags().GetBool(flagForce)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve command flag information while trying to roll back the agent: %w", err)
+	}
+
+	if input.agentInfo.IsManaged {
+		if !force {
+			return fmt.Errorf("aborting rollback: %w", unsupportedRollbackError)
+		}
+		if !input.isRoot {
+			return fmt.Errorf("aborting rollback: %w", nonRootRollbackError)
+		}
+	}
+
+	isBeingUpgraded, err := upgrade.IsInProgress(input.c, utils.GetWatcherPIDs)
+	if err != nil {
+		return fmt.Errorf("failed to check if upgrade is already in progress: %w", err)
+	}
+	if isBeingUpgraded {
+		return rollbackDuringUpgradeError
+	}
+
+	version, err = input.c.Rollback(context.Background(), version)
+	if err != nil {
+		s, ok := status.FromError(err)
+		// the gRPC server may shut down before replying to the command, see upgradeCmdWithClient
+		isConnectionInterrupted := ok && s.Code() == codes.Unavailable && strings.Contains(s.Message(), "EOF")
+		if !isConnectionInterrupted {
+			return errors.New(err, "Failed trigger rollback of daemon")
+		}
+	}
+	fmt.Fprintf(input.streams.Out, "Rollback triggered to version %s, Elastic Agent is currently restarting\n", version)
+	return nil
+}
+
+// listRetainedInstalls prints the installs kept in topPath the running agent can be rolled back to.
+func listRetainedInstalls(streams *cli.IOStreams, topPath string) error {
+	currentVersionedHome, err := filepath.Rel(topPath, paths.VersionedHome(topPath))
+	if err != nil {
+		return fmt.Errorf("failed to get the versioned home of the running agent: %w", err)
+	}
+	installs, err := upgrade.RetainedInstalls(topPath, currentVersionedHome, release.ShortCommit())
+	if err != nil {
+		return fmt.Errorf("failed to list the retained installs: %w", err)
+	}
+	if len(installs) == 0 {
+		return rollbackVersionMissingError
+	}
+
+	tw := tabwriter.NewWriter(streams.Out, 4, 1, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tHASH\tINSTALLED ON\tPATH")
+	for _, install := range installs {
+		version := install.Version
+		if version == "" {
+			version = "unknown"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", version, install.Hash, install.InstalledOn.UTC().Format(time.RFC3339), install.VersionedHome)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,89 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	clientmocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/version"
+)
+
+func TestRollbackCmd(t *testing.T) {
+	newInput := func(t *testing.T, c client.Client, agentInfo client.AgentStateInfo, isRoot bool, flags map[string]string) (*rollbackInput, *cli.IOStreams) {
+		streams, _, _, _ := cli.NewTestingIOStreams()
+		cmd := newRollbackCommandWithArgs(nil, streams)
+		cmd.SetContext(context.Background())
+		for name, value := range flags {
+			require.NoError(t, cmd.Flags().Set(name, value))
+		}
+		return &rollbackInput{streams, cmd, c, agentInfo, isRoot}, streams
+	}
+
+	t.Run("rolls back a standalone agent", func(t *testing.T) {
+		mockClient := clientmocks.NewClient(t)
+		mockClient.EXPECT().State(mock.Anything).Return(&client.AgentState{State: cproto.State_HEALTHY}, nil)
+		mockClient.EXPECT().Rollback(mock.Anything, "8.13.0").Return("8.13.0", nil)
+
+		input, _ := newInput(t, mockClient, client.AgentStateInfo{}, false, map[string]string{flagRollbackTo: "8.13.0"})
+		require.NoError(t, rollbackCmdWithClient(input))
+	})
+
+	t.Run("managed agent requires force", func(t *testing.T) {
+		mockClient := clientmocks.NewClient(t)
+
+		input, _ := newInput(t, mockClient, client.AgentStateInfo{IsManaged: true}, true, map[string]string{flagRollbackTo: "8.13.0"})
+		err := rollbackCmdWithClient(input)
+		assert.ErrorIs(t, err, unsupportedRollbackError)
+	})
+
+	t.Run("managed agent requires root", func(t *testing.T) {
+		mockClient := clientmocks.NewClient(t)
+
+		input, _ := newInput(t, mockClient, client.AgentStateInfo{IsManaged: true}, false, map[string]string{flagRollbackTo: "8.13.0", flagForce: "true"})
+		err := rollbackCmdWithClient(input)
+		assert.ErrorIs(t, err, nonRootRollbackError)
+	})
+
+	t.Run("rollback failure is reported", func(t *testing.T) {
+		mockClient := clientmocks.NewClient(t)
+		mockClient.EXPECT().State(mock.Anything).Return(&client.AgentState{State: cproto.State_HEALTHY}, nil)
+		mockClient.EXPECT().Rollback(mock.Anything, "8.13.0").Return("", assert.AnError)
+
+		input, _ := newInput(t, mockClient, client.AgentStateInfo{IsManaged: true}, true, map[string]string{flagRollbackTo: "8.13.0", flagForce: "true"})
+		err := rollbackCmdWithClient(input)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestListRetainedInstalls(t *testing.T) {
+	topDir := t.TempDir()
+	installedOn := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	home := filepath.Join(topDir, "data", "elastic-agent-8.13.0-abcdef")
+	require.NoError(t, os.MkdirAll(home, 0o750))
+	versionFile := filepath.Join(home, version.PackageVersionFileName)
+	require.NoError(t, os.WriteFile(versionFile, []byte("8.13.0"), 0o600))
+	require.NoError(t, os.Chtimes(versionFile, installedOn, installedOn))
+
+	streams, _, out, _ := cli.NewTestingIOStreams()
+	require.NoError(t, listRetainedInstalls(streams, topDir))
+	assert.Contains(t, out.String(), "VERSION")
+	assert.Contains(t, out.String(), "8.13.0   abcdef  2025-06-01T10:00:00Z")
+
+	streams, _, _, _ = cli.NewTestingIOStreams()
+	err := listRetainedInstalls(streams, t.TempDir())
+	assert.Error(t, err)
+}
@@ -42,6 +42,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/peer"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
@@ -218,6 +219,15 @@ func runElasticAgent(ctx context.Context, cancel context.CancelFunc, override ap
 		return logReturn(l, fmt.Errorf("failed to read/write secrets: %w", err))
 	}
 
+	// Complete the rotation of the agent key interrupted before the end, the stores stay readable
+	// during the rotation so the agent starts if it fails.
+	resumed, err := storage.ResumeAgentKeyRotation(ctx, storage.WithUnprivileged(!isRoot))
+	if err != nil {
+		l.Warnf("failed to resume the interrupted rotation of the agent key: %v", err)
+	} else if resumed {
+		l.Info("resumed the interrupted rotation of the agent key")
+	}
+
 	// Migrate .yml files if the corresponding .enc does not exist
 
 	// the encrypted config does not exist but the unencrypted file does
@@ -301,6 +311,15 @@ func runElasticAgent(ctx context.Context, cancel context.CancelFunc, override ap
 		}
 	}()
 
+	peerCacheServer := peer.NewServerReloader(l.Named("peer-cache"), cfg.Settings.DownloadConfig)
+	if err := peerCacheServer.Apply(); err != nil {
+		return logReturn(l, errors.New(err, "could not start the peer cache server"))
+	}
+	coord.RegisterPeerCacheServer(peerCacheServer)
+	defer func() {
+		_ = peerCacheServer.Stop()
+	}()
+
 	diagHooks := diagnostics.GlobalHooks()
 	diagHooks = append(diagHooks, coord.DiagnosticHooks()...)
 	controlLog := l.Named("control")
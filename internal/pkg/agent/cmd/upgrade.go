@@ -33,6 +33,7 @@ const (
 	flagPGPBytesPath   = "pgp-path"
 	flagPGPBytesURI    = "pgp-uri"
 	flagForce          = "force"
+	flagDryRun         = "dry-run"
 )
 
 var (
@@ -64,6 +65,7 @@ func newUpgradeCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Comman
 	cmd.Flags().String(flagPGPBytesURI, "", "Path to a web location containing PGP to use for package verification")
 	cmd.Flags().String(flagPGPBytesPath, "", "Path to a file containing PGP to use for package verification")
 	cmd.Flags().BoolP(flagForce, "", false, "Advanced option to force an upgrade on a fleet managed agent")
+	cmd.Flags().BoolP(flagDryRun, "", false, "Run every check of the upgrade, including downloading, verifying and unpacking the package, without upgrading")
 	err := cmd.Flags().MarkHidden(flagForce)
 	if err != nil {
 		fmt.Fprintf(streams.Err, "error while setting upgrade force flag attributes: %s", err.Error())
@@ -167,8 +169,14 @@ func upgradeCmdWithClient(input *upgradeInput) error {
 		return fmt.Errorf("failed to retrieve %s flag information while upgrading the agent: %w", flagSkipVerify, err)
 	}
 
+	dryRun, err := cmd.Flags().GetBool(flagDryRun)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve %s flag information while upgrading the agent: %w", flagDryRun, err)
+	}
+
 	err = checkUpgradable(upgradeCond{
-		isManaged:  input.agentInfo.IsManaged,
+		// a dry run leaves a fleet managed agent untouched
+		isManaged:  input.agentInfo.IsManaged && !dryRun,
 		force:      force,
 		isRoot:     input.isRoot,
 		skipVerify: skipVerification,
@@ -215,6 +223,9 @@ func upgradeCmdWithClient(input *upgradeInput) error {
 		}
 	}
 	skipDefaultPgp, _ := cmd.Flags().GetBool(flagSkipDefaultPgp)
+	if dryRun {
+		return upgradeDryRun(input, version, sourceURI, skipVerification, skipDefaultPgp, pgpChecks...)
+	}
 	version, err = c.Upgrade(context.Background(), version, sourceURI, skipVerification, skipDefaultPgp, pgpChecks...)
 	if err != nil {
 		s, ok := status.FromError(err)
@@ -229,3 +240,33 @@ func upgradeCmdWithClient(input *upgradeInput) error {
 	fmt.Fprintf(input.streams.Out, "Upgrade triggered to version %s, Elastic Agent is currently restarting\n", version)
 	return nil
 }
+
+func upgradeDryRun(input *upgradeInput, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpChecks ...string) error {
+	checks, err := input.c.UpgradeDryRun(context.Background(), version, sourceURI, skipVerify, skipDefaultPgp, pgpChecks...)
+	if err != nil {
+		if s, ok := status.FromError(err); ok && s.Code() == codes.Unimplemented {
+			return errors.New("the running Elastic Agent does not support upgrade dry runs, it must be upgraded to a version supporting them first")
+		}
+		return errors.New(err, "Failed to run the upgrade dry run")
+	}
+
+	fmt.Fprintf(input.streams.Out, "Upgrade dry run to version %s:\n", version)
+	passed := true
+	for _, check := range checks {
+		result := "PASSED"
+		if !check.Passed {
+			result = "FAILED"
+			passed = false
+		}
+		if check.Message == "" {
+			fmt.Fprintf(input.streams.Out, "  %s %s\n", result, check.Name)
+		} else {
+			fmt.Fprintf(input.streams.Out, "  %s %s: %s\n", result, check.Name, check.Message)
+		}
+	}
+	if !passed {
+		return fmt.Errorf("upgrade dry run to version %s failed", version)
+	}
+	fmt.Fprintf(input.streams.Out, "Every upgrade check passed, Elastic Agent can be upgraded to version %s\n", version)
+	return nil
+}
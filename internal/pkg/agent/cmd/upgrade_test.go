@@ -16,6 +16,8 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -260,6 +262,88 @@ func TestUpgradeCmd(t *testing.T) {
 		err = upgradeCmdWithClient(commandInput)
 		assert.NoError(t, err)
 	})
+	t.Run("dry run a fleet managed agent without --force", func(t *testing.T) {
+		mockClient := clientmocks.NewClient(t)
+		mockClient.EXPECT().State(mock.Anything).Return(&client.AgentState{State: cproto.State_HEALTHY}, nil)
+		mockClient.EXPECT().UpgradeDryRun(mock.Anything, "8.13.0", "", false, false).Return([]client.UpgradeCheck{
+			{Name: "upgradeable", Passed: true},
+			{Name: "download", Passed: true, Message: "/downloads/elastic-agent-8.13.0-linux-x86_64.tar.gz"},
+		}, nil)
+
+		args := []string{"8.13.0"} // Version argument
+		streams, _, out, _ := cli.NewTestingIOStreams()
+		cmd := newUpgradeCommandWithArgs(args, streams)
+		cmd.SetContext(context.Background())
+		err := cmd.Flags().Set(flagDryRun, "true")
+		require.NoError(t, err)
+
+		commandInput := &upgradeInput{
+			streams,
+			cmd,
+			args,
+			mockClient,
+			client.AgentStateInfo{IsManaged: true},
+			true,
+		}
+
+		err = upgradeCmdWithClient(commandInput)
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "PASSED upgradeable\n")
+		assert.Contains(t, out.String(), "PASSED download: /downloads/elastic-agent-8.13.0-linux-x86_64.tar.gz\n")
+	})
+	t.Run("dry run reports the failed check", func(t *testing.T) {
+		mockClient := clientmocks.NewClient(t)
+		mockClient.EXPECT().State(mock.Anything).Return(&client.AgentState{State: cproto.State_HEALTHY}, nil)
+		mockClient.EXPECT().UpgradeDryRun(mock.Anything, "8.13.0", "", false, false).Return([]client.UpgradeCheck{
+			{Name: "upgradeable", Passed: true},
+			{Name: "disk_space", Passed: false, Message: "not enough space"},
+		}, nil)
+
+		args := []string{"8.13.0"} // Version argument
+		streams, _, out, _ := cli.NewTestingIOStreams()
+		cmd := newUpgradeCommandWithArgs(args, streams)
+		cmd.SetContext(context.Background())
+		err := cmd.Flags().Set(flagDryRun, "true")
+		require.NoError(t, err)
+
+		commandInput := &upgradeInput{
+			streams,
+			cmd,
+			args,
+			mockClient,
+			client.AgentStateInfo{},
+			true,
+		}
+
+		err = upgradeCmdWithClient(commandInput)
+		require.Error(t, err)
+		assert.Contains(t, out.String(), "FAILED disk_space: not enough space\n")
+	})
+	t.Run("dry run not supported by the daemon", func(t *testing.T) {
+		mockClient := clientmocks.NewClient(t)
+		mockClient.EXPECT().State(mock.Anything).Return(&client.AgentState{State: cproto.State_HEALTHY}, nil)
+		mockClient.EXPECT().UpgradeDryRun(mock.Anything, "8.13.0", "", false, false).Return(nil, status.Error(codes.Unimplemented, "unknown method UpgradeDryRun"))
+
+		args := []string{"8.13.0"} // Version argument
+		streams := cli.NewIOStreams()
+		cmd := newUpgradeCommandWithArgs(args, streams)
+		cmd.SetContext(context.Background())
+		err := cmd.Flags().Set(flagDryRun, "true")
+		require.NoError(t, err)
+
+		commandInput := &upgradeInput{
+			streams,
+			cmd,
+			args,
+			mockClient,
+			client.AgentStateInfo{},
+			true,
+		}
+
+		err = upgradeCmdWithClient(commandInput)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not support upgrade dry runs")
+	})
 }
 
 type mockServer struct {
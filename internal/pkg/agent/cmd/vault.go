@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func newVaultCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vault",
+		Short: "Manage the secrets Elastic Agent stores in its vault",
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newVaultRotateCommand(streams))
+
+	return cmd
+}
+
+func newVaultRotateCommand(streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotate the key Elastic Agent encrypts its configuration and state with",
+		Long: `This command generates a new key in the vault of the running Elastic Agent and re-encrypts the Fleet
+configuration and the state store with it, the seed of the vault is then replaced. The previous key is kept
+until every file is re-encrypted, an interrupted rotation is resumed by running the command again.`,
+		Args: cobra.NoArgs,
+		Run: func(_ *cobra.Command, _ []string) {
+			if err := vaultRotateCmd(streams); err != nil {
+				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func vaultRotateCmd(streams *cli.IOStreams) error {
+	c := client.New()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err := c.Connect(ctx)
+	if err != nil {
+		return errors.New(err, "failed communicating to running daemon", errors.TypeNetwork, errors.M("socket", control.Address()))
+	}
+	defer c.Disconnect()
+
+	return vaultRotateCmdWithClient(ctx, streams, c)
+}
+
+func vaultRotateCmdWithClient(ctx context.Context, streams *cli.IOStreams, c client.Client) error {
+	version, err := c.RotateKey(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to rotate the agent key: %w", err)
+	}
+	fmt.Fprintf(streams.Out, "Agent key rotated to version %d\n", version)
+	return nil
+}
@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	clientmocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
+)
+
+func TestVaultRotateCmd(t *testing.T) {
+	t.Run("rotates the key", func(t *testing.T) {
+		mockClient := clientmocks.NewClient(t)
+		mockClient.EXPECT().RotateKey(mock.Anything).Return(uint32(2), nil)
+
+		streams, _, out, _ := cli.NewTestingIOStreams()
+		require.NoError(t, vaultRotateCmdWithClient(context.Background(), streams, mockClient))
+		assert.Equal(t, "Agent key rotated to version 2\n", out.String())
+	})
+
+	t.Run("rotation failure is reported", func(t *testing.T) {
+		mockClient := clientmocks.NewClient(t)
+		mockClient.EXPECT().RotateKey(mock.Anything).Return(uint32(0), assert.AnError)
+
+		streams, _, out, _ := cli.NewTestingIOStreams()
+		err := vaultRotateCmdWithClient(context.Background(), streams, mockClient)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Empty(t, out.String())
+	})
+}
@@ -53,7 +53,11 @@ func newWatchCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command
 			// Make sure to flush any buffered logs before we're done.
 			defer log.Sync() //nolint:errcheck // flushing buffered logs is best effort.
 
-			if err := watchCmd(log, paths.Top(), cfg.Settings.Upgrade.Watcher, new(upgradeAgentWatcher), new(upgradeInstallationModifier)); err != nil {
+			installModifier := &upgradeInstallationModifier{}
+			if cfg.Settings.Upgrade.Rollback != nil {
+				installModifier.retained = cfg.Settings.Upgrade.Rollback.RetainedInstalls
+			}
+			if err := watchCmd(log, paths.Top(), cfg.Settings.Upgrade.Watcher, &upgradeAgentWatcher{healthGates: cfg.Settings.Upgrade.Watcher.HealthGates}, installModifier); err != nil {
 				log.Errorw("Watch command failed", "error.message", err)
 				fmt.Fprintf(streams.Err, "Watch command failed: %v\n%s\n", err, troubleshootMessage())
 				os.Exit(4)
@@ -132,7 +136,12 @@ func watchCmd(log *logp.Logger, topDir string, cfg *configuration.UpgradeWatcher
 	if err := watcher.Watch(ctx, tilGrace, errorCheckInterval, log); err != nil {
 		log.Error("Error detected, proceeding to rollback: %v", err)
 
-		upgradeDetails.SetStateWithReason(details.StateRollback, details.ReasonWatchFailed)
+		var healthGateErr *upgrade.HealthGateError
+		if errors.As(err, &healthGateErr) {
+			upgradeDetails.SetRollbackForHealthGate(healthGateErr.Gate)
+		} else {
+			upgradeDetails.SetStateWithReason(details.StateRollback, details.ReasonWatchFailed)
+		}
 		err = installModifier.Rollback(ctx, log, client.New(), paths.Top(), marker.PrevVersionedHome, marker.PrevHash)
 		if err != nil {
 			log.Error("rollback failed", err)
@@ -180,7 +189,7 @@ func isWindows() bool {
 	return runtime.GOOS == "windows"
 }
 
-func watch(ctx context.Context, tilGrace time.Duration, errorCheckInterval time.Duration, log *logger.Logger) error {
+func watch(ctx context.Context, tilGrace time.Duration, errorCheckInterval time.Duration, healthGates *upgrade.HealthGates, log *logger.Logger) error {
 	errChan := make(chan error)
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -191,9 +200,17 @@ func watch(ctx context.Context, tilGrace time.Duration, errorCheckInterval time.
 		close(errChan)
 	}()
 
-	agentWatcher := upgrade.NewAgentWatcher(errChan, log, errorCheckInterval)
+	agentWatcher := upgrade.NewAgentWatcher(errChan, log, errorCheckInterval).WithHealthGates(healthGates)
 	go agentWatcher.Run(ctx)
 
+	// health gates are checked on every error check interval, a nil channel never fires
+	var gatesC <-chan time.Time
+	if healthGates != nil && healthGates.Len() > 0 && errorCheckInterval > 0 {
+		gatesTicker := time.NewTicker(errorCheckInterval)
+		defer gatesTicker.Stop()
+		gatesC = gatesTicker.C
+	}
+
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
 
@@ -210,8 +227,26 @@ WATCHLOOP:
 			break WATCHLOOP
 		// grace period passed, agent is considered stable
 		case <-t.C:
+			if healthGates != nil {
+				wait, err := healthGates.Final(ctx, time.Now())
+				if err != nil {
+					log.Errorf("Health gate failed at the end of the grace period: %s", err.Error())
+					return err
+				}
+				if wait > 0 {
+					// gates failing within their timeout are given until it expires
+					log.Infof("Grace period passed, waiting up to %s for failing health gates", wait)
+					t.Reset(wait)
+					continue
+				}
+			}
 			log.Info("Grace period passed, not watching")
 			break WATCHLOOP
+		case now := <-gatesC:
+			if err := healthGates.Check(ctx, now); err != nil {
+				log.Errorf("Health gate failed: %s", err.Error())
+				return err
+			}
 		// Agent in degraded state.
 		case err := <-errChan:
 			log.Errorf("Agent Error detected: %s", err.Error())
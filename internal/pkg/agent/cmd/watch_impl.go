@@ -9,23 +9,65 @@ import (
 	"time"
 
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
-type upgradeAgentWatcher struct{}
+type upgradeAgentWatcher struct {
+	healthGates []configuration.HealthGateConfig
+}
 
 func (a upgradeAgentWatcher) Watch(ctx context.Context, tilGrace, errorCheckInterval time.Duration, log *logp.Logger) error {
-	return watch(ctx, tilGrace, errorCheckInterval, log)
+	var healthGates *upgrade.HealthGates
+	if len(a.healthGates) > 0 {
+		var err error
+		healthGates, err = upgrade.NewHealthGates(a.healthGates, monitoring.FetchComponentStats, metricsComponents(log))
+		if err != nil {
+			// invalid gates must not roll back a healthy upgrade
+			log.Errorf("Ignoring invalid upgrade health gates: %v", err)
+			healthGates = nil
+		}
+	}
+	return watch(ctx, tilGrace, errorCheckInterval, healthGates, log)
+}
+
+// metricsComponents returns the filter of the components reporting their metrics, the beats. It
+// returns nil when the component specifications can't be loaded, the gates then apply to all the
+// components.
+func metricsComponents(log *logp.Logger) upgrade.ComponentFilter {
+	platform, err := component.LoadPlatformDetail()
+	if err != nil {
+		log.Warnf("Failed to load the platform details, the health gates apply to all the components: %v", err)
+		return nil
+	}
+	specs, err := component.LoadRuntimeSpecs(paths.Components(), platform)
+	if err != nil {
+		log.Warnf("Failed to load the component specifications, the health gates apply to all the components: %v", err)
+		return nil
+	}
+	return func(comp client.ComponentState) bool {
+		spec, err := specs.GetInput(comp.Name)
+		if err != nil {
+			return false
+		}
+		return monitoring.IsSupportedBeatsBinary(spec.BinaryName)
+	}
 }
 
-type upgradeInstallationModifier struct{}
+type upgradeInstallationModifier struct {
+	// retained is the number of previous installs kept by the cleanup
+	retained int
+}
 
 func (a upgradeInstallationModifier) Cleanup(log *logger.Logger, topDirPath, currentVersionedHome, currentHash string, removeMarker, keepLogs bool) error {
-	return upgrade.Cleanup(log, topDirPath, currentVersionedHome, currentHash, removeMarker, keepLogs)
+	return upgrade.Cleanup(log, topDirPath, currentVersionedHome, currentHash, removeMarker, keepLogs, a.retained)
 }
 
 func (a upgradeInstallationModifier) Rollback(ctx context.Context, log *logger.Logger, c client.Client, topDirPath, prevVersionedHome, prevHash string) error {
-	return upgrade.Rollback(ctx, log, c, topDirPath, prevVersionedHome, prevHash)
+	return upgrade.Rollback(ctx, log, c, topDirPath, prevVersionedHome, prevHash, a.retained)
 }
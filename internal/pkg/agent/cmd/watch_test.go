@@ -281,3 +281,43 @@ func Test_watchCmd(t *testing.T) {
 		})
 	}
 }
+
+func Test_watchCmdHealthGateRollback(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+	topDir := t.TempDir()
+	dataDirPath := paths.DataFrom(topDir)
+	require.NoError(t, os.MkdirAll(dataDirPath, 0755))
+	err := upgrade.SaveMarker(
+		dataDirPath,
+		&upgrade.UpdateMarker{
+			Version:           "4.5.6",
+			Hash:              "newver",
+			VersionedHome:     "elastic-agent-4.5.6-newver",
+			UpdatedOn:         time.Now(),
+			PrevVersion:       "1.2.3",
+			PrevHash:          "prvver",
+			PrevVersionedHome: "elastic-agent-prvver",
+			DesiredOutcome:    upgrade.OUTCOME_UPGRADE,
+		},
+		true,
+	)
+	require.NoError(t, err)
+
+	mockWatcher := cmdmocks.NewAgentWatcher(t)
+	mockWatcher.EXPECT().
+		Watch(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&upgrade.HealthGateError{Gate: "filestream-healthy", Err: errors.New("component filestream-default is not running")})
+	mockInstallModifier := cmdmocks.NewInstallationModifier(t)
+	mockInstallModifier.EXPECT().
+		Rollback(mock.Anything, mock.Anything, mock.Anything, paths.Top(), "elastic-agent-prvver", "prvver").
+		Return(nil)
+
+	require.NoError(t, watchCmd(log, topDir, configuration.DefaultUpgradeConfig().Watcher, mockWatcher, mockInstallModifier))
+
+	marker, err := upgrade.LoadMarker(dataDirPath)
+	require.NoError(t, err)
+	require.NotNil(t, marker.Details)
+	assert.Equal(t, details.StateRollback, marker.Details.State)
+	assert.Equal(t, details.ReasonHealthGateFailed, marker.Details.Metadata.Reason)
+	assert.Equal(t, "filestream-healthy", marker.Details.Metadata.FailedHealthGate)
+}
@@ -6,6 +6,7 @@ package configuration
 
 import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/certrenewal"
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
 )
 
@@ -19,6 +20,9 @@ type FleetAgentConfig struct {
 	Client              remote.Config      `config:",inline" yaml:",inline"`
 	Info                *AgentInfo         `config:"agent" yaml:"agent"`
 	Server              *FleetServerConfig `config:"server" yaml:"server,omitempty"`
+	// CertificateRenewal is only set in the local configuration, it's never
+	// persisted with the configuration received from fleet.
+	CertificateRenewal *certrenewal.Config `config:"certificate_renewal" yaml:"-"`
 }
 
 // Valid validates the required fields for accessing the API.
@@ -47,5 +51,7 @@ func DefaultFleetAgentConfig() *FleetAgentConfig {
 		Enabled: false,
 		Client:  remote.DefaultClientConfig(),
 		Info:    &AgentInfo{},
+
+		CertificateRenewal: certrenewal.DefaultConfig(),
 	}
 }
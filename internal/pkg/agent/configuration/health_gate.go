@@ -0,0 +1,72 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/eql"
+)
+
+const (
+	// HealthGateComponentHealthy passes when the component is healthy.
+	HealthGateComponentHealthy = "component_healthy"
+	// HealthGateNoDegradedUnits passes when no unit of the component, or of any component, is degraded or failed.
+	HealthGateNoDegradedUnits = "no_degraded_units"
+	// HealthGateEventsPublished passes when the component, or all the components, published events.
+	HealthGateEventsPublished = "events_published"
+	// HealthGateCondition passes when the EQL condition evaluated over the state of the agent is true.
+	HealthGateCondition = "condition"
+)
+
+// HealthGateConfig is a check the upgraded Agent must pass during the grace period of the upgrade watcher. A gate
+// fails the upgrade when it doesn't pass for longer than its timeout. A gate without a timeout fails the upgrade when
+// it doesn't pass when the grace period ends, a gate with a timeout still failing then extends the grace period until
+// it passes or its timeout expires.
+type HealthGateConfig struct {
+	// Name identifies the gate in the logs and the upgrade details.
+	Name string `yaml:"name" config:"name" json:"name"`
+	// Type is one of component_healthy, no_degraded_units, events_published or condition.
+	Type string `yaml:"type" config:"type" json:"type"`
+	// Component is the ID of the checked component, required by component_healthy. The other gates check all the
+	// components when it's empty.
+	Component string `yaml:"component,omitempty" config:"component" json:"component,omitempty"`
+	// Condition is the EQL expression of the condition gate. Its variables must be escaped as $${...} in the
+	// configuration file, so they are not resolved when the configuration is loaded.
+	Condition string `yaml:"condition,omitempty" config:"condition" json:"condition,omitempty"`
+	// Timeout is how long the gate can fail before failing the upgrade, including after the grace period ends. When
+	// zero the gate is only checked when the grace period ends.
+	Timeout time.Duration `yaml:"timeout,omitempty" config:"timeout" json:"timeout,omitempty"`
+}
+
+// Validate returns an error if the health gate is invalid.
+func (g *HealthGateConfig) Validate() error {
+	if g.Name == "" {
+		return errors.New("health gate name is required")
+	}
+	if g.Timeout < 0 {
+		return fmt.Errorf("health gate %q timeout cannot be negative", g.Name)
+	}
+
+	switch g.Type {
+	case HealthGateComponentHealthy:
+		if g.Component == "" {
+			return fmt.Errorf("health gate %q of type %s requires a component", g.Name, g.Type)
+		}
+	case HealthGateNoDegradedUnits, HealthGateEventsPublished:
+	case HealthGateCondition:
+		if g.Condition == "" {
+			return fmt.Errorf("health gate %q of type %s requires a condition", g.Name, g.Type)
+		}
+		if _, err := eql.New(g.Condition); err != nil {
+			return fmt.Errorf("health gate %q has an invalid condition: %w", g.Name, err)
+		}
+	default:
+		return fmt.Errorf("health gate %q has an unknown type %q", g.Name, g.Type)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const timeOfDayLayout = "15:04"
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// MaintenanceWindow is a recurring period of time during which upgrades are allowed to run.
+type MaintenanceWindow struct {
+	// Days are the days of the week the window opens, either the full name or its first three letters. The window
+	// opens every day when empty.
+	Days []string `yaml:"days" config:"days" json:"days"`
+	// Start is the time of day, formatted as HH:MM, the window opens.
+	Start string `yaml:"start" config:"start" json:"start"`
+	// End is the time of day, formatted as HH:MM, the window closes. A window ending before it starts closes on the
+	// following day.
+	End string `yaml:"end" config:"end" json:"end"`
+	// Timezone is the IANA time zone of Start and End, the local time zone is used when empty.
+	Timezone string `yaml:"timezone" config:"timezone" json:"timezone"`
+}
+
+// MaintenanceWindows is a set of maintenance windows. Upgrades are allowed at any time when it's empty.
+type MaintenanceWindows []MaintenanceWindow
+
+// Validate returns an error if the maintenance window is invalid.
+func (w *MaintenanceWindow) Validate() error {
+	_, err := w.parse()
+	return err
+}
+
+// Next returns ts and true when ts is inside a maintenance window. Otherwise, it returns the time the next maintenance
+// window opens and false.
+func (mw MaintenanceWindows) Next(ts time.Time) (time.Time, bool) {
+	if len(mw) == 0 {
+		return ts, true
+	}
+
+	var next time.Time
+	for _, w := range mw {
+		p, err := w.parse()
+		if err != nil {
+			// invalid windows are rejected when the configuration is unpacked
+			continue
+		}
+		start, open := p.next(ts)
+		if open {
+			return ts, true
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next, false
+}
+
+// parsedMaintenanceWindow is the representation of a MaintenanceWindow used to compute when it opens.
+type parsedMaintenanceWindow struct {
+	days     map[time.Weekday]bool
+	start    time.Duration
+	end      time.Duration
+	location *time.Location
+}
+
+func (w *MaintenanceWindow) parse() (parsedMaintenanceWindow, error) {
+	p := parsedMaintenanceWindow{location: time.Local}
+
+	if len(w.Days) > 0 {
+		p.days = make(map[time.Weekday]bool, len(w.Days))
+	}
+	for _, day := range w.Days {
+		weekday, ok := parseWeekday(day)
+		if !ok {
+			return p, fmt.Errorf("invalid maintenance window day %q", day)
+		}
+		p.days[weekday] = true
+	}
+
+	var err error
+	if p.start, err = parseTimeOfDay(w.Start); err != nil {
+		return p, fmt.Errorf("invalid maintenance window start: %w", err)
+	}
+	if p.end, err = parseTimeOfDay(w.End); err != nil {
+		return p, fmt.Errorf("invalid maintenance window end: %w", err)
+	}
+	if p.start == p.end {
+		return p, errors.New("maintenance window start and end cannot be the same")
+	}
+
+	if w.Timezone != "" {
+		if p.location, err = time.LoadLocation(w.Timezone); err != nil {
+			return p, fmt.Errorf("invalid maintenance window timezone: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// next returns ts and true when ts is inside the window, otherwise it returns the time the window opens next and
+// false.
+func (p parsedMaintenanceWindow) next(ts time.Time) (time.Time, bool) {
+	t := ts.In(p.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, p.location)
+	// start the day before to account for a window opened yesterday and closing today
+	for offset := -1; offset <= 7; offset++ {
+		day := midnight.AddDate(0, 0, offset)
+		if p.days != nil && !p.days[day.Weekday()] {
+			continue
+		}
+		start := dayTime(day, p.start)
+		end := dayTime(day, p.end)
+		if p.end < p.start {
+			end = dayTime(day.AddDate(0, 0, 1), p.end)
+		}
+		if !ts.Before(start) && ts.Before(end) {
+			return ts, true
+		}
+		if start.After(ts) {
+			return start, false
+		}
+	}
+	// unreachable, every window opens at least once a week
+	return time.Time{}, false
+}
+
+// dayTime returns the time of the day at the given offset from midnight, keeping it consistent across DST changes.
+func dayTime(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(offset.Hours()), int(offset.Minutes())%60, 0, 0, day.Location())
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse(timeOfDayLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day formatted as HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for name, weekday := range weekdays {
+		if s == name || (len(s) == 3 && strings.HasPrefix(name, s)) {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
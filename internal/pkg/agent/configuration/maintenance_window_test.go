@@ -0,0 +1,148 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	tests := map[string]struct {
+		window      map[string]any
+		expectedErr string
+	}{
+		"valid": {
+			window: map[string]any{"days": []any{"Mon", "tuesday"}, "start": "01:00", "end": "05:00", "timezone": "UTC"},
+		},
+		"invalid day": {
+			window:      map[string]any{"days": []any{"someday"}, "start": "01:00", "end": "05:00"},
+			expectedErr: `invalid maintenance window day "someday"`,
+		},
+		"invalid start": {
+			window:      map[string]any{"start": "1am", "end": "05:00"},
+			expectedErr: "invalid maintenance window start",
+		},
+		"missing end": {
+			window:      map[string]any{"start": "01:00"},
+			expectedErr: "invalid maintenance window end",
+		},
+		"empty window": {
+			window:      map[string]any{"start": "01:00", "end": "01:00"},
+			expectedErr: "maintenance window start and end cannot be the same",
+		},
+		"invalid timezone": {
+			window:      map[string]any{"start": "01:00", "end": "05:00", "timezone": "Nowhere/Special"},
+			expectedErr: "invalid maintenance window timezone",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := DefaultUpgradeConfig()
+			cfg := config.MustNewConfigFrom(map[string]any{"maintenance_windows": []any{test.window}})
+			err := cfg.UnpackTo(c)
+			if test.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestMaintenanceWindowsNext(t *testing.T) {
+	// 2025-06-04 is a Wednesday
+	wednesday := func(hour, min int) time.Time {
+		return time.Date(2025, time.June, 4, hour, min, 0, 0, time.UTC)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		windows      MaintenanceWindows
+		ts           time.Time
+		expectedNext time.Time
+		expectedOpen bool
+	}{
+		"no windows": {
+			ts:           wednesday(12, 0),
+			expectedNext: wednesday(12, 0),
+			expectedOpen: true,
+		},
+		"inside a daily window": {
+			windows:      MaintenanceWindows{{Start: "11:00", End: "13:00", Timezone: "UTC"}},
+			ts:           wednesday(12, 0),
+			expectedNext: wednesday(12, 0),
+			expectedOpen: true,
+		},
+		"end is exclusive": {
+			windows:      MaintenanceWindows{{Start: "11:00", End: "12:00", Timezone: "UTC"}},
+			ts:           wednesday(12, 0),
+			expectedNext: wednesday(11, 0).AddDate(0, 0, 1),
+		},
+		"later the same day": {
+			windows:      MaintenanceWindows{{Start: "22:00", End: "23:00", Timezone: "UTC"}},
+			ts:           wednesday(12, 0),
+			expectedNext: wednesday(22, 0),
+		},
+		"next allowed day": {
+			windows:      MaintenanceWindows{{Days: []string{"sat", "sun"}, Start: "01:00", End: "05:00", Timezone: "UTC"}},
+			ts:           wednesday(12, 0),
+			expectedNext: wednesday(1, 0).AddDate(0, 0, 3),
+		},
+		"same day next week": {
+			windows:      MaintenanceWindows{{Days: []string{"wednesday"}, Start: "01:00", End: "05:00", Timezone: "UTC"}},
+			ts:           wednesday(12, 0),
+			expectedNext: wednesday(1, 0).AddDate(0, 0, 7),
+		},
+		"inside a window opened the day before": {
+			windows:      MaintenanceWindows{{Days: []string{"tue"}, Start: "22:00", End: "04:00", Timezone: "UTC"}},
+			ts:           wednesday(3, 0),
+			expectedNext: wednesday(3, 0),
+			expectedOpen: true,
+		},
+		"after a window opened the day before": {
+			windows:      MaintenanceWindows{{Days: []string{"tue"}, Start: "22:00", End: "04:00", Timezone: "UTC"}},
+			ts:           wednesday(4, 0),
+			expectedNext: wednesday(22, 0).AddDate(0, 0, 6),
+		},
+		"timezone": {
+			windows:      MaintenanceWindows{{Start: "02:00", End: "04:00", Timezone: "Europe/Berlin"}},
+			ts:           wednesday(12, 0),
+			expectedNext: time.Date(2025, time.June, 5, 2, 0, 0, 0, berlin),
+		},
+		"earliest of several windows": {
+			windows: MaintenanceWindows{
+				{Days: []string{"fri"}, Start: "01:00", End: "02:00", Timezone: "UTC"},
+				{Days: []string{"thu"}, Start: "23:00", End: "23:30", Timezone: "UTC"},
+			},
+			ts:           wednesday(12, 0),
+			expectedNext: wednesday(23, 0).AddDate(0, 0, 1),
+		},
+		"inside one of several windows": {
+			windows: MaintenanceWindows{
+				{Days: []string{"fri"}, Start: "01:00", End: "02:00", Timezone: "UTC"},
+				{Days: []string{"wed"}, Start: "11:00", End: "13:00", Timezone: "UTC"},
+			},
+			ts:           wednesday(12, 0),
+			expectedNext: wednesday(12, 0),
+			expectedOpen: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			next, open := test.windows.Next(test.ts)
+			assert.Equal(t, test.expectedOpen, open)
+			assert.True(t, test.expectedNext.Equal(next), "expected next %s, got %s", test.expectedNext, next)
+		})
+	}
+}
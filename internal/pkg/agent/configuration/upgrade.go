@@ -22,11 +22,17 @@ const (
 type UpgradeConfig struct {
 	Watcher  *UpgradeWatcherConfig  `yaml:"watcher" config:"watcher" json:"watcher"`
 	Rollback *UpgradeRollbackConfig `yaml:"rollback" config:"rollback" json:"rollback"`
+	// MaintenanceWindows restricts when upgrades run, upgrades received outside a window are deferred until the
+	// next window opens.
+	MaintenanceWindows MaintenanceWindows `yaml:"maintenance_windows,omitempty" config:"maintenance_windows" json:"maintenance_windows,omitempty"`
 }
 
 type UpgradeWatcherConfig struct {
 	GracePeriod time.Duration             `yaml:"grace_period" config:"grace_period" json:"grace_period"`
 	ErrorCheck  UpgradeWatcherCheckConfig `yaml:"error_check" config:"error_check" json:"error_check"`
+	// HealthGates are the checks the upgraded Agent must pass during the grace period, a failing gate rolls back
+	// the upgrade.
+	HealthGates []HealthGateConfig `yaml:"health_gates,omitempty" config:"health_gates" json:"health_gates,omitempty"`
 }
 type UpgradeWatcherCheckConfig struct {
 	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
@@ -34,6 +40,9 @@ type UpgradeWatcherCheckConfig struct {
 
 type UpgradeRollbackConfig struct {
 	Window time.Duration `yaml:"window" config:"window" json:"window"`
+	// RetainedInstalls is the number of previous installs kept besides the running one, they can be rolled back to
+	// with the rollback command.
+	RetainedInstalls int `yaml:"retained_installs" config:"retained_installs" json:"retained_installs"`
 }
 
 func DefaultUpgradeConfig() *UpgradeConfig {
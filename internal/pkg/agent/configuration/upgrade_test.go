@@ -86,6 +86,66 @@ func TestParseUpgradeConfig(t *testing.T) {
 				},
 			},
 		},
+		"maintenance_windows": {
+			cfg: map[string]any{
+				"maintenance_windows": []any{
+					map[string]any{
+						"days":     []any{"sat", "sunday"},
+						"start":    "22:00",
+						"end":      "04:30",
+						"timezone": "Europe/Berlin",
+					},
+				},
+			},
+			expected: UpgradeConfig{
+				Watcher: &UpgradeWatcherConfig{
+					GracePeriod: defaultGracePeriodDuration,
+					ErrorCheck: UpgradeWatcherCheckConfig{
+						Interval: defaultStatusCheckInterval,
+					},
+				},
+				Rollback: &UpgradeRollbackConfig{
+					Window: defaultRollbackWindowDuration,
+				},
+				MaintenanceWindows: MaintenanceWindows{{
+					Days:     []string{"sat", "sunday"},
+					Start:    "22:00",
+					End:      "04:30",
+					Timezone: "Europe/Berlin",
+				}},
+			},
+		},
+		"watcher_health_gates": {
+			cfg: map[string]any{
+				"watcher.health_gates": []any{
+					map[string]any{
+						"name":      "filestream-healthy",
+						"type":      "component_healthy",
+						"component": "filestream-default",
+					},
+					map[string]any{
+						"name":    "no-degraded-units",
+						"type":    "no_degraded_units",
+						"timeout": "5m",
+					},
+				},
+			},
+			expected: UpgradeConfig{
+				Watcher: &UpgradeWatcherConfig{
+					GracePeriod: defaultGracePeriodDuration,
+					ErrorCheck: UpgradeWatcherCheckConfig{
+						Interval: defaultStatusCheckInterval,
+					},
+					HealthGates: []HealthGateConfig{
+						{Name: "filestream-healthy", Type: HealthGateComponentHealthy, Component: "filestream-default"},
+						{Name: "no-degraded-units", Type: HealthGateNoDegradedUnits, Timeout: 5 * time.Minute},
+					},
+				},
+				Rollback: &UpgradeRollbackConfig{
+					Window: defaultRollbackWindowDuration,
+				},
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -97,3 +157,23 @@ func TestParseUpgradeConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestParseUpgradeConfigInvalidHealthGate(t *testing.T) {
+	tests := map[string]struct {
+		gate map[string]any
+		err  string
+	}{
+		"missing name":      {gate: map[string]any{"type": "no_degraded_units"}, err: "health gate name is required"},
+		"unknown type":      {gate: map[string]any{"name": "g", "type": "bogus"}, err: `health gate "g" has an unknown type "bogus"`},
+		"missing component": {gate: map[string]any{"name": "g", "type": "component_healthy"}, err: `health gate "g" of type component_healthy requires a component`},
+		"invalid condition": {gate: map[string]any{"name": "g", "type": "condition", "condition": "$${state} =="}, err: `health gate "g" has an invalid condition`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := config.MustNewConfigFrom(map[string]any{"watcher.health_gates": []any{test.gate}})
+			err := cfg.UnpackTo(DefaultUpgradeConfig())
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
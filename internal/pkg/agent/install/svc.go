@@ -118,7 +118,7 @@ func newService(topPath string, opt ...serviceOpt) (service.Service, error) {
 	if runtime.GOOS == "linux" {
 		// The github.com/kardianos/service library doesn't support KillMode in their prebuilt template.
 		// This option allows to pass our own template for the systemd unit configuration, which is a copy
-		// of the prebuilt template with added KillMode option
+		// of the prebuilt template with added KillMode and Delegate options
 		cfg.Option["SystemdScript"] = linuxSystemdScript
 
 		// By setting KillMode=process in Elastic Agent's systemd unit configuration file, we ensure
@@ -127,6 +127,10 @@ func newService(topPath string, opt ...serviceOpt) (service.Service, error) {
 		// initiate a rollback.
 		// See also https://github.com/elastic/elastic-agent/pull/3220#issuecomment-1673935694.
 		cfg.Option["KillMode"] = "process"
+
+		// The cgroup of the service is delegated to the Elastic Agent for it to create the cgroups
+		// enforcing the CPU and memory limits of the components, systemd doesn't manage them then.
+		cfg.Option["Delegate"] = "yes"
 	}
 
 	if runtime.GOOS == "darwin" {
@@ -282,7 +286,7 @@ const darwinLaunchdConfig = `<?xml version='1.0' encoding='UTF-8'?>
 `
 
 // A copy of the systemd config template from github.com/kardianos/service
-// with added .Config.Option.KillMode option
+// with added .Config.Option.KillMode and .Config.Option.Delegate options
 const linuxSystemdScript = `[Unit]
 Description={{.Description}}
 ConditionFileIsExecutable={{.Path|cmdEscape}}
@@ -309,6 +313,7 @@ StandardError=file:/var/log/{{.Name}}.err
 {{if .Restart}}Restart={{.Restart}}{{end}}
 {{if .SuccessExitStatus}}SuccessExitStatus={{.SuccessExitStatus}}{{end}}
 {{if .Config.Option.KillMode}}KillMode={{.Config.Option.KillMode}}{{end}}
+{{if .Config.Option.Delegate}}Delegate={{.Config.Option.Delegate}}{{end}}
 RestartSec=120
 EnvironmentFile=-/etc/sysconfig/{{.Name}}
 
@@ -388,7 +388,8 @@ func uninstallComponents(ctx context.Context, cfgFile string, uninstallToken str
 
 	// remove each service component
 	for _, comp := range comps {
-		if !caps.AllowInput(comp.InputType) || !caps.AllowOutput(comp.OutputType) {
+		if !caps.AllowInput(comp.InputType) || !caps.AllowOutput(comp.OutputType) ||
+			(comp.InputSpec != nil && !caps.AllowComponent(comp.InputSpec.BinaryName)) {
 			// This component is not active
 			continue
 		}
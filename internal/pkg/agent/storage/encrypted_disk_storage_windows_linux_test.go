@@ -9,18 +9,21 @@ package storage
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/crypto"
 	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
 )
 
@@ -115,3 +118,169 @@ func TestEncryptedDiskStorageWindowsLinuxLoad(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestEncryptedDiskStorageWindowsLinuxRotateKey(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "encrypted disk storage does not use NewGCMWithRandomNonce.")
+	dir := t.TempDir()
+
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	err := secret.CreateAgentSecret(ctx, vault.WithVaultPath(dir))
+	require.NoError(t, err)
+	require.NoError(t, secret.Set(ctx, "other", secret.Secret{Value: []byte("other value")}, vault.WithVaultPath(dir)))
+	entries := vaultEntries(t, dir)
+
+	// a store encrypted before the key versions were recorded
+	previous, err := secret.GetAgentSecret(ctx, vault.WithVaultPath(dir))
+	require.NoError(t, err)
+	legacy := filepath.Join(dir, "legacy.enc")
+	buf := new(bytes.Buffer)
+	w, err := crypto.NewWriterWithDefaults(buf, previous.Value)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("legacy data"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(legacy, buf.Bytes(), 0o600))
+
+	fp := filepath.Join(dir, testConfigFile)
+	s, err := NewEncryptedDiskStore(ctx, fp, WithVaultPath(dir))
+	require.NoError(t, err)
+	require.NoError(t, s.Save(bytes.NewBufferString("foobar config")))
+
+	targets := []string{legacy, fp, filepath.Join(dir, "missing.enc")}
+	version, err := RotateKey(ctx, targets, WithVaultPath(dir))
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), version)
+
+	keyring, err := secret.GetAgentKeyring(ctx, vault.WithVaultPath(dir))
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), keyring.Current.Version)
+	require.Nil(t, keyring.Next)
+	require.NotEqual(t, previous.Value, keyring.Current.Value)
+
+	// the seed of the vault is replaced, the other entries are re-encrypted with it
+	require.NotEqual(t, entries, vaultEntries(t, dir))
+	other, err := secret.Get(ctx, "other", vault.WithVaultPath(dir))
+	require.NoError(t, err)
+	require.Equal(t, "other value", string(other.Value))
+
+	for target, expected := range map[string]string{legacy: "legacy data", fp: "foobar config"} {
+		// the stores are readable by the agents not knowing the key versions
+		b, err := os.ReadFile(target)
+		require.NoError(t, err)
+		r, err := crypto.NewReaderWithDefaults(bytes.NewReader(b), keyring.Current.Value)
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(content))
+
+		// the store created before the rotation reads the new key as well
+		store := Storage(s)
+		if target == legacy {
+			store, err = NewEncryptedDiskStore(ctx, target, WithVaultPath(dir))
+			require.NoError(t, err)
+		}
+		r2, err := store.Load()
+		require.NoError(t, err)
+		content, err = io.ReadAll(r2)
+		require.NoError(t, r2.Close())
+		require.NoError(t, err)
+		require.Equal(t, expected, string(content))
+	}
+	_, err = os.Stat(filepath.Join(dir, "missing.enc"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// a rotation interrupted after re-encrypting a store resumes with the same key
+	keyring, err = secret.BeginAgentSecretRotation(ctx, vault.WithVaultPath(dir))
+	require.NoError(t, err)
+	require.NoError(t, incrementKeyringGeneration(dir))
+	require.NoError(t, s.Save(bytes.NewBufferString("saved during the rotation")))
+	v, err := s.(*EncryptedDiskStore).keyVersion()
+	require.NoError(t, err)
+	require.Equal(t, keyring.Next.Version, v)
+
+	resumed, err := ResumeKeyRotation(ctx, targets, WithVaultPath(dir))
+	require.NoError(t, err)
+	require.True(t, resumed)
+	active, err := secret.GetAgentSecret(ctx, vault.WithVaultPath(dir))
+	require.NoError(t, err)
+	require.Equal(t, keyring.Next.Version, active.Version)
+	_, err = os.Stat(filepath.Join(dir, rotationMarkerFile))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	generation, err := keyringGeneration(dir)
+	require.NoError(t, err)
+	require.Zero(t, generation%2)
+	v, err = s.(*EncryptedDiskStore).keyVersion()
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), v)
+
+	r, err := s.Load()
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	require.NoError(t, r.Close())
+	require.NoError(t, err)
+	require.Equal(t, "saved during the rotation", string(b))
+
+	// a rotation interrupted after the new key replaced the current one replaces the seed of the vault
+	entries = vaultEntries(t, dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, rotationMarkerFile), nil, 0o600))
+	resumed, err = ResumeKeyRotation(ctx, targets, WithVaultPath(dir))
+	require.NoError(t, err)
+	require.True(t, resumed)
+	require.NotEqual(t, entries, vaultEntries(t, dir))
+	keyring, err = secret.GetAgentKeyring(ctx, vault.WithVaultPath(dir))
+	require.NoError(t, err)
+	require.Equal(t, active.Version, keyring.Current.Version)
+	other, err = secret.Get(ctx, "other", vault.WithVaultPath(dir))
+	require.NoError(t, err)
+	require.Equal(t, "other value", string(other.Value))
+
+	// nothing to resume once the rotation completed
+	entries = vaultEntries(t, dir)
+	resumed, err = ResumeKeyRotation(ctx, targets, WithVaultPath(dir))
+	require.NoError(t, err)
+	require.False(t, resumed)
+	require.Equal(t, entries, vaultEntries(t, dir))
+}
+
+func TestEncryptedDiskStorageWindowsLinuxRotationLock(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "encrypted disk storage does not use NewGCMWithRandomNonce.")
+	dir := t.TempDir()
+
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+
+	require.NoError(t, secret.CreateAgentSecret(ctx, vault.WithVaultPath(dir)))
+	s, err := NewEncryptedDiskStore(ctx, filepath.Join(dir, testConfigFile), WithVaultPath(dir))
+	require.NoError(t, err)
+
+	// the lock of a rotation in progress, another process holds it the same way
+	lock, err := lockRotation(ctx, dir, true)
+	require.NoError(t, err)
+
+	saved := make(chan error)
+	go func() {
+		saved <- s.Save(bytes.NewBufferString("foobar config"))
+	}()
+	select {
+	case err := <-saved:
+		t.Fatalf("save completed during the rotation: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, lock.Unlock())
+	require.NoError(t, <-saved)
+}
+
+// vaultEntries returns the names of the entries of the file vault at dir.
+func vaultEntries(t *testing.T, dir string) []string {
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var entries []string
+	for _, f := range files {
+		if len(f.Name()) == sha256.Size*2 {
+			entries = append(entries, f.Name())
+		}
+	}
+	return entries
+}
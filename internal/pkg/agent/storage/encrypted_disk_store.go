@@ -5,6 +5,7 @@
 package storage
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -28,6 +29,9 @@ const darwin = "darwin"
 
 var encryptionDisabled bool
 
+// errEmptyStore is returned when nothing was written to the encrypted disk store.
+var errEmptyStore = errors.New("encrypted disk store is empty")
+
 // DisableEncryptionDarwin disables storage encryption.
 // Is needed for existing unit tests on Mac OS, because the system keychain requires sudo
 func DisableEncryptionDarwin() {
@@ -103,17 +107,51 @@ func (d *EncryptedDiskStore) Exists() (bool, error) {
 	return true, nil
 }
 
-func (d *EncryptedDiskStore) ensureKey(ctx context.Context) error {
-	if d.key == nil {
-		key, err := secret.GetAgentSecret(ctx, vault.WithVaultPath(d.vaultPath), vault.WithUnprivileged(d.unprivileged))
+// ensureKeyring reads the agent keyring, it is read again when the keyring was rotated since by any
+// agent process. It must be called with the rotation lock held.
+func (d *EncryptedDiskStore) ensureKeyring(ctx context.Context) error {
+	generation, err := keyringGeneration(d.vaultPath)
+	if err != nil {
+		return fmt.Errorf("could not read the agent keyring generation: %w", err)
+	}
+	if d.keyring == nil || d.keyringGeneration != generation {
+		keyring, err := secret.GetAgentKeyring(ctx, d.vaultOptions()...)
 		if err != nil {
 			return fmt.Errorf("could not get agent key: %w", err)
 		}
-		d.key = key.Value
+		d.keyring = &keyring
+		d.keyringGeneration = generation
 	}
 	return nil
 }
 
+// keyForVersion returns the agent key of the given version, the version 0 is the current agent key.
+// The keyring is read again when it does not hold the version.
+func (d *EncryptedDiskStore) keyForVersion(ctx context.Context, version uint32) ([]byte, error) {
+	if err := d.ensureKeyring(ctx); err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		return d.keyring.Current.Value, nil
+	}
+	key, ok := d.keyring.ByVersion(version)
+	if !ok {
+		d.keyring = nil
+		if err := d.ensureKeyring(ctx); err != nil {
+			return nil, err
+		}
+		key, ok = d.keyring.ByVersion(version)
+		if !ok {
+			return nil, fmt.Errorf("agent key version %d not found", version)
+		}
+	}
+	return key.Value, nil
+}
+
+func (d *EncryptedDiskStore) vaultOptions() []vault.OptionFunc {
+	return []vault.OptionFunc{vault.WithVaultPath(d.vaultPath), vault.WithUnprivileged(d.unprivileged)}
+}
+
 // Save will read 'in' and write its contents encrypted to disk.
 // If EncryptedDiskStore.Load() was called, the io.ReadCloser it returns MUST be
 // closed before Save() can be called. It is so because Save() writes to a .tmp
@@ -122,11 +160,31 @@ func (d *EncryptedDiskStore) ensureKey(ctx context.Context) error {
 // Specially on windows systems, if the original files is still open because of
 // Load(), Save() would fail.
 func (d *EncryptedDiskStore) Save(in io.Reader) error {
+	// a rotation of the agent key waits for the saves in progress before re-encrypting the stores
+	lock, err := lockRotation(d.ctx, d.vaultPath, false)
+	if err != nil {
+		return errors.New(err, "failed to lock the agent key rotation")
+	}
+	defer func() {
+		_ = lock.Unlock()
+	}()
+
+	return d.save(in)
+}
+
+func (d *EncryptedDiskStore) save(in io.Reader) error {
 	// Ensure has agent key
-	err := d.ensureKey(d.ctx)
+	err := d.ensureKeyring(d.ctx)
 	if err != nil {
 		return errors.New(err, "failed to ensure key")
 	}
+	key := d.keyring.Active()
+	// the key version is only recorded while a rotation is in progress, the store is otherwise written
+	// in the format of the agents not knowing the key versions, they can read it after a rollback
+	keyVersion := uint32(0)
+	if d.keyring.Next != nil {
+		keyVersion = key.Version
+	}
 
 	tmpFile := d.target + ".tmp"
 
@@ -142,7 +200,7 @@ func (d *EncryptedDiskStore) Save(in io.Reader) error {
 	defer os.Remove(tmpFile)
 
 	// Wrap into crypto writer, reusing already existing crypto writer, open to other suggestions
-	w, err := crypto.NewWriterWithDefaults(fd, d.key)
+	w, err := crypto.NewWriterWithKeyVersion(fd, key.Value, keyVersion, crypto.DefaultOptions)
 	if err != nil {
 		fd.Close()
 		return errors.New(err, "failed to open crypto writers")
@@ -198,7 +256,20 @@ func (d *EncryptedDiskStore) Save(in io.Reader) error {
 }
 
 // Load returns an io.ReadCloser for the target.
-func (d *EncryptedDiskStore) Load() (rc io.ReadCloser, err error) {
+func (d *EncryptedDiskStore) Load() (io.ReadCloser, error) {
+	// the key the target is encrypted with is kept until the rotation lock is released
+	lock, err := lockRotation(d.ctx, d.vaultPath, false)
+	if err != nil {
+		return nil, errors.New(err, "failed to lock the agent key rotation")
+	}
+	defer func() {
+		_ = lock.Unlock()
+	}()
+
+	return d.load()
+}
+
+func (d *EncryptedDiskStore) load() (rc io.ReadCloser, err error) {
 	fd, err := os.OpenFile(d.target, os.O_RDONLY, permMask)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -218,11 +289,54 @@ func (d *EncryptedDiskStore) Load() (rc io.ReadCloser, err error) {
 		}
 	}()
 
+	r, version, err := peekKeyVersion(fd)
+	if errors.Is(err, errEmptyStore) {
+		// nothing was written to the store
+		_ = fd.Close()
+		return io.NopCloser(bytes.NewReader([]byte{})), nil
+	}
+	if err != nil {
+		return nil, errors.New(err,
+			fmt.Sprintf("could not read the header of %s", d.target),
+			errors.TypeFilesystem,
+			errors.M(errors.MetaKeyPath, d.target))
+	}
+
 	// Ensure has agent key
-	err = d.ensureKey(d.ctx)
+	key, err := d.keyForVersion(d.ctx, version)
 	if err != nil {
 		return nil, errors.New(err, "failed to ensure key during encrypted disk store Load")
 	}
 
-	return crypto.NewReaderWithDefaults(fd, d.key)
+	return crypto.NewReaderWithDefaults(r, key)
+}
+
+// keyVersion returns the version of the agent key the target is encrypted with, 0 when it is encrypted
+// with the current agent key without recording its version.
+func (d *EncryptedDiskStore) keyVersion() (uint32, error) {
+	fd, err := os.Open(d.target)
+	if err != nil {
+		return 0, err
+	}
+	defer fd.Close()
+
+	_, version, err := peekKeyVersion(fd)
+	return version, err
+}
+
+// peekKeyVersion returns the version of the agent key the content of fd is encrypted with and a
+// reader of the whole content closing fd.
+func peekKeyVersion(fd *os.File) (io.ReadCloser, uint32, error) {
+	br := bufio.NewReader(fd)
+	if _, err := br.Peek(1); errors.Is(err, io.EOF) {
+		return nil, 0, errEmptyStore
+	}
+	version, err := crypto.PeekKeyVersion(br)
+	if err != nil {
+		return nil, 0, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{br, fd}, version, nil
 }
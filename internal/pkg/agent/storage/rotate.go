@@ -0,0 +1,274 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+
+	"github.com/elastic/elastic-agent-libs/file"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+)
+
+const (
+	// rotationLockFile is the file of the vault directory locked exclusively by the rotation of the
+	// agent key, the encrypted disk stores of every agent process hold a shared lock on it while they
+	// read or write their target.
+	rotationLockFile = ".rotation.lock"
+
+	// keyringGenerationFile holds the generation of the agent keyring, the rotation increments it every
+	// time the keyring changes to tell the encrypted disk stores to read the keyring again. It is odd
+	// while the keyring holds the key of a rotation in progress.
+	keyringGenerationFile = ".keyring.generation"
+
+	// rotationMarkerFile exists in the vault directory from the start of the rotation of the agent key
+	// until the seed of the vault is replaced, it tells that a rotation was interrupted once the new
+	// key replaced the current one.
+	rotationMarkerFile = ".rotation"
+
+	rotationLockRetryDelay = 10 * time.Millisecond
+)
+
+// RotateAgentKey replaces the agent key and re-encrypts the fleet configuration and the state store
+// of the agent with the new key, the seed of the vault is replaced as well. It returns the version
+// of the new key.
+func RotateAgentKey(ctx context.Context, opts ...EncryptedOptionFunc) (uint32, error) {
+	return RotateKey(ctx, agentKeyTargets(), opts...)
+}
+
+// ResumeAgentKeyRotation completes the rotation of the agent key interrupted before the end, it must
+// be called before the fleet configuration and the state store of the agent are opened. It reports
+// whether a rotation was resumed.
+func ResumeAgentKeyRotation(ctx context.Context, opts ...EncryptedOptionFunc) (bool, error) {
+	return ResumeKeyRotation(ctx, agentKeyTargets(), opts...)
+}
+
+// agentKeyTargets returns the encrypted disk stores of the agent encrypted with the agent key.
+func agentKeyTargets() []string {
+	return []string{paths.AgentConfigFile(), paths.AgentStateStoreFile()}
+}
+
+// RotateKey replaces the agent key the encrypted disk stores at targets are encrypted with, then the
+// seed of the vault the agent key is stored in, every entry of the vault is re-encrypted with it.
+//
+// The new key is saved in the vault next to the current one, the targets are then re-encrypted with
+// it one by one and the current key is replaced once all of them are rewritten. The targets record
+// the version of the key they are encrypted with during the rotation, a rotation interrupted before
+// the end is resumed by calling RotateKey or ResumeKeyRotation, the targets already encrypted with
+// the new key are left as is. Once the new key replaced the current one, the targets are written
+// again without the key version, in the format the agent versions not knowing the key versions read.
+//
+// The rotation holds the rotation lock of the vault for its whole duration, the encrypted disk stores
+// of every agent process wait for it to complete. It returns the version of the new key.
+func RotateKey(ctx context.Context, targets []string, opts ...EncryptedOptionFunc) (uint32, error) {
+	version, _, err := rotateKey(ctx, targets, false, opts...)
+	return version, err
+}
+
+// ResumeKeyRotation completes the rotation of the agent key the encrypted disk stores at targets are
+// encrypted with when it was interrupted before the end, see RotateKey. A rotation is interrupted when
+// the vault holds the key of a rotation in progress, when the keyring generation is odd or when the
+// rotation marker was left in the vault directory. It reports whether a rotation was resumed.
+func ResumeKeyRotation(ctx context.Context, targets []string, opts ...EncryptedOptionFunc) (bool, error) {
+	_, resumed, err := rotateKey(ctx, targets, true, opts...)
+	return resumed, err
+}
+
+// rotateKey rotates the agent key the encrypted disk stores at targets are encrypted with, when
+// resume is set only the rotation in progress is completed and no new key is created.
+func rotateKey(ctx context.Context, targets []string, resume bool, opts ...EncryptedOptionFunc) (uint32, bool, error) {
+	stores := make([]*EncryptedDiskStore, 0, len(targets))
+	for _, target := range targets {
+		s, err := NewEncryptedDiskStore(ctx, target, opts...)
+		if err != nil {
+			return 0, false, fmt.Errorf("error instantiating encrypted disk store %s: %w", target, err)
+		}
+		eds, ok := s.(*EncryptedDiskStore)
+		if !ok {
+			// encryption is disabled, nothing to rotate
+			return 0, false, nil
+		}
+		stores = append(stores, eds)
+	}
+	if len(stores) == 0 {
+		return 0, false, nil
+	}
+	vaultPath := stores[0].vaultPath
+	vaultOpts := stores[0].vaultOptions()
+
+	lock, err := lockRotation(ctx, vaultPath, true)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() {
+		_ = lock.Unlock()
+	}()
+
+	marker := filepath.Join(vaultPath, rotationMarkerFile)
+	_, err = os.Stat(marker)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, false, fmt.Errorf("failed to read the rotation marker: %w", err)
+	}
+	marked := err == nil
+
+	keyring, err := secret.GetAgentKeyring(ctx, vaultOpts...)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read the agent keyring: %w", err)
+	}
+	generation, err := keyringGeneration(vaultPath)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read the agent keyring generation: %w", err)
+	}
+	if resume && !marked && keyring.Next == nil && generation%2 == 0 {
+		return 0, false, nil
+	}
+
+	if !marked {
+		if err := os.WriteFile(marker, nil, permMask); err != nil {
+			return 0, false, fmt.Errorf("failed to write the rotation marker: %w", err)
+		}
+	}
+
+	if keyring.Next == nil && generation%2 == 1 {
+		// the new key replaced the current one before the generation was incremented
+		if err := incrementKeyringGeneration(vaultPath); err != nil {
+			return 0, false, err
+		}
+		generation++
+	}
+
+	version := keyring.Current.Version
+	if keyring.Next != nil || !resume {
+		keyring, err = secret.BeginAgentSecretRotation(ctx, vaultOpts...)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to create the new agent key: %w", err)
+		}
+		if generation%2 == 0 {
+			if err := incrementKeyringGeneration(vaultPath); err != nil {
+				return 0, false, err
+			}
+		}
+		next := keyring.Active()
+
+		for _, s := range stores {
+			if err := s.reencrypt(next.Version); err != nil {
+				// the current key is kept, the stores already re-encrypted are read with the new key
+				return 0, false, fmt.Errorf("failed to re-encrypt %s: %w", s.target, err)
+			}
+		}
+
+		if err := secret.CommitAgentSecretRotation(ctx, vaultOpts...); err != nil {
+			return 0, false, fmt.Errorf("failed to replace the agent key: %w", err)
+		}
+		if err := incrementKeyringGeneration(vaultPath); err != nil {
+			return 0, false, err
+		}
+		version = next.Version
+	}
+
+	for _, s := range stores {
+		if err := s.reencrypt(0); err != nil {
+			return 0, false, fmt.Errorf("failed to write %s without the key version: %w", s.target, err)
+		}
+	}
+
+	if err := vault.RotateSeed(ctx, vaultOpts...); err != nil {
+		return 0, false, fmt.Errorf("failed to replace the vault seed: %w", err)
+	}
+
+	if err := os.Remove(marker); err != nil {
+		return 0, false, fmt.Errorf("failed to remove the rotation marker: %w", err)
+	}
+	return version, true, nil
+}
+
+// reencrypt rewrites the content of the store with the active agent key when it is not encrypted
+// with the key version yet. It must be called with the rotation lock held.
+func (d *EncryptedDiskStore) reencrypt(version uint32) error {
+	current, err := d.keyVersion()
+	if errors.Is(err, errEmptyStore) || errors.Is(err, os.ErrNotExist) || (err == nil && current == version) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r, err := d.load()
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		return fmt.Errorf("could not decrypt the content: %w", err)
+	}
+
+	return d.save(bytes.NewReader(content))
+}
+
+// lockRotation acquires the rotation lock of the vault at vaultPath, exclusive for the rotation of
+// the agent key and shared for the encrypted disk stores.
+func lockRotation(ctx context.Context, vaultPath string, exclusive bool) (*flock.Flock, error) {
+	if err := os.MkdirAll(vaultPath, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create vault path: %v, err: %w", vaultPath, err)
+	}
+
+	lock := flock.New(filepath.Join(vaultPath, rotationLockFile))
+	var err error
+	if exclusive {
+		_, err = lock.TryLockContext(ctx, rotationLockRetryDelay)
+	} else {
+		_, err = lock.TryRLockContext(ctx, rotationLockRetryDelay)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire the rotation lock %s: %w", lock.Path(), err)
+	}
+	return lock, nil
+}
+
+// keyringGeneration returns the generation of the agent keyring of the vault at vaultPath, 0 when the
+// agent key was never rotated.
+func keyringGeneration(vaultPath string) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(vaultPath, keyringGenerationFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// incrementKeyringGeneration increments the generation of the agent keyring of the vault at
+// vaultPath. It must be called with the exclusive rotation lock held.
+func incrementKeyringGeneration(vaultPath string) error {
+	generation, err := keyringGeneration(vaultPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the agent keyring generation: %w", err)
+	}
+
+	fp := filepath.Join(vaultPath, keyringGenerationFile)
+	tmpFile := fp + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(strconv.FormatUint(generation+1, 10)), permMask); err != nil {
+		return fmt.Errorf("failed to write the agent keyring generation: %w", err)
+	}
+	if err := file.SafeFileRotate(fp, tmpFile); err != nil {
+		return fmt.Errorf("failed to replace the agent keyring generation: %w", err)
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
@@ -45,7 +46,9 @@ type EncryptedDiskStore struct {
 	ctx          context.Context
 	target       string
 	vaultPath    string
-	key          []byte
 	unprivileged bool
 	ownership    *utils.FileOwner
+
+	keyring           *secret.Keyring
+	keyringGeneration uint64 // generation of the agent keyring when it was read
 }
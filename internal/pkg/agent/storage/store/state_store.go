@@ -10,12 +10,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"reflect"
+	"slices"
 	"sync"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/internal/pkg/queue"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -23,6 +26,14 @@ import (
 // introduced, it should be increased and a migration added.
 const Version = "1"
 
+// maxPendingAcks is the maximum number of acks kept pending to be sent to fleet,
+// the oldest acks are dropped first.
+const maxPendingAcks = 1024
+
+// maxStatusJournal is the maximum number of status transitions kept in the
+// status journal, the oldest transitions are dropped first.
+const maxStatusJournal = 256
+
 type saver interface {
 	Save(io.Reader) error
 }
@@ -34,8 +45,10 @@ type saveLoader interface {
 
 // StateStore stores the agent state:
 //   - the last fleet action (not all actions are stored, refer to Save for details)
-//   - a queue of scheduled actions
+//   - a queue of scheduled actions and their scheduling state
 //   - the ack token
+//   - the acks pending to be sent to fleet
+//   - the status transitions that happened while fleet could not be reached
 //
 // See each method documentation for details.
 type StateStore struct {
@@ -48,10 +61,14 @@ type StateStore struct {
 }
 
 type state struct {
-	Version          string           `json:"version"`
-	ActionSerializer actionSerializer `json:"action,omitempty"`
-	AckToken         string           `json:"ack_token,omitempty"`
-	Queue            actionQueue      `json:"action_queue,omitempty"`
+	Version          string                             `json:"version"`
+	ActionSerializer actionSerializer                   `json:"action,omitempty"`
+	AckToken         string                             `json:"ack_token,omitempty"`
+	Queue            actionQueue                        `json:"action_queue,omitempty"`
+	QueueScheduling  queue.Scheduling                   `json:"action_queue_scheduling,omitzero"`
+	PendingAcks      []*fleetapi.AckedAction            `json:"pending_acks,omitempty"`
+	StatusJournal    []fleetapi.CheckinStatusTransition `json:"status_journal,omitempty"`
+	StatusHistory    bool                               `json:"status_history_supported,omitempty"`
 }
 
 // actionSerializer is JSON Marshaler/Unmarshaler for fleetapi.Action.
@@ -220,6 +237,76 @@ func (s *StateStore) SetQueue(q []fleetapi.ScheduledAction) {
 	s.dirty = true
 }
 
+// SetQueueScheduling sets the scheduling state of the action_queue to agent state
+func (s *StateStore) SetQueueScheduling(scheduling queue.Scheduling) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.state.QueueScheduling = scheduling
+	s.dirty = true
+}
+
+// SetPendingAcks sets the acks pending to be sent to fleet, replacing the
+// previous ones. Only the most recent acks are kept once there are too many.
+func (s *StateStore) SetPendingAcks(acks []*fleetapi.AckedAction) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if len(acks) == 0 && len(s.state.PendingAcks) == 0 {
+		return
+	}
+	if len(acks) > maxPendingAcks {
+		s.log.Warnf("too many acks pending to be sent to fleet, dropping the %d oldest", len(acks)-maxPendingAcks)
+		acks = acks[len(acks)-maxPendingAcks:]
+	}
+	s.state.PendingAcks = slices.Clone(acks)
+	s.dirty = true
+}
+
+// AppendStatusTransition appends a status transition to the status journal
+// and reports whether it was appended. The transition is dropped if it's
+// identical to the last one, apart from its timestamp. Once the journal is
+// full the oldest transitions are dropped.
+func (s *StateStore) AppendStatusTransition(t fleetapi.CheckinStatusTransition) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if n := len(s.state.StatusJournal); n > 0 {
+		last := s.state.StatusJournal[n-1]
+		last.Timestamp = t.Timestamp
+		if reflect.DeepEqual(last, t) {
+			return false
+		}
+	}
+	s.state.StatusJournal = append(s.state.StatusJournal, t)
+	if len(s.state.StatusJournal) > maxStatusJournal {
+		s.state.StatusJournal = slices.Clone(s.state.StatusJournal[len(s.state.StatusJournal)-maxStatusJournal:])
+	}
+	s.dirty = true
+	return true
+}
+
+// TrimStatusJournal removes the n oldest status transitions, once they have
+// been sent to fleet.
+func (s *StateStore) TrimStatusJournal(n int) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if n <= 0 {
+		return
+	}
+	n = min(n, len(s.state.StatusJournal))
+	s.state.StatusJournal = slices.Clone(s.state.StatusJournal[n:])
+	s.dirty = true
+}
+
+// SetStatusHistorySupported records whether fleet accepts the status history.
+func (s *StateStore) SetStatusHistorySupported(supported bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.state.StatusHistory == supported {
+		return
+	}
+	s.state.StatusHistory = supported
+	s.dirty = true
+}
+
 // Save saves the actions into the state store. If the action type is not
 // supported or if any error happens, it returns a non-nil error.
 func (s *StateStore) Save() (err error) {
@@ -268,6 +355,17 @@ func (s *StateStore) Queue() []fleetapi.ScheduledAction {
 	return q
 }
 
+// QueueScheduling returns a copy of the scheduling state of the queue
+func (s *StateStore) QueueScheduling() queue.Scheduling {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return queue.Scheduling{
+		Schedules: maps.Clone(s.state.QueueScheduling.Schedules),
+		Completed: maps.Clone(s.state.QueueScheduling.Completed),
+		Failed:    maps.Clone(s.state.QueueScheduling.Failed),
+	}
+}
+
 // Action the action to execute. See SetAction for the possible action types.
 func (s *StateStore) Action() fleetapi.Action {
 	s.mx.RLock()
@@ -280,6 +378,28 @@ func (s *StateStore) Action() fleetapi.Action {
 	return s.state.ActionSerializer.Action
 }
 
+// PendingAcks returns a copy of the acks pending to be sent to fleet.
+func (s *StateStore) PendingAcks() []*fleetapi.AckedAction {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return slices.Clone(s.state.PendingAcks)
+}
+
+// StatusJournal returns a copy of the status transitions, oldest first.
+func (s *StateStore) StatusJournal() []fleetapi.CheckinStatusTransition {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return slices.Clone(s.state.StatusJournal)
+}
+
+// StatusHistorySupported returns whether fleet accepted the status history on
+// the last checkin.
+func (s *StateStore) StatusHistorySupported() bool {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return s.state.StatusHistory
+}
+
 // AckToken return the agent state persisted ack_token
 func (s *StateStore) AckToken() string {
 	s.mx.RLock()
@@ -6,6 +6,7 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -20,6 +21,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/queue"
 	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
 	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
 )
@@ -371,6 +373,56 @@ func runTestStateStore(t *testing.T, ackToken string) {
 		assert.Equal(t, ts, start)
 	})
 
+	t.Run("can save a queue with its scheduling state", func(t *testing.T) {
+		ts := time.Now().UTC().Round(time.Second)
+		schedule := &fleetapi.ActionSchedule{
+			Recurrence: "0 2 * * 6",
+			DependsOn:  []string{"other"},
+		}
+		actions := []fleetapi.ScheduledAction{&fleetapi.ActionUpgrade{
+			ActionID:        "test",
+			ActionType:      fleetapi.ActionTypeUpgrade,
+			ActionStartTime: ts.Format(time.RFC3339),
+			ActionSchedule:  schedule,
+			Data: fleetapi.ActionUpgradeData{
+				Version:   "1.2.3",
+				SourceURI: "https://example.com",
+			}}}
+		scheduling := queue.Scheduling{
+			Schedules: map[string]queue.Schedule{
+				"test": {
+					Recurrence: schedule.Recurrence,
+					DependsOn:  schedule.DependsOn,
+					Next:       ts.Add(time.Hour),
+				},
+			},
+			Completed: map[string]time.Time{"other": ts.Add(-time.Hour)},
+			Failed:    map[string]time.Time{"failed": ts.Add(-time.Minute)},
+		}
+
+		storePath := filepath.Join(t.TempDir(), "state.json")
+		s, err := storage.NewDiskStore(storePath)
+		require.NoError(t, err, "failed creating DiskStore")
+
+		store, err := NewStateStore(log, s)
+		require.NoError(t, err)
+
+		store.SetQueue(actions)
+		store.SetQueueScheduling(scheduling)
+		err = store.Save()
+		require.NoError(t, err)
+
+		s, err = storage.NewDiskStore(storePath)
+		require.NoError(t, err, "failed creating DiskStore")
+
+		store, err = NewStateStore(log, s)
+		require.NoError(t, err)
+
+		require.Len(t, store.Queue(), 1)
+		assert.Equal(t, actions[0], store.Queue()[0])
+		assert.Equal(t, scheduling, store.QueueScheduling())
+	})
+
 	t.Run("can save a queue with two actions", func(t *testing.T) {
 		ts := time.Now().UTC().Round(time.Second)
 		queue := []fleetapi.ScheduledAction{&fleetapi.ActionUpgrade{
@@ -627,6 +679,10 @@ func runTestStateStore(t *testing.T, ackToken string) {
 				ActionType:       fleetapi.ActionTypeUpgrade,
 				ActionStartTime:  now.Format(time.RFC3339),
 				ActionExpiration: now.Add(time.Hour).Format(time.RFC3339),
+				ActionSchedule: &fleetapi.ActionSchedule{
+					Recurrence: "@daily",
+					DependsOn:  []string{"other"},
+				},
 				Data: fleetapi.ActionUpgradeData{
 					Version:   "1.2.3",
 					SourceURI: "https://example.com",
@@ -676,6 +732,80 @@ func runTestStateStore(t *testing.T, ackToken string) {
 	})
 }
 
+func TestStateStorePendingAcksAndStatusJournal(t *testing.T) {
+	log, _ := loggertest.New("state_store")
+	storePath := filepath.Join(t.TempDir(), "state.json")
+
+	s, err := storage.NewDiskStore(storePath)
+	require.NoError(t, err, "failed creating DiskStore")
+	store, err := NewStateStore(log, s)
+	require.NoError(t, err)
+
+	ackedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	pending := []*fleetapi.AckedAction{
+		fleetapi.NewAckedAction(&fleetapi.ActionUnenroll{ActionID: "id1", ActionType: fleetapi.ActionTypeUnenroll}, ackedAt),
+		fleetapi.NewAckedAction(&fleetapi.ActionPolicyChange{ActionID: "id2", ActionType: fleetapi.ActionTypePolicyChange}, ackedAt),
+	}
+	store.SetPendingAcks(pending)
+
+	degraded := fleetapi.CheckinStatusTransition{
+		Timestamp: ackedAt,
+		Status:    "DEGRADED",
+		Message:   "component failed",
+	}
+	assert.True(t, store.AppendStatusTransition(degraded))
+	// identical apart from the timestamp, it's dropped
+	again := degraded
+	again.Timestamp = ackedAt.Add(time.Minute)
+	assert.False(t, store.AppendStatusTransition(again))
+	healthy := fleetapi.CheckinStatusTransition{
+		Timestamp: ackedAt.Add(2 * time.Minute),
+		Status:    "HEALTHY",
+		Message:   "Running",
+	}
+	assert.True(t, store.AppendStatusTransition(healthy))
+	store.SetStatusHistorySupported(true)
+	require.NoError(t, store.Save())
+
+	s, err = storage.NewDiskStore(storePath)
+	require.NoError(t, err, "failed creating DiskStore")
+	store, err = NewStateStore(log, s)
+	require.NoError(t, err)
+
+	assert.Equal(t, pending, store.PendingAcks())
+	assert.Equal(t, []fleetapi.CheckinStatusTransition{degraded, healthy}, store.StatusJournal())
+	assert.True(t, store.StatusHistorySupported())
+
+	store.TrimStatusJournal(1)
+	assert.Equal(t, []fleetapi.CheckinStatusTransition{healthy}, store.StatusJournal())
+	store.TrimStatusJournal(10)
+	assert.Empty(t, store.StatusJournal())
+
+	for i := 0; i < maxStatusJournal+10; i++ {
+		store.AppendStatusTransition(fleetapi.CheckinStatusTransition{
+			Timestamp: ackedAt,
+			Status:    "DEGRADED",
+			Message:   fmt.Sprintf("failure %d", i),
+		})
+	}
+	journal := store.StatusJournal()
+	require.Len(t, journal, maxStatusJournal)
+	assert.Equal(t, "failure 10", journal[0].Message, "the oldest transitions are dropped")
+
+	many := make([]*fleetapi.AckedAction, 0, maxPendingAcks+10)
+	for i := 0; i < maxPendingAcks+10; i++ {
+		many = append(many, fleetapi.NewAckedAction(&fleetapi.ActionUnenroll{ActionID: fmt.Sprintf("id%d", i), ActionType: fleetapi.ActionTypeUnenroll}, ackedAt))
+	}
+	store.SetPendingAcks(many)
+	acks := store.PendingAcks()
+	require.Len(t, acks, maxPendingAcks)
+	assert.Equal(t, "id10", acks[0].ID(), "the oldest acks are dropped")
+
+	store.SetPendingAcks(nil)
+	require.NoError(t, store.Save())
+	assert.Empty(t, store.PendingAcks())
+}
+
 type testAcker struct {
 	acked     []string
 	ackedLock sync.Mutex
@@ -1194,6 +1194,43 @@ func Lookup(a *AST, selector Selector) (Node, bool) {
 	return current, true
 }
 
+// HasTimeDependentConditions returns true when a condition of the node calls a time dependent function, like
+// now(). The node must be rendered again as time passes for the result of its conditions to be up to date.
+func HasTimeDependentConditions(node Node) bool {
+	switch n := node.(type) {
+	case *Dict:
+		for _, v := range n.value {
+			if HasTimeDependentConditions(v) {
+				return true
+			}
+		}
+	case *List:
+		for _, v := range n.value {
+			if HasTimeDependentConditions(v) {
+				return true
+			}
+		}
+	case *Key:
+		if n.name != conditionKey {
+			return n.value != nil && HasTimeDependentConditions(n.value)
+		}
+		v, ok := n.value.(*StrVal)
+		if !ok {
+			return false
+		}
+		condition := n.condition
+		if condition == nil {
+			var err error
+			condition, err = eql.New(v.value)
+			if err != nil {
+				return false
+			}
+		}
+		return condition.IsTimeDependent()
+	}
+	return false
+}
+
 // Insert inserts an AST into an existing AST, will return and error if the target position cannot
 // accept a new node.
 func (a *AST) Insert(b *AST, to Selector) error {
@@ -1208,6 +1208,42 @@ func TestCondition(t *testing.T) {
 	assert.Nil(t, input2.condition)
 }
 
+func TestHasTimeDependentConditions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input    map[string]interface{}
+		expected bool
+	}{
+		"no condition": {
+			input:    map[string]interface{}{"inputs": []interface{}{map[string]interface{}{"type": "logfile"}}},
+			expected: false,
+		},
+		"condition on variables": {
+			input: map[string]interface{}{"inputs": []interface{}{map[string]interface{}{
+				"type":      "logfile",
+				"condition": "${host.name} == 'foo'",
+			}}},
+			expected: false,
+		},
+		"nested condition calling now": {
+			input: map[string]interface{}{"inputs": []interface{}{map[string]interface{}{
+				"type": "logfile",
+				"streams": []interface{}{map[string]interface{}{
+					"condition": "now() < timestamp('2030-01-01T00:00:00Z')",
+				}},
+			}}},
+			expected: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ast, err := NewAST(tc.input)
+			require.NoError(t, err)
+			inputs, ok := Lookup(ast, "inputs")
+			require.True(t, ok)
+			assert.Equal(t, tc.expected, HasTimeDependentConditions(inputs))
+		})
+	}
+}
+
 // check that all the methods handle nil values correctly
 func TestNullValues(t *testing.T) {
 	cfgMap := map[string]any{
@@ -0,0 +1,283 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrFilterFailed is returned when a filter of a variable substitution fails on the value of the variable.
+var ErrFilterFailed = errors.New("filter failed")
+
+// errFilterNoValue is returned by a filter when its result has no value, the variable is then handled as not
+// matching.
+var errFilterNoValue = errors.New("no value")
+
+// filter transforms the value of a variable, filters are called after the variables of a substitution:
+//
+//	${kubernetes.labels.app | default("unknown") | replace("-", "_")}
+type filter struct {
+	// args is the number of quoted arguments the filter takes.
+	args int
+	// missing is true when the filter also applies to a variable without value, the value is then empty.
+	missing bool
+	apply   func(value string, args []string) (string, error)
+}
+
+// filterRegistry holds the filters available in variable substitutions by name.
+var filterRegistry = map[string]filter{
+	"lower": {
+		apply: func(value string, _ []string) (string, error) {
+			return strings.ToLower(value), nil
+		},
+	},
+	"upper": {
+		apply: func(value string, _ []string) (string, error) {
+			return strings.ToUpper(value), nil
+		},
+	},
+	"trim": {
+		apply: func(value string, _ []string) (string, error) {
+			return strings.TrimSpace(value), nil
+		},
+	},
+	"default": {
+		args:    1,
+		missing: true,
+		apply: func(value string, args []string) (string, error) {
+			if value == "" {
+				return args[0], nil
+			}
+			return value, nil
+		},
+	},
+	"replace": {
+		args: 2,
+		apply: func(value string, args []string) (string, error) {
+			return strings.ReplaceAll(value, args[0], args[1]), nil
+		},
+	},
+	"base64encode": {
+		apply: func(value string, _ []string) (string, error) {
+			return base64.StdEncoding.EncodeToString([]byte(value)), nil
+		},
+	},
+	"base64decode": {
+		apply: func(value string, _ []string) (string, error) {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+			if err != nil {
+				return "", fmt.Errorf("value is not valid base64: %w", err)
+			}
+			return string(decoded), nil
+		},
+	},
+	"json": {
+		args:  1,
+		apply: jsonFilter,
+	},
+}
+
+// jsonFilter extracts the value at the dotted path of the JSON document value. Objects and arrays are returned
+// encoded in JSON.
+func jsonFilter(value string, args []string) (string, error) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(value), &doc); err != nil {
+		return "", fmt.Errorf("value is not valid JSON: %w", err)
+	}
+
+	if path := args[0]; path != "" && path != varsSeparator {
+		for _, key := range strings.Split(path, varsSeparator) {
+			switch d := doc.(type) {
+			case map[string]interface{}:
+				v, ok := d[key]
+				if !ok {
+					return "", errFilterNoValue
+				}
+				doc = v
+			case []interface{}:
+				idx, err := strconv.Atoi(key)
+				if err != nil || idx < 0 || idx >= len(d) {
+					return "", errFilterNoValue
+				}
+				doc = d[idx]
+			default:
+				return "", errFilterNoValue
+			}
+		}
+	}
+
+	switch v := doc.(type) {
+	case nil:
+		return "", errFilterNoValue
+	case string:
+		return v, nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	}
+}
+
+// filterCall is a filter with its arguments in a variable substitution.
+type filterCall struct {
+	name   string
+	filter filter
+	args   []string
+}
+
+// parseFilterCall parses the tokens of a segment of a variable substitution as a filter call, a filter name followed
+// by its quoted arguments between parentheses. When bare is true, a filter name without parentheses is also a filter
+// call. It returns false when the tokens are not a filter call.
+func parseFilterCall(tokens []varToken, bare bool) (*filterCall, bool, error) {
+	var name strings.Builder
+	rest := -1
+	var after string
+	for i, t := range tokens {
+		if t.quoted {
+			return nil, false, nil
+		}
+		if idx := strings.IndexRune(t.value, '('); idx >= 0 {
+			name.WriteString(t.value[:idx])
+			after = t.value[idx+1:]
+			rest = i + 1
+			break
+		}
+		name.WriteString(t.value)
+	}
+	if rest < 0 {
+		if !bare || len(tokens) != 1 {
+			return nil, false, nil
+		}
+		f, ok := filterRegistry[name.String()]
+		if !ok {
+			return nil, false, nil
+		}
+		if f.args != 0 {
+			return nil, true, fmt.Errorf("filter %s takes %d arguments, got 0", name.String(), f.args)
+		}
+		return &filterCall{name: name.String(), filter: f, args: []string{}}, true, nil
+	}
+
+	f, ok := filterRegistry[name.String()]
+	if !ok {
+		return nil, true, fmt.Errorf("unknown filter %s", name.String())
+	}
+
+	args := []string{}
+	expectArg := true
+	closed := false
+	parsePunctuation := func(value string) error {
+		for _, r := range value {
+			switch {
+			case closed:
+				return fmt.Errorf("unexpected %s after the arguments of filter %s", value, name.String())
+			case r == ',' && !expectArg:
+				expectArg = true
+			case r == ')' && (!expectArg || len(args) == 0):
+				closed = true
+			default:
+				return fmt.Errorf("arguments of filter %s must be quoted and separated by ','", name.String())
+			}
+		}
+		return nil
+	}
+	if err := parsePunctuation(after); err != nil {
+		return nil, true, err
+	}
+	for _, t := range tokens[rest:] {
+		if !t.quoted {
+			if err := parsePunctuation(t.value); err != nil {
+				return nil, true, err
+			}
+			continue
+		}
+		if closed || !expectArg {
+			return nil, true, fmt.Errorf("arguments of filter %s must be quoted and separated by ','", name.String())
+		}
+		args = append(args, t.value)
+		expectArg = false
+	}
+	if !closed {
+		return nil, true, fmt.Errorf("filter %s is missing ending )", name.String())
+	}
+
+	if len(args) != f.args {
+		return nil, true, fmt.Errorf("filter %s takes %d arguments, got %d", name.String(), f.args, len(args))
+	}
+	return &filterCall{name: name.String(), filter: f, args: args}, true, nil
+}
+
+// applyFilters runs the filters over the value of the node, found is false when the variable has no value. It
+// returns the filtered value and false when the result has no value.
+func applyFilters(calls []*filterCall, node Node, found bool) (Node, bool, error) {
+	value := ""
+	if found {
+		var err error
+		value, err = filterInput(node)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	for _, call := range calls {
+		if !found && !call.filter.missing {
+			continue
+		}
+		res, err := call.filter.apply(value, call.args)
+		if errors.Is(err, errFilterNoValue) {
+			value, found = "", false
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %s: %w", ErrFilterFailed, call.name, err)
+		}
+		value, found = res, true
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return NewStrVal(value), true, nil
+}
+
+// filterInput returns the value of the node filters apply to, dictionaries and lists are encoded in JSON.
+func filterInput(node Node) (string, error) {
+	switch n := node.(type) {
+	case *StrVal:
+		return n.value, nil
+	case *Dict, *List:
+		m := &MapVisitor{}
+		(&AST{root: n}).Accept(m)
+		encoded, err := json.Marshal(m.Content)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode value: %w", err)
+		}
+		return string(encoded), nil
+	default:
+		return node.String(), nil
+	}
+}
+
+func (c *filterCall) String() string {
+	var sb strings.Builder
+	sb.WriteString(c.name)
+	sb.WriteString("(")
+	for i, arg := range c.args {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(`'`)
+		sb.WriteString(arg)
+		sb.WriteString(`'`)
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
@@ -0,0 +1,279 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVars_ReplaceWithFilters(t *testing.T) {
+	vars := mustMakeVarsWithDefault(map[string]interface{}{
+		"env": map[string]interface{}{
+			"REGION":  "EU-West-1",
+			"PADDED":  "  value  ",
+			"EMPTY":   "",
+			"ENCODED": "c2VjcmV0",
+			"lower":   "not a filter",
+			"DOC":     `{"db":{"hosts":["a:9200","b:9200"],"user":"elastic","tls":{"enabled":true},"none":null}}`,
+		},
+		"kubernetes": map[string]interface{}{
+			"labels": map[string]interface{}{
+				"app": "my-app",
+			},
+			"dict": map[string]interface{}{
+				"key1": "value1",
+			},
+		},
+	}, "env")
+	tests := []struct {
+		Input   string
+		Result  Node
+		Error   bool
+		NoMatch bool
+	}{
+		{
+			"${env.REGION | lower()}",
+			NewStrVal("eu-west-1"),
+			false,
+			false,
+		},
+		{
+			"${env.REGION|upper()}",
+			NewStrVal("EU-WEST-1"),
+			false,
+			false,
+		},
+		{
+			"${env.PADDED | trim() | upper()}",
+			NewStrVal("VALUE"),
+			false,
+			false,
+		},
+		{
+			`${kubernetes.labels.app | default("unknown") | replace("-", "_")}`,
+			NewStrVal("my_app"),
+			false,
+			false,
+		},
+		{
+			`${kubernetes.labels.missing | default ("unknown") | replace( "-","_" )}`,
+			NewStrVal("unknown"),
+			false,
+			false,
+		},
+		{
+			`${env.EMPTY | default('fallback')}`,
+			NewStrVal("fallback"),
+			false,
+			false,
+		},
+		{
+			`${env.MISSING | kubernetes.labels.app | upper()}`,
+			NewStrVal("MY-APP"),
+			false,
+			false,
+		},
+		{
+			`${env.MISSING | "constant value" | upper()}`,
+			NewStrVal("CONSTANT VALUE"),
+			false,
+			false,
+		},
+		{
+			"${env.MISSING | lower()}",
+			NewStrVal(""),
+			false,
+			true,
+		},
+		{
+			"prefix-${env.REGION | lower()}-suffix",
+			NewStrVal("prefix-eu-west-1-suffix"),
+			false,
+			false,
+		},
+		{
+			"${env.ENCODED | base64decode()}",
+			NewStrVal("secret"),
+			false,
+			false,
+		},
+		{
+			"${env.ENCODED | base64decode() | base64encode()}",
+			NewStrVal("c2VjcmV0"),
+			false,
+			false,
+		},
+		{
+			"${env.REGION | base64decode()}",
+			nil,
+			true,
+			false,
+		},
+		{
+			`${env.DOC | json("db.user")}`,
+			NewStrVal("elastic"),
+			false,
+			false,
+		},
+		{
+			`${env.DOC | json("db.hosts.1")}`,
+			NewStrVal("b:9200"),
+			false,
+			false,
+		},
+		{
+			`${env.DOC | json("db.tls")}`,
+			NewStrVal(`{"enabled":true}`),
+			false,
+			false,
+		},
+		{
+			`${env.DOC | json("db.missing")}`,
+			NewStrVal(""),
+			false,
+			true,
+		},
+		{
+			`${env.DOC | json("db.none") | default("none")}`,
+			NewStrVal("none"),
+			false,
+			false,
+		},
+		{
+			`${env.REGION | json("db")}`,
+			nil,
+			true,
+			false,
+		},
+		{
+			`${kubernetes.dict | json("key1")}`,
+			NewStrVal("value1"),
+			false,
+			false,
+		},
+		{
+			// a filter name is a variable when nothing precedes it
+			"${lower}",
+			NewStrVal("not a filter"),
+			false,
+			false,
+		},
+		{
+			// filters without arguments can be called without parentheses
+			"${env.REGION | lower}",
+			NewStrVal("eu-west-1"),
+			false,
+			false,
+		},
+		{
+			"${env.PADDED | trim | upper()}",
+			NewStrVal("VALUE"),
+			false,
+			false,
+		},
+		{
+			"${env.MISSING | lower}",
+			NewStrVal(""),
+			false,
+			true,
+		},
+		{
+			`${env.MISSING | "Constant" | lower}`,
+			NewStrVal("constant"),
+			false,
+			false,
+		},
+		{
+			"${env.REGION | default}",
+			nil,
+			true,
+			false,
+		},
+		{
+			"${env.REGION | lower | env.MISSING}",
+			nil,
+			true,
+			false,
+		},
+		{
+			"${env.REGION | unknown()}",
+			nil,
+			true,
+			false,
+		},
+		{
+			"${env.REGION | lower() | env.MISSING}",
+			nil,
+			true,
+			false,
+		},
+		{
+			"${env.REGION | replace('a')}",
+			nil,
+			true,
+			false,
+		},
+		{
+			"${env.REGION | default(unknown)}",
+			nil,
+			true,
+			false,
+		},
+		{
+			"${env.REGION | upper('arg')}",
+			nil,
+			true,
+			false,
+		},
+		{
+			`${env.REGION | replace("a" "b")}`,
+			nil,
+			true,
+			false,
+		},
+		{
+			`${env.REGION | replace("a", "b",)}`,
+			nil,
+			true,
+			false,
+		},
+		{
+			`${env.REGION | lower(}`,
+			nil,
+			true,
+			false,
+		},
+		{
+			`${env.REGION | lower() "a"}`,
+			nil,
+			true,
+			false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			res, err := vars.Replace(test.Input)
+			if test.Error {
+				assert.Error(t, err)
+				assert.NotContains(t, err.Error(), "missing ending }")
+				assert.NotErrorIs(t, err, ErrNoMatch)
+			} else if test.NoMatch {
+				assert.ErrorIs(t, err, ErrNoMatch)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.Result, res)
+			}
+		})
+	}
+
+	// parentheses are only allowed after the first '|'
+	_, err := vars.Replace("${lower()}")
+	assert.ErrorContains(t, err, "missing ending }")
+	_, err = vars.Replace("${env.REGION(x)}")
+	assert.ErrorContains(t, err, "missing ending }")
+}
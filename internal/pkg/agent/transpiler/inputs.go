@@ -16,6 +16,11 @@ const (
 	// an input defines a set of streams and after conditions are applied all the streams are removed then
 	// the entire input is removed.
 	streamsKey = "streams"
+
+	// RenderErrorKey is the name of the dictionary key set on an input that failed to render because a filter of a
+	// variable substitution failed. The input is kept so the error is reported on its unit instead of failing the
+	// rendering of all the inputs.
+	RenderErrorKey = "_render_error"
 )
 
 // RenderInputs renders dynamic inputs section
@@ -43,7 +48,11 @@ func RenderInputs(inputs Node, varsArray []*Vars) (Node, error) {
 				// has a variable that didn't exist, so we ignore it
 				continue
 			}
-			if err != nil {
+			if errors.Is(err, ErrFilterFailed) {
+				// only this input is in error, keep it unrendered with the error attached
+				n = dict.Clone()
+				n.(*Dict).Insert(NewKey(RenderErrorKey, NewStrVal(err.Error())))
+			} else if err != nil {
 				// another error that needs to be reported
 				return nil, err
 			}
@@ -25,6 +25,32 @@ func TestRenderInputs(t *testing.T) {
 				mustMakeVars(map[string]interface{}{}),
 			},
 		},
+		"filter error is attached to the input": {
+			input: NewKey("inputs", NewList([]Node{
+				NewDict([]Node{
+					NewKey("key", NewStrVal("${var1.name|base64decode()}")),
+				}),
+				NewDict([]Node{
+					NewKey("key", NewStrVal("${var1.name}")),
+				}),
+			})),
+			expected: NewList([]Node{
+				NewDict([]Node{
+					NewKey("key", NewStrVal("${var1.name|base64decode()}")),
+					NewKey(RenderErrorKey, NewStrVal(`error applying filters of variable "${var1.name|base64decode()}": filter failed: base64decode: value is not valid base64: illegal base64 data at input byte 3`)),
+				}),
+				NewDict([]Node{
+					NewKey("key", NewStrVal("not base64")),
+				}),
+			}),
+			varsArray: []*Vars{
+				mustMakeVars(map[string]interface{}{
+					"var1": map[string]interface{}{
+						"name": "not base64",
+					},
+				}),
+			},
+		},
 		"bad variable error": {
 			input: NewKey("inputs", NewList([]Node{
 				NewDict([]Node{
@@ -17,7 +17,9 @@ import (
 
 const varsSeparator = "."
 
-var varsRegex = regexp.MustCompile(`\$\$?{([\p{L}\d\s\\\-_|.'":\/]*)}`)
+// varsRegex matches a variable substitution, the parentheses and commas of filter calls are only allowed after the
+// first '|'.
+var varsRegex = regexp.MustCompile(`\$\$?{([\p{L}\d\s\\\-_.'":\/]*(?:\|[\p{L}\d\s\\\-_|.'":\/(),]*)?)}`)
 
 // ErrNoMatch is return when the replace didn't fail, just that no vars match to perform the replace.
 var ErrNoMatch = errors.New("no matching vars")
@@ -124,29 +126,27 @@ func replaceVars(value string, replacer func(variable string) (Node, Processors,
 				continue
 			}
 			// match on a non-escaped var
-			vars, err := extractVars(value[r[i+2]:r[i+3]], defaultProvider)
+			vars, filters, err := extractVars(value[r[i+2]:r[i+3]], defaultProvider)
 			if err != nil {
 				return nil, fmt.Errorf(`error parsing variable "%s": %w`, value[r[i]:r[i+1]], err)
 			}
+			var node Node
 			set := false
+			// only variables and filtered values replace a complete object
+			replacesObject := len(filters) > 0
 			for _, val := range vars {
 				switch val.(type) {
 				case *constString:
-					result += value[lastIndex:r[0]] + val.Value()
+					node = NewStrVal(val.Value())
 					set = true
 				case *varString:
-					node, nodeProcessors, ok := replacer(val.Value())
+					varNode, nodeProcessors, ok := replacer(val.Value())
 					if ok {
-						node := nodeToValue(node)
+						node = nodeToValue(varNode)
 						if nodeProcessors != nil {
 							processors = nodeProcessors
 						}
-						if r[i] == 0 && r[i+1] == len(value) {
-							// possible for complete replacement of object, because the variable
-							// is not inside of a string
-							return attachProcessors(node, processors), nil
-						}
-						result += value[lastIndex:r[0]] + node.String()
+						replacesObject = true
 						set = true
 					}
 				}
@@ -154,8 +154,21 @@ func replaceVars(value string, replacer func(variable string) (Node, Processors,
 					break
 				}
 			}
-			if !set && reqMatch {
-				return NewStrVal(""), fmt.Errorf("%w: %s", ErrNoMatch, toRepresentation(vars))
+			if len(filters) > 0 {
+				node, set, err = applyFilters(filters, node, set)
+				if err != nil {
+					return nil, fmt.Errorf(`error applying filters of variable "%s": %w`, value[r[i]:r[i+1]], err)
+				}
+			}
+			if set {
+				if replacesObject && r[i] == 0 && r[i+1] == len(value) {
+					// possible for complete replacement of object, because the variable
+					// is not inside of a string
+					return attachProcessors(node, processors), nil
+				}
+				result += value[lastIndex:r[0]] + node.String()
+			} else if reqMatch {
+				return NewStrVal(""), fmt.Errorf("%w: %s", ErrNoMatch, toRepresentation(vars, filters))
 			}
 			lastIndex = r[1]
 		}
@@ -163,7 +176,7 @@ func replaceVars(value string, replacer func(variable string) (Node, Processors,
 	return NewStrValWithProcessors(result+value[lastIndex:], processors), nil
 }
 
-func toRepresentation(vars []varI) string {
+func toRepresentation(vars []varI, filters []*filterCall) string {
 	var sb strings.Builder
 	sb.WriteString("${")
 	for i, val := range vars {
@@ -179,6 +192,10 @@ func toRepresentation(vars []varI) string {
 			}
 		}
 	}
+	for _, f := range filters {
+		sb.WriteString("|")
+		sb.WriteString(f.String())
+	}
 	sb.WriteString("}")
 	return sb.String()
 }
@@ -230,30 +247,85 @@ func (v *constString) Value() string {
 	return v.value
 }
 
-func extractVars(i string, defaultProvider string) ([]varI, error) {
+// varToken is a whitespace separated word or a quoted string of a variable.
+type varToken struct {
+	value  string
+	quoted bool
+}
+
+// extractVars parses the content of a variable substitution into its fallback chain of variables and constants,
+// followed by the filters applied to the first of them with a value. A filter is called with its quoted arguments
+// between parentheses, e.g. replace("-", "_"), the parentheses are optional for the filters without arguments once
+// a variable or a constant precedes them, e.g. ${env.REGION | lower}.
+func extractVars(i string, defaultProvider string) ([]varI, []*filterCall, error) {
 	const out = rune(0)
 
 	quote := out
-	constant := false
+	quoted := false
 	escape := false
 	is := make([]rune, 0, len(i))
+	tokens := make([]varToken, 0)
 	res := make([]varI, 0)
+	var filters []*filterCall
+
+	flushToken := func() {
+		if len(is) > 0 || quoted {
+			tokens = append(tokens, varToken{value: string(is), quoted: quoted})
+		}
+		is = is[:0] // slice to zero length; to keep allocated memory
+		quoted = false
+	}
+	flushSegment := func() error {
+		flushToken()
+		defer func() {
+			tokens = tokens[:0]
+		}()
+		if len(tokens) == 0 {
+			return nil
+		}
+
+		call, isFilter, err := parseFilterCall(tokens, len(res) > 0)
+		if err != nil {
+			return err
+		}
+		if isFilter {
+			if len(res) == 0 {
+				return fmt.Errorf("filter %s must follow a variable or a constant", call.name)
+			}
+			filters = append(filters, call)
+			return nil
+		}
+		if len(filters) > 0 {
+			return fmt.Errorf("variables and constants cannot follow a filter")
+		}
+
+		// whitespace outside of quotes is ignored
+		var sb strings.Builder
+		constant := false
+		for _, t := range tokens {
+			sb.WriteString(t.value)
+			constant = constant || t.quoted
+		}
+		if constant {
+			res = append(res, &constString{sb.String()})
+			return nil
+		}
+		if strings.HasSuffix(sb.String(), varsSeparator) {
+			return fmt.Errorf("variable cannot end with '.'")
+		}
+		res = append(res, &varString{maybeAddDefaultProvider(sb.String(), defaultProvider)})
+		return nil
+	}
+
 	for _, r := range i {
 		if r == '|' {
 			if escape {
-				return nil, fmt.Errorf(`variable pipe cannot be escaped; remove \ before |`)
+				return nil, nil, fmt.Errorf(`variable pipe cannot be escaped; remove \ before |`)
 			}
 			if quote == out {
-				if constant {
-					res = append(res, &constString{string(is)})
-				} else if len(is) > 0 {
-					if is[len(is)-1] == '.' {
-						return nil, fmt.Errorf("variable cannot end with '.'")
-					}
-					res = append(res, &varString{maybeAddDefaultProvider(string(is), defaultProvider)})
+				if err := flushSegment(); err != nil {
+					return nil, nil, err
 				}
-				is = is[:0] // slice to zero length; to keep allocated memory
-				constant = false
 			} else {
 				is = append(is, r)
 			}
@@ -262,11 +334,13 @@ func extractVars(i string, defaultProvider string) ([]varI, error) {
 		if !escape && (r == '"' || r == '\'') {
 			if quote == out {
 				// start of unescaped quote
+				flushToken()
 				quote = r
-				constant = true
+				quoted = true
 			} else if quote == r {
 				// end of unescaped quote
 				quote = out
+				flushToken()
 			} else {
 				is = append(is, r)
 			}
@@ -280,20 +354,17 @@ func extractVars(i string, defaultProvider string) ([]varI, error) {
 			}
 		} else if quote != out || !unicode.IsSpace(r) {
 			is = append(is, r)
+		} else {
+			flushToken()
 		}
 	}
 	if quote != out {
-		return nil, fmt.Errorf(`starting %s is missing ending %s`, string(quote), string(quote))
+		return nil, nil, fmt.Errorf(`starting %s is missing ending %s`, string(quote), string(quote))
 	}
-	if constant {
-		res = append(res, &constString{string(is)})
-	} else if len(is) > 0 {
-		if is[len(is)-1] == '.' {
-			return nil, fmt.Errorf("variable cannot end with '.'")
-		}
-		res = append(res, &varString{maybeAddDefaultProvider(string(is), defaultProvider)})
+	if err := flushSegment(); err != nil {
+		return nil, nil, err
 	}
-	return res, nil
+	return res, filters, nil
 }
 
 func varPrefixMatched(val string, key string) bool {
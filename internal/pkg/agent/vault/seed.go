@@ -83,3 +83,27 @@ func getOrCreateSeed(path string, readonly bool) ([]byte, int, error) {
 	}
 	return createSeedIfNotExists(path)
 }
+
+// replaceSeedFile writes content to a temporary file that replaces the seed file fp once synced, the
+// vault is left with either the previous or the new seed.
+func replaceSeedFile(fp string, content []byte) error {
+	tmp := fp + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not create seed file: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not write seed file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not sync seed file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not close seed file: %w", err)
+	}
+	return os.Rename(tmp, fp)
+}
@@ -68,3 +68,14 @@ func createSeedIfNotExists(path string) ([]byte, int, error) {
 
 	return seed, defaultSaltSizeV2, nil
 }
+
+// replaceSeed replaces the V2 seed file with seed and saltSize.
+func replaceSeed(path string, seed []byte, saltSize int) error {
+	mxSeed.Lock()
+	defer mxSeed.Unlock()
+
+	l := make([]byte, 4)
+	binary.LittleEndian.PutUint32(l, uint32(saltSize))
+
+	return replaceSeedFile(filepath.Join(path, seedFileV2), append(seed, l...))
+}
@@ -60,3 +60,11 @@ func createSeedIfNotExists(path string) ([]byte, int, error) {
 
 	return seed, saltSizeV1, nil
 }
+
+// replaceSeed replaces the v1 .seed file with seed, the salt size of v1 seeds is fixed.
+func replaceSeed(path string, seed []byte, _ int) error {
+	mxSeed.Lock()
+	defer mxSeed.Unlock()
+
+	return replaceSeedFile(filepath.Join(path, seedFile), seed)
+}
@@ -6,6 +6,7 @@ package vault
 
 import (
 	"context"
+	"fmt"
 	"runtime"
 	"time"
 )
@@ -38,3 +39,27 @@ func New(ctx context.Context, opts ...OptionFunc) (Vault, error) {
 
 	return NewFileVault(ctx, options)
 }
+
+// RotateSeed replaces the seed of the file vault and re-encrypts every entry of the vault with it.
+// The keychain vault on darwin is not derived from a seed, it is left as is.
+func RotateSeed(ctx context.Context, opts ...OptionFunc) error {
+	options, err := ApplyOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "darwin" && !options.unprivileged {
+		return nil
+	}
+
+	v, err := NewFileVault(ctx, options)
+	if err != nil {
+		return err
+	}
+	defer v.Close()
+
+	if err := v.rotateSeed(ctx); err != nil {
+		return fmt.Errorf("could not rotate the seed of the vault at %s: %w", v.path, err)
+	}
+	return nil
+}
@@ -8,20 +8,29 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/gofrs/flock"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
 )
 
+// indexKey is the key of the entry listing the keys stored in the vault, the names of the entries are
+// hashes of their key and the seed rotation needs the keys to name the entries for the new seed.
+const indexKey = ".index"
+
 type FileVault struct {
 	path     string
 	seed     []byte
 	saltSize int
+	readonly bool
 
 	lockRetryDelay time.Duration
 	lock           *flock.Flock
@@ -63,6 +72,7 @@ func NewFileVault(ctx context.Context, options Options) (v *FileVault, err error
 
 	r := &FileVault{
 		path:           path,
+		readonly:       options.readonly,
 		lockRetryDelay: options.lockRetryDelay,
 		lock:           flock.New(filepath.Join(path, lockFile)),
 	}
@@ -85,11 +95,6 @@ func NewFileVault(ctx context.Context, options Options) (v *FileVault, err error
 
 // Set stores the key in the vault store
 func (v *FileVault) Set(ctx context.Context, key string, data []byte) (err error) {
-	enc, err := v.encrypt(data)
-	if err != nil {
-		return fmt.Errorf("vault Set: could not encrypt key: %w", err)
-	}
-
 	err = v.tryLock(ctx)
 	if err != nil {
 		return fmt.Errorf("vault Set: could acquire lock: %w", err)
@@ -101,29 +106,84 @@ func (v *FileVault) Set(ctx context.Context, key string, data []byte) (err error
 		}
 	}()
 
+	err = v.reloadSeed()
+	if err != nil {
+		return fmt.Errorf("vault Set: %w", err)
+	}
+
+	enc, err := v.encrypt(data)
+	if err != nil {
+		return fmt.Errorf("vault Set: could not encrypt key: %w", err)
+	}
+
 	err = writeFile(v.filepathFromKey(key), enc)
 	if err != nil {
 		return fmt.Errorf("vaukt: could not write key to file: %w", err)
 	}
+
+	err = v.addToIndex(key)
+	if err != nil {
+		return fmt.Errorf("vault Set: %w", err)
+	}
 	return nil
 }
 
 // Get retrieves the key from the vault store
-func (v *FileVault) Get(ctx context.Context, key string) (dec []byte, err error) {
-	err = v.tryRLock(ctx)
+func (v *FileVault) Get(ctx context.Context, key string) ([]byte, error) {
+	dec, indexed, err := v.get(ctx, key)
 	if err != nil {
 		return nil, err
 	}
+	if !indexed && !v.readonly {
+		// the entries stored before the vault indexed its keys are indexed once read, best effort as
+		// the vault may not be writable by this process
+		_ = v.index(ctx, key)
+	}
+	return dec, nil
+}
+
+// get reads and decrypts the entry of key, indexed is false when the key is not in the index of the
+// vault yet.
+func (v *FileVault) get(ctx context.Context, key string) (dec []byte, indexed bool, err error) {
+	err = v.tryRLock(ctx)
+	if err != nil {
+		return nil, false, err
+	}
 	defer func() {
 		err = v.unlockAndJoinErrors(err)
 	}()
 
+	if err = v.reloadSeed(); err != nil {
+		return nil, false, err
+	}
+
 	enc, err := os.ReadFile(v.filepathFromKey(key))
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	dec, err = v.decrypt(enc)
+	if err != nil {
+		return nil, false, err
 	}
 
-	return v.decrypt(enc)
+	keys, err := v.readIndex()
+	return dec, err == nil && slices.Contains(keys, key), nil
+}
+
+// index adds key to the index of the vault.
+func (v *FileVault) index(ctx context.Context, key string) (err error) {
+	err = v.tryLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = v.unlockAndJoinErrors(err)
+	}()
+
+	if err = v.reloadSeed(); err != nil {
+		return err
+	}
+	return v.addToIndex(key)
 }
 
 // Exists checks if the key exists
@@ -136,6 +196,10 @@ func (v *FileVault) Exists(ctx context.Context, key string) (ok bool, err error)
 		err = v.unlockAndJoinErrors(err)
 	}()
 
+	if err = v.reloadSeed(); err != nil {
+		return false, err
+	}
+
 	if _, err = os.Stat(v.filepathFromKey(key)); err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			return false, nil
@@ -155,7 +219,194 @@ func (v *FileVault) Remove(ctx context.Context, key string) (err error) {
 		err = v.unlockAndJoinErrors(err)
 	}()
 
-	return os.RemoveAll(v.filepathFromKey(key))
+	if err = v.reloadSeed(); err != nil {
+		return err
+	}
+
+	if err = os.RemoveAll(v.filepathFromKey(key)); err != nil {
+		return err
+	}
+	return v.removeFromIndex(key)
+}
+
+// readIndex returns the keys of the index of the vault. It must be called with the lock held.
+func (v *FileVault) readIndex() ([]string, error) {
+	enc, err := os.ReadFile(v.filepathFromKey(indexKey))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read the index: %w", err)
+	}
+	dec, err := v.decrypt(enc)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt the index: %w", err)
+	}
+	var keys []string
+	if err := json.Unmarshal(dec, &keys); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the index: %w", err)
+	}
+	return keys, nil
+}
+
+// writeIndex replaces the index of the vault with keys. It must be called with the exclusive lock held.
+func (v *FileVault) writeIndex(keys []string) error {
+	b, err := json.Marshal(keys)
+	if err != nil {
+		return fmt.Errorf("could not marshal the index: %w", err)
+	}
+	enc, err := v.encrypt(b)
+	if err != nil {
+		return fmt.Errorf("could not encrypt the index: %w", err)
+	}
+	if err := writeFile(v.filepathFromKey(indexKey), enc); err != nil {
+		return fmt.Errorf("could not write the index: %w", err)
+	}
+	return nil
+}
+
+// addToIndex adds key to the index of the vault. It must be called with the exclusive lock held.
+func (v *FileVault) addToIndex(key string) error {
+	if key == indexKey {
+		return nil
+	}
+	keys, err := v.readIndex()
+	if err != nil || slices.Contains(keys, key) {
+		return err
+	}
+	return v.writeIndex(append(keys, key))
+}
+
+// removeFromIndex removes key from the index of the vault. It must be called with the exclusive lock
+// held.
+func (v *FileVault) removeFromIndex(key string) error {
+	keys, err := v.readIndex()
+	if err != nil || !slices.Contains(keys, key) {
+		return err
+	}
+	return v.writeIndex(slices.DeleteFunc(keys, func(k string) bool {
+		return k == key
+	}))
+}
+
+// rotateSeed replaces the seed of the vault with a new one and re-encrypts every entry of the vault
+// with it. The keys of the entries are read from the index of the vault, the rotation fails without
+// changing the vault when it holds an entry readable with the current seed whose key isn't indexed.
+// The files that cannot be decrypted with the current seed are left over by an interrupted rotation,
+// they are removed.
+//
+// The entries are written under their name for the new seed before the new seed replaces the current
+// one and the entries of the current seed are removed last, the vault is readable with either seed
+// if the rotation is interrupted.
+func (v *FileVault) rotateSeed(ctx context.Context) (err error) {
+	err = v.tryLock(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = v.unlockAndJoinErrors(err)
+	}()
+
+	if err = v.reloadSeed(); err != nil {
+		return err
+	}
+
+	keys, err := v.readIndex()
+	if err != nil {
+		return err
+	}
+	keyByName := make(map[string]string, len(keys)+1)
+	for _, key := range append(keys, indexKey) {
+		keyByName[fileNameFromKey(v.seed, key)] = key
+	}
+
+	dir, err := os.ReadDir(v.path)
+	if err != nil {
+		return fmt.Errorf("could not list the entries: %w", err)
+	}
+	entries := make(map[string][]byte, len(keyByName))
+	var leftovers []string
+	unindexed := 0
+	for _, f := range dir {
+		if f.IsDir() || !isEntryName(f.Name()) {
+			continue
+		}
+		enc, err := os.ReadFile(filepath.Join(v.path, f.Name()))
+		if err != nil {
+			return fmt.Errorf("could not read entry %s: %w", f.Name(), err)
+		}
+		dec, err := v.decrypt(enc)
+		key, indexed := keyByName[f.Name()]
+		switch {
+		case err != nil && indexed:
+			return fmt.Errorf("could not decrypt key %s: %w", key, err)
+		case err != nil:
+			leftovers = append(leftovers, f.Name())
+		case !indexed:
+			unindexed++
+		default:
+			entries[key] = dec
+		}
+	}
+	if unindexed > 0 {
+		return fmt.Errorf("the vault holds %d entries whose key is not indexed, they must be read once before the rotation", unindexed)
+	}
+
+	seed, err := aesgcm.NewKey(aesgcm.AES256)
+	if err != nil {
+		return err
+	}
+	rotated := &FileVault{path: v.path, seed: seed, saltSize: v.saltSize}
+	for key, data := range entries {
+		enc, err := rotated.encrypt(data)
+		if err != nil {
+			return fmt.Errorf("could not encrypt key %s: %w", key, err)
+		}
+		if err := writeFile(rotated.filepathFromKey(key), enc); err != nil {
+			return fmt.Errorf("could not write key %s: %w", key, err)
+		}
+	}
+
+	if err := replaceSeed(v.path, seed, v.saltSize); err != nil {
+		for key := range entries {
+			_ = os.Remove(rotated.filepathFromKey(key))
+		}
+		return fmt.Errorf("could not replace the seed: %w", err)
+	}
+
+	var errs []error
+	for key := range entries {
+		if err := os.Remove(v.filepathFromKey(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("could not remove key %s encrypted with the previous seed: %w", key, err))
+		}
+	}
+	for _, name := range leftovers {
+		if err := os.Remove(filepath.Join(v.path, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("could not remove entry %s of a previous seed: %w", name, err))
+		}
+	}
+	v.seed = seed
+	return errors.Join(errs...)
+}
+
+// isEntryName reports whether name is the name of a vault entry, see fileNameFromKey.
+func isEntryName(name string) bool {
+	if len(name) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+// reloadSeed reads the seed of the vault again, it may have been rotated by another process since the
+// vault was opened. It must be called with the lock held.
+func (v *FileVault) reloadSeed() error {
+	seed, saltSize, err := getSeed(v.path)
+	if err != nil {
+		return fmt.Errorf("could not read the seed of the vault at %s: %w", v.path, err)
+	}
+	v.seed, v.saltSize = seed, saltSize
+	return nil
 }
 
 // Close closes the vault store
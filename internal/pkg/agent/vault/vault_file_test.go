@@ -8,12 +8,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
@@ -127,6 +131,105 @@ func TestFileVault(t *testing.T) {
 	}
 }
 
+func TestFileVaultRotateSeed(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "vault does not use NewGCMWithRandomNonce.")
+	vaultPath := getTestFileVaultPath(t)
+
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+	options, err := ApplyOptions(WithVaultPath(vaultPath))
+	require.NoError(t, err)
+	v, err := NewFileVault(ctx, options)
+	require.NoError(t, err)
+	defer v.Close()
+
+	values := map[string]string{"key1": "value1", "key2": "value22", "key3": "value3"}
+	for key, value := range values {
+		require.NoError(t, v.Set(ctx, key, []byte(value)))
+	}
+	require.NoError(t, v.Set(ctx, "removed", []byte("value4")))
+	require.NoError(t, v.Remove(ctx, "removed"))
+	seed, _, err := getSeed(vaultPath)
+	require.NoError(t, err)
+
+	// an entry left over by an interrupted rotation cannot be decrypted with the current seed
+	leftover := fileNameFromKey([]byte("previous seed"), "key1")
+	require.NoError(t, os.WriteFile(filepath.Join(vaultPath, leftover), []byte("leftover"), 0600))
+
+	err = RotateSeed(ctx, WithVaultPath(vaultPath))
+	require.NoError(t, err)
+
+	rotated, _, err := getSeed(vaultPath)
+	require.NoError(t, err)
+	assert.NotEqual(t, seed, rotated)
+
+	// the vault opened before the rotation reads the new seed
+	for key, value := range values {
+		b, err := v.Get(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, value, string(b))
+	}
+	exists, err := v.Exists(ctx, "removed")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// only the entries and the index encrypted with the new seed are left
+	assert.ElementsMatch(t, []string{
+		fileNameFromKey(rotated, "key1"),
+		fileNameFromKey(rotated, "key2"),
+		fileNameFromKey(rotated, "key3"),
+		fileNameFromKey(rotated, indexKey),
+	}, vaultEntries(t, vaultPath))
+}
+
+func TestFileVaultRotateSeedUnindexed(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "vault does not use NewGCMWithRandomNonce.")
+	vaultPath := getTestFileVaultPath(t)
+
+	ctx, cn := context.WithCancel(context.Background())
+	defer cn()
+	options, err := ApplyOptions(WithVaultPath(vaultPath))
+	require.NoError(t, err)
+	v, err := NewFileVault(ctx, options)
+	require.NoError(t, err)
+	defer v.Close()
+
+	require.NoError(t, v.Set(ctx, "key1", []byte("value1")))
+	require.NoError(t, v.Set(ctx, "legacy", []byte("value2")))
+	// entries stored before the vault indexed its keys
+	require.NoError(t, os.Remove(v.filepathFromKey(indexKey)))
+	entries := vaultEntries(t, vaultPath)
+
+	err = RotateSeed(ctx, WithVaultPath(vaultPath))
+	require.Error(t, err)
+	assert.ElementsMatch(t, entries, vaultEntries(t, vaultPath), "the failed rotation changed the vault")
+
+	// the entries are indexed once read
+	for _, key := range []string{"key1", "legacy"} {
+		_, err := v.Get(ctx, key)
+		require.NoError(t, err)
+	}
+	require.NoError(t, RotateSeed(ctx, WithVaultPath(vaultPath)))
+
+	b, err := v.Get(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "value2", string(b))
+}
+
+// vaultEntries returns the names of the entries of the vault at vaultPath.
+func vaultEntries(t *testing.T, vaultPath string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(vaultPath)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
 type secret struct {
 	Value     []byte    `json:"v"` // binary value
 	CreatedOn time.Time `json:"t"` // date/time the secret was created on
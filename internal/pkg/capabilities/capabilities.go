@@ -19,13 +19,22 @@ type Capabilities interface {
 	AllowUpgrade(version string, sourceURI string) bool
 	AllowInput(name string) bool
 	AllowOutput(name string) bool
+	// AllowAction checks whether a Fleet action of the given type, e.g. REQUEST_DIAGNOSTICS, can be run.
+	AllowAction(actionType string) bool
+	// AllowComponent checks whether a component with the given binary, e.g. endpoint-security, can be run.
+	AllowComponent(binaryName string) bool
+	// AllowLogLevel checks whether a SETTINGS action can set the given log level.
+	AllowLogLevel(level string) bool
 }
 
 type capabilitiesManager struct {
-	log          *logger.Logger
-	inputChecks  []*stringMatcher
-	outputChecks []*stringMatcher
-	upgradeCaps  []*upgradeCapability
+	log             *logger.Logger
+	inputChecks     []*stringMatcher
+	outputChecks    []*stringMatcher
+	upgradeCaps     []*upgradeCapability
+	actionChecks    []*stringMatcher
+	componentChecks []*stringMatcher
+	logLevelChecks  []*stringMatcher
 }
 
 func (cm *capabilitiesManager) AllowInput(inputType string) bool {
@@ -36,6 +45,18 @@ func (cm *capabilitiesManager) AllowOutput(outputType string) bool {
 	return matchString(outputType, cm.outputChecks)
 }
 
+func (cm *capabilitiesManager) AllowAction(actionType string) bool {
+	return matchString(actionType, cm.actionChecks)
+}
+
+func (cm *capabilitiesManager) AllowComponent(binaryName string) bool {
+	return matchString(binaryName, cm.componentChecks)
+}
+
+func (cm *capabilitiesManager) AllowLogLevel(level string) bool {
+	return matchString(level, cm.logLevelChecks)
+}
+
 func (cm *capabilitiesManager) AllowUpgrade(version string, uri string) bool {
 	return allowUpgrade(cm.log, version, uri, cm.upgradeCaps)
 }
@@ -68,8 +89,11 @@ func Load(capsReader io.Reader, log *logger.Logger) (Capabilities, error) {
 	caps := spec.Capabilities
 
 	return &capabilitiesManager{
-		inputChecks:  caps.inputChecks,
-		outputChecks: caps.outputChecks,
-		upgradeCaps:  caps.upgradeChecks,
+		inputChecks:     caps.inputChecks,
+		outputChecks:    caps.outputChecks,
+		upgradeCaps:     caps.upgradeChecks,
+		actionChecks:    caps.actionChecks,
+		componentChecks: caps.componentChecks,
+		logLevelChecks:  caps.logLevelChecks,
 	}, nil
 }
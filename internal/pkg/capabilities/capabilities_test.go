@@ -101,6 +101,58 @@ capabilities:
 
 }
 
+func TestDenyActions(t *testing.T) {
+	yml := `
+capabilities:
+- rule: deny
+  action: REQUEST_DIAGNOSTICS
+- rule: deny
+  action: MIGRATE
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	assert.False(t, caps.AllowAction("REQUEST_DIAGNOSTICS"))
+	assert.False(t, caps.AllowAction("MIGRATE"))
+	assert.True(t, caps.AllowAction("POLICY_CHANGE"))
+	assert.True(t, caps.AllowAction("UPGRADE"))
+}
+
+func TestDenyComponents(t *testing.T) {
+	yml := `
+capabilities:
+- rule: deny
+  component: endpoint-security
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	assert.False(t, caps.AllowComponent("endpoint-security"))
+	assert.True(t, caps.AllowComponent("agentbeat"))
+	assert.True(t, caps.AllowInput("endpoint"))
+}
+
+func TestAllowLogLevels(t *testing.T) {
+	// Only allow Fleet to raise the log level up to info
+	yml := `
+capabilities:
+- rule: allow
+  log_level: info
+- rule: allow
+  log_level: warning
+- rule: allow
+  log_level: error
+- rule: deny
+  log_level: "*"
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	assert.True(t, caps.AllowLogLevel("info"))
+	assert.True(t, caps.AllowLogLevel("error"))
+	assert.False(t, caps.AllowLogLevel("debug"))
+}
+
 func TestNoCaps(t *testing.T) {
 	// Make sure capabilities loaded from a nonexistent file don't interfere
 	// with anything
@@ -111,4 +163,7 @@ func TestNoCaps(t *testing.T) {
 	assert.True(t, caps.AllowInput("system/metrics"))
 	assert.True(t, caps.AllowInput("system/logs"))
 	assert.True(t, caps.AllowOutput("elasticsearch"))
+	assert.True(t, caps.AllowAction("REQUEST_DIAGNOSTICS"))
+	assert.True(t, caps.AllowComponent("endpoint-security"))
+	assert.True(t, caps.AllowLogLevel("debug"))
 }
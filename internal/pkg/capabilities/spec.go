@@ -13,9 +13,12 @@ import (
 // capabilitiesList deserializes a YAML list of capabilities into organized
 // arrays based on their type, for easy use by capabilitiesManager.
 type capabilitiesList struct {
-	inputChecks   []*stringMatcher
-	outputChecks  []*stringMatcher
-	upgradeChecks []*upgradeCapability
+	inputChecks     []*stringMatcher
+	outputChecks    []*stringMatcher
+	upgradeChecks   []*upgradeCapability
+	actionChecks    []*stringMatcher
+	componentChecks []*stringMatcher
+	logLevelChecks  []*stringMatcher
 }
 
 // a type for capability values that must equal "allow" or "deny", enforced
@@ -66,6 +69,36 @@ func (r *capabilitiesList) UnmarshalYAML(unmarshal func(interface{}) error) erro
 			}
 			r.outputChecks = append(r.outputChecks,
 				&stringMatcher{pattern: spec.Output, rule: spec.Type})
+		} else if _, found = mm["action"]; found {
+			spec := struct {
+				Type   allowOrDeny `yaml:"rule"`
+				Action string      `yaml:"action"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			r.actionChecks = append(r.actionChecks,
+				&stringMatcher{pattern: spec.Action, rule: spec.Type})
+		} else if _, found = mm["component"]; found {
+			spec := struct {
+				Type      allowOrDeny `yaml:"rule"`
+				Component string      `yaml:"component"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			r.componentChecks = append(r.componentChecks,
+				&stringMatcher{pattern: spec.Component, rule: spec.Type})
+		} else if _, found = mm["log_level"]; found {
+			spec := struct {
+				Type     allowOrDeny `yaml:"rule"`
+				LogLevel string      `yaml:"log_level"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			r.logLevelChecks = append(r.logLevelChecks,
+				&stringMatcher{pattern: spec.LogLevel, rule: spec.Type})
 		} else if _, found = mm["upgrade"]; found {
 			// Serialize upgrade constraints to a temporary struct so we can
 			// safely assemble the associated EQL expression
@@ -23,6 +23,9 @@ func TestUnmarshal(t *testing.T) {
 		assert.Equal(t, 1, len(rr.Capabilities.inputChecks))
 		assert.Equal(t, 1, len(rr.Capabilities.outputChecks))
 		assert.Equal(t, 1, len(rr.Capabilities.upgradeChecks))
+		assert.Equal(t, 1, len(rr.Capabilities.actionChecks))
+		assert.Equal(t, 1, len(rr.Capabilities.componentChecks))
+		assert.Equal(t, 1, len(rr.Capabilities.logLevelChecks))
 	})
 
 	t.Run("invalid yaml", func(t *testing.T) {
@@ -44,6 +47,15 @@ var yamlDefinitionValid = []byte(`capabilities:
 -
   output: "elasticsearch"
   rule: "allow"
+-
+  action: "REQUEST_DIAGNOSTICS"
+  rule: "deny"
+-
+  component: "endpoint-security"
+  rule: "deny"
+-
+  log_level: "debug"
+  rule: "deny"
 `)
 
 var yamlDefinitionInvalid = []byte(`
@@ -5,20 +5,54 @@
 package docker
 
 import (
+	"errors"
 	"time"
 
 	"github.com/elastic/elastic-agent-autodiscover/docker"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
+const hintsInputsPathPattern = "/usr/share/elastic-agent/docker.hints.inputs.d/*.yml"
+
 // Config for docker provider
 type Config struct {
 	Host           string            `config:"host"`
 	TLS            *docker.TLSConfig `config:"ssl"`
 	CleanupTimeout time.Duration     `config:"cleanup_timeout" validate:"positive"`
+
+	Hints  Hints  `config:"hints"`
+	Prefix string `config:"prefix"`
+}
+
+// Hints config section for hints' config blocks
+type Hints struct {
+	Enabled              bool `config:"enabled"`
+	DefaultContainerLogs bool `config:"default_container_logs"`
+}
+
+// GetHintsInputConfigPath returns the path pattern of the hints templates when hints are enabled for the docker
+// provider.
+func GetHintsInputConfigPath(log *logger.Logger, agentCfg map[string]interface{}) string {
+	hintsVal, err := utils.GetNestedMap(agentCfg, "providers", "docker", "hints", "enabled")
+	if err != nil {
+		if errors.Is(err, utils.ErrKeyNotFound) {
+			return ""
+		}
+		log.Errorf("error at reading providers.docker.hints.enabled from config: %v", err)
+		return ""
+	}
+	hintsEnabled, ok := hintsVal.(bool)
+	if !ok || !hintsEnabled {
+		return ""
+	}
+	return hintsInputsPathPattern
 }
 
 // InitDefaults initializes the default values for the config.
 func (c *Config) InitDefaults() {
 	c.Host = "unix:///var/run/docker.sock"
 	c.CleanupTimeout = 60 * time.Second
+	c.Prefix = "co.elastic"
+	c.Hints.DefaultContainerLogs = true
 }
@@ -32,8 +32,9 @@ type dockerContainerData struct {
 	processors []map[string]interface{}
 }
 type dynamicProvider struct {
-	logger *logger.Logger
-	config *Config
+	logger  *logger.Logger
+	config  *Config
+	managed bool
 }
 
 // Run runs the environment context provider.
@@ -80,9 +81,15 @@ func (c *dynamicProvider) Run(comm composable.DynamicProviderComm) error {
 				delete(stoppers, data.container.ID)
 				continue
 			}
-			err = comm.AddOrUpdate(data.container.ID, ContainerPriority, data.mapping, data.processors)
-			if err != nil {
-				c.logger.Errorf("%s", err)
+			if c.config.Hints.Enabled { // This is "hints based autodiscovery flow"
+				if !c.managed {
+					c.addHintsMapping(comm, data)
+				}
+			} else { // This is the "template-based autodiscovery" flow
+				err = comm.AddOrUpdate(data.container.ID, ContainerPriority, data.mapping, data.processors)
+				if err != nil {
+					c.logger.Errorf("%s", err)
+				}
 			}
 		case event := <-stopListener.Events():
 			data, err := generateData(event)
@@ -111,7 +118,27 @@ func DynamicProviderBuilder(logger *logger.Logger, c *config.Config, managed boo
 	if err != nil {
 		return nil, errors.New(err, "failed to unpack configuration")
 	}
-	return &dynamicProvider{logger, &cfg}, nil
+	return &dynamicProvider{logger, &cfg, managed}, nil
+}
+
+// addHintsMapping adds the mapping generated from the hints in the labels of the container, nothing is added when
+// the container has no hints and the default container logs collection is disabled.
+func (c *dynamicProvider) addHintsMapping(comm composable.DynamicProviderComm, data *dockerContainerData) {
+	hintData := getHintsData(data, c.config.Prefix, c.config.Hints.DefaultContainerLogs, c.logger)
+	if len(hintData.composableMapping) == 0 {
+		return
+	}
+	processors := data.processors
+	for _, processor := range hintData.processors {
+		processors = append(processors, processor)
+	}
+	// the container metadata is kept next to the hints for the templates
+	mapping := mapstr.M(data.mapping).Clone()
+	mapping[hints] = hintData.composableMapping
+	err := comm.AddOrUpdate(data.container.ID, ContainerPriority, mapping, processors)
+	if err != nil {
+		c.logger.Errorf("%s", err)
+	}
 }
 
 func generateData(event bus.Event) (*dockerContainerData, error) {
@@ -0,0 +1,170 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/elastic/elastic-agent-autodiscover/utils"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+const (
+	hints = "hints"
+
+	integration = "package"
+	datastreams = "data_streams"
+	host        = "host"
+	period      = "period"
+	timeout     = "timeout"
+	metricspath = "metrics_path"
+	username    = "username"
+	password    = "password"
+	stream      = "stream" // this is the container stream: stdout/stderr
+	multiline   = "multiline"
+	processors  = "processors"
+)
+
+// allSupportedHints is the hint vocabulary of the kubernetes provider with the multiline settings of the container logs.
+var allSupportedHints = []string{"enabled", integration, datastreams, host, period, timeout, metricspath, username, password, stream, multiline, processors}
+
+// hintsSettings are the hints copied to the integration and to each of its data streams.
+var hintsSettings = []string{host, period, timeout, metricspath, username, password, stream}
+
+// metaVarRegex matches the variables of hints like `'${docker.container.ip}:6379'` replaced with the container metadata.
+var metaVarRegex = regexp.MustCompile(`\${([^{}]+)}`)
+
+// hintsData hold the generated mapping data needed for hints based autodiscovery
+type hintsData struct {
+	composableMapping mapstr.M
+	processors        []mapstr.M
+}
+
+// getFromMeta replaces hints like `'${docker.container.ip}:6379'` with the actual values from the container
+// metadata. An empty string is returned when a variable cannot be resolved.
+func getFromMeta(value string, meta mapstr.M, logger *logp.Logger) string {
+	if value == "" {
+		return ""
+	}
+	for _, match := range metaVarRegex.FindAllString(value, -1) {
+		key := strings.TrimSuffix(strings.TrimPrefix(match, "${docker."), "}")
+		val, err := meta.GetValue(key)
+		if err != nil {
+			logger.Debugf("cannot retrieve key from container metadata: %v", key)
+			return ""
+		}
+		hintVal, ok := val.(string)
+		if !ok {
+			logger.Debugf("cannot convert value into string: %v", val)
+			return ""
+		}
+		value = strings.ReplaceAll(value, match, hintVal)
+	}
+	return value
+}
+
+// containerLogsHints returns the hints of the container logs collection, the container stream and the multiline
+// settings are set from the hints.
+func containerLogsHints(hintsExtracted mapstr.M) mapstr.M {
+	containerLogs := mapstr.M{
+		"enabled": true,
+	}
+	if containerStream := utils.GetHintString(hintsExtracted, hints, stream); containerStream != "" {
+		containerLogs[stream] = containerStream
+	}
+	if ml := utils.GetHintMapStr(hintsExtracted, hints, multiline); len(ml) > 0 {
+		containerLogs[multiline] = ml
+	}
+	return containerLogs
+}
+
+// generateHintsMapping constructs the hints' mapping to be emitted from the hints extracted from the container
+// labels. The mapping is empty when no package is set.
+func generateHintsMapping(hintsExtracted mapstr.M, meta mapstr.M, logger *logp.Logger, containerID string) mapstr.M {
+	hintsMapping := mapstr.M{}
+	integrationName := utils.GetHintString(hintsExtracted, hints, integration)
+	if integrationName == "" {
+		return hintsMapping
+	}
+
+	integrationHints := mapstr.M{}
+	_, _ = hintsMapping.Put("container_id", containerID)
+	// Add the default container log fallback to enable any template which defines
+	// a log input with a `"${docker.hints.container_logs.enabled} == true"` condition
+	_, _ = integrationHints.Put("container_logs", containerLogsHints(hintsExtracted))
+
+	settings := mapstr.M{}
+	for _, setting := range hintsSettings {
+		if value := getFromMeta(utils.GetHintString(hintsExtracted, hints, setting), meta, logger); value != "" {
+			settings[setting] = value
+		}
+	}
+	integrationHints.DeepUpdate(settings)
+
+	dataStreams := utils.GetHintAsList(hintsExtracted, hints, datastreams)
+	if len(dataStreams) == 0 {
+		_, _ = integrationHints.Put("enabled", true)
+	}
+	for _, dataStream := range dataStreams {
+		streamHints := settings.Clone()
+		streamHints["enabled"] = true
+		for _, setting := range hintsSettings {
+			key := fmt.Sprintf("%v.%v", dataStream, setting)
+			if value := getFromMeta(utils.GetHintString(hintsExtracted, hints, key), meta, logger); value != "" {
+				streamHints[setting] = value
+			}
+		}
+		_, _ = integrationHints.Put(dataStream, streamHints)
+	}
+
+	_, _ = hintsMapping.Put(integrationName, integrationHints)
+	return hintsMapping
+}
+
+// getHintsData generates the hints and processor mappings from the labels of the container. The default host of the
+// integration is the first IP address and port of the container.
+func getHintsData(data *dockerContainerData, prefix string, defaultContainerLogs bool, logger *logp.Logger) hintsData {
+	hintData := hintsData{
+		composableMapping: mapstr.M{},
+	}
+	container := data.container
+
+	labels := mapstr.M{}
+	if containerMeta, ok := data.mapping["container"].(map[string]interface{}); ok {
+		if l, ok := containerMeta["labels"].(mapstr.M); ok {
+			labels = l
+		}
+	}
+
+	hintsExtracted, incorrectHints := utils.GenerateHints(labels, "", prefix, true, allSupportedHints)
+	for _, value := range incorrectHints {
+		logger.Warnf("provided hint: %s/%s is not in the supported list", prefix, value)
+	}
+
+	meta := mapstr.M(data.mapping).Clone()
+	if len(container.IPAddresses) > 0 {
+		_, _ = meta.Put("container.ip", container.IPAddresses[0])
+		if len(container.Ports) > 0 && len(hintsExtracted) > 0 {
+			if _, err := hintsExtracted.GetValue(hints + "." + host); err != nil {
+				_, _ = hintsExtracted.Put(hints+"."+host, fmt.Sprintf("${docker.container.ip}:%d", container.Ports[0].PrivatePort))
+			}
+		}
+	}
+
+	hintData.composableMapping = generateHintsMapping(hintsExtracted, meta, logger, container.ID)
+	if len(hintData.composableMapping) == 0 && defaultContainerLogs {
+		// in case of no package detected in the hints fallback to the generic log collection
+		_, _ = hintData.composableMapping.Put("container_logs", containerLogsHints(hintsExtracted))
+		_, _ = hintData.composableMapping.Put("container_id", container.ID)
+	}
+	if len(hintData.composableMapping) > 0 {
+		hintData.processors = utils.GetConfigs(labels, prefix, hints+"/"+processors)
+	}
+	logger.Debugf("Generated hints mappings :%v", hintData.composableMapping)
+	return hintData
+}
@@ -0,0 +1,210 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-autodiscover/bus"
+	"github.com/elastic/elastic-agent-autodiscover/docker"
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+	ctesting "github.com/elastic/elastic-agent/internal/pkg/composable/testing"
+)
+
+func TestGetHintsData(t *testing.T) {
+	logger := logp.NewLogger("docker-hints")
+
+	for _, tc := range []struct {
+		name                 string
+		container            *docker.Container
+		defaultContainerLogs bool
+		expectedMapping      mapstr.M
+		expectedProcessors   []mapstr.M
+	}{
+		{
+			name: "package with data streams",
+			container: &docker.Container{
+				ID:          "abc",
+				Name:        "redis",
+				IPAddresses: []string{"172.17.0.2"},
+				Ports:       []container.Port{{PrivatePort: 6379}},
+				Labels: map[string]string{
+					"co.elastic.hints/package":               "redis",
+					"co.elastic.hints/data_streams":          "info, key",
+					"co.elastic.hints/period":                "10s",
+					"co.elastic.hints/info.period":           "5s",
+					"co.elastic.hints/key.host":              "${docker.container.name}:6380",
+					"co.elastic.hints/multiline.pattern":     "^\\[",
+					"co.elastic.hints/multiline.negate":      "true",
+					"co.elastic.hints/processors.1.add_tags": `{"tags": ["redis"]}`,
+				},
+			},
+			expectedMapping: mapstr.M{
+				"container_id": "abc",
+				"redis": mapstr.M{
+					"container_logs": mapstr.M{
+						"enabled": true,
+						"multiline": mapstr.M{
+							"pattern": "^\\[",
+							"negate":  "true",
+						},
+					},
+					"host":   "172.17.0.2:6379",
+					"period": "10s",
+					"info": mapstr.M{
+						"enabled": true,
+						"host":    "172.17.0.2:6379",
+						"period":  "5s",
+					},
+					"key": mapstr.M{
+						"enabled": true,
+						"host":    "redis:6380",
+						"period":  "10s",
+					},
+				},
+			},
+			expectedProcessors: []mapstr.M{
+				{"add_tags": `{"tags": ["redis"]}`},
+			},
+		},
+		{
+			name: "package without data streams",
+			container: &docker.Container{
+				ID:   "abc",
+				Name: "nginx",
+				Labels: map[string]string{
+					"co.elastic.hints/package": "nginx",
+					"co.elastic.hints/host":    "nginx:80",
+				},
+			},
+			expectedMapping: mapstr.M{
+				"container_id": "abc",
+				"nginx": mapstr.M{
+					"container_logs": mapstr.M{
+						"enabled": true,
+					},
+					"enabled": true,
+					"host":    "nginx:80",
+				},
+			},
+			expectedProcessors: nil,
+		},
+		{
+			name: "default container logs",
+			container: &docker.Container{
+				ID:   "abc",
+				Name: "app",
+				Labels: map[string]string{
+					"co.elastic.hints/stream":            "stderr",
+					"co.elastic.hints/multiline.pattern": "^\\s",
+				},
+			},
+			defaultContainerLogs: true,
+			expectedMapping: mapstr.M{
+				"container_id": "abc",
+				"container_logs": mapstr.M{
+					"enabled": true,
+					"stream":  "stderr",
+					"multiline": mapstr.M{
+						"pattern": "^\\s",
+					},
+				},
+			},
+			expectedProcessors: nil,
+		},
+		{
+			name: "no hints",
+			container: &docker.Container{
+				ID:   "abc",
+				Name: "app",
+			},
+			expectedMapping:    mapstr.M{},
+			expectedProcessors: nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := generateData(bus.Event{"container": tc.container})
+			require.NoError(t, err)
+
+			hintData := getHintsData(data, "co.elastic", tc.defaultContainerLogs, logger)
+			assert.Equal(t, tc.expectedMapping, hintData.composableMapping)
+			assert.Equal(t, tc.expectedProcessors, hintData.processors)
+		})
+	}
+}
+
+func TestAddHintsMapping(t *testing.T) {
+	cfg := &Config{}
+	cfg.InitDefaults()
+	cfg.Hints.Enabled = true
+	p := &dynamicProvider{logger: logp.NewLogger("docker-hints"), config: cfg}
+
+	data, err := generateData(bus.Event{"container": &docker.Container{
+		ID:   "abc",
+		Name: "app",
+		Labels: map[string]string{
+			"co.elastic.hints/package":                         "redis",
+			"co.elastic.hints/processors.add_fields.fields.id": "1",
+		},
+	}})
+	require.NoError(t, err)
+
+	comm := ctesting.NewDynamicComm(context.Background())
+	p.addHintsMapping(comm, data)
+
+	state, ok := comm.Current("abc")
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"container_id": "abc",
+		"redis": map[string]interface{}{
+			"container_logs": map[string]interface{}{"enabled": true},
+			"enabled":        true,
+		},
+	}, state.Mapping["hints"])
+	// the container metadata is kept for the templates
+	name, err := mapstr.M(state.Mapping).GetValue("container.name")
+	require.NoError(t, err)
+	assert.Equal(t, "app", name)
+	require.Len(t, state.Processors, 2)
+	assert.Equal(t, map[string]interface{}{
+		"add_fields": map[string]interface{}{
+			"fields": map[string]interface{}{"id": "1"},
+		},
+	}, state.Processors[1])
+
+	// no mapping without hints nor default container logs
+	cfg.Hints.DefaultContainerLogs = false
+	data, err = generateData(bus.Event{"container": &docker.Container{ID: "def", Name: "other"}})
+	require.NoError(t, err)
+	p.addHintsMapping(comm, data)
+	_, ok = comm.Current("def")
+	assert.False(t, ok)
+}
+
+func TestGetHintsInputConfigPath(t *testing.T) {
+	logger := logp.NewLogger("docker-hints")
+
+	assert.Empty(t, GetHintsInputConfigPath(logger, map[string]interface{}{}))
+	assert.Empty(t, GetHintsInputConfigPath(logger, map[string]interface{}{
+		"providers": map[string]interface{}{
+			"docker": map[string]interface{}{
+				"hints": map[string]interface{}{"enabled": false},
+			},
+		},
+	}))
+	assert.Equal(t, hintsInputsPathPattern, GetHintsInputConfigPath(logger, map[string]interface{}{
+		"providers": map[string]interface{}{
+			"docker": map[string]interface{}{
+				"hints": map[string]interface{}{"enabled": true},
+			},
+		},
+	}))
+}
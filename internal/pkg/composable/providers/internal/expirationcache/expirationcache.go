@@ -0,0 +1,139 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package expirationcache provides the cache of the secrets providers, it expires the secrets that are not accessed
+// anymore.
+package expirationcache
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache is a store that expires items after time.Now - entry.LastAccess > ttl (if ttl > 0) at Get or List.
+// Cache works with *Entry, a pointer struct that wraps the value, instead of the value directly because map
+// structure in standard go library never removes the buckets from memory even after removing all the elements from it.
+// However, since *Entry is a pointer it can be garbage collected when no longer referenced by the GC, such as
+// when deleted from the map. More importantly working with a pointer makes the entry in the map bucket, that doesn't
+// get deallocated, to utilise only 8 bytes on a 64-bit system.
+type Cache[T any] struct {
+	sync.Mutex
+	// ttl is the time-to-live for items in the cache
+	ttl time.Duration
+	// items is the underlying cache store.
+	items map[string]*Entry[T]
+}
+
+// Entry is a value of the cache with the time it was last accessed.
+type Entry[T any] struct {
+	Value      T
+	LastAccess time.Time
+}
+
+// ConditionFn decides if a value is added to the cache, existing is the value in the cache if exists is true.
+type ConditionFn[T any] func(existing T, exists bool) bool
+
+// New creates and returns a Cache expiring the items not accessed for ttl, items never expire if ttl <= 0.
+func New[T any](ttl time.Duration) *Cache[T] {
+	return &Cache[T]{
+		items: make(map[string]*Entry[T]),
+		ttl:   ttl,
+	}
+}
+
+// Get returns the value associated with the given key from the store if it exists and is not expired. If updateAccess is true
+// and the value exists, essentially the expiration check is skipped and the LastAccess timestamp is updated to time.Now().
+func (c *Cache[T]) Get(key string, updateAccess bool) (T, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	var empty T
+	entry, exists := c.items[key]
+	if !exists {
+		return empty, false
+	}
+	if updateAccess {
+		entry.LastAccess = time.Now()
+	} else if c.isExpired(entry.LastAccess) {
+		delete(c.items, key)
+		return empty, false
+	}
+
+	return entry.Value, true
+}
+
+// AddConditionally adds the given value to the store if the given condition returns true. If there is no existing
+// value, the condition will be called with an empty value and false. If updateAccess is true and the value already exists,
+// then the LastAccess timestamp is updated to time.Now() independently of the condition result.
+// Note: if the given condition is nil, then it is considered as a condition that always returns false.
+func (c *Cache[T]) AddConditionally(key string, in T, updateAccess bool, condition ConditionFn[T]) {
+	c.Lock()
+	defer c.Unlock()
+	entry, exists := c.items[key]
+	if !exists {
+		var empty T
+		if condition != nil && condition(empty, false) {
+			c.items[key] = &Entry[T]{in, time.Now()}
+		}
+		return
+	}
+
+	if condition != nil && condition(entry.Value, true) {
+		entry.Value = in
+		entry.LastAccess = time.Now()
+	} else if updateAccess {
+		entry.LastAccess = time.Now()
+	}
+}
+
+// isExpired returns true if the item has expired based on the ttl
+func (c *Cache[T]) isExpired(lastAccess time.Time) bool {
+	if c.ttl <= 0 {
+		// no expiration
+		return false
+	}
+	// we expire if the last access is older than the ttl
+	return time.Since(lastAccess) > c.ttl
+}
+
+// ListKeys returns a list of all the keys of the values in the store without checking for expiration
+func (c *Cache[T]) ListKeys() []string {
+	c.Lock()
+	defer c.Unlock()
+
+	length := len(c.items)
+	if length == 0 {
+		return nil
+	}
+	list := make([]string, 0, length)
+	for key := range c.items {
+		list = append(list, key)
+	}
+	return list
+}
+
+// List returns a list of all the values in the store that are not expired
+func (c *Cache[T]) List() []T {
+	c.Lock()
+	defer c.Unlock()
+
+	length := len(c.items)
+	if length == 0 {
+		return nil
+	}
+	list := make([]T, 0, length)
+	for _, entry := range c.items {
+		if c.isExpired(entry.LastAccess) {
+			continue
+		}
+		list = append(list, entry.Value)
+	}
+	return list
+}
+
+// Entries returns the underlying entries of the store without checking for expiration. The returned map is not a
+// copy, it must not be used concurrently with the store.
+func (c *Cache[T]) Entries() map[string]*Entry[T] {
+	return c.items
+}
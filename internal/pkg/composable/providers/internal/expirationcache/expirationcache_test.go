@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package expirationcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	always := func(string, bool) bool { return true }
+	never := func(string, bool) bool { return false }
+
+	c := New[string](time.Minute)
+	c.AddConditionally("skipped", "value", true, never)
+	_, found := c.Get("skipped", false)
+	assert.False(t, found, "condition returning false must not add the value")
+
+	c.AddConditionally("key", "first", false, always)
+	c.AddConditionally("key", "second", false, func(existing string, exists bool) bool {
+		return exists && existing == "first"
+	})
+	value, found := c.Get("key", false)
+	assert.True(t, found)
+	assert.Equal(t, "second", value)
+
+	// expired entries are not listed and removed at Get
+	c.Entries()["key"].LastAccess = time.Now().Add(-time.Hour)
+	assert.Equal(t, []string{"key"}, c.ListKeys())
+	assert.Empty(t, c.List())
+	_, found = c.Get("key", false)
+	assert.False(t, found)
+	assert.Empty(t, c.ListKeys())
+
+	// updating the access skips the expiration
+	c.AddConditionally("key", "third", false, always)
+	c.Entries()["key"].LastAccess = time.Now().Add(-time.Hour)
+	value, found = c.Get("key", true)
+	assert.True(t, found)
+	assert.Equal(t, "third", value)
+	assert.Equal(t, []string{"third"}, c.List())
+
+	// no expiration without ttl
+	c = New[string](0)
+	c.AddConditionally("key", "value", false, always)
+	c.Entries()["key"].LastAccess = time.Now().Add(-time.Hour)
+	assert.Equal(t, []string{"value"}, c.List())
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/internal/expirationcache"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	corecomp "github.com/elastic/elastic-agent/internal/pkg/core/composable"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -63,7 +64,7 @@ type secret struct {
 	apiFetchTime time.Time
 }
 
-type conditionFn func(existing secret, exists bool) bool
+type conditionFn = expirationcache.ConditionFn[secret]
 
 type contextProviderK8SSecrets struct {
 	logger    *logger.Logger
@@ -96,7 +97,7 @@ func ContextProviderBuilder(logger *logger.Logger, c *config.Config, _ bool) (co
 		config:  cfg,
 		client:  nil,
 		running: make(chan struct{}),
-		store:   newExpirationCache(cfg.TTLDelete),
+		store:   expirationcache.New[secret](cfg.TTLDelete),
 	}, nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"runtime"
 	"sync"
 	"testing"
@@ -24,10 +25,13 @@ import (
 
 	"github.com/elastic/elastic-agent-autodiscover/kubernetes"
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/internal/expirationcache"
 	ctesting "github.com/elastic/elastic-agent/internal/pkg/composable/testing"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 )
 
+type cacheEntry = expirationcache.Entry[secret]
+
 func Test_Fetch(t *testing.T) {
 	testDataBuilder := secretTestDataBuilder{
 		namespace: "default",
@@ -53,7 +57,7 @@ func Test_Fetch(t *testing.T) {
 			k8sClient: k8sfake.NewClientset(
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
-			storeInit:     func(t *testing.T) store { return newExpirationCache(time.Minute) },
+			storeInit:     func(t *testing.T) store { return expirationcache.New[secret](time.Minute) },
 			keyToFetch:    "secret_name",
 			expectedValue: "",
 			expectedFound: false,
@@ -67,7 +71,7 @@ func Test_Fetch(t *testing.T) {
 			k8sClient: k8sfake.NewClientset(
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
-			storeInit:     func(t *testing.T) store { return newExpirationCache(time.Minute) },
+			storeInit:     func(t *testing.T) store { return expirationcache.New[secret](time.Minute) },
 			keyToFetch:    fmt.Sprintf("%s.default.secret_name", k8sSecretsProviderName),
 			expectedValue: "",
 			expectedFound: false,
@@ -81,7 +85,7 @@ func Test_Fetch(t *testing.T) {
 			k8sClient: k8sfake.NewClientset(
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
-			storeInit:     func(t *testing.T) store { return newExpirationCache(time.Minute) },
+			storeInit:     func(t *testing.T) store { return expirationcache.New[secret](time.Minute) },
 			keyToFetch:    fmt.Sprintf("%s.default.secret_name.wrong", k8sSecretsProviderName),
 			expectedValue: "",
 			expectedFound: false,
@@ -95,7 +99,7 @@ func Test_Fetch(t *testing.T) {
 				RequestTimeout: time.Second,
 			},
 			k8sClient:     nil,
-			storeInit:     func(t *testing.T) store { return newExpirationCache(time.Minute) },
+			storeInit:     func(t *testing.T) store { return expirationcache.New[secret](time.Minute) },
 			keyToFetch:    testDataBuilder.getFetchKey(),
 			expectedValue: "",
 			expectedFound: false,
@@ -113,7 +117,7 @@ func Test_Fetch(t *testing.T) {
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
 			keyToFetch:    testDataBuilder.getFetchKey(),
-			storeInit:     func(t *testing.T) store { return newExpirationCache(time.Minute) },
+			storeInit:     func(t *testing.T) store { return expirationcache.New[secret](time.Minute) },
 			expectedValue: "secret_value",
 			expectedFound: true,
 		},
@@ -125,7 +129,7 @@ func Test_Fetch(t *testing.T) {
 			},
 			k8sClient:     k8sfake.NewClientset(),
 			keyToFetch:    testDataBuilder.getFetchKey(),
-			storeInit:     func(t *testing.T) store { return newExpirationCache(time.Minute) },
+			storeInit:     func(t *testing.T) store { return expirationcache.New[secret](time.Minute) },
 			expectedValue: "",
 			expectedFound: false,
 		},
@@ -139,11 +143,11 @@ func Test_Fetch(t *testing.T) {
 			),
 			keyToFetch: testDataBuilder.getFetchKey(),
 			storeInit: func(t *testing.T) store {
-				s := newExpirationCache(time.Minute)
+				s := expirationcache.New[secret](time.Minute)
 				s.Lock()
-				s.items = buildCacheMap(
+				maps.Copy(s.Entries(), buildCacheMap(
 					testDataBuilder.buildCacheEntry("secret_value", true, time.Now(), time.Now()),
-				)
+				))
 				s.Unlock()
 				return s
 			},
@@ -162,7 +166,7 @@ func Test_Fetch(t *testing.T) {
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
 			keyToFetch:    testDataBuilder.getFetchKey(),
-			storeInit:     func(t *testing.T) store { return newExpirationCache(time.Minute) },
+			storeInit:     func(t *testing.T) store { return expirationcache.New[secret](time.Minute) },
 			expectedValue: "secret_value",
 			expectedFound: true,
 			expectedCache: buildCacheMap(
@@ -176,7 +180,7 @@ func Test_Fetch(t *testing.T) {
 			},
 			k8sClient:     k8sfake.NewClientset(),
 			keyToFetch:    testDataBuilder.getFetchKey(),
-			storeInit:     func(t *testing.T) store { return newExpirationCache(time.Minute) },
+			storeInit:     func(t *testing.T) store { return expirationcache.New[secret](time.Minute) },
 			expectedValue: "",
 			expectedFound: false,
 			expectedCache: buildCacheMap(
@@ -191,7 +195,7 @@ func Test_Fetch(t *testing.T) {
 			k8sClient:  k8sfake.NewClientset(),
 			keyToFetch: testDataBuilder.getFetchKey(),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				ms := newMockStore(t)
 				ms.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 					// when Fetch calls Get, we silently insert another secret with the same key
@@ -201,7 +205,7 @@ func Test_Fetch(t *testing.T) {
 					// an existing item.
 					key := args.Get(0).(string)
 					exps.Lock()
-					exps.items[key] = testDataBuilder.buildCacheEntry("value_from_cache", true, time.Now().Add(1*time.Hour), time.Now().Add(-time.Hour))
+					exps.Entries()[key] = testDataBuilder.buildCacheEntry("value_from_cache", true, time.Now().Add(1*time.Hour), time.Now().Add(-time.Hour))
 					exps.Unlock()
 				}).Return(secret{}, false).Once()
 				ms.On("AddConditionally", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
@@ -232,7 +236,7 @@ func Test_Fetch(t *testing.T) {
 			k8sClient:  k8sfake.NewClientset(),
 			keyToFetch: testDataBuilder.getFetchKey(),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				ms := newMockStore(t)
 				ms.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 					// when Fetch calls Get, we silently insert another secret with the same key
@@ -242,7 +246,7 @@ func Test_Fetch(t *testing.T) {
 					// an existing item.
 					key := args.Get(0).(string)
 					exps.Lock()
-					exps.items[key] = testDataBuilder.buildCacheEntry("secret_value", true, time.Now().Add(1*time.Hour), time.Now().Add(-time.Hour))
+					exps.Entries()[key] = testDataBuilder.buildCacheEntry("secret_value", true, time.Now().Add(1*time.Hour), time.Now().Add(-time.Hour))
 					exps.Unlock()
 				}).Return(secret{}, false).Once()
 				ms.On("AddConditionally", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
@@ -276,7 +280,7 @@ func Test_Fetch(t *testing.T) {
 			),
 			keyToFetch: testDataBuilder.getFetchKey(),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				ms := newMockStore(t)
 				ms.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 					// when Fetch calls Get, we silently insert another secret with the same key
@@ -286,7 +290,7 @@ func Test_Fetch(t *testing.T) {
 					// an existing item.
 					key := args.Get(0).(string)
 					exps.Lock()
-					exps.items[key] = testDataBuilder.buildCacheEntry("value_from_cache", true, time.Now(), time.Now().Add(-time.Hour))
+					exps.Entries()[key] = testDataBuilder.buildCacheEntry("value_from_cache", true, time.Now(), time.Now().Add(-time.Hour))
 					exps.Unlock()
 				}).Return(secret{}, false).Once()
 				ms.On("AddConditionally", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
@@ -336,12 +340,12 @@ func Test_Fetch(t *testing.T) {
 			for k, v := range tc.expectedCache {
 				inCache, exists := cacheMap[k]
 				require.True(t, exists)
-				require.Equal(t, v.s.key, inCache.key)
-				require.Equal(t, v.s.name, inCache.name)
-				require.Equal(t, v.s.namespace, inCache.namespace)
-				require.Equal(t, v.s.key, inCache.key)
-				require.Equal(t, v.s.value, inCache.value)
-				require.Equal(t, v.s.apiExists, inCache.apiExists)
+				require.Equal(t, v.Value.key, inCache.key)
+				require.Equal(t, v.Value.name, inCache.name)
+				require.Equal(t, v.Value.namespace, inCache.namespace)
+				require.Equal(t, v.Value.key, inCache.key)
+				require.Equal(t, v.Value.value, inCache.value)
+				require.Equal(t, v.Value.apiExists, inCache.apiExists)
 			}
 		})
 	}
@@ -369,11 +373,11 @@ func Test_UpdateCache(t *testing.T) {
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				exps.Lock()
-				exps.items = buildCacheMap(
+				maps.Copy(exps.Entries(), buildCacheMap(
 					testDataBuilder.buildCacheEntry("secret_value", true, time.Now(), time.Now().Add(-time.Hour)),
-				)
+				))
 				exps.Unlock()
 				return exps
 			},
@@ -386,11 +390,11 @@ func Test_UpdateCache(t *testing.T) {
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				exps.Lock()
-				exps.items = buildCacheMap(
+				maps.Copy(exps.Entries(), buildCacheMap(
 					testDataBuilder.buildCacheEntry("secret_value", true, time.Now(), time.Now()),
-				)
+				))
 				exps.Unlock()
 				return exps
 			},
@@ -406,11 +410,11 @@ func Test_UpdateCache(t *testing.T) {
 				testDataBuilder.buildK8SSecret("secret_value_new"),
 			),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				exps.Lock()
-				exps.items = buildCacheMap(
+				maps.Copy(exps.Entries(), buildCacheMap(
 					testDataBuilder.buildCacheEntry("secret_value", true, time.Now(), time.Now()),
-				)
+				))
 				exps.Unlock()
 				return exps
 			},
@@ -424,11 +428,11 @@ func Test_UpdateCache(t *testing.T) {
 			name:      "secret-change API-miss",
 			k8sClient: k8sfake.NewClientset(),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				exps.Lock()
-				exps.items = buildCacheMap(
+				maps.Copy(exps.Entries(), buildCacheMap(
 					testDataBuilder.buildCacheEntry("secret_value", true, time.Now(), time.Now()),
-				)
+				))
 				exps.Unlock()
 				return exps
 			},
@@ -444,11 +448,11 @@ func Test_UpdateCache(t *testing.T) {
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				exps.Lock()
-				exps.items = buildCacheMap(
+				maps.Copy(exps.Entries(), buildCacheMap(
 					testDataBuilder.buildCacheEntry("secret_value_old", true, time.Now(), time.Now()),
-				)
+				))
 				exps.Unlock()
 				ms := newMockStore(t)
 				getMockCall := ms.On("Get", mock.Anything, mock.Anything)
@@ -458,7 +462,7 @@ func Test_UpdateCache(t *testing.T) {
 					// when updateSecrets calls Get, we silently shift one hour back the lastAccess of an existing secret to test
 					// that the AddConditionally in updateCache works as expected and that the lastAccess is updated
 					exps.Lock()
-					exps.items[key].lastAccess = time.Now().Add(-time.Hour)
+					exps.Entries()[key].LastAccess = time.Now().Add(-time.Hour)
 					exps.Unlock()
 					getMockCall.Return(ret, exists)
 				}).Once()
@@ -493,11 +497,11 @@ func Test_UpdateCache(t *testing.T) {
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				exps.Lock()
-				exps.items = buildCacheMap(
+				maps.Copy(exps.Entries(), buildCacheMap(
 					testDataBuilder.buildCacheEntry("secret_value", true, time.Now(), time.Now()),
-				)
+				))
 				exps.Unlock()
 				ms := newMockStore(t)
 				getMockCall := ms.On("Get", mock.Anything, mock.Anything)
@@ -508,7 +512,7 @@ func Test_UpdateCache(t *testing.T) {
 					// that the AddConditionally in updateCache works as expected and that the lastAccess is not updated
 					// if there is no update
 					exps.Lock()
-					exps.items[key].lastAccess = time.Now().Add(-time.Hour)
+					exps.Entries()[key].LastAccess = time.Now().Add(-time.Hour)
 					exps.Unlock()
 					getMockCall.Return(ret, exists)
 				}).Once()
@@ -539,11 +543,11 @@ func Test_UpdateCache(t *testing.T) {
 			name:      "secret-change contention secret removed",
 			k8sClient: k8sfake.NewClientset(),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				exps.Lock()
-				exps.items = buildCacheMap(
+				maps.Copy(exps.Entries(), buildCacheMap(
 					testDataBuilder.buildCacheEntry("secret_value", true, time.Now(), time.Now()),
-				)
+				))
 				exps.Unlock()
 				ms := newMockStore(t)
 				getMockCall := ms.On("Get", mock.Anything, mock.Anything)
@@ -553,7 +557,7 @@ func Test_UpdateCache(t *testing.T) {
 					// when updateSecrets calls Get, we silently remove an existing secret to test
 					// that the AddConditionally in updateCache works as expected when the secret is removed
 					exps.Lock()
-					delete(exps.items, key)
+					delete(exps.Entries(), key)
 					exps.Unlock()
 					getMockCall.Return(ret, exists)
 				}).Once()
@@ -586,11 +590,11 @@ func Test_UpdateCache(t *testing.T) {
 				testDataBuilder.buildK8SSecret("secret_value"),
 			),
 			storeInit: func(t *testing.T) store {
-				exps := newExpirationCache(time.Minute)
+				exps := expirationcache.New[secret](time.Minute)
 				exps.Lock()
-				exps.items = buildCacheMap(
+				maps.Copy(exps.Entries(), buildCacheMap(
 					testDataBuilder.buildCacheEntry("secret_value_cached", true, time.Now(), time.Now()),
-				)
+				))
 				exps.Unlock()
 				ms := newMockStore(t)
 				getMock := ms.On("Get", mock.Anything, mock.Anything)
@@ -600,7 +604,7 @@ func Test_UpdateCache(t *testing.T) {
 					// when updateSecrets calls Get, we silently mark the existing secret to a newer fetch from API time
 					// to test that the AddConditionally in updateCache works as expected
 					exps.Lock()
-					exps.items[key].s.apiFetchTime = time.Now().Add(time.Hour)
+					exps.Entries()[key].Value.apiFetchTime = time.Now().Add(time.Hour)
 					exps.Unlock()
 					getMock.Return(ret, exists)
 				}).Once()
@@ -653,12 +657,12 @@ func Test_UpdateCache(t *testing.T) {
 			for k, v := range tc.expectedCache {
 				inCache, exists := cacheMap[k]
 				require.True(t, exists)
-				require.Equal(t, v.s.key, inCache.key)
-				require.Equal(t, v.s.name, inCache.name)
-				require.Equal(t, v.s.namespace, inCache.namespace)
-				require.Equal(t, v.s.key, inCache.key)
-				require.Equal(t, v.s.value, inCache.value)
-				require.Equal(t, v.s.apiExists, inCache.apiExists)
+				require.Equal(t, v.Value.key, inCache.key)
+				require.Equal(t, v.Value.name, inCache.name)
+				require.Equal(t, v.Value.namespace, inCache.namespace)
+				require.Equal(t, v.Value.key, inCache.key)
+				require.Equal(t, v.Value.value, inCache.value)
+				require.Equal(t, v.Value.apiExists, inCache.apiExists)
 			}
 		})
 	}
@@ -803,7 +807,7 @@ func Test_Run(t *testing.T) {
 			p, is := provider.(*contextProviderK8SSecrets)
 			require.True(t, is)
 
-			ec, is := p.store.(*expirationCache)
+			ec, is := p.store.(*expirationcache.Cache[secret])
 			require.True(t, is)
 
 			if tc.k8sClientErr != nil {
@@ -812,7 +816,7 @@ func Test_Run(t *testing.T) {
 			}
 
 			ec.Lock()
-			ec.items = tc.preCacheState
+			maps.Copy(ec.Entries(), tc.preCacheState)
 			ec.Unlock()
 
 			ctx, cancel := context.WithCancel(context.Background())
@@ -860,12 +864,12 @@ func Test_Run(t *testing.T) {
 			for k, v := range tc.postCacheState {
 				inCache, exists := cacheMap[k]
 				require.True(t, exists)
-				assert.Equal(t, v.s.key, inCache.key)
-				assert.Equal(t, v.s.name, inCache.name)
-				assert.Equal(t, v.s.namespace, inCache.namespace)
-				assert.Equal(t, v.s.key, inCache.key)
-				assert.Equal(t, v.s.value, inCache.value)
-				assert.Equal(t, v.s.apiExists, inCache.apiExists)
+				assert.Equal(t, v.Value.key, inCache.key)
+				assert.Equal(t, v.Value.name, inCache.name)
+				assert.Equal(t, v.Value.namespace, inCache.namespace)
+				assert.Equal(t, v.Value.key, inCache.key)
+				assert.Equal(t, v.Value.value, inCache.value)
+				assert.Equal(t, v.Value.apiExists, inCache.apiExists)
 			}
 		})
 	}
@@ -1029,13 +1033,13 @@ func buildSecret(namespace string, name string, key string, value string, exists
 
 func buildCacheEntry(namespace string, name string, key string, value string, exists bool, apiFetchTime time.Time, lastAccess time.Time) *cacheEntry {
 	return &cacheEntry{
-		s:          buildSecret(namespace, name, key, value, exists, apiFetchTime),
-		lastAccess: lastAccess,
+		Value:      buildSecret(namespace, name, key, value, exists, apiFetchTime),
+		LastAccess: lastAccess,
 	}
 }
 
 func buildCacheEntryKey(e *cacheEntry) string {
-	return fmt.Sprintf("%s.%s.%s.%s", k8sSecretsProviderName, e.s.namespace, e.s.name, e.s.key)
+	return fmt.Sprintf("%s.%s.%s.%s", k8sSecretsProviderName, e.Value.namespace, e.Value.name, e.Value.key)
 }
 
 func buildK8SSecret(namespace string, name string, key string, value string) *v1.Secret {
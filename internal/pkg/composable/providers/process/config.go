@@ -0,0 +1,26 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package process
+
+import (
+	"time"
+)
+
+// Config for process and systemd providers
+type Config struct {
+	// Period is the interval between two scans of the running processes.
+	Period time.Duration `config:"period" validate:"positive,nonzero"`
+	// Include limits the mappings to the processes, or units for the systemd provider, with a name matching one of
+	// the glob patterns. All processes or units are included when empty.
+	Include []string `config:"include"`
+	// IncludeArgs adds the arguments and the command line of the processes to the mappings, they are left out by
+	// default as they can hold credentials.
+	IncludeArgs bool `config:"include_args"`
+}
+
+// InitDefaults initializes the default values for the config.
+func (c *Config) InitDefaults() {
+	c.Period = 10 * time.Second
+}
@@ -0,0 +1,253 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package process
+
+import (
+	"errors"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	agenterrors "github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// ProcessPriority is the priority that process mappings are added to the provider.
+	ProcessPriority = 0
+	// UnitPriority is the priority that systemd unit mappings are added to the provider.
+	UnitPriority = 0
+)
+
+var errScanNotSupported = errors.New("scanning processes is not supported on this platform")
+
+func init() {
+	composable.Providers.MustAddDynamicProvider("process", ProcessProviderBuilder)
+	composable.Providers.MustAddDynamicProvider("systemd", SystemdProviderBuilder)
+}
+
+// processInfo is a running process.
+type processInfo struct {
+	PID        int
+	Name       string
+	Executable string
+	Args       []string
+	// Cgroup is the cgroup path of the process.
+	Cgroup string
+	// Unit is the systemd service the process belongs to, empty when the process isn't part of a service.
+	Unit string
+	// Ports are the listening ports of the process.
+	Ports []int
+}
+
+// mappingData is the mapping and processors of a process or a systemd unit.
+type mappingData struct {
+	id         string
+	priority   int
+	mapping    map[string]interface{}
+	processors []map[string]interface{}
+}
+
+type dynamicProvider struct {
+	logger *logger.Logger
+	config *Config
+	// generate returns the mappings of the running processes
+	generate func(processes []processInfo, cfg *Config) []mappingData
+	// scan returns the running processes
+	scan func() ([]processInfo, error)
+}
+
+// Run runs the dynamic provider, the running processes are scanned every period.
+func (p *dynamicProvider) Run(comm composable.DynamicProviderComm) error {
+	known := map[string]struct{}{}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-comm.Done():
+			return comm.Err()
+		case <-timer.C:
+		}
+
+		processes, err := p.scan()
+		if errors.Is(err, errScanNotSupported) {
+			// info only; return nil (do nothing)
+			p.logger.Infof("Process provider skipped: %s", err)
+			return nil
+		}
+		if err != nil {
+			p.logger.Errorf("failed to scan processes: %s", err)
+			timer.Reset(p.config.Period)
+			continue
+		}
+
+		current := map[string]struct{}{}
+		for _, data := range p.generate(processes, p.config) {
+			current[data.id] = struct{}{}
+			if err := comm.AddOrUpdate(data.id, data.priority, data.mapping, data.processors); err != nil {
+				p.logger.Errorf("%s", err)
+			}
+		}
+		for id := range known {
+			if _, ok := current[id]; !ok {
+				comm.Remove(id)
+			}
+		}
+		known = current
+		timer.Reset(p.config.Period)
+	}
+}
+
+// ProcessProviderBuilder builds the process dynamic provider, it adds a mapping for each running process.
+func ProcessProviderBuilder(logger *logger.Logger, c *config.Config, _ bool) (composable.DynamicProvider, error) {
+	return newDynamicProvider(logger, c, generateProcessData)
+}
+
+// SystemdProviderBuilder builds the systemd dynamic provider, it adds a mapping for each running systemd service.
+func SystemdProviderBuilder(logger *logger.Logger, c *config.Config, _ bool) (composable.DynamicProvider, error) {
+	return newDynamicProvider(logger, c, generateUnitData)
+}
+
+func newDynamicProvider(logger *logger.Logger, c *config.Config, generate func([]processInfo, *Config) []mappingData) (*dynamicProvider, error) {
+	var cfg Config
+	if c == nil {
+		c = config.New()
+	}
+	err := c.UnpackTo(&cfg)
+	if err != nil {
+		return nil, agenterrors.New(err, "failed to unpack configuration")
+	}
+	for _, pattern := range cfg.Include {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, agenterrors.New(err, "invalid include pattern "+pattern)
+		}
+	}
+	return &dynamicProvider{
+		logger:   logger,
+		config:   &cfg,
+		generate: generate,
+		scan: func() ([]processInfo, error) {
+			return scanProcesses("/proc")
+		},
+	}, nil
+}
+
+// included returns true when name matches one of the patterns, or when there are no patterns.
+func included(name string, include []string) bool {
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func generateProcessData(processes []processInfo, cfg *Config) []mappingData {
+	var data []mappingData
+	for _, p := range processes {
+		if !included(p.Name, cfg.Include) {
+			continue
+		}
+		mapping := map[string]interface{}{
+			"process": processMapping(p, cfg.IncludeArgs),
+		}
+		if p.Unit != "" {
+			mapping["systemd"] = map[string]interface{}{
+				"unit": p.Unit,
+			}
+		}
+		data = append(data, mappingData{
+			id:       strconv.Itoa(p.PID),
+			priority: ProcessPriority,
+			mapping:  mapping,
+		})
+	}
+	return data
+}
+
+// generateUnitData groups the processes by systemd service. The process of a service with the lowest PID is its
+// main process.
+func generateUnitData(processes []processInfo, cfg *Config) []mappingData {
+	units := map[string][]processInfo{}
+	var names []string
+	for _, p := range processes {
+		if p.Unit == "" || !included(p.Unit, cfg.Include) {
+			continue
+		}
+		if _, ok := units[p.Unit]; !ok {
+			names = append(names, p.Unit)
+		}
+		units[p.Unit] = append(units[p.Unit], p)
+	}
+	slices.Sort(names)
+
+	data := make([]mappingData, 0, len(names))
+	for _, unit := range names {
+		procs := units[unit]
+		slices.SortFunc(procs, func(a, b processInfo) int {
+			return a.PID - b.PID
+		})
+
+		// the PIDs of the service are left out, the mapping would change every time a worker process is started
+		var ports []int
+		for _, p := range procs {
+			for _, port := range p.Ports {
+				if !slices.Contains(ports, port) {
+					ports = append(ports, port)
+				}
+			}
+		}
+		slices.Sort(ports)
+
+		main := procs[0]
+		data = append(data, mappingData{
+			id:       unit,
+			priority: UnitPriority,
+			mapping: map[string]interface{}{
+				"systemd": map[string]interface{}{
+					"unit":   unit,
+					"name":   strings.TrimSuffix(unit, ".service"),
+					"cgroup": main.Cgroup,
+					"ports":  ports,
+				},
+				"process": processMapping(main, cfg.IncludeArgs),
+			},
+			processors: []map[string]interface{}{
+				{
+					"add_fields": map[string]interface{}{
+						"fields": map[string]interface{}{
+							"unit": unit,
+						},
+						"target": "systemd",
+					},
+				},
+			},
+		})
+	}
+	return data
+}
+
+// processMapping returns the mapping of a process, its arguments are only added when includeArgs is true.
+func processMapping(p processInfo, includeArgs bool) map[string]interface{} {
+	mapping := map[string]interface{}{
+		"pid":        p.PID,
+		"name":       p.Name,
+		"executable": p.Executable,
+		"cgroup":     p.Cgroup,
+		"ports":      p.Ports,
+	}
+	if includeArgs {
+		mapping["args"] = p.Args
+		mapping["cmdline"] = strings.Join(p.Args, " ")
+	}
+	return mapping
+}
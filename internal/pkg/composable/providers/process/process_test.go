@@ -0,0 +1,177 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package process
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	ctesting "github.com/elastic/elastic-agent/internal/pkg/composable/testing"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+var testProcesses = []processInfo{
+	{
+		PID:        120,
+		Name:       "nginx",
+		Executable: "/usr/sbin/nginx",
+		Args:       []string{"nginx: master process", "/usr/sbin/nginx", "-g", "daemon on;"},
+		Cgroup:     "/system.slice/nginx.service",
+		Unit:       "nginx.service",
+		Ports:      []int{80, 443},
+	},
+	{
+		PID:        121,
+		Name:       "nginx",
+		Executable: "/usr/sbin/nginx",
+		Args:       []string{"nginx: worker process"},
+		Cgroup:     "/system.slice/nginx.service",
+		Unit:       "nginx.service",
+		Ports:      []int{80, 8080},
+	},
+	{
+		PID:        300,
+		Name:       "postgres",
+		Executable: "/usr/lib/postgresql/16/bin/postgres",
+		Args:       []string{"/usr/lib/postgresql/16/bin/postgres", "-D", "/var/lib/postgresql/16/main"},
+		Cgroup:     "/system.slice/system-postgresql.slice/postgresql@16-main.service",
+		Unit:       "postgresql@16-main.service",
+		Ports:      []int{5432},
+	},
+	{
+		PID:    4242,
+		Name:   "bash",
+		Args:   []string{"-bash"},
+		Cgroup: "/user.slice/user-1000.slice/session-1.scope",
+	},
+}
+
+func TestGenerateProcessData(t *testing.T) {
+	data := generateProcessData(testProcesses, &Config{IncludeArgs: true})
+	require.Len(t, data, 4)
+
+	assert.Equal(t, mappingData{
+		id:       "120",
+		priority: ProcessPriority,
+		mapping: map[string]interface{}{
+			"process": map[string]interface{}{
+				"pid":        120,
+				"name":       "nginx",
+				"executable": "/usr/sbin/nginx",
+				"args":       []string{"nginx: master process", "/usr/sbin/nginx", "-g", "daemon on;"},
+				"cmdline":    "nginx: master process /usr/sbin/nginx -g daemon on;",
+				"cgroup":     "/system.slice/nginx.service",
+				"ports":      []int{80, 443},
+			},
+			"systemd": map[string]interface{}{
+				"unit": "nginx.service",
+			},
+		},
+	}, data[0])
+	assert.NotContains(t, data[3].mapping, "systemd", "process outside of a service")
+
+	data = generateProcessData(testProcesses, &Config{Include: []string{"post*", "bash"}})
+	require.Len(t, data, 2)
+	assert.Equal(t, "300", data[0].id)
+	assert.Equal(t, "4242", data[1].id)
+	assert.NotContains(t, data[0].mapping["process"], "args", "arguments are only added when enabled")
+	assert.NotContains(t, data[0].mapping["process"], "cmdline", "arguments are only added when enabled")
+}
+
+func TestGenerateUnitData(t *testing.T) {
+	data := generateUnitData(testProcesses, &Config{})
+	require.Len(t, data, 2)
+
+	assert.Equal(t, mappingData{
+		id:       "nginx.service",
+		priority: UnitPriority,
+		mapping: map[string]interface{}{
+			"systemd": map[string]interface{}{
+				"unit":   "nginx.service",
+				"name":   "nginx",
+				"cgroup": "/system.slice/nginx.service",
+				"ports":  []int{80, 443, 8080},
+			},
+			"process": processMapping(testProcesses[0], false),
+		},
+		processors: []map[string]interface{}{
+			{
+				"add_fields": map[string]interface{}{
+					"fields": map[string]interface{}{
+						"unit": "nginx.service",
+					},
+					"target": "systemd",
+				},
+			},
+		},
+	}, data[0])
+	assert.Equal(t, "postgresql@16-main.service", data[1].id)
+
+	data = generateUnitData(testProcesses, &Config{Include: []string{"postgresql@*"}})
+	require.Len(t, data, 1)
+	assert.Equal(t, "postgresql@16-main.service", data[0].id)
+}
+
+func TestDynamicProvider_Run(t *testing.T) {
+	c, err := config.NewConfigFrom(map[string]interface{}{
+		"period": 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	provider, err := SystemdProviderBuilder(logp.NewLogger("test_process"), c, false)
+	require.NoError(t, err)
+	p, ok := provider.(*dynamicProvider)
+	require.True(t, ok)
+
+	var mx sync.Mutex
+	processes := testProcesses
+	p.scan = func() ([]processInfo, error) {
+		mx.Lock()
+		defer mx.Unlock()
+		return processes, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	comm := ctesting.NewDynamicComm(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Run(comm)
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(comm.CurrentIDs()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// postgresql stopped
+	mx.Lock()
+	processes = testProcesses[:2]
+	mx.Unlock()
+	require.Eventually(t, func() bool {
+		return comm.Deleted("postgresql@16-main.service")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"nginx.service"}, comm.CurrentIDs())
+
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+}
+
+func TestDynamicProvider_Config(t *testing.T) {
+	c, err := config.NewConfigFrom(map[string]interface{}{
+		"include": []string{"[nginx"},
+	})
+	require.NoError(t, err)
+	_, err = ProcessProviderBuilder(logp.NewLogger("test_process"), c, false)
+	assert.Error(t, err)
+
+	provider, err := ProcessProviderBuilder(logp.NewLogger("test_process"), nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, provider.(*dynamicProvider).config.Period)
+}
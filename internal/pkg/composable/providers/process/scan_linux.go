@@ -0,0 +1,179 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// tcpListen is the state of a listening socket in /proc/net/tcp
+	tcpListen = "0A"
+	// udpUnconnected is the state of a bound and not connected socket in /proc/net/udp
+	udpUnconnected = "07"
+)
+
+// scanProcesses returns the user space processes running on the host read from the procfs mounted at procRoot.
+func scanProcesses(procRoot string) ([]processInfo, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", procRoot, err)
+	}
+
+	// listening sockets are only known for the network namespace of the agent
+	sockets := listeningSockets(procRoot)
+
+	var processes []processInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		p, ok := readProcess(procRoot, pid, sockets)
+		if !ok {
+			continue
+		}
+		processes = append(processes, p)
+	}
+	slices.SortFunc(processes, func(a, b processInfo) int {
+		return a.PID - b.PID
+	})
+	return processes, nil
+}
+
+// readProcess reads the process pid, false is returned for kernel threads and processes that exited.
+func readProcess(procRoot string, pid int, sockets map[string]int) (processInfo, bool) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil || len(cmdline) == 0 {
+		// exited or kernel thread
+		return processInfo{}, false
+	}
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return processInfo{}, false
+	}
+
+	p := processInfo{
+		PID:  pid,
+		Name: strings.TrimSpace(string(comm)),
+		Args: strings.Split(string(bytes.TrimRight(cmdline, "\x00")), "\x00"),
+	}
+	// the executable and the sockets of processes of other users can only be read with privileges
+	if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+		p.Executable = exe
+	}
+	if cgroup, err := os.ReadFile(filepath.Join(dir, "cgroup")); err == nil {
+		p.Cgroup = parseCgroup(string(cgroup))
+		p.Unit = unitFromCgroup(p.Cgroup)
+	}
+	p.Ports = processPorts(dir, sockets)
+	return p, true
+}
+
+// parseCgroup returns the cgroup path of the unified hierarchy, or of the systemd hierarchy on cgroup v1 hosts.
+func parseCgroup(content string) string {
+	var first string
+	for _, line := range strings.Split(content, "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return parts[2]
+		}
+		if parts[1] == "name=systemd" {
+			return parts[2]
+		}
+		if first == "" {
+			first = parts[2]
+		}
+	}
+	return first
+}
+
+// unitFromCgroup returns the innermost systemd service of the cgroup path.
+func unitFromCgroup(cgroup string) string {
+	elems := strings.Split(cgroup, "/")
+	for i := len(elems) - 1; i >= 0; i-- {
+		if strings.HasSuffix(elems[i], ".service") {
+			return elems[i]
+		}
+	}
+	return ""
+}
+
+// listeningSockets returns the ports of the listening TCP sockets and bound UDP sockets by socket inode.
+func listeningSockets(procRoot string) map[string]int {
+	sockets := map[string]int{}
+	for file, state := range map[string]string{
+		"tcp":  tcpListen,
+		"tcp6": tcpListen,
+		"udp":  udpUnconnected,
+		"udp6": udpUnconnected,
+	} {
+		f, err := os.Open(filepath.Join(procRoot, "net", file))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		// skip header
+		scanner.Scan()
+		for scanner.Scan() {
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != state {
+				continue
+			}
+			idx := strings.LastIndex(fields[1], ":")
+			if idx < 0 {
+				continue
+			}
+			port, err := strconv.ParseUint(fields[1][idx+1:], 16, 16)
+			if err != nil {
+				continue
+			}
+			sockets[fields[9]] = int(port)
+		}
+		_ = f.Close()
+	}
+	return sockets
+}
+
+// processPorts returns the sorted listening ports of the sockets opened by the process.
+func processPorts(dir string, sockets map[string]int) []int {
+	if len(sockets) == 0 {
+		return nil
+	}
+	fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return nil
+	}
+
+	var ports []int
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		port, ok := sockets[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")]
+		if ok && !slices.Contains(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	slices.Sort(ports)
+	return ports
+}
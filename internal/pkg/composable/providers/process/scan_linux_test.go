@@ -0,0 +1,112 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProc writes the files of a fake process in the procfs at root.
+func writeProc(t *testing.T, root string, pid int, comm, cmdline, cgroup string, sockets ...string) {
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fd"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup"), []byte(cgroup), 0o600))
+	for i, socket := range sockets {
+		require.NoError(t, os.Symlink("socket:["+socket+"]", filepath.Join(dir, "fd", strconv.Itoa(i+3))))
+	}
+}
+
+func TestScanProcesses(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "net"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "net", "tcp"), []byte(
+		`  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1538 00000000:0000 0A 00000000:00000000 00:00000000 00000000   113        0 3001 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:D431 0100007F:1538 01 00000000:00000000 00:00000000 00000000   113        0 3002 1 0000000000000000 100 0 0 10 0
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "net", "tcp6"), []byte(
+		`  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:01BB 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "net", "udp"), []byte(
+		`   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 00000000:1FBD 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 4001 2 0000000000000000 0
+`), 0o600))
+
+	writeProc(t, root, 120, "nginx", "nginx: master process /usr/sbin/nginx\x00-g\x00daemon on;\x00",
+		"0::/system.slice/nginx.service\n", "1001", "1002", "9999")
+	writeProc(t, root, 300, "postgres", "/usr/lib/postgresql/16/bin/postgres\x00-D\x00/var/lib/postgresql\x00",
+		"12:memory:/system.slice/postgresql@16-main.service\n1:name=systemd:/system.slice/system-postgresql.slice/postgresql@16-main.service\n", "3001", "3002")
+	writeProc(t, root, 500, "statsd", "statsd\x00",
+		"0::/user.slice/user-1000.slice/user@1000.service/app.slice/statsd.service\n", "4001")
+	writeProc(t, root, 4242, "bash", "-bash\x00", "0::/user.slice/user-1000.slice/session-1.scope\n")
+	// kernel thread
+	writeProc(t, root, 2, "kthreadd", "", "0::/\n")
+	// not a process
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sys"), 0o750))
+
+	processes, err := scanProcesses(root)
+	require.NoError(t, err)
+	assert.Equal(t, []processInfo{
+		{
+			PID:    120,
+			Name:   "nginx",
+			Args:   []string{"nginx: master process /usr/sbin/nginx", "-g", "daemon on;"},
+			Cgroup: "/system.slice/nginx.service",
+			Unit:   "nginx.service",
+			Ports:  []int{80, 443},
+		},
+		{
+			PID:    300,
+			Name:   "postgres",
+			Args:   []string{"/usr/lib/postgresql/16/bin/postgres", "-D", "/var/lib/postgresql"},
+			Cgroup: "/system.slice/system-postgresql.slice/postgresql@16-main.service",
+			Unit:   "postgresql@16-main.service",
+			Ports:  []int{5432},
+		},
+		{
+			PID:    500,
+			Name:   "statsd",
+			Args:   []string{"statsd"},
+			Cgroup: "/user.slice/user-1000.slice/user@1000.service/app.slice/statsd.service",
+			Unit:   "statsd.service",
+			Ports:  []int{8125},
+		},
+		{
+			PID:    4242,
+			Name:   "bash",
+			Args:   []string{"-bash"},
+			Cgroup: "/user.slice/user-1000.slice/session-1.scope",
+		},
+	}, processes)
+}
+
+func TestScanProcesses_Self(t *testing.T) {
+	processes, err := scanProcesses("/proc")
+	require.NoError(t, err)
+
+	var self *processInfo
+	for i := range processes {
+		if processes[i].PID == os.Getpid() {
+			self = &processes[i]
+		}
+	}
+	require.NotNil(t, self, "running test process not found")
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	assert.Equal(t, exe, self.Executable)
+	assert.Equal(t, os.Args, self.Args)
+}
@@ -0,0 +1,59 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secrets
+
+import (
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+)
+
+// Config for secrets provider
+type Config struct {
+	Files FilesConfig `config:"files"`
+	Vault VaultConfig `config:"vault"`
+
+	RefreshInterval time.Duration `config:"cache_refresh_interval" validate:"positive,nonzero"`
+	TTLDelete       time.Duration `config:"cache_ttl"`
+	RequestTimeout  time.Duration `config:"cache_request_timeout" validate:"positive,nonzero"`
+	DisableCache    bool          `config:"cache_disable"`
+}
+
+// FilesConfig for the files backend, each file of the directory is a secret named after the file, like systemd
+// credentials or Docker secrets. The backend is disabled when Path is empty.
+type FilesConfig struct {
+	Path    string `config:"path"`
+	MaxSize int    `config:"max_size" validate:"positive,nonzero"`
+}
+
+// VaultConfig for the vault backend reading secrets from a key/value store compatible with the HashiCorp Vault KV
+// version 2 API. The backend is disabled when Address is empty.
+type VaultConfig struct {
+	Address string `config:"address"`
+	Mount   string `config:"mount"`
+	// Token authenticates the requests, it is read from TokenFile at each request when TokenFile is set.
+	Token     string `config:"token"`
+	TokenFile string `config:"token_file"`
+	Namespace string `config:"namespace"`
+
+	Transport httpcommon.HTTPTransportSettings `config:",inline" yaml:",inline"`
+}
+
+// defaultConfig returns default configuration for secrets provider
+func defaultConfig() *Config {
+	return &Config{
+		Files: FilesConfig{
+			MaxSize: 64 * 1024,
+		},
+		Vault: VaultConfig{
+			Mount:     "secret",
+			Transport: httpcommon.DefaultHTTPTransportSettings(),
+		},
+		RefreshInterval: 60 * time.Second,
+		TTLDelete:       1 * time.Hour,
+		RequestTimeout:  5 * time.Second,
+		DisableCache:    false,
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const filesBackendName = "files"
+
+// filesBackend reads secrets from the files of a directory.
+type filesBackend struct {
+	dir     string
+	maxSize int
+}
+
+func newFilesBackend(cfg FilesConfig) *filesBackend {
+	return &filesBackend{dir: cfg.Path, maxSize: cfg.MaxSize}
+}
+
+// Get returns the content of the file at path relative to the secrets directory.
+func (b *filesBackend) Get(_ context.Context, path string) (string, error) {
+	path = filepath.FromSlash(path)
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("path %q is outside of the secrets directory", path)
+	}
+
+	f, err := os.Open(filepath.Join(b.dir, path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", errSecretNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("path %q is a directory", path)
+	}
+
+	// read one byte over the limit to detect files that are too large
+	content, err := io.ReadAll(io.LimitReader(f, int64(b.maxSize)+1))
+	if err != nil {
+		return "", err
+	}
+	if len(content) > b.maxSize {
+		return "", fmt.Errorf("secret is larger than the maximum size of %d bytes", b.maxSize)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,258 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	agenterrors "github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/internal/expirationcache"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	corecomp "github.com/elastic/elastic-agent/internal/pkg/core/composable"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+var _ corecomp.FetchContextProvider = (*contextProviderSecrets)(nil)
+
+const secretsProviderName = "secrets"
+
+// errSecretNotFound is returned by a backend when the secret does not exist.
+var errSecretNotFound = errors.New("secret not found")
+
+func init() {
+	composable.Providers.MustAddContextProvider(secretsProviderName, ContextProviderBuilder)
+}
+
+// backend reads secrets from a secret store.
+type backend interface {
+	// Get returns the value of the secret at path, errSecretNotFound is returned when it does not exist.
+	Get(ctx context.Context, path string) (string, error)
+}
+
+// secret represents the data of a secret that is stored in the cache
+type secret struct {
+	// backend is the name of the backend holding the secret, and it derives from key
+	backend string
+	// path is the path of the secret in the backend, and it derives from key
+	path string
+	// value is the value of the secret
+	value string
+	// apiExists is true if the secret was fetched from the backend with no errors
+	apiExists bool
+	// apiFetchTime is the time the secret was fetched from the backend
+	apiFetchTime time.Time
+}
+
+type contextProviderSecrets struct {
+	logger   *logger.Logger
+	config   *Config
+	backends map[string]backend
+	store    *expirationcache.Cache[secret]
+}
+
+// ContextProviderBuilder builds the secrets context provider. It resolves variables of the format
+// secrets.<backend>.<path> from the configured backends:
+//
+//   - files: ${secrets.files.<file name>} is the content of the file in the configured directory.
+//   - vault: ${secrets.vault.<secret path>.<key>} is the key of the secret in a HashiCorp Vault KV v2 engine.
+//
+// Like the kubernetes_secrets provider, this provider employs a cache refreshing the secrets referenced during each
+// Fetch call every Config.RefreshInterval and expiring the references after Config.TTLDelete. When a secret value
+// rotates or a reference expires the provider calls the ContextProviderComm.Signal() to notify the agent. The cache
+// mechanism can be disabled by setting Config.DisableCache to true.
+func ContextProviderBuilder(logger *logger.Logger, c *config.Config, _ bool) (corecomp.ContextProvider, error) {
+	cfg := defaultConfig()
+
+	if c == nil {
+		c = config.New()
+	}
+
+	err := c.UnpackTo(cfg)
+	if err != nil {
+		return nil, agenterrors.New(err, "failed to unpack configuration")
+	}
+
+	backends := map[string]backend{}
+	if cfg.Files.Path != "" {
+		backends[filesBackendName] = newFilesBackend(cfg.Files)
+	}
+	if cfg.Vault.Address != "" {
+		vault, err := newVaultBackend(logger, cfg.Vault)
+		if err != nil {
+			return nil, agenterrors.New(err, "failed to create vault backend")
+		}
+		backends[vaultBackendName] = vault
+	}
+
+	return &contextProviderSecrets{
+		logger:   logger,
+		config:   cfg,
+		backends: backends,
+		store:    expirationcache.New[secret](cfg.TTLDelete),
+	}, nil
+}
+
+// Run runs the secrets context provider.
+func (p *contextProviderSecrets) Run(ctx context.Context, comm corecomp.ContextProviderComm) error {
+	if !p.config.DisableCache {
+		go p.refreshCache(ctx, comm)
+	}
+
+	<-comm.Done()
+	return comm.Err()
+}
+
+// Fetch returns the secret value for the given key
+func (p *contextProviderSecrets) Fetch(key string) (string, bool) {
+	// Make sure the key has the expected format "secrets.backend.path"
+	tokens := strings.SplitN(key, ".", 3)
+	if len(tokens) > 0 && tokens[0] != secretsProviderName {
+		return "", false
+	}
+	if len(tokens) != 3 || tokens[2] == "" {
+		p.logger.Warnf(`Invalid secret key format: %q. Secrets should be of the format secrets.backend.path`, key)
+		return "", false
+	}
+
+	ctx := context.Background()
+
+	backendName := tokens[1]
+	path := tokens[2]
+
+	if p.config.DisableCache {
+		// cache disabled - fetch secret from the backend
+		return p.fetchFromBackend(ctx, backendName, path)
+	}
+
+	// cache enabled
+	sd, exists := p.store.Get(key, true)
+	if exists {
+		// cache hit
+		return sd.value, sd.apiExists
+	}
+
+	// cache miss - fetch secret from the backend
+	apiSecretValue, apiExists := p.fetchFromBackend(ctx, backendName, path)
+	now := time.Now()
+	sd = secret{
+		backend:      backendName,
+		path:         path,
+		value:        apiSecretValue,
+		apiExists:    apiExists,
+		apiFetchTime: now,
+	}
+	p.store.AddConditionally(key, sd, true, func(existing secret, exists bool) bool {
+		if !exists {
+			// no existing secret in the cache thus add it
+			p.logger.Infof(`Fetch: %q inserted`, key)
+			return true
+		}
+		if existing.value != apiSecretValue && !existing.apiFetchTime.After(now) {
+			// there is an existing secret in the cache but its value has changed since the last time
+			// it was fetched from the backend thus update it
+			p.logger.Infof(`Fetch: %q updated`, key)
+			return true
+		}
+		// there is an existing secret in the cache, and it points already to the latest value
+		// thus do not update it and derive the value and apiExists from the existing secret
+		apiSecretValue = existing.value
+		apiExists = existing.apiExists
+		return false
+	})
+	return apiSecretValue, apiExists
+}
+
+// refreshCache refreshes the secrets in the cache every p.config.RefreshInterval
+func (p *contextProviderSecrets) refreshCache(ctx context.Context, comm corecomp.ContextProviderComm) {
+	timer := time.NewTimer(p.config.RefreshInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			p.logger.Debug("Cache: refresh started")
+			hasUpdate := p.updateSecrets(ctx)
+			if hasUpdate {
+				p.logger.Info("Cache: refresh ended with updates, agent will be notified")
+				comm.Signal()
+			} else {
+				p.logger.Debug("Cache: refresh ended without updates")
+			}
+			timer.Reset(p.config.RefreshInterval)
+		}
+	}
+}
+
+// updateSecrets causes all the non-expired secrets to be re-fetched from their backends and returns true if
+// any of the secrets has an updated value or has expired
+func (p *contextProviderSecrets) updateSecrets(ctx context.Context) bool {
+	// Keep track whether the cache had updates
+	hasUpdates := false
+
+	secretKeys := p.store.ListKeys()
+	for _, key := range secretKeys {
+		sd, exists := p.store.Get(key, false)
+		if !exists {
+			// this item has expired thus mark that the cache has updates and continue
+			p.logger.Infof(`Cache: %q expired`, key)
+			hasUpdates = true
+			continue
+		}
+
+		apiSecretValue, apiExists := p.fetchFromBackend(ctx, sd.backend, sd.path)
+		now := time.Now()
+		sd = secret{
+			backend:      sd.backend,
+			path:         sd.path,
+			value:        apiSecretValue,
+			apiExists:    apiExists,
+			apiFetchTime: now,
+		}
+
+		p.store.AddConditionally(key, sd, false, func(existing secret, exists bool) bool {
+			if !exists {
+				// no existing secret which means it has been removed until we fetched it
+				// from the backend. In this case we do not want to update the cache, but we
+				// mark that the cache has updates
+				hasUpdates = true
+				return false
+			}
+			if (existing.value != apiSecretValue || existing.apiExists != apiExists) && !existing.apiFetchTime.After(now) {
+				// the secret value has rotated and the above fetchFromBackend is more recent thus
+				// add it and mark that the cache has updates
+				hasUpdates = true
+				p.logger.Infof(`Cache: %q updated`, key)
+				return true
+			}
+			// the secret value has not changed
+			return false
+		})
+	}
+
+	return hasUpdates
+}
+
+// fetchFromBackend fetches the secret value at path from the named backend
+func (p *contextProviderSecrets) fetchFromBackend(ctx context.Context, backendName string, path string) (string, bool) {
+	b, ok := p.backends[backendName]
+	if !ok {
+		p.logger.Warnf(`Could not retrieve secret %q because backend %q is not configured`, path, backendName)
+		return "", false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.RequestTimeout)
+	defer cancel()
+
+	value, err := b.Get(ctx, path)
+	if err != nil {
+		p.logger.Warnf(`Could not retrieve secret %q from backend %q: %s`, path, backendName, err.Error())
+		return "", false
+	}
+	return value, true
+}
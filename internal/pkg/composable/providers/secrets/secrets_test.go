@@ -0,0 +1,327 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	ctesting "github.com/elastic/elastic-agent/internal/pkg/composable/testing"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+// fakeVault serves the KV version 2 read secret endpoint from data, keyed by secret path.
+type fakeVault struct {
+	mu    sync.Mutex
+	token string
+	data  map[string]map[string]interface{}
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+	secret, ok := v.data[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")]
+	if !ok || !strings.HasPrefix(r.URL.Path, "/v1/secret/data/") {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{
+			"data":     secret,
+			"metadata": map[string]interface{}{"version": 1},
+		},
+	})
+}
+
+func (v *fakeVault) set(path, key string, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.data[path] == nil {
+		v.data[path] = map[string]interface{}{}
+	}
+	v.data[path][key] = value
+}
+
+func newTestProvider(t *testing.T, cfg map[string]interface{}) *contextProviderSecrets {
+	t.Helper()
+	c, err := config.NewConfigFrom(cfg)
+	require.NoError(t, err)
+	provider, err := ContextProviderBuilder(logp.NewLogger("test_secrets"), c, true)
+	require.NoError(t, err)
+	p, ok := provider.(*contextProviderSecrets)
+	require.True(t, ok)
+	return p
+}
+
+func Test_Fetch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db.password"), []byte("changeme"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "token"), []byte("nested-token"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large"), []byte(strings.Repeat("a", 32)), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(dir), "outside"), []byte("outside"), 0o600))
+
+	vault := &fakeVault{token: "root", data: map[string]map[string]interface{}{}}
+	vault.set("myapp/db", "password", "vault-password")
+	vault.set("myapp/db", "port", 5432)
+	srv := httptest.NewServer(vault)
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("root\n"), 0o600))
+
+	for _, tc := range []struct {
+		name          string
+		cfg           map[string]interface{}
+		key           string
+		expectedValue string
+		expectedFound bool
+	}{
+		{
+			name:          "file",
+			key:           "secrets.files.db.password",
+			expectedValue: "changeme",
+			expectedFound: true,
+		},
+		{
+			name:          "nested file",
+			key:           "secrets.files.nested/token",
+			expectedValue: "nested-token",
+			expectedFound: true,
+		},
+		{
+			name: "missing file",
+			key:  "secrets.files.missing",
+		},
+		{
+			name: "file outside of the directory",
+			key:  "secrets.files.../outside",
+		},
+		{
+			name: "directory",
+			key:  "secrets.files.nested",
+		},
+		{
+			name: "file too large",
+			cfg:  map[string]interface{}{"files.max_size": 16},
+			key:  "secrets.files.large",
+		},
+		{
+			name:          "vault",
+			key:           "secrets.vault.myapp/db.password",
+			expectedValue: "vault-password",
+			expectedFound: true,
+		},
+		{
+			name:          "vault not a string",
+			key:           "secrets.vault.myapp/db.port",
+			expectedValue: "5432",
+			expectedFound: true,
+		},
+		{
+			name:          "vault token file",
+			cfg:           map[string]interface{}{"vault.token": "", "vault.token_file": tokenFile},
+			key:           "secrets.vault.myapp/db.password",
+			expectedValue: "vault-password",
+			expectedFound: true,
+		},
+		{
+			name: "vault missing key",
+			key:  "secrets.vault.myapp/db.user",
+		},
+		{
+			name: "vault missing secret",
+			key:  "secrets.vault.myapp/other.password",
+		},
+		{
+			name: "vault no key",
+			key:  "secrets.vault.myapp/db",
+		},
+		{
+			name: "vault permission denied",
+			cfg:  map[string]interface{}{"vault.token": "wrong"},
+			key:  "secrets.vault.myapp/db.password",
+		},
+		{
+			name: "unknown backend",
+			key:  "secrets.unknown.db.password",
+		},
+		{
+			name: "backend not configured",
+			cfg:  map[string]interface{}{"vault.address": ""},
+			key:  "secrets.vault.myapp/db.password",
+		},
+		{
+			name: "invalid format",
+			key:  "secrets.files",
+		},
+		{
+			name: "other provider",
+			key:  "kubernetes_secrets.default.name.key",
+		},
+	} {
+		for _, disableCache := range []bool{false, true} {
+			name := tc.name
+			if disableCache {
+				name += " without cache"
+			}
+			t.Run(name, func(t *testing.T) {
+				cfg := map[string]interface{}{
+					"files.path":    dir,
+					"vault.address": srv.URL,
+					"vault.token":   "root",
+					"cache_disable": disableCache,
+				}
+				for k, v := range tc.cfg {
+					cfg[k] = v
+				}
+				p := newTestProvider(t, cfg)
+
+				value, found := p.Fetch(tc.key)
+				assert.Equal(t, tc.expectedFound, found)
+				assert.Equal(t, tc.expectedValue, value)
+
+				keys := p.store.ListKeys()
+				if disableCache || !strings.HasPrefix(tc.key, secretsProviderName+".") || strings.Count(tc.key, ".") < 2 {
+					assert.Empty(t, keys)
+				} else {
+					assert.Equal(t, []string{tc.key}, keys)
+				}
+			})
+		}
+	}
+}
+
+func Test_UpdateCache(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(secretFile, []byte("first"), 0o600))
+
+	vault := &fakeVault{token: "root", data: map[string]map[string]interface{}{}}
+	vault.set("myapp", "password", "first")
+	srv := httptest.NewServer(vault)
+	defer srv.Close()
+
+	p := newTestProvider(t, map[string]interface{}{
+		"files.path":    dir,
+		"vault.address": srv.URL,
+		"vault.token":   "root",
+		"cache_ttl":     time.Hour,
+	})
+
+	value, found := p.Fetch("secrets.files.password")
+	require.True(t, found)
+	assert.Equal(t, "first", value)
+	value, found = p.Fetch("secrets.vault.myapp.password")
+	require.True(t, found)
+	assert.Equal(t, "first", value)
+
+	assert.False(t, p.updateSecrets(context.Background()), "no secret rotated")
+
+	// rotate the secrets
+	require.NoError(t, os.WriteFile(secretFile, []byte("second"), 0o600))
+	vault.set("myapp", "password", "second")
+	assert.True(t, p.updateSecrets(context.Background()), "secrets rotated")
+
+	value, found = p.Fetch("secrets.files.password")
+	require.True(t, found)
+	assert.Equal(t, "second", value)
+	value, found = p.Fetch("secrets.vault.myapp.password")
+	require.True(t, found)
+	assert.Equal(t, "second", value)
+
+	// remove a secret
+	require.NoError(t, os.Remove(secretFile))
+	assert.True(t, p.updateSecrets(context.Background()), "secret removed")
+	_, found = p.Fetch("secrets.files.password")
+	assert.False(t, found)
+
+	// expire the references
+	for _, entry := range p.store.Entries() {
+		entry.LastAccess = time.Now().Add(-2 * time.Hour)
+	}
+	assert.True(t, p.updateSecrets(context.Background()), "secrets expired")
+	assert.Empty(t, p.store.ListKeys())
+}
+
+func Test_Run(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(secretFile, []byte("first"), 0o600))
+
+	p := newTestProvider(t, map[string]interface{}{
+		"files.path":             dir,
+		"cache_refresh_interval": 10 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	comm := ctesting.NewContextComm(ctx)
+	signal := make(chan struct{}, 10)
+	comm.CallOnSignal(func() {
+		select {
+		case <-comm.Done():
+		case signal <- struct{}{}:
+		}
+	})
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = p.Run(ctx, comm)
+	}()
+
+	value, found := p.Fetch("secrets.files.password")
+	require.True(t, found)
+	assert.Equal(t, "first", value)
+
+	require.NoError(t, os.WriteFile(secretFile, []byte("second"), 0o600))
+	select {
+	case <-signal:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent was not notified of the rotated secret")
+	}
+
+	value, found = p.Fetch("secrets.files.password")
+	require.True(t, found)
+	assert.Equal(t, "second", value)
+
+	cancel()
+	wg.Wait()
+}
+
+func Test_Config(t *testing.T) {
+	p := newTestProvider(t, map[string]interface{}{})
+	assert.Empty(t, p.backends, "backends are disabled by default")
+	assert.Equal(t, 64*1024, p.config.Files.MaxSize)
+	assert.Equal(t, "secret", p.config.Vault.Mount)
+
+	c, err := config.NewConfigFrom(map[string]interface{}{"cache_refresh_interval": 0})
+	require.NoError(t, err)
+	_, err = ContextProviderBuilder(logp.NewLogger("test_secrets"), c, true)
+	assert.Error(t, err)
+}
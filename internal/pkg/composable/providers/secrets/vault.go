@@ -0,0 +1,128 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const vaultBackendName = "vault"
+
+// vaultBackend reads secrets from a key/value store compatible with the HashiCorp Vault KV version 2 API.
+type vaultBackend struct {
+	client    *http.Client
+	address   string
+	mount     string
+	token     string
+	tokenFile string
+	namespace string
+}
+
+// vaultKVResponse is the response of the read secret version endpoint of the KV version 2 API.
+type vaultKVResponse struct {
+	Data struct {
+		Data map[string]interface{} `json:"data"`
+	} `json:"data"`
+}
+
+func newVaultBackend(log *logger.Logger, cfg VaultConfig) (*vaultBackend, error) {
+	if _, err := url.Parse(cfg.Address); err != nil {
+		return nil, fmt.Errorf("invalid vault address %q: %w", cfg.Address, err)
+	}
+	client, err := cfg.Transport.Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+	if cfg.Token == "" && cfg.TokenFile == "" {
+		log.Warn("No token configured for the vault backend of the secrets provider, requests are not authenticated")
+	}
+	return &vaultBackend{
+		client:    client,
+		address:   strings.TrimSuffix(cfg.Address, "/"),
+		mount:     strings.Trim(cfg.Mount, "/"),
+		token:     cfg.Token,
+		tokenFile: cfg.TokenFile,
+		namespace: cfg.Namespace,
+	}, nil
+}
+
+// Get returns the value of a key of a secret, path is the path of the secret followed by the key separated with a
+// '.': myapp/db.password. Values that are not strings are returned encoded in JSON.
+func (b *vaultBackend) Get(ctx context.Context, path string) (string, error) {
+	idx := strings.LastIndex(path, ".")
+	if idx <= 0 || idx == len(path)-1 {
+		return "", fmt.Errorf("path %q must be of the format secret_path.key", path)
+	}
+	secretPath, key := strings.Trim(path[:idx], "/"), path[idx+1:]
+
+	token, err := b.readToken()
+	if err != nil {
+		return "", err
+	}
+
+	reqURL := fmt.Sprintf("%s/v1/%s/data/%s", b.address, b.mount, secretPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if b.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", b.namespace)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", errSecretNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to read secret: status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var kv vaultKVResponse
+	if err := json.NewDecoder(resp.Body).Decode(&kv); err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+	value, ok := kv.Data.Data[key]
+	if !ok || value == nil {
+		return "", errSecretNotFound
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode value of key %q: %w", key, err)
+	}
+	return string(encoded), nil
+}
+
+// readToken returns the token of the requests, the token file is read at each request so rotated tokens are used.
+func (b *vaultBackend) readToken() (string, error) {
+	if b.tokenFile == "" {
+		return b.token, nil
+	}
+	content, err := os.ReadFile(b.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
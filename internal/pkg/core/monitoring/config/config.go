@@ -184,6 +184,9 @@ func defaultLimit() Limit {
 type Diagnostics struct {
 	Uploader Uploader `config:"uploader"`
 	Limit    Limit    `config:"limit"`
+	// RedactionPolicy is the path of the redaction policy applied to the bundles
+	// collected by the action handler.
+	RedactionPolicy string `config:"redaction_policy"`
 }
 
 func defaultDiagnostics() Diagnostics {
@@ -5,6 +5,7 @@
 package crypto
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
@@ -45,6 +46,14 @@ var DefaultOptions = &Option{
 // can be used to change how the decryption work in future version.
 var versionMagicHeader = []byte("v2")
 
+// keyVersionMagicHeader is the format version of the data encrypted with a versioned key, the version
+// of the key follows the magic header as a little endian uint32. Data encrypted with the
+// versionMagicHeader format has the key version 0.
+var keyVersionMagicHeader = []byte("v3")
+
+// keyVersionLength is the length of the key version in the header.
+const keyVersionLength = 4
+
 // Writer is an io.Writer implementation that will encrypt any data that it need to write, before
 // writing any data to the wrapped writer it will lazy write an header with the necessary information
 // to be able to decrypt the data.
@@ -53,6 +62,8 @@ type Writer struct {
 	password  []byte
 	writer    io.Writer
 	generator bytesGen
+	// keyVersion is recorded in the header when set.
+	keyVersion uint32
 
 	// internal
 	wroteHeader bool
@@ -94,6 +105,18 @@ func NewWriterWithDefaults(writer io.Writer, password []byte) (*Writer, error) {
 	return NewWriter(writer, password, DefaultOptions)
 }
 
+// NewWriterWithKeyVersion returns a new encrypted Writer recording the version of the password in
+// the header, the version can be read back with PeekKeyVersion to select the password to decrypt
+// the data with.
+func NewWriterWithKeyVersion(writer io.Writer, password []byte, keyVersion uint32, option *Option) (*Writer, error) {
+	w, err := NewWriter(writer, password, option)
+	if err != nil {
+		return nil, err
+	}
+	w.keyVersion = keyVersion
+	return w, nil
+}
+
 // Write takes a byte slice and encrypt to the destination writer, it will return any errors when
 // generating the header information or when we try to encode the data.
 func (w *Writer) Write(b []byte) (int, error) {
@@ -131,9 +154,15 @@ func (w *Writer) Write(b []byte) (int, error) {
 		w.gcm = aesgcm
 
 		// Write headers
-		// VERSION|SALT|IV|PAYLOAD
+		// VERSION|SALT|IV|PAYLOAD or VERSION|KEY VERSION|SALT|IV|PAYLOAD
 		header := new(bytes.Buffer)
-		header.Write(versionMagicHeader)
+		if w.keyVersion > 0 {
+			header.Write(keyVersionMagicHeader)
+			//nolint:errcheck // writing to a bytes.Buffer does not fail.
+			binary.Write(header, binary.LittleEndian, w.keyVersion)
+		} else {
+			header.Write(versionMagicHeader)
+		}
 		header.Write(w.salt)
 
 		n, err := w.writer.Write(header.Bytes())
@@ -231,19 +260,31 @@ func (r *Reader) Read(b []byte) (int, error) {
 	if !r.readHeader {
 		r.readHeader = true
 		vLen := len(versionMagicHeader)
-		buf := make([]byte, vLen+r.option.SaltLength)
-		n, err := io.ReadAtLeast(r.reader, buf, len(buf))
+		v := make([]byte, vLen)
+		n, err := io.ReadAtLeast(r.reader, v, vLen)
 		if err != nil {
 			r.err = fmt.Errorf("fail to read encoding header: %w", err)
 			return n, err
 		}
 
-		v := buf[0:vLen]
-		if !bytes.Equal(versionMagicHeader, v) {
+		switch {
+		case bytes.Equal(versionMagicHeader, v):
+		case bytes.Equal(keyVersionMagicHeader, v):
+			// the key version is only used to select the password, skip it.
+			if _, err := io.ReadFull(r.reader, make([]byte, keyVersionLength)); err != nil {
+				r.err = fmt.Errorf("fail to read encoding header: %w", err)
+				return 0, err
+			}
+		default:
 			return 0, fmt.Errorf("unknown version %s (%+v)", string(v), v)
 		}
 
-		salt := buf[vLen : vLen+r.option.SaltLength]
+		salt := make([]byte, r.option.SaltLength)
+		n, err = io.ReadAtLeast(r.reader, salt, len(salt))
+		if err != nil {
+			r.err = fmt.Errorf("fail to read encoding header: %w", err)
+			return n, err
+		}
 
 		// Stretch the user provided key.
 		passwordBytes, err := stretchPassword(
@@ -346,6 +387,29 @@ func (r *Reader) Close() error {
 	return nil
 }
 
+// PeekKeyVersion returns the version of the key the data of the reader was encrypted with, without
+// consuming the header. Data written without a key version has the version 0.
+func PeekKeyVersion(r *bufio.Reader) (uint32, error) {
+	vLen := len(versionMagicHeader)
+	v, err := r.Peek(vLen)
+	if err != nil {
+		return 0, fmt.Errorf("fail to read encoding header: %w", err)
+	}
+
+	switch {
+	case bytes.Equal(versionMagicHeader, v):
+		return 0, nil
+	case bytes.Equal(keyVersionMagicHeader, v):
+		header, err := r.Peek(vLen + keyVersionLength)
+		if err != nil {
+			return 0, fmt.Errorf("fail to read encoding header: %w", err)
+		}
+		return binary.LittleEndian.Uint32(header[vLen:]), nil
+	default:
+		return 0, fmt.Errorf("unknown version %s (%+v)", string(v), v)
+	}
+}
+
 func randomBytes(length int) ([]byte, error) {
 	r := make([]byte, length)
 	_, err := rand.Read(r)
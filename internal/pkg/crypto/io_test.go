@@ -195,4 +195,36 @@ func TestIO(t *testing.T) {
 
 		require.Equal(t, expected, content)
 	})
+
+	t.Run("records the key version in the header", func(t *testing.T) {
+		passwd := bytes.Repeat([]byte("hello"), 10)
+		msg := []byte("bonjour la famille")
+
+		for _, keyVersion := range []uint32{0, 1, 42} {
+			dest := new(bytes.Buffer)
+
+			w, err := NewWriterWithKeyVersion(dest, passwd, keyVersion, DefaultOptions)
+			require.NoError(t, err)
+
+			_, err = w.Write(msg)
+			require.NoError(t, err)
+
+			br := bufio.NewReader(dest)
+			v, err := PeekKeyVersion(br)
+			require.NoError(t, err)
+			require.Equal(t, keyVersion, v)
+
+			r, err := NewReaderWithDefaults(br, passwd)
+			require.NoError(t, err)
+
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, msg, content)
+		}
+	})
+
+	t.Run("peek the key version of an unknown format", func(t *testing.T) {
+		_, err := PeekKeyVersion(bufio.NewReader(bytes.NewBufferString("v9 not encrypted")))
+		require.Error(t, err)
+	})
 }
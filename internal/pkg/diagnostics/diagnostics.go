@@ -172,6 +172,8 @@ func CreateCPUProfile(ctx context.Context, period time.Duration) ([]byte, error)
 }
 
 // ZipArchive creates a zipped diagnostics bundle using the passed writer with the passed diagnostics and local logs.
+// When a redaction policy is passed it is applied to every file of the bundle, logs included, and a manifest
+// of what was redacted is written as RedactionManifestFilename.
 // If any error is encountered when writing the contents of the archive it is returned.
 func ZipArchive(
 	errOut,
@@ -180,13 +182,18 @@ func ZipArchive(
 	agentDiag []client.DiagnosticFileResult,
 	unitDiags []client.DiagnosticUnitResult,
 	compDiags []client.DiagnosticComponentResult,
-	excludeEvents bool) error {
+	excludeEvents bool,
+	policy *RedactionPolicy) error {
 
 	ts := time.Now().UTC()
 	zw := zip.NewWriter(w)
 	defer zw.Close()
+	r := newRedactor(errOut, policy)
 	// Write agent diagnostics content
 	for _, ad := range agentDiag {
+		if !r.includeResult(ad.Filename, ad) {
+			continue
+		}
 		zf, err := zw.CreateHeader(&zip.FileHeader{
 			Name:     ad.Filename,
 			Method:   zip.Deflate,
@@ -195,7 +202,7 @@ func ZipArchive(
 		if err != nil {
 			return fmt.Errorf("error creating header for agent diagnostics: %w", err)
 		}
-		err = writeRedacted(errOut, zf, ad.Filename, ad)
+		err = r.writeResult(zf, ad.Filename, ad)
 		if err != nil {
 			return fmt.Errorf("error writing file for agent diagnostics: %w", err)
 		}
@@ -239,7 +246,7 @@ func ZipArchive(
 		if comp, ok := componentResults[dirName]; ok {
 			// check for component-level errors
 			if comp.Err != nil {
-				err = writeErrorResult(zw, fmt.Sprintf("components/%s/error.txt", dirName), comp.Err.Error())
+				err = writeErrorResult(zw, r, fmt.Sprintf("components/%s/error.txt", dirName), comp.Err.Error())
 				if err != nil {
 					return fmt.Errorf("error while writing error result for component %s: %w", comp.ComponentID, err)
 				}
@@ -247,6 +254,9 @@ func ZipArchive(
 				for _, res := range comp.Results {
 
 					filePath := fmt.Sprintf("components/%s/%s", dirName, res.Filename)
+					if !r.includeResult(filePath, res) {
+						continue
+					}
 					resFileWriter, err := zw.CreateHeader(&zip.FileHeader{
 						Name:     filePath,
 						Method:   zip.Deflate,
@@ -255,7 +265,7 @@ func ZipArchive(
 					if err != nil {
 						return fmt.Errorf("error creating .zip header for %s: %w", res.Filename, err)
 					}
-					err = writeRedacted(errOut, resFileWriter, filePath, res)
+					err = r.writeResult(resFileWriter, filePath, res)
 					if err != nil {
 						return fmt.Errorf("error writing %s in zip file: %w", res.Filename, err)
 					}
@@ -276,7 +286,7 @@ func ZipArchive(
 			}
 			// check for unit-level errors
 			if ud.Err != nil {
-				err = writeErrorResult(zw, fmt.Sprintf("components/%s/%s/error.txt", dirName, unitDir), ud.Err.Error())
+				err = writeErrorResult(zw, r, fmt.Sprintf("components/%s/%s/error.txt", dirName, unitDir), ud.Err.Error())
 				if err != nil {
 					return fmt.Errorf("error while writing error result for unit %s: %w", ud.UnitID, err)
 				}
@@ -284,6 +294,9 @@ func ZipArchive(
 			}
 			for _, fr := range ud.Results {
 				filePath := fmt.Sprintf("components/%s/%s/%s", dirName, unitDir, fr.Filename)
+				if !r.includeResult(filePath, fr) {
+					continue
+				}
 				w, err := zw.CreateHeader(&zip.FileHeader{
 					Name:     filePath,
 					Method:   zip.Deflate,
@@ -292,7 +305,7 @@ func ZipArchive(
 				if err != nil {
 					return err
 				}
-				err = writeRedacted(errOut, w, filePath, fr)
+				err = r.writeResult(w, filePath, fr)
 				if err != nil {
 					return err
 				}
@@ -301,10 +314,28 @@ func ZipArchive(
 	}
 
 	// Gather Logs:
-	return zipLogs(zw, ts, topPath, excludeEvents)
+	if err := zipLogs(zw, ts, topPath, excludeEvents, r); err != nil {
+		return err
+	}
+
+	if policy == nil {
+		return nil
+	}
+	mw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     RedactionManifestFilename,
+		Method:   zip.Deflate,
+		Modified: ts,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating .zip header for %s: %w", RedactionManifestFilename, err)
+	}
+	return r.writeManifest(mw)
 }
 
-func writeErrorResult(zw *zip.Writer, path string, errBody string) error {
+func writeErrorResult(zw *zip.Writer, r *redactor, path string, errBody string) error {
+	if !r.include(path) {
+		return nil
+	}
 	ts := time.Now().UTC()
 	w, err := zw.CreateHeader(&zip.FileHeader{
 		Name:     path,
@@ -314,7 +345,7 @@ func writeErrorResult(zw *zip.Writer, path string, errBody string) error {
 	if err != nil {
 		return fmt.Errorf("error writing header for error.txt file for component: %w", err)
 	}
-	_, err = fmt.Fprintf(w, "%s\n", errBody)
+	err = r.copyRedacted(path, w, strings.NewReader(errBody+"\n"))
 	if err != nil {
 		return fmt.Errorf("error writing error.txt file for component: %w", err)
 	}
@@ -323,33 +354,7 @@ func writeErrorResult(zw *zip.Writer, path string, errBody string) error {
 }
 
 func writeRedacted(errOut, resultWriter io.Writer, fullFilePath string, fileResult client.DiagnosticFileResult) error {
-	out := &fileResult.Content
-
-	// Should we support json too?
-	if fileResult.ContentType == "application/yaml" {
-		var unmarshalled any
-		err := yaml.Unmarshal(fileResult.Content, &unmarshalled)
-		if err != nil {
-			// Best effort, output a warning but still include the file
-			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to unmarshalling error: %s\n", fullFilePath, err)
-		} else {
-			switch t := unmarshalled.(type) { // could be a plain string, we only redact if this is a proper map
-			case map[string]any:
-				t = Redact(t, errOut)
-				redacted, err := yaml.Marshal(t)
-				if err != nil {
-					// Best effort, output a warning but still include the file
-					fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to marshalling error: %s\n", fullFilePath, err)
-				} else {
-					out = &redacted
-				}
-			default:
-			}
-		}
-	}
-
-	_, err := resultWriter.Write(*out)
-	return err
+	return newRedactor(errOut, nil).writeResult(resultWriter, fullFilePath, fileResult)
 }
 
 // redactMap sensitive values from the underlying map
@@ -357,6 +362,12 @@ func writeRedacted(errOut, resultWriter io.Writer, fullFilePath string, fileResu
 // we have no way of guaranteeing we'll get a "normal" map[string]interface{},
 // since the diagnostic interface is a bit of a free-for-all
 func redactMap[K comparable](errOut io.Writer, inputMap map[K]interface{}) map[K]interface{} {
+	return redactMapWith(errOut, inputMap, redactKey, nil)
+}
+
+// redactMapWith redacts the string values of the keys matched by shouldRedact,
+// onRedact, if set, is called with every redacted key.
+func redactMapWith[K comparable](errOut io.Writer, inputMap map[K]interface{}, shouldRedact func(string) bool, onRedact func(string)) map[K]interface{} {
 	if inputMap == nil {
 		return nil
 	}
@@ -364,15 +375,18 @@ func redactMap[K comparable](errOut io.Writer, inputMap map[K]interface{}) map[K
 		if rootValue != nil {
 			switch cast := rootValue.(type) {
 			case map[string]interface{}:
-				rootValue = redactMap(errOut, cast)
+				rootValue = redactMapWith(errOut, cast, shouldRedact, onRedact)
 			case map[interface{}]interface{}:
-				rootValue = redactMap(errOut, cast)
+				rootValue = redactMapWith(errOut, cast, shouldRedact, onRedact)
 			case map[int]interface{}:
-				rootValue = redactMap(errOut, cast)
+				rootValue = redactMapWith(errOut, cast, shouldRedact, onRedact)
 			case string:
 				if keyString, ok := any(rootKey).(string); ok {
-					if redactKey(keyString) {
+					if shouldRedact(keyString) {
 						rootValue = REDACTED
+						if onRedact != nil {
+							onRedact(keyString)
+						}
 					}
 				}
 			default:
@@ -407,14 +421,14 @@ func redactKey(k string) bool {
 		strings.Contains(k, "secret")
 }
 
-func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool) error {
+func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool, r *redactor) error {
 	homePath := paths.HomeFrom(topPath)
 	dataPath := paths.DataFrom(topPath)
 	currentDir := filepath.Base(homePath)
 	if !paths.IsVersionHome() {
 		// running in a container with custom top path set
 		// logs are directly under top path
-		return zipLogsWithPath(homePath, currentDir, true, excludeEvents, zw, ts)
+		return zipLogsWithPath(homePath, currentDir, true, excludeEvents, zw, ts, r)
 	}
 
 	dataDir, err := os.Open(dataPath)
@@ -435,7 +449,7 @@ func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool) e
 		}
 		collectServices := dir == currentDir
 		path := filepath.Join(dataPath, dir)
-		if err := zipLogsWithPath(path, dir, collectServices, excludeEvents, zw, ts); err != nil {
+		if err := zipLogsWithPath(path, dir, collectServices, excludeEvents, zw, ts, r); err != nil {
 			return err
 		}
 	}
@@ -444,7 +458,7 @@ func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool) e
 }
 
 // zipLogs walks paths.Logs() and copies the file structure into zw in "logs/"
-func zipLogsWithPath(pathsHome, commitName string, collectServices, excludeEvents bool, zw *zip.Writer, ts time.Time) error {
+func zipLogsWithPath(pathsHome, commitName string, collectServices, excludeEvents bool, zw *zip.Writer, ts time.Time, r *redactor) error {
 	_, err := zw.CreateHeader(&zip.FileHeader{
 		Name:     "logs/",
 		Method:   zip.Deflate,
@@ -455,7 +469,7 @@ func zipLogsWithPath(pathsHome, commitName string, collectServices, excludeEvent
 	}
 
 	if collectServices {
-		if err := collectServiceComponentsLogs(zw); err != nil {
+		if err := collectServiceComponentsLogs(zw, r); err != nil {
 			return fmt.Errorf("failed to collect endpoint-security logs: %w", err)
 		}
 	}
@@ -510,14 +524,14 @@ func zipLogsWithPath(pathsHome, commitName string, collectServices, excludeEvent
 
 		// Add the file to the zip.
 		// Ignore files that don't exist to account for races with log rotation.
-		if err := saveLogs(name, path, zw); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		if err := saveLogs(name, path, zw, r); err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
 		return nil
 	})
 }
 
-func collectServiceComponentsLogs(zw *zip.Writer) error {
+func collectServiceComponentsLogs(zw *zip.Writer, r *redactor) error {
 	platform, err := component.LoadPlatformDetail()
 	if err != nil {
 		return fmt.Errorf("failed to gather system information: %w", err)
@@ -551,7 +565,7 @@ func collectServiceComponentsLogs(zw *zip.Writer) error {
 				return nil
 			}
 
-			return saveLogs("services/"+name, path, zw)
+			return saveLogs("services/"+name, path, zw, r)
 		})
 		if err != nil {
 			return err
@@ -560,7 +574,11 @@ func collectServiceComponentsLogs(zw *zip.Writer) error {
 	return nil
 }
 
-func saveLogs(name string, logPath string, zw *zip.Writer) error {
+func saveLogs(name string, logPath string, zw *zip.Writer, r *redactor) error {
+	zipPath := "logs/" + filepath.ToSlash(name)
+	if !r.include(zipPath) {
+		return nil
+	}
 	ts := time.Now().UTC()
 	lf, err := os.Open(logPath)
 	if err != nil {
@@ -571,14 +589,14 @@ func saveLogs(name string, logPath string, zw *zip.Writer) error {
 		ts = li.ModTime()
 	}
 	zf, err := zw.CreateHeader(&zip.FileHeader{
-		Name:     "logs/" + filepath.ToSlash(name),
+		Name:     zipPath,
 		Method:   zip.Deflate,
 		Modified: ts,
 	})
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(zf, lf)
+	err = r.copyRedacted(zipPath, zf, lf)
 	if err != nil {
 		return err
 	}
@@ -400,7 +400,7 @@ func zipLogsAndAssertFiles(t *testing.T, topPath string, excludeEvents bool, exp
 	// Zip the logs directory.
 	buf := new(bytes.Buffer)
 	w := zip.NewWriter(buf)
-	require.NoError(t, zipLogs(w, time.Now(), topPath, excludeEvents))
+	require.NoError(t, zipLogs(w, time.Now(), topPath, excludeEvents, newRedactor(io.Discard, nil)))
 	require.NoError(t, w.Close())
 
 	// Read back the contents.
@@ -0,0 +1,502 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagnostics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+// RedactionManifestFilename is the name of the file, at the root of the diagnostics
+// archive, listing what was redacted when a redaction policy is used.
+const RedactionManifestFilename = "redaction-manifest.yaml"
+
+// builtinValuePatterns are the value patterns that can be referenced by name only
+// in a redaction policy.
+var builtinValuePatterns = map[string]struct {
+	pattern  string
+	validate func(string) bool
+}{
+	"ipv4": {
+		pattern: `\b\d{1,3}(?:\.\d{1,3}){3}\b`,
+		validate: func(s string) bool {
+			return net.ParseIP(s) != nil
+		},
+	},
+	"ipv6": {
+		pattern: `(?i)[0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}(?:%[0-9a-z]+)?`,
+		validate: func(s string) bool {
+			// "::" alone is a valid address but too common in text to be meaningful
+			if len(s) <= 2 {
+				return false
+			}
+			s, _, _ = strings.Cut(s, "%")
+			return net.ParseIP(s) != nil
+		},
+	},
+	"email": {
+		pattern: `[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`,
+	},
+	"hostname": {
+		pattern:  `\b(?:[a-zA-Z0-9](?:[a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]{2,63}\b`,
+		validate: isHostname,
+	},
+}
+
+// hostnameTLDs are the top-level domains a hostname with only two labels must end
+// with. Country codes that are also common file extensions, like .md or .sh, are left out.
+var hostnameTLDs = map[string]struct{}{
+	"com": {}, "net": {}, "org": {}, "edu": {}, "gov": {}, "mil": {}, "int": {}, "info": {}, "biz": {},
+	"io": {}, "co": {}, "dev": {}, "app": {}, "cloud": {}, "tech": {}, "online": {},
+	"local": {}, "localdomain": {}, "internal": {}, "lan": {}, "corp": {}, "home": {}, "arpa": {},
+	"us": {}, "uk": {}, "ca": {}, "de": {}, "fr": {}, "nl": {}, "be": {}, "ch": {}, "at": {}, "es": {},
+	"it": {}, "se": {}, "no": {}, "dk": {}, "fi": {}, "ie": {}, "eu": {}, "jp": {}, "cn": {}, "kr": {},
+	"au": {}, "nz": {}, "br": {}, "mx": {}, "ar": {}, "za": {}, "ru": {},
+}
+
+// fileExtensions are the extensions of files commonly named in the diagnostics, a
+// name ending with one of them is not a hostname.
+var fileExtensions = map[string]struct{}{
+	"yaml": {}, "yml": {}, "json": {}, "ndjson": {}, "log": {}, "txt": {}, "enc": {}, "go": {}, "gz": {},
+	"zip": {}, "tar": {}, "tgz": {}, "pprof": {}, "pem": {}, "crt": {}, "key": {}, "sock": {}, "exe": {},
+	"dll": {}, "so": {}, "bak": {}, "lock": {}, "tmp": {}, "old": {}, "pid": {}, "conf": {}, "cfg": {},
+	"xml": {}, "sh": {}, "ps1": {}, "md": {}, "rpm": {}, "deb": {}, "msi": {}, "pkg": {}, "sha512": {},
+	"asc": {}, "part": {}, "validator": {}, "db": {},
+}
+
+// isHostname reports whether a dotted name is a hostname: it ends with a known
+// top-level domain, or has at least three labels and doesn't end with a file
+// extension. Go selectors, like fmt.Sprintf or config.Info, are not hostnames.
+func isHostname(s string) bool {
+	labels := strings.Split(s, ".")
+	if isGoSelector(labels) {
+		return false
+	}
+	tld := strings.ToLower(labels[len(labels)-1])
+	if _, ok := hostnameTLDs[tld]; ok {
+		return true
+	}
+	if _, ok := fileExtensions[tld]; ok {
+		return false
+	}
+	return len(labels) >= 3
+}
+
+// isGoSelector reports whether the labels are Go identifiers selecting an
+// exported name: the last one starts with an upper case letter and isn't all
+// upper case, like Sprintf.
+func isGoSelector(labels []string) bool {
+	for _, label := range labels {
+		if !isGoIdentifier(label) {
+			return false
+		}
+	}
+	last := labels[len(labels)-1]
+	first, _ := utf8.DecodeRuneInString(last)
+	return unicode.IsUpper(first) && strings.ToUpper(last) != last
+}
+
+func isGoIdentifier(s string) bool {
+	for i, c := range s {
+		if c != '_' && !unicode.IsLetter(c) && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// RedactionPolicy describes what gets redacted from a diagnostics bundle on top of
+// the default redaction of sensitive keys.
+type RedactionPolicy struct {
+	// Keys are regular expressions matched, case-insensitive, against the keys of
+	// YAML and JSON documents and of JSON log lines. The values of matching keys are
+	// redacted in addition to the keys redacted by default.
+	Keys []string `yaml:"keys"`
+	// Values are patterns replaced wherever they appear in the content of a file.
+	Values []ValuePattern `yaml:"values"`
+	// Paths controls which files are included in the archive.
+	Paths PathRules `yaml:"paths"`
+
+	source string
+	keys   []*regexp.Regexp
+	values []valueMatcher
+}
+
+// ValuePattern is a named regular expression. When Pattern is empty, Name must
+// be one of the built-in patterns: ipv4, ipv6, email or hostname.
+type ValuePattern struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+// PathRules are glob patterns, as understood by path.Match, matched against the
+// path of a file in the archive or any of its parent directories. When Allow is
+// not empty only matching files are included; files matching Deny are never included.
+type PathRules struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+type valueMatcher struct {
+	name     string
+	re       *regexp.Regexp
+	validate func(string) bool
+}
+
+// RedactionManifest lists what was redacted from a diagnostics archive.
+type RedactionManifest struct {
+	Policy string `yaml:"policy,omitempty"`
+	// Redacted are the files with redacted content.
+	Redacted []RedactedFile `yaml:"redacted,omitempty"`
+	// Excluded are the files left out of the archive by the path rules and the
+	// binary files, like profiles, whose values can't be redacted.
+	Excluded []string `yaml:"excluded,omitempty"`
+}
+
+// RedactedFile lists the number of redactions in a file, by key and by value pattern name.
+type RedactedFile struct {
+	Path   string         `yaml:"path"`
+	Keys   map[string]int `yaml:"keys,omitempty"`
+	Values map[string]int `yaml:"values,omitempty"`
+}
+
+// LoadRedactionPolicy reads and validates the redaction policy stored in the file at path.
+func LoadRedactionPolicy(path string) (*RedactionPolicy, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open redaction policy: %w", err)
+	}
+	defer f.Close()
+
+	var policy RedactionPolicy
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse redaction policy %q: %w", path, err)
+	}
+	if err := policy.compile(); err != nil {
+		return nil, fmt.Errorf("invalid redaction policy %q: %w", path, err)
+	}
+	policy.source = path
+	return &policy, nil
+}
+
+func (p *RedactionPolicy) compile() error {
+	p.keys = p.keys[:0]
+	for _, k := range p.Keys {
+		re, err := regexp.Compile("(?i)" + k)
+		if err != nil {
+			return fmt.Errorf("invalid key pattern %q: %w", k, err)
+		}
+		p.keys = append(p.keys, re)
+	}
+
+	p.values = p.values[:0]
+	for _, v := range p.Values {
+		if v.Name == "" {
+			return fmt.Errorf("value pattern %q has no name", v.Pattern)
+		}
+		m := valueMatcher{name: v.Name}
+		pattern := v.Pattern
+		if pattern == "" {
+			builtin, ok := builtinValuePatterns[v.Name]
+			if !ok {
+				return fmt.Errorf("value pattern %q has no pattern and is not a built-in pattern", v.Name)
+			}
+			pattern, m.validate = builtin.pattern, builtin.validate
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid value pattern %q: %w", v.Name, err)
+		}
+		m.re = re
+		p.values = append(p.values, m)
+	}
+
+	for _, glob := range append(append([]string{}, p.Paths.Allow...), p.Paths.Deny...) {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid path pattern %q: %w", glob, err)
+		}
+	}
+	return nil
+}
+
+// redactKey reports whether the value of the key k must be redacted.
+func (p *RedactionPolicy) redactKey(k string) bool {
+	if redactKey(k) {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	for _, re := range p.keys {
+		if re.MatchString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// includes reports whether the file at name belongs in the archive.
+func (p *RedactionPolicy) includes(name string) bool {
+	if p == nil {
+		return true
+	}
+	if matchesPath(p.Paths.Deny, name) {
+		return false
+	}
+	return len(p.Paths.Allow) == 0 || matchesPath(p.Paths.Allow, name)
+}
+
+// matchesPath reports whether name or one of its parent directories matches one of the globs.
+func matchesPath(globs []string, name string) bool {
+	name = strings.TrimSuffix(name, "/")
+	for _, glob := range globs {
+		glob = strings.TrimSuffix(glob, "/")
+		for p := name; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+			if ok, _ := path.Match(glob, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redactor applies the default redaction and, if set, a redaction policy to the
+// files written in a diagnostics archive and keeps track of what was redacted.
+type redactor struct {
+	errOut   io.Writer
+	policy   *RedactionPolicy
+	manifest RedactionManifest
+	// files indexes the manifest entries by path
+	files map[string]*RedactedFile
+}
+
+func newRedactor(errOut io.Writer, policy *RedactionPolicy) *redactor {
+	r := &redactor{
+		errOut: errOut,
+		policy: policy,
+		files:  make(map[string]*RedactedFile),
+	}
+	if policy != nil {
+		r.manifest.Policy = policy.source
+	}
+	return r
+}
+
+// include reports whether the file at name belongs in the archive, recording it
+// in the manifest when it doesn't.
+func (r *redactor) include(name string) bool {
+	if r.policy.includes(name) {
+		return true
+	}
+	r.manifest.Excluded = append(r.manifest.Excluded, name)
+	return false
+}
+
+// includeResult reports whether the diagnostic file result at name belongs in
+// the archive. Binary results are left out when a policy is set, the values
+// they hold can't be redacted.
+func (r *redactor) includeResult(name string, fileResult client.DiagnosticFileResult) bool {
+	if r.policy != nil && fileResult.ContentType == "application/octet-stream" {
+		r.manifest.Excluded = append(r.manifest.Excluded, name)
+		return false
+	}
+	return r.include(name)
+}
+
+func (r *redactor) keyRedacted(name, key string) {
+	if r.policy == nil {
+		return
+	}
+	f := r.file(name)
+	if f.Keys == nil {
+		f.Keys = make(map[string]int)
+	}
+	f.Keys[key]++
+}
+
+func (r *redactor) valueRedacted(name, pattern string, count int) {
+	f := r.file(name)
+	if f.Values == nil {
+		f.Values = make(map[string]int)
+	}
+	f.Values[pattern] += count
+}
+
+func (r *redactor) file(name string) *RedactedFile {
+	f, ok := r.files[name]
+	if !ok {
+		f = &RedactedFile{Path: name}
+		r.files[name] = f
+	}
+	return f
+}
+
+// writeResult writes the redacted content of a diagnostic file result.
+func (r *redactor) writeResult(w io.Writer, name string, fileResult client.DiagnosticFileResult) error {
+	out := fileResult.Content
+
+	switch {
+	case fileResult.ContentType == "application/yaml":
+		out = r.redactStructured(name, out, yaml.Unmarshal, yaml.Marshal)
+	case fileResult.ContentType == "application/json" && r.policy != nil:
+		out = r.redactStructured(name, out, json.Unmarshal, func(v any) ([]byte, error) {
+			return marshalJSON(v, "  ")
+		})
+	}
+
+	if r.policy != nil {
+		out = r.redactValues(name, out)
+	}
+
+	_, err := w.Write(out)
+	return err
+}
+
+// redactStructured redacts the keys of a YAML or JSON document. On failure the
+// content is returned unchanged and a warning is written.
+func (r *redactor) redactStructured(name string, content []byte, unmarshal func([]byte, any) error, marshal func(any) ([]byte, error)) []byte {
+	var unmarshalled any
+	err := unmarshal(content, &unmarshalled)
+	if err != nil {
+		// Best effort, output a warning but still include the file
+		fmt.Fprintf(r.errOut, "[WARNING] Could not redact %s due to unmarshalling error: %s\n", name, err)
+		return content
+	}
+
+	// could be a plain string, we only redact if this is a proper map
+	t, ok := unmarshalled.(map[string]any)
+	if !ok {
+		return content
+	}
+	t = redactMapWith(r.errOut, RedactSecretPaths(t, r.errOut), r.policy.redactKey, func(key string) {
+		r.keyRedacted(name, key)
+	})
+	redacted, err := marshal(t)
+	if err != nil {
+		// Best effort, output a warning but still include the file
+		fmt.Fprintf(r.errOut, "[WARNING] Could not redact %s due to marshalling error: %s\n", name, err)
+		return content
+	}
+	return redacted
+}
+
+// redactValues replaces every match of the policy value patterns in content.
+func (r *redactor) redactValues(name string, content []byte) []byte {
+	for _, m := range r.policy.values {
+		count := 0
+		content = m.re.ReplaceAllFunc(content, func(match []byte) []byte {
+			if m.validate != nil && !m.validate(string(match)) {
+				return match
+			}
+			count++
+			return []byte(REDACTED)
+		})
+		if count > 0 {
+			r.valueRedacted(name, m.name, count)
+		}
+	}
+	return content
+}
+
+// copyRedacted copies src into dst line by line, redacting the keys of JSON lines
+// and the policy value patterns. Without a policy src is copied as is.
+func (r *redactor) copyRedacted(name string, dst io.Writer, src io.Reader) error {
+	if r.policy == nil {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+
+	br := bufio.NewReader(src)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			line = r.redactValues(name, r.redactJSONLine(name, line))
+			if _, err := dst.Write(line); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// redactJSONLine redacts the keys of a JSON object on a single line, as found in
+// ndjson log files. Lines that aren't JSON objects, or without anything to redact,
+// are returned unchanged.
+func (r *redactor) redactJSONLine(name string, line []byte) []byte {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return line
+	}
+	var event map[string]any
+	if err := json.Unmarshal(trimmed, &event); err != nil {
+		return line
+	}
+	redacted := false
+	event = redactMapWith(io.Discard, event, r.policy.redactKey, func(key string) {
+		redacted = true
+		r.keyRedacted(name, key)
+	})
+	if !redacted {
+		return line
+	}
+	out, err := marshalJSON(event, "")
+	if err != nil {
+		return line
+	}
+	return out
+}
+
+// marshalJSON encodes v followed by a newline, without escaping HTML characters
+// so the redaction placeholder stays readable.
+func marshalJSON(v any, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeManifest writes the redaction manifest, files are sorted by path.
+func (r *redactor) writeManifest(w io.Writer) error {
+	r.manifest.Redacted = r.manifest.Redacted[:0]
+	for _, f := range r.files {
+		r.manifest.Redacted = append(r.manifest.Redacted, *f)
+	}
+	sort.Slice(r.manifest.Redacted, func(i, j int) bool {
+		return r.manifest.Redacted[i].Path < r.manifest.Redacted[j].Path
+	})
+	out, err := yaml.Marshal(r.manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal redaction manifest: %w", err)
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,285 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadRedactionPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		errMsg string
+	}{
+		{
+			name: "valid",
+			policy: `
+keys: ["^client_id$"]
+values:
+  - name: ipv4
+  - name: internal_host
+    pattern: '[a-z0-9-]+\.corp\.example\.com'
+paths:
+  deny: ["logs/services"]
+`,
+		},
+		{
+			name:   "empty",
+			policy: "",
+		},
+		{
+			name:   "invalid key pattern",
+			policy: `keys: ["("]`,
+			errMsg: `invalid key pattern "("`,
+		},
+		{
+			name:   "unknown built-in pattern",
+			policy: `values: [{name: phone}]`,
+			errMsg: `value pattern "phone" has no pattern and is not a built-in pattern`,
+		},
+		{
+			name:   "value pattern without name",
+			policy: `values: [{pattern: 'abc'}]`,
+			errMsg: `value pattern "abc" has no name`,
+		},
+		{
+			name:   "invalid path pattern",
+			policy: `paths: {allow: ["logs/["]}`,
+			errMsg: `invalid path pattern "logs/["`,
+		},
+		{
+			name:   "unknown field",
+			policy: `key: ["password"]`,
+			errMsg: "field key not found",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := LoadRedactionPolicy(writePolicy(t, tc.policy))
+			if tc.errMsg != "" {
+				assert.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, policy)
+		})
+	}
+}
+
+func TestMatchesPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		globs []string
+		path  string
+		match bool
+	}{
+		{"exact", []string{"state.yaml"}, "state.yaml", true},
+		{"glob", []string{"*.pprof.gz"}, "heap.pprof.gz", true},
+		{"parent directory", []string{"logs/services"}, "logs/services/endpoint/endpoint.log", true},
+		{"parent directory glob", []string{"components/*"}, "components/filestream-default/state.yaml", true},
+		{"directory entry", []string{"logs"}, "logs/", true},
+		{"no match", []string{"logs/services"}, "logs/elastic-agent-1234/elastic-agent.ndjson", false},
+		{"no globs", nil, "state.yaml", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.match, matchesPath(tc.globs, tc.path))
+		})
+	}
+}
+
+func TestRedactValues(t *testing.T) {
+	policy, err := LoadRedactionPolicy(writePolicy(t, `
+values:
+  - name: email
+  - name: ipv4
+  - name: ipv6
+  - name: hostname
+`))
+	require.NoError(t, err)
+	r := newRedactor(io.Discard, policy)
+
+	out := r.redactValues("test", []byte("user jane.doe@example.com connected from 10.0.0.12 and fe80::1ff:fe23:4567:890a%eth0 to es01.internal.example.org at 12:30:45, version 999.1.1.1 read from fleet.enc by fmt.Sprintf"))
+	assert.Equal(t, "user <REDACTED> connected from <REDACTED> and <REDACTED> to <REDACTED> at 12:30:45, version 999.1.1.1 read from fleet.enc by fmt.Sprintf", string(out))
+	assert.Equal(t, map[string]int{"email": 1, "ipv4": 1, "ipv6": 1, "hostname": 1}, r.file("test").Values)
+}
+
+func TestIsHostname(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname bool
+	}{
+		{"example.com", true},
+		{"fleet.example.org", true},
+		{"ES01.EXAMPLE.COM", true},
+		{"es01.internal", true},
+		{"es01.prod.acme", true},
+		{"Host01.corp.example.com", true},
+		{"DESKTOP-Ab12.corp.local", true},
+		{"fleet.enc", false},
+		{"elastic-agent.yml", false},
+		{"heap.pprof.gz", false},
+		{"fmt.Sprintf", false},
+		{"cfg.Settings.Upgrade", false},
+		{"errors.New", false},
+		{"config.Info", false},
+		{"state.yaml", false},
+		{"component.state", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.hostname, isHostname(tc.name))
+		})
+	}
+}
+
+func TestZipArchiveWithRedactionPolicy(t *testing.T) {
+	topPath := t.TempDir()
+	logs := filepath.Join(paths.HomeFrom(topPath), "logs")
+	require.NoError(t, os.MkdirAll(logs, 0o700))
+	logLines := `{"log.level":"info","message":"connecting to 192.168.1.10"}
+{"log.level":"info","message":"enrolled","client_id":"abc123"}
+plain text line from 192.168.1.11
+`
+	require.NoError(t, os.WriteFile(filepath.Join(logs, "elastic-agent.ndjson"), []byte(logLines), 0o600))
+
+	policy, err := LoadRedactionPolicy(writePolicy(t, `
+keys: ["^client_id$"]
+values:
+  - name: ipv4
+paths:
+  deny: ["*.txt"]
+`))
+	require.NoError(t, err)
+
+	now := time.Now()
+	agentDiag := []client.DiagnosticFileResult{
+		{
+			Filename:    "pre-config.yaml",
+			ContentType: "application/yaml",
+			Content:     []byte("outputs:\n  default:\n    hosts: [\"https://10.1.2.3:9200\"]\n    password: changeme\n    client_id: abc123\n"),
+			Generated:   now,
+		},
+		{
+			Filename:    "heap.pprof.gz",
+			ContentType: "application/octet-stream",
+			Content:     []byte("10.1.2.3"),
+			Generated:   now,
+		},
+		{
+			Filename:    "notes.txt",
+			ContentType: "text/plain",
+			Content:     []byte("10.1.2.3"),
+			Generated:   now,
+		},
+	}
+
+	buf := new(bytes.Buffer)
+	errOut := new(strings.Builder)
+	require.NoError(t, ZipArchive(errOut, buf, topPath, agentDiag, nil, nil, false, policy))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	assert.NotContains(t, files, "notes.txt")
+	assert.NotContains(t, files, "heap.pprof.gz", "binary files can't be scrubbed")
+
+	assert.NotContains(t, files["pre-config.yaml"], "10.1.2.3")
+	assert.NotContains(t, files["pre-config.yaml"], "changeme")
+	assert.NotContains(t, files["pre-config.yaml"], "abc123")
+
+	agentLog := files["logs/elastic-agent-unknow/elastic-agent.ndjson"]
+	assert.Equal(t, `{"log.level":"info","message":"connecting to <REDACTED>"}
+{"client_id":"<REDACTED>","log.level":"info","message":"enrolled"}
+plain text line from <REDACTED>
+`, agentLog)
+
+	var manifest RedactionManifest
+	require.NoError(t, yaml.Unmarshal([]byte(files[RedactionManifestFilename]), &manifest))
+	assert.Equal(t, RedactionManifest{
+		Policy: policy.source,
+		Redacted: []RedactedFile{
+			{
+				Path:   "logs/elastic-agent-unknow/elastic-agent.ndjson",
+				Keys:   map[string]int{"client_id": 1},
+				Values: map[string]int{"ipv4": 2},
+			},
+			{
+				Path:   "pre-config.yaml",
+				Keys:   map[string]int{"client_id": 1, "password": 1},
+				Values: map[string]int{"ipv4": 1},
+			},
+		},
+		Excluded: []string{"heap.pprof.gz", "notes.txt"},
+	}, manifest)
+}
+
+func TestZipArchiveWithoutRedactionPolicy(t *testing.T) {
+	agentDiag := []client.DiagnosticFileResult{
+		{
+			Filename:    "notes.txt",
+			ContentType: "text/plain",
+			Content:     []byte("10.1.2.3"),
+			Generated:   time.Now(),
+		},
+	}
+
+	topPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(paths.DataFrom(topPath), 0o700))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, ZipArchive(io.Discard, buf, topPath, agentDiag, nil, nil, false, nil))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	for _, f := range zr.File {
+		assert.NotEqual(t, RedactionManifestFilename, f.Name, "no manifest is written without a policy")
+		if f.Name == "notes.txt" {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			rc.Close()
+			assert.Equal(t, "10.1.2.3", string(content))
+		}
+	}
+}
@@ -316,6 +316,20 @@ func TestEql(t *testing.T) {
 		{expression: "modulo(2, 2, 2) == 4", err: true},
 		{expression: "modulo('str', 'str') == 4", err: true},
 
+		// methods net
+		{expression: "cidrMatch('10.1.2.3', '10.0.0.0/8')", result: true},
+		{expression: "cidrMatch('192.168.1.1', '10.0.0.0/8')", result: false},
+		{expression: "cidrMatch('192.168.1.1', '10.0.0.0/8', '192.168.0.0/16')", result: true},
+		{expression: "cidrMatch('fe80::1', 'fe80::/10')", result: true},
+		{expression: "cidrMatch(${host.ip}, '10.0.0.0/8')", result: true},
+		{expression: "cidrMatch(${host.ip}, '172.16.0.0/12')", result: false},
+		{expression: "cidrMatch('not an ip', '10.0.0.0/8')", result: false},
+		{expression: "cidrMatch(${null}, '10.0.0.0/8')", allowMissingVars: true, result: false},
+		{expression: "cidrMatch('10.1.2.3', 'not a cidr')", err: true},
+		{expression: "cidrMatch('10.1.2.3', 8)", err: true},
+		{expression: "cidrMatch(8, '10.0.0.0/8')", err: true},
+		{expression: "cidrMatch('not enough')", err: true},
+
 		// methods str
 		{expression: "concat('hello ', 2, ' the world') == 'hello 2 the world'", result: true},
 		{expression: "concat('h', 2, 2.0, ['a', 'b'], true, {key: 'value'}) == 'h22E+00[a,b]true{key:value}'", result: true},
@@ -328,6 +342,8 @@ func TestEql(t *testing.T) {
 		{expression: "indexOf('elastic-agent.elastic.co', '.', 15.2) == 21", err: true},
 		{expression: "indexOf('elastic-agent.elastic.co', '.', 'not int') == 21", err: true},
 		{expression: "indexOf('elastic-agent.elastic.co', '.', '15, 'too many args') == 21", err: true},
+		{expression: "lower('Hello World') == 'hello world'", result: true},
+		{expression: "lower('Hello', 'too many') == 'hello'", err: true},
 		{expression: "match('elastic.co', '[a-z]+.[a-z]{2}')", result: true},
 		{expression: "match('elastic.co', '[a-z]+', '[a-z]+.[a-z]{2}')", result: true},
 		{expression: "match('not enough')", err: true},
@@ -336,6 +352,14 @@ func TestEql(t *testing.T) {
 		{expression: "number('0xbeef', 16) == 48879", result: true},
 		{expression: "number('not a number') == 'not'", err: true},
 		{expression: "number('0xbeef', 16, 2) == 'too many args'", err: true},
+		{expression: "regexCapture('elastic-agent-9.1.0', '-([0-9.]+)$') == '9.1.0'", result: true},
+		{expression: "regexCapture('elastic-agent-9.1.0', '^([a-z]+)-([a-z]+)', 2) == 'agent'", result: true},
+		{expression: "regexCapture('elastic-agent-9.1.0', '(?P<major>[0-9]+)\\.', 'major') == '9'", result: true},
+		{expression: "regexCapture('elastic', '([0-9]+)') == 'elastic'", result: false},
+		{expression: "regexCapture('elastic', '([0-9]+)', 2)", err: true},
+		{expression: "regexCapture('elastic', '(?P<a>[0-9]+)', 'missing')", err: true},
+		{expression: "regexCapture('elastic', '([a-z')", err: true},
+		{expression: "regexCapture('not enough')", err: true},
 		{expression: "startsWith('hello world', 'hello')", result: true},
 		{expression: "startsWith('hello world', 'llo')", result: false},
 		{expression: "startsWith('hello world', 'hello', 'too many args')", err: true},
@@ -353,6 +377,26 @@ func TestEql(t *testing.T) {
 		{expression: "stringContains('hello world', 'o w', 'too many')", err: true},
 		{expression: "stringContains(0, 'o w', 'too many')", err: true},
 		{expression: "stringContains('hello world', 0)", result: false},
+		{expression: "trim('  hello world  ') == 'hello world'", result: true},
+		{expression: "trim() == ''", err: true},
+		{expression: "upper('Hello World') == 'HELLO WORLD'", result: true},
+		{expression: "upper('Hello', 'too many') == 'HELLO'", err: true},
+
+		// methods time
+		{expression: "timestamp('2024-01-02T03:04:05Z') == 1704164645", result: true},
+		{expression: "timestamp('2024-01-02', '2006-01-02') < timestamp('2024-01-03', '2006-01-02')", result: true},
+		{expression: "timestamp('2024-01-02T03:04:05Z') < now()", result: true},
+		{expression: "timestamp('not a date') > 0", err: true},
+		{expression: "timestamp('2024-01-02', 2006) > 0", err: true},
+		{expression: "now(1) > 0", err: true},
+
+		// methods version
+		{expression: "semverCompare('8.19.0', '9.0.0') == -1", result: true},
+		{expression: "semverCompare('9.1.0', '9.1.0') == 0", result: true},
+		{expression: "semverCompare('9.1.0', '9.1.0-SNAPSHOT') == 1", result: true},
+		{expression: "semverCompare(${agent.version}, '9.0.0') >= 0", result: true},
+		{expression: "semverCompare('not a version', '9.0.0') == 0", err: true},
+		{expression: "semverCompare('9.0.0') == 0", err: true},
 
 		// Bad expression and malformed expression
 		{expression: "length('hello')", err: true},
@@ -368,6 +412,8 @@ func TestEql(t *testing.T) {
 			"env.HOSTNAME":    "my-hostname",
 			"env.HOSTSAME":    "my-hostname",
 			"host.name":       "host-name",
+			"host.ip":         []interface{}{"127.0.0.1", "10.1.2.3"},
+			"agent.version":   "9.1.0",
 			"data.array":      []interface{}{"array1", "array2", "array3"},
 			"data.with-dash":  "dash-value",
 			"data.with/slash": "some/path",
@@ -404,6 +450,63 @@ func TestEql(t *testing.T) {
 	}
 }
 
+func TestRegisterFunction(t *testing.T) {
+	t.Cleanup(func() {
+		methodsMx.Lock()
+		delete(methods, "testDouble")
+		methodsMx.Unlock()
+	})
+
+	err := RegisterFunction("testDouble", func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("testDouble: accepts exactly 1 argument; received %d", len(args))
+		}
+		return add([]interface{}{args[0], args[0]})
+	})
+	require.NoError(t, err)
+
+	r, err := Eval("testDouble(2) == 4", nil, true)
+	require.NoError(t, err)
+	assert.True(t, r)
+
+	err = RegisterFunction("testDouble", func(args []interface{}) (interface{}, error) { return nil, nil })
+	assert.EqualError(t, err, "function testDouble is already registered")
+
+	err = RegisterFunction("length", func(args []interface{}) (interface{}, error) { return nil, nil })
+	assert.EqualError(t, err, "function length is already registered")
+
+	err = RegisterFunction("not-valid", func(args []interface{}) (interface{}, error) { return nil, nil })
+	assert.EqualError(t, err, `invalid function name "not-valid"`)
+
+	err = RegisterFunction("nilFunc", nil)
+	assert.EqualError(t, err, "function nilFunc is nil")
+}
+
+func TestExpressionIsTimeDependent(t *testing.T) {
+	t.Cleanup(func() {
+		methodsMx.Lock()
+		delete(methods, "testClock")
+		delete(timeDependentMethods, "testClock")
+		methodsMx.Unlock()
+	})
+
+	require.NoError(t, RegisterTimeDependentFunction("testClock", now))
+
+	for expression, expected := range map[string]bool{
+		"${host.name} == 'foo'":                         false,
+		"length('foo') == 3":                            false,
+		"now() > timestamp('2020-01-01T00:00:00Z')":     true,
+		"${host.name} == 'foo' and (add(now(), 1) > 0)": true,
+		"testClock() > 0":                               true,
+	} {
+		t.Run(expression, func(t *testing.T) {
+			e, err := New(expression)
+			require.NoError(t, err)
+			assert.Equal(t, expected, e.IsTimeDependent())
+		})
+	}
+}
+
 func debug(t *testing.T, expression string) {
 	raw := antlr.NewInputStream(expression)
 
@@ -62,6 +62,24 @@ func (e *Expression) Eval(store VarStore, allowMissingVars bool) (result bool, e
 	return r.(bool), nil
 }
 
+// IsTimeDependent returns true when the expression calls a time dependent function, like now(). The result
+// of the expression changes over time even when its variables don't.
+func (e *Expression) IsTimeDependent() bool {
+	return callsTimeDependentMethod(e.tree)
+}
+
+func callsTimeDependentMethod(tree antlr.Tree) bool {
+	if f, ok := tree.(*parser.ExpFunctionContext); ok && isTimeDependentMethod(f.NAME().GetText()) {
+		return true
+	}
+	for _, child := range tree.GetChildren() {
+		if callsTimeDependentMethod(child) {
+			return true
+		}
+	}
+	return false
+}
+
 // New create a new boolean expression parser will return an error if the expression if invalid.
 func New(expression string) (*Expression, error) {
 	if len(expression) == 0 {
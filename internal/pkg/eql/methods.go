@@ -4,12 +4,21 @@
 
 package eql
 
-// callFunc is a function called while the expression evaluation is done, the function is responsible
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// Function is a function called while the expression evaluation is done, the function is responsible
 // of doing the type conversion and allow checking the arity of the function.
-type callFunc func(args []interface{}) (interface{}, error)
+type Function func(args []interface{}) (interface{}, error)
+
+// functionName matches the NAME token of the grammar, only those names can be called from an expression.
+var functionName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 
 // methods are the methods enabled in EQL.
-var methods = map[string]callFunc{
+var methods = map[string]Function{
 	// array
 	"arrayContains": arrayContains,
 
@@ -26,13 +35,84 @@ var methods = map[string]callFunc{
 	"divide":   divide,
 	"modulo":   modulo,
 
+	// net
+	"cidrMatch": cidrMatch,
+
 	// str
 	"concat":         concat,
 	"endsWith":       endsWith,
 	"indexOf":        indexOf,
+	"lower":          lower,
 	"match":          match,
 	"number":         number,
+	"regexCapture":   regexCapture,
 	"startsWith":     startsWith,
 	"string":         str,
 	"stringContains": stringContains,
+	"trim":           trim,
+	"upper":          upper,
+
+	// time
+	"now":       now,
+	"timestamp": timestamp,
+
+	// version
+	"semverCompare": semverCompare,
+}
+
+// timeDependentMethods are the methods returning a different result over time for the same arguments.
+var timeDependentMethods = map[string]struct{}{
+	"now": {},
+}
+
+// methodsMx protects methods and timeDependentMethods from concurrent registration and lookup.
+var methodsMx sync.RWMutex
+
+// RegisterFunction adds a function that can be called from EQL expressions. It returns an error if the
+// name isn't a valid function name or if a function with the same name is already registered, built-in
+// functions can't be replaced.
+func RegisterFunction(name string, fn Function) error {
+	return registerFunction(name, fn, false)
+}
+
+// RegisterTimeDependentFunction adds a function that can be called from EQL expressions and returns a
+// different result over time for the same arguments, like now(). The expressions calling it are
+// evaluated again periodically, see Expression.IsTimeDependent.
+func RegisterTimeDependentFunction(name string, fn Function) error {
+	return registerFunction(name, fn, true)
+}
+
+func registerFunction(name string, fn Function, timeDependent bool) error {
+	if !functionName.MatchString(name) {
+		return fmt.Errorf("invalid function name %q", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("function %s is nil", name)
+	}
+	methodsMx.Lock()
+	defer methodsMx.Unlock()
+	if _, ok := methods[name]; ok {
+		return fmt.Errorf("function %s is already registered", name)
+	}
+	methods[name] = fn
+	if timeDependent {
+		timeDependentMethods[name] = struct{}{}
+	}
+	return nil
+}
+
+// lookupMethod returns the function registered with the given name.
+func lookupMethod(name string) (Function, bool) {
+	methodsMx.RLock()
+	defer methodsMx.RUnlock()
+	method, ok := methods[name]
+	return method, ok
+}
+
+// isTimeDependentMethod returns true when the function registered with the given name is time dependent.
+func isTimeDependentMethod(name string) bool {
+	methodsMx.RLock()
+	defer methodsMx.RUnlock()
+	_, ok := timeDependentMethods[name]
+	return ok
 }
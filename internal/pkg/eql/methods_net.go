@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package eql
+
+import (
+	"fmt"
+	"net"
+)
+
+// cidrMatch returns true if the IP address, or any of the IP addresses when given an array,
+// is part of any of the provided CIDR blocks
+func cidrMatch(args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("cidrMatch: accepts minimum of 2 arguments; received %d", len(args))
+	}
+	var nets []*net.IPNet
+	for i, arg := range args[1:] {
+		cidr, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("cidrMatch: argument %d must be a string; received %T", i+1, arg)
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("cidrMatch: failed to parse CIDR: %w", err)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	var ips []interface{}
+	switch a := args[0].(type) {
+	case *null:
+		return false, nil
+	case string:
+		ips = []interface{}{a}
+	case []interface{}:
+		ips = a
+	default:
+		return nil, fmt.Errorf("cidrMatch: first argument must be a string or an array; received %T", args[0])
+	}
+	for _, item := range ips {
+		ip := net.ParseIP(toString(item))
+		if ip == nil {
+			continue
+		}
+		for _, ipNet := range nets {
+			if ipNet.Contains(ip) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
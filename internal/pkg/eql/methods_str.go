@@ -46,6 +46,14 @@ func indexOf(args []interface{}) (interface{}, error) {
 	return start + strings.Index(input[start:], substring), nil
 }
 
+// lower converts the string to lower case
+func lower(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("lower: accepts exactly 1 argument; received %d", len(args))
+	}
+	return strings.ToLower(toString(args[0])), nil
+}
+
 // match returns true if the string matches any of the provided regular expressions
 func match(args []interface{}) (interface{}, error) {
 	if len(args) < 2 {
@@ -93,6 +101,45 @@ func number(args []interface{}) (interface{}, error) {
 	return int(n), nil
 }
 
+// regexCapture returns the text captured by a group of the regular expression, the first group is
+// used unless a group index or name is provided; null is returned when the string doesn't match
+func regexCapture(args []interface{}) (interface{}, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("regexCapture: accepts between 2-3 arguments; received %d", len(args))
+	}
+	input := toString(args[0])
+	r, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("regexCapture: argument 1 must be a string; received %T", args[1])
+	}
+	exp, err := regexp.Compile(r)
+	if err != nil {
+		return nil, fmt.Errorf("regexCapture: failed to compile regexp: %w", err)
+	}
+	group := 1
+	if len(args) > 2 {
+		switch g := args[2].(type) {
+		case int:
+			group = g
+		case string:
+			group = exp.SubexpIndex(g)
+			if group < 0 {
+				return nil, fmt.Errorf("regexCapture: regexp has no group named '%s'", g)
+			}
+		default:
+			return nil, fmt.Errorf("regexCapture: argument 2 must be an integer or a string; received %T", args[2])
+		}
+	}
+	if group < 0 || group > exp.NumSubexp() {
+		return nil, fmt.Errorf("regexCapture: regexp has no group %d", group)
+	}
+	matches := exp.FindStringSubmatch(input)
+	if matches == nil {
+		return Null, nil
+	}
+	return matches[group], nil
+}
+
 // startsWith returns true if the string starts with given prefix
 func startsWith(args []interface{}) (interface{}, error) {
 	if len(args) != 2 {
@@ -117,6 +164,22 @@ func stringContains(args []interface{}) (interface{}, error) {
 	return strings.Contains(toString(args[0]), toString(args[1])), nil
 }
 
+// trim removes leading and trailing white space from the string
+func trim(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("trim: accepts exactly 1 argument; received %d", len(args))
+	}
+	return strings.TrimSpace(toString(args[0])), nil
+}
+
+// upper converts the string to upper case
+func upper(args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("upper: accepts exactly 1 argument; received %d", len(args))
+	}
+	return strings.ToUpper(toString(args[0])), nil
+}
+
 func toString(arg interface{}) string {
 	switch a := arg.(type) {
 	case *null:
@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package eql
+
+import (
+	"fmt"
+	"time"
+)
+
+// now returns the current time as seconds since the Unix epoch
+func now(args []interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("now: accepts no arguments; received %d", len(args))
+	}
+	return int(time.Now().Unix()), nil
+}
+
+// timestamp parses a date into seconds since the Unix epoch, so dates can be compared with each
+// other and with now(). The date is parsed as RFC 3339 unless a Go time layout is provided.
+func timestamp(args []interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("timestamp: accepts between 1-2 arguments; received %d", len(args))
+	}
+	layout := time.RFC3339
+	if len(args) > 1 {
+		l, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("timestamp: argument 1 must be a string; received %T", args[1])
+		}
+		layout = l
+	}
+	t, err := time.Parse(layout, toString(args[0]))
+	if err != nil {
+		return nil, fmt.Errorf("timestamp: failed to parse '%s': %w", toString(args[0]), err)
+	}
+	return int(t.Unix()), nil
+}
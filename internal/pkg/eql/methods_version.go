@@ -0,0 +1,35 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package eql
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent/pkg/version"
+)
+
+// semverCompare compares two semantic versions, it returns -1 when the first version is lower,
+// 0 when both are equal and 1 when the first version is greater
+func semverCompare(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("semverCompare: accepts exactly 2 arguments; received %d", len(args))
+	}
+	a, err := version.ParseVersion(toString(args[0]))
+	if err != nil {
+		return nil, fmt.Errorf("semverCompare: failed to parse version '%s': %w", toString(args[0]), err)
+	}
+	b, err := version.ParseVersion(toString(args[1]))
+	if err != nil {
+		return nil, fmt.Errorf("semverCompare: failed to parse version '%s': %w", toString(args[1]), err)
+	}
+	switch {
+	case a.Less(*b):
+		return -1, nil
+	case b.Less(*a):
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
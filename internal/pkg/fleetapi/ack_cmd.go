@@ -10,6 +10,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
+	"time"
 
 	"go.elastic.co/apm/v2"
 
@@ -19,6 +22,12 @@ import (
 
 const ackPath = "/api/fleet/agents/%s/acks"
 
+// ErrAckRejected is returned when Fleet rejects the whole ack request with a 4xx status code.
+var ErrAckRejected = errors.New("ack request rejected by fleet")
+
+// AckTimeFormat is the format of the AckEvent timestamp.
+const AckTimeFormat = "2006-01-02T15:04:05.99999-07:00"
+
 // AckEvent is an event sent in an ACK request.
 type AckEvent struct {
 	EventType string          `json:"type"`              //  'STATE' | 'ERROR' | 'ACTION_RESULT' | 'ACTION'
@@ -38,6 +47,52 @@ type AckEvent struct {
 	Error           string                 `json:"error,omitempty"`             // optional action error
 }
 
+// AckedAction is an action restored from the acks journal. It only keeps the
+// ack event of the original action, as computed when the action was acked, so
+// it can be sent again to Fleet after a restart.
+type AckedAction struct {
+	ActionType string   `json:"type"`
+	Event      AckEvent `json:"event"`
+}
+
+// NewAckedAction creates an AckedAction from the action acked at the given time.
+// An AckedAction is returned as is.
+func NewAckedAction(action Action, ackedAt time.Time) *AckedAction {
+	if a, ok := action.(*AckedAction); ok {
+		return a
+	}
+	event := action.AckEvent()
+	if event.Timestamp == "" {
+		event.Timestamp = ackedAt.Format(AckTimeFormat)
+	}
+	return &AckedAction{ActionType: action.Type(), Event: event}
+}
+
+// Type returns the type of the acked action.
+func (a *AckedAction) Type() string {
+	return a.ActionType
+}
+
+// ID returns the ID of the acked action.
+func (a *AckedAction) ID() string {
+	return a.Event.ActionID
+}
+
+// AckEvent returns the ack event of the acked action.
+func (a *AckedAction) AckEvent() AckEvent {
+	return a.Event
+}
+
+func (a *AckedAction) String() string {
+	var s strings.Builder
+	s.WriteString("id: ")
+	s.WriteString(a.ID())
+	s.WriteString(", type: ")
+	s.WriteString(a.ActionType)
+	s.WriteString(" (acked)")
+	return s.String()
+}
+
 // AckRequest consists of multiple actions acked to fleet ui.
 // POST /agents/{agentId}/acks
 // Authorization: ApiKey {AgentAccessApiKey}
@@ -139,7 +194,11 @@ func (e *AckCmd) Execute(ctx context.Context, r *AckRequest) (_ *AckResponse, er
 
 	// if action is not "acks", try to extract the error
 	if ackResponse.Action != "acks" {
-		return nil, client.ExtractError(bytes.NewReader(body))
+		err := client.ExtractError(bytes.NewReader(body))
+		if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, fmt.Errorf("%w: %w", ErrAckRejected, err)
+		}
+		return nil, err
 	}
 
 	return &ackResponse, nil
@@ -7,6 +7,7 @@ package fleetapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
@@ -75,4 +76,31 @@ func TestAck(t *testing.T) {
 			require.Equal(t, "acks", r.Action)
 		},
 	))
+	for status, rejected := range map[int]bool{
+		http.StatusBadRequest:         true,
+		http.StatusTooManyRequests:    false,
+		http.StatusServiceUnavailable: false,
+	} {
+		t.Run(fmt.Sprintf("Test ack failing with status %d", status), withServerWithAuthClient(
+			func(t *testing.T) *http.ServeMux {
+				mux := http.NewServeMux()
+				path := fmt.Sprintf("/api/fleet/agents/%s/acks", agentInfo.AgentID())
+				mux.HandleFunc(path, authHandler(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(status)
+					fmt.Fprintf(w, `{"statusCode": %d, "error": "%s"}`, status, http.StatusText(status))
+				}, withAPIKey))
+				return mux
+			}, withAPIKey,
+			func(t *testing.T, client client.Sender) {
+				cmd := NewAckCmd(&agentinfo{}, client)
+				request := AckRequest{
+					Events: []AckEvent{{EventType: "ACTION_RESULT", SubType: "ACKNOWLEDGED", ActionID: "my-id"}},
+				}
+
+				_, err := cmd.Execute(context.Background(), &request)
+				require.Error(t, err)
+				require.Equal(t, rejected, errors.Is(err, ErrAckRejected))
+			},
+		))
+	}
 }
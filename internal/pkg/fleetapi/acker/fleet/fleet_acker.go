@@ -18,8 +18,6 @@ import (
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
-const fleetTimeFormat = "2006-01-02T15:04:05.99999-07:00"
-
 type agentInfo interface {
 	AgentID() string
 }
@@ -61,7 +59,7 @@ func (f *Acker) Ack(ctx context.Context, action fleetapi.Action) (err error) {
 	cmd := fleetapi.NewAckCmd(f.agentInfo, f.client)
 	event := action.AckEvent()
 	event.AgentID = agentID
-	event.Timestamp = time.Now().Format(fleetTimeFormat)
+	event.Timestamp = time.Now().Format(fleetapi.AckTimeFormat)
 	req := &fleetapi.AckRequest{
 		Events: []fleetapi.AckEvent{event},
 	}
@@ -91,7 +89,10 @@ func (f *Acker) AckBatch(ctx context.Context, actions []fleetapi.Action) (res *f
 	for _, action := range actions {
 		event := action.AckEvent()
 		event.AgentID = agentID
-		event.Timestamp = time.Now().Format(fleetTimeFormat)
+		// acks replayed from the journal keep the time they happened
+		if event.Timestamp == "" {
+			event.Timestamp = time.Now().Format(fleetapi.AckTimeFormat)
+		}
 		events = append(events, event)
 		ids = append(ids, action.ID())
 	}
@@ -6,7 +6,9 @@ package retrier
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"slices"
 	"sync"
 	"time"
 
@@ -27,18 +29,28 @@ type BatchAcker interface {
 	AckBatch(ctx context.Context, actions []fleetapi.Action) (*fleetapi.AckResponse, error)
 }
 
+// Journal persists the acks pending to be sent, so they survive restarts and
+// extended fleet outages. It's implemented by the state store.
+type Journal interface {
+	PendingAcks() []*fleetapi.AckedAction
+	SetPendingAcks(acks []*fleetapi.AckedAction)
+	Save() error
+}
+
 // Option Retrier option function
 type Option func(*Retrier)
 
 // Retrier implements retrier for actions acks
 type Retrier struct {
-	acker BatchAcker // AckBatch provider
-	log   *logger.Logger
+	acker   BatchAcker // AckBatch provider
+	log     *logger.Logger
+	journal Journal // optional, persists pending actions
 
 	doneCh chan struct{} // signal channel to kickoff retry loop if not running
 	kickCh chan struct{} // signal channel when retry loop is done
 
-	actions []fleetapi.Action // pending actions
+	actions  []fleetapi.Action // pending actions
+	inflight []fleetapi.Action // actions being acked by the retry loop
 
 	maxRetryInterval     time.Duration // max retry interval
 	maxRetries           int           // configurable maxNumber of retries per action
@@ -61,6 +73,15 @@ func New(acker BatchAcker, log *logger.Logger, opts ...Option) *Retrier {
 	for _, opt := range opts {
 		opt(r)
 	}
+	if r.journal != nil {
+		for _, a := range r.journal.PendingAcks() {
+			r.actions = append(r.actions, a)
+		}
+		if len(r.actions) > 0 {
+			r.log.Infof("ack retrier: %d acks restored from the journal", len(r.actions))
+			r.kickCh <- struct{}{}
+		}
+	}
 	return r
 }
 
@@ -86,6 +107,17 @@ func WithMaxAckRetries(maxRetries int) Option {
 	}
 }
 
+// WithJournal configures the retrier to persist the pending actions in the
+// journal. The actions found in the journal are retried first. With a journal,
+// actions are only dropped after maxRetries rejections by fleet, failing to
+// reach fleet doesn't count as a retry. Once the journal is full the oldest
+// actions are dropped.
+func WithJournal(journal Journal) Option {
+	return func(f *Retrier) {
+		f.journal = journal
+	}
+}
+
 // Done signals when retry loop is done, useful for testing
 func (r *Retrier) Done() <-chan struct{} {
 	return r.doneCh
@@ -111,7 +143,16 @@ func (r *Retrier) Enqueue(actions []fleetapi.Action) {
 	}
 
 	r.mx.Lock()
-	r.actions = append(r.actions, actions...)
+	if r.journal != nil {
+		// keep only what is needed to ack, as it was when the ack failed
+		now := time.Now()
+		for _, a := range actions {
+			r.actions = append(r.actions, fleetapi.NewAckedAction(a, now))
+		}
+		r.persist()
+	} else {
+		r.actions = append(r.actions, actions...)
+	}
 	r.mx.Unlock()
 
 	// Signal to kick off retry loop, non blocking if the signal is already pending
@@ -133,13 +174,18 @@ func (r *Retrier) runRetries(ctx context.Context) {
 		r.mx.Lock()
 		actions := r.actions
 		r.actions = nil
+		r.inflight = actions
 		r.mx.Unlock()
 
 		var failed []fleetapi.Action
 		r.log.Debug("ack retrier: before AckBatch")
 		resp, err := r.acker.AckBatch(ctx, actions)
 		r.log.Debugf("ack retrier: after AckBatch: %#v, %#v", resp, err)
-		if err != nil {
+		if err != nil && r.journal != nil && !errors.Is(err, fleetapi.ErrAckRejected) {
+			r.log.Errorf("ack retrier: commit failed with error, keeping %d journaled actions: %v", len(actions), err)
+			// Commit failed, fleet could not be reached, keep every action
+			failed = actions
+		} else if err != nil {
 			r.log.Errorf("ack retrier: commit failed with error: %v", err)
 			// Commit failed, update retry map from actions
 			failed = r.updateRetriesMap(retries, actions, nil)
@@ -157,7 +203,9 @@ func (r *Retrier) runRetries(ctx context.Context) {
 			b.Reset() // reset backoff if new actions came while committing
 		}
 		r.actions = append(failed, r.actions...)
+		r.inflight = nil
 		r.log.Debugf("ack retrier: total actions: %#v", r.actions)
+		r.persist()
 		exit := (len(r.actions) == 0)
 
 		r.mx.Unlock()
@@ -175,6 +223,45 @@ func (r *Retrier) runRetries(ctx context.Context) {
 	r.log.Debug("ack retrier: exit retry loop")
 }
 
+// persist compacts the pending actions and saves them, with the actions being
+// acked, in the journal if any. The caller must hold the lock.
+func (r *Retrier) persist() {
+	if r.journal == nil {
+		return
+	}
+
+	r.actions = compact(r.actions)
+	pending := compact(append(slices.Clone(r.inflight), r.actions...))
+	acks := make([]*fleetapi.AckedAction, 0, len(pending))
+	now := time.Now()
+	for _, a := range pending {
+		acks = append(acks, fleetapi.NewAckedAction(a, now))
+	}
+	r.journal.SetPendingAcks(acks)
+	if err := r.journal.Save(); err != nil {
+		r.log.Errorf("ack retrier: failed to save the pending actions: %v", err)
+	}
+}
+
+// compact removes the duplicated actions, only the most recent ack of an
+// action is kept, at the position it was acked.
+func compact(actions []fleetapi.Action) []fleetapi.Action {
+	last := make(map[string]int, len(actions))
+	for i, a := range actions {
+		last[a.ID()] = i
+	}
+	if len(last) == len(actions) {
+		return actions
+	}
+	compacted := make([]fleetapi.Action, 0, len(last))
+	for i, a := range actions {
+		if last[a.ID()] == i {
+			compacted = append(compacted, a)
+		}
+	}
+	return compacted
+}
+
 func (r *Retrier) updateRetriesMap(retries map[string]int, actions []fleetapi.Action, resp *fleetapi.AckResponse) (failed []fleetapi.Action) {
 	isFailed := func(pos int) bool {
 		// Response is nil when all actions fail, still need to update attempts bookkeeping
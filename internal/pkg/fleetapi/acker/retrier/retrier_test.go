@@ -7,11 +7,15 @@ package retrier
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -136,3 +140,133 @@ func TestRetrier(t *testing.T) {
 		})
 	}
 }
+
+type testJournal struct {
+	mx    sync.Mutex
+	acks  []*fleetapi.AckedAction
+	saves int
+}
+
+func (j *testJournal) PendingAcks() []*fleetapi.AckedAction {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	return j.acks
+}
+
+func (j *testJournal) SetPendingAcks(acks []*fleetapi.AckedAction) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	j.acks = acks
+}
+
+func (j *testJournal) Save() error {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	j.saves++
+	return nil
+}
+
+// chanAcker sends the acked actions on calls and fails with err, if set.
+type chanAcker struct {
+	calls chan []fleetapi.Action
+	err   error
+}
+
+func (a *chanAcker) AckBatch(_ context.Context, actions []fleetapi.Action) (*fleetapi.AckResponse, error) {
+	a.calls <- actions
+	if a.err != nil {
+		return nil, a.err
+	}
+	items := make([]fleetapi.AckResponseItem, len(actions))
+	for i := range items {
+		items[i].Status = http.StatusOK
+	}
+	return &fleetapi.AckResponse{Items: items}, nil
+}
+
+func TestRetrierWithJournal(t *testing.T) {
+	log, _ := logger.New("", false)
+	journal := &testJournal{}
+
+	upgrade := &fleetapi.ActionUpgrade{ActionID: "1", ActionType: fleetapi.ActionTypeUpgrade, Err: errBar}
+	unknown := &fleetapi.ActionUnknown{ActionID: "2"}
+
+	// fleet cannot be reached, the actions are retried more than the max retries
+	// and journaled
+	offline := &chanAcker{calls: make(chan []fleetapi.Action, 10), err: errBar}
+	ctx, cancel := context.WithCancel(context.Background())
+	retrier := New(offline, log,
+		WithInitialRetryInterval(10*time.Millisecond),
+		WithMaxRetryInterval(20*time.Millisecond),
+		WithMaxAckRetries(1),
+		WithJournal(journal),
+	)
+	stopped := make(chan struct{})
+	go func() {
+		retrier.Run(ctx)
+		close(stopped)
+	}()
+
+	retrier.Enqueue([]fleetapi.Action{upgrade, unknown})
+	retrier.Enqueue([]fleetapi.Action{upgrade})
+	for i := 0; i < 3; i++ {
+		<-offline.calls
+	}
+	cancel()
+	<-stopped
+
+	pending := journal.PendingAcks()
+	require.Len(t, pending, 2, "duplicated acks are compacted")
+	assert.Equal(t, "2", pending[0].ID())
+	assert.Equal(t, "1", pending[1].ID())
+	assert.Equal(t, errBar.Error(), pending[1].AckEvent().Error, "the ack event is journaled as it was acked")
+	ackedAt := pending[1].AckEvent().Timestamp
+	assert.NotEmpty(t, ackedAt)
+
+	// after a restart the journaled actions are acked first, in order
+	online := &chanAcker{calls: make(chan []fleetapi.Action, 10)}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	retrier = New(online, log,
+		WithInitialRetryInterval(10*time.Millisecond),
+		WithMaxRetryInterval(20*time.Millisecond),
+		WithJournal(journal),
+	)
+	go retrier.Run(ctx)
+
+	acked := <-online.calls
+	<-retrier.Done()
+	require.Len(t, acked, 2)
+	assert.Equal(t, "2", acked[0].ID())
+	assert.Equal(t, "1", acked[1].ID())
+	assert.Equal(t, ackedAt, acked[1].AckEvent().Timestamp, "the original ack time is kept")
+	assert.Empty(t, journal.PendingAcks())
+}
+
+func TestRetrierWithJournalRejected(t *testing.T) {
+	log, _ := logger.New("", false)
+	journal := &testJournal{}
+
+	// fleet rejects the acks, the rejections count against the max retries
+	rejecting := &chanAcker{
+		calls: make(chan []fleetapi.Action, 10),
+		err:   fmt.Errorf("%w: status code: 400", fleetapi.ErrAckRejected),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	retrier := New(rejecting, log,
+		WithInitialRetryInterval(10*time.Millisecond),
+		WithMaxRetryInterval(20*time.Millisecond),
+		WithMaxAckRetries(2),
+		WithJournal(journal),
+	)
+	go retrier.Run(ctx)
+
+	retrier.Enqueue([]fleetapi.Action{&fleetapi.ActionUnknown{ActionID: "1"}})
+	<-rejecting.calls
+	<-rejecting.calls
+	<-retrier.Done()
+
+	assert.Empty(t, rejecting.calls, "rejected actions are dropped after the max retries")
+	assert.Empty(t, journal.PendingAcks())
+}
@@ -36,6 +36,10 @@ const (
 	ActionTypeDiagnostics = "REQUEST_DIAGNOSTICS"
 	// ActionTypeDiagnostics specifies a diagnostics action.
 	ActionTypeMigrate = "MIGRATE"
+	// ActionTypeRollback specifies a rollback to a retained install action.
+	ActionTypeRollback = "ROLLBACK"
+	// ActionTypeRotateKey specifies a rotation of the agent encryption key action.
+	ActionTypeRotateKey = "ROTATE_KEY"
 )
 
 // Error values that the Action interface can return
@@ -88,6 +92,22 @@ type RetryableAction interface {
 	SetError(error)
 }
 
+// SchedulableAction is a ScheduledAction that may recur or depend on other actions.
+type SchedulableAction interface {
+	ScheduledAction
+	// Schedule returns the recurrence and dependencies of the action, nil if the action only runs once at its start
+	// time.
+	Schedule() *ActionSchedule
+}
+
+// ActionSchedule holds the recurrence and dependencies of a scheduled action.
+type ActionSchedule struct {
+	// Recurrence is a cron expression, the action runs on every occurrence until its expiration.
+	Recurrence string `json:"recurrence,omitempty" yaml:"recurrence,omitempty"`
+	// DependsOn lists the IDs of the actions that must succeed before the action runs.
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+}
+
 type Signed struct {
 	Data      string `json:"data" yaml:"data" mapstructure:"data"`
 	Signature string `json:"signature" yaml:"signature"  mapstructure:"signature"`
@@ -111,6 +131,10 @@ func NewAction(actionType string) Action {
 		action = &ActionPolicyChange{}
 	case ActionTypePolicyReassign:
 		action = &ActionPolicyReassign{}
+	case ActionTypeRollback:
+		action = &ActionRollback{}
+	case ActionTypeRotateKey:
+		action = &ActionRotateKey{}
 	case ActionTypeSettings:
 		action = &ActionSettings{}
 	case ActionTypeUnenroll:
@@ -249,10 +273,11 @@ func (a *ActionPolicyChange) AckEvent() AckEvent {
 
 // ActionUpgrade is a request for agent to upgrade.
 type ActionUpgrade struct {
-	ActionID         string `json:"id" yaml:"id" mapstructure:"id"`
-	ActionType       string `json:"type" yaml:"type" mapstructure:"type"`
-	ActionStartTime  string `json:"start_time" yaml:"start_time,omitempty" mapstructure:"-"` // TODO change to time.Time in unmarshal
-	ActionExpiration string `json:"expiration" yaml:"expiration,omitempty" mapstructure:"-"`
+	ActionID         string          `json:"id" yaml:"id" mapstructure:"id"`
+	ActionType       string          `json:"type" yaml:"type" mapstructure:"type"`
+	ActionStartTime  string          `json:"start_time" yaml:"start_time,omitempty" mapstructure:"-"` // TODO change to time.Time in unmarshal
+	ActionExpiration string          `json:"expiration" yaml:"expiration,omitempty" mapstructure:"-"`
+	ActionSchedule   *ActionSchedule `json:"schedule,omitempty" yaml:"schedule,omitempty" mapstructure:"-"`
 	// does anyone know why those aren't mapped to mapstructure?
 	Data   ActionUpgradeData `json:"data,omitempty" mapstructure:"-"`
 	Signed *Signed           `json:"signed,omitempty" yaml:"signed,omitempty" mapstructure:"signed,omitempty"`
@@ -329,6 +354,11 @@ func (a *ActionUpgrade) Expiration() (time.Time, error) {
 	return ts.UTC(), nil
 }
 
+// Schedule returns the recurrence and dependencies of the action or nil if there are none.
+func (a *ActionUpgrade) Schedule() *ActionSchedule {
+	return a.ActionSchedule
+}
+
 // RetryAttempt will return the retry_attempt of the action
 func (a *ActionUpgrade) RetryAttempt() int {
 	return a.Data.Retry
@@ -479,6 +509,84 @@ type ActionMigrateData struct {
 	Settings json.RawMessage `json:"settings" yaml:"settings,omitempty"`
 }
 
+// ActionRollback is a request to switch the agent to a retained install of a previous version.
+type ActionRollback struct {
+	ActionID   string             `json:"id" yaml:"id"`
+	ActionType string             `json:"type" yaml:"type"`
+	Data       ActionRollbackData `json:"data,omitempty"`
+
+	Err error `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// ID returns the ID of the Action.
+func (a *ActionRollback) ID() string {
+	return a.ActionID
+}
+
+// Type returns the type of the Action.
+func (a *ActionRollback) Type() string {
+	return a.ActionType
+}
+
+func (a *ActionRollback) String() string {
+	var s strings.Builder
+	s.WriteString("id: ")
+	s.WriteString(a.ActionID)
+	s.WriteString(", type: ")
+	s.WriteString(a.ActionType)
+	s.WriteString(", version: ")
+	s.WriteString(a.Data.Version)
+	return s.String()
+}
+
+func (a *ActionRollback) AckEvent() AckEvent {
+	event := newAckEvent(a.ActionID, a.ActionType)
+	if a.Err != nil {
+		event.Error = a.Err.Error()
+	}
+	return event
+}
+
+type ActionRollbackData struct {
+	// Version: version of the retained install to roll back to.
+	Version string `json:"version" yaml:"version"`
+}
+
+// ActionRotateKey is a request to rotate the key the agent encrypts its configuration and state with.
+type ActionRotateKey struct {
+	ActionID   string `json:"id" yaml:"id"`
+	ActionType string `json:"type" yaml:"type"`
+
+	Err error `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+// ID returns the ID of the Action.
+func (a *ActionRotateKey) ID() string {
+	return a.ActionID
+}
+
+// Type returns the type of the Action.
+func (a *ActionRotateKey) Type() string {
+	return a.ActionType
+}
+
+func (a *ActionRotateKey) String() string {
+	var s strings.Builder
+	s.WriteString("id: ")
+	s.WriteString(a.ActionID)
+	s.WriteString(", type: ")
+	s.WriteString(a.ActionType)
+	return s.String()
+}
+
+func (a *ActionRotateKey) AckEvent() AckEvent {
+	event := newAckEvent(a.ActionID, a.ActionType)
+	if a.Err != nil {
+		event.Error = a.Err.Error()
+	}
+	return event
+}
+
 func (a *ActionSettings) AckEvent() AckEvent {
 	return newAckEvent(a.ActionID, a.ActionType)
 }
@@ -520,12 +628,16 @@ func (a *ActionCancel) AckEvent() AckEvent {
 }
 
 // ActionDiagnostics is a request to gather and upload a diagnostics bundle.
+// It may be scheduled to run at its start time, on a recurring schedule or once other actions completed.
 type ActionDiagnostics struct {
-	ActionID   string                `json:"id"`
-	ActionType string                `json:"type"`
-	Data       ActionDiagnosticsData `json:"data"`
-	UploadID   string                `json:"-"`
-	Err        error                 `json:"-"`
+	ActionID         string                `json:"id"`
+	ActionType       string                `json:"type"`
+	ActionStartTime  string                `json:"start_time,omitempty"`
+	ActionExpiration string                `json:"expiration,omitempty"`
+	ActionSchedule   *ActionSchedule       `json:"schedule,omitempty"`
+	Data             ActionDiagnosticsData `json:"data"`
+	UploadID         string                `json:"-"`
+	Err              error                 `json:"-"`
 }
 
 type ActionDiagnosticsData struct {
@@ -552,6 +664,35 @@ func (a *ActionDiagnostics) String() string {
 	return s.String()
 }
 
+// StartTime returns the start time of the action as a UTC time.Time or ErrNoStartTime if there is no start time.
+func (a *ActionDiagnostics) StartTime() (time.Time, error) {
+	if a.ActionStartTime == "" {
+		return time.Time{}, ErrNoStartTime
+	}
+	ts, err := time.Parse(time.RFC3339, a.ActionStartTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ts.UTC(), nil
+}
+
+// Expiration returns the expiration as a UTC time.Time or ErrNoExpiration if there is no expiration.
+func (a *ActionDiagnostics) Expiration() (time.Time, error) {
+	if a.ActionExpiration == "" {
+		return time.Time{}, ErrNoExpiration
+	}
+	ts, err := time.Parse(time.RFC3339, a.ActionExpiration)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ts.UTC(), nil
+}
+
+// Schedule returns the recurrence and dependencies of the action or nil if there are none.
+func (a *ActionDiagnostics) Schedule() *ActionSchedule {
+	return a.ActionSchedule
+}
+
 func (a *ActionDiagnostics) AckEvent() AckEvent {
 	event := newAckEvent(a.ActionID, a.ActionType)
 	if a.Err != nil {
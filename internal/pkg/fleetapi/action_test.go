@@ -7,6 +7,7 @@ package fleetapi
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
@@ -174,6 +175,42 @@ func TestActionsUnmarshalJSON(t *testing.T) {
 		require.Len(t, action.Data.AdditionalMetrics, 1)
 		assert.Equal(t, "CPU", action.Data.AdditionalMetrics[0])
 	})
+	t.Run("ActionDiagnostics with schedule", func(t *testing.T) {
+		p := []byte(`[{"id":"testid","type":"REQUEST_DIAGNOSTICS","start_time":"2022-01-02T12:00:00Z","expiration":"2022-02-02T12:00:00Z","schedule":{"recurrence":"0 2 * * *","depends_on":["other"]},"data":{}}]`)
+		a := &Actions{}
+		err := a.UnmarshalJSON(p)
+		require.Nil(t, err)
+		action, ok := (*a)[0].(SchedulableAction)
+		require.True(t, ok, "diagnostics action must be schedulable")
+		start, err := action.StartTime()
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2022, 1, 2, 12, 0, 0, 0, time.UTC), start)
+		exp, err := action.Expiration()
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2022, 2, 2, 12, 0, 0, 0, time.UTC), exp)
+		assert.Equal(t, &ActionSchedule{Recurrence: "0 2 * * *", DependsOn: []string{"other"}}, action.Schedule())
+	})
+	t.Run("ActionRollback", func(t *testing.T) {
+		p := []byte(`[{"id":"testid","type":"ROLLBACK","data":{"version":"1.2.3"}}]`)
+		a := &Actions{}
+		err := a.UnmarshalJSON(p)
+		require.Nil(t, err)
+		action, ok := (*a)[0].(*ActionRollback)
+		require.True(t, ok, "unable to cast action to specific type")
+		assert.Equal(t, "testid", action.ActionID)
+		assert.Equal(t, ActionTypeRollback, action.ActionType)
+		assert.Equal(t, "1.2.3", action.Data.Version)
+	})
+	t.Run("ActionRotateKey", func(t *testing.T) {
+		p := []byte(`[{"id":"testid","type":"ROTATE_KEY"}]`)
+		a := &Actions{}
+		err := a.UnmarshalJSON(p)
+		require.Nil(t, err)
+		action, ok := (*a)[0].(*ActionRotateKey)
+		require.True(t, ok, "unable to cast action to specific type")
+		assert.Equal(t, "testid", action.ActionID)
+		assert.Equal(t, ActionTypeRotateKey, action.ActionType)
+	})
 }
 
 func TestActionUnenrollMarshalMap(t *testing.T) {
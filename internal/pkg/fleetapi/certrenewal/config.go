@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package certrenewal
+
+import (
+	"errors"
+	"time"
+)
+
+// Config is the configuration of the Fleet client certificate renewal.
+type Config struct {
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// RenewBefore is how long before the certificate expires it's renewed.
+	RenewBefore time.Duration `config:"renew_before" yaml:"renew_before,omitempty"`
+	// CheckInterval is the time between two checks of the certificate expiry,
+	// and between two attempts to obtain the renewed certificate.
+	CheckInterval time.Duration `config:"check_interval" yaml:"check_interval,omitempty"`
+	// Endpoint is the URL the certificate signing request is posted to, the
+	// renewed certificate is expected in the response.
+	Endpoint string `config:"endpoint" yaml:"endpoint,omitempty"`
+	// DropDir is the directory the certificate signing request is written
+	// to, the renewed certificate is expected to be dropped next to it.
+	DropDir string `config:"drop_dir" yaml:"drop_dir,omitempty"`
+}
+
+// DefaultConfig returns the default configuration, renewal is disabled.
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:       false,
+		RenewBefore:   30 * 24 * time.Hour,
+		CheckInterval: time.Hour,
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Endpoint == "" && c.DropDir == "" {
+		return errors.New("certificate renewal requires either an endpoint or a drop_dir")
+	}
+	if c.Endpoint != "" && c.DropDir != "" {
+		return errors.New("certificate renewal accepts either an endpoint or a drop_dir, not both")
+	}
+	if c.RenewBefore <= 0 {
+		return errors.New("certificate renewal renew_before must be greater than 0")
+	}
+	if c.CheckInterval <= 0 {
+		return errors.New("certificate renewal check_interval must be greater than 0")
+	}
+	return nil
+}
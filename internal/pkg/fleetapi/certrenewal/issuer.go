@@ -0,0 +1,143 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package certrenewal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+)
+
+const (
+	// dropCSRFile is the certificate signing request written to the drop directory.
+	dropCSRFile = "fleet-client.csr"
+	// dropCertFile is the renewed certificate expected in the drop directory.
+	dropCertFile = "fleet-client.crt"
+
+	// maxCertificateSize is the maximum size of a renewed certificate chain.
+	maxCertificateSize = 1 << 20
+)
+
+// ErrPending is returned by an Issuer when the renewed certificate is not
+// available yet.
+var ErrPending = errors.New("renewed certificate is not available yet")
+
+// Issuer obtains a certificate for a certificate signing request.
+type Issuer interface {
+	// Issue returns the PEM encoded certificate chain issued for the PEM
+	// encoded certificate signing request, or ErrPending when it isn't
+	// available yet.
+	Issue(ctx context.Context, csr []byte, clientCfg remote.Config) ([]byte, error)
+}
+
+// NewIssuer returns the Issuer for the configuration.
+func NewIssuer(cfg *Config) Issuer {
+	if cfg.DropDir != "" {
+		return &fileIssuer{dir: cfg.DropDir}
+	}
+	return &endpointIssuer{url: cfg.Endpoint}
+}
+
+// endpointIssuer posts the certificate signing request to an endpoint, using
+// the current client certificate to authenticate.
+type endpointIssuer struct {
+	url string
+}
+
+func (e *endpointIssuer) Issue(ctx context.Context, csr []byte, clientCfg remote.Config) ([]byte, error) {
+	rt, err := clientCfg.Transport.RoundTripper()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the transport: %w", err)
+	}
+	client := http.Client{Transport: rt, Timeout: clientCfg.Transport.Timeout}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(csr))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/pkcs10")
+	req.Header.Set("Accept", "application/x-pem-file")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send the certificate signing request to %s: %w", e.url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCertificateSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response from %s: %w", e.url, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return body, nil
+	case http.StatusAccepted:
+		return nil, ErrPending
+	default:
+		return nil, fmt.Errorf("certificate renewal endpoint %s returned status %d: %s", e.url, resp.StatusCode, string(body))
+	}
+}
+
+// fileIssuer writes the certificate signing request to a directory and waits
+// for the renewed certificate to be dropped next to it.
+type fileIssuer struct {
+	dir string
+}
+
+func (f *fileIssuer) Issue(_ context.Context, csr []byte, _ remote.Config) ([]byte, error) {
+	csrPath := filepath.Join(f.dir, dropCSRFile)
+	certPath := filepath.Join(f.dir, dropCertFile)
+
+	current, err := os.ReadFile(csrPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", csrPath, err)
+	}
+	if !bytes.Equal(current, csr) {
+		// a new request, a certificate dropped for a previous one is stale
+		if err := os.Remove(certPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale %s: %w", certPath, err)
+		}
+		if err := os.MkdirAll(f.dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", f.dir, err)
+		}
+		if err := os.WriteFile(csrPath, csr, 0o640); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", csrPath, err)
+		}
+		return nil, ErrPending
+	}
+
+	cert, err := os.ReadFile(certPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", certPath, err)
+	}
+	return cert, nil
+}
+
+// Done removes the certificate signing request and the renewed certificate
+// once the renewed certificate is in use.
+func (f *fileIssuer) Done() error {
+	return errors.Join(
+		removeIfExists(filepath.Join(f.dir, dropCSRFile)),
+		removeIfExists(filepath.Join(f.dir, dropCertFile)),
+	)
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package certrenewal renews the client certificate used for the mutual TLS
+// connection with Fleet Server before it expires.
+package certrenewal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// VaultKey is the key the renewed certificate is stored under in the vault.
+const VaultKey = "fleet_client_certificate"
+
+type clientSetter interface {
+	SetClient(client.Sender)
+}
+
+// storedCertificate is the renewed certificate and its key, and the key and
+// the certificate signing request of a renewal in progress, as stored in the vault.
+type storedCertificate struct {
+	Certificate string `json:"certificate,omitempty"`
+	Key         string `json:"key,omitempty"`
+	PendingKey  string `json:"pending_key,omitempty"`
+	PendingCSR  string `json:"pending_csr,omitempty"`
+}
+
+// Renewer renews the Fleet client certificate before it expires. The renewed
+// certificate and its key are kept in the vault, they take precedence over the
+// certificate from the configuration. Once renewed, a new Fleet client is
+// handed to the client setters, no restart is needed.
+type Renewer struct {
+	log       *logger.Logger
+	cfg       *Config
+	apiKey    string
+	issuer    Issuer
+	vaultOpts []vault.OptionFunc
+	now       func() time.Time
+
+	mx      sync.Mutex
+	base    remote.Config
+	stored  storedCertificate
+	setters []clientSetter
+}
+
+// New creates a Renewer for the Fleet client configuration, restoring the
+// certificate renewed by a previous run from the vault.
+func New(ctx context.Context, log *logger.Logger, cfg *Config, apiKey string, base remote.Config, vaultOpts ...vault.OptionFunc) (*Renewer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	stored, err := load(ctx, vaultOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the renewed fleet client certificate from the vault: %w", err)
+	}
+
+	return &Renewer{
+		log:       log,
+		cfg:       cfg,
+		apiKey:    apiKey,
+		issuer:    NewIssuer(cfg),
+		vaultOpts: vaultOpts,
+		now:       time.Now,
+		base:      base,
+		stored:    stored,
+	}, nil
+}
+
+// AddSetter adds a client setter receiving the Fleet client once the
+// certificate is renewed.
+func (r *Renewer) AddSetter(cs clientSetter) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.setters = append(r.setters, cs)
+}
+
+// ClientConfig returns cfg using the renewed certificate, if there is one.
+func (r *Renewer) ClientConfig(cfg remote.Config) remote.Config {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.apply(cfg)
+}
+
+// SetClientConfig sets the Fleet client configuration in use, the Fleet
+// client created once the certificate is renewed uses it.
+func (r *Renewer) SetClientConfig(cfg remote.Config) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.base = cfg
+}
+
+func (r *Renewer) apply(cfg remote.Config) remote.Config {
+	if r.stored.Certificate == "" {
+		return cfg
+	}
+
+	tlsCfg := tlscommon.Config{}
+	if cfg.Transport.TLS != nil {
+		tlsCfg = *cfg.Transport.TLS
+	}
+	tlsCfg.Certificate = tlscommon.CertificateConfig{
+		Certificate: r.stored.Certificate,
+		Key:         r.stored.Key,
+	}
+	cfg.Transport.TLS = &tlsCfg
+	return cfg
+}
+
+// Run checks the certificate expiry every check interval until ctx is
+// cancelled.
+func (r *Renewer) Run(ctx context.Context) {
+	t := time.NewTimer(0)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := r.check(ctx); err != nil {
+				r.log.Errorf("failed to renew the fleet client certificate: %v", err)
+			}
+			t.Reset(r.cfg.CheckInterval)
+		}
+	}
+}
+
+// check renews the certificate when it's about to expire.
+func (r *Renewer) check(ctx context.Context) error {
+	r.mx.Lock()
+	clientCfg := r.apply(r.base)
+	stored := r.stored
+	r.mx.Unlock()
+
+	current, err := currentCertificate(r.log, clientCfg)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		r.log.Debug("no fleet client certificate configured, nothing to renew")
+		return nil
+	}
+	if r.now().Before(current.NotAfter.Add(-r.cfg.RenewBefore)) {
+		return nil
+	}
+	r.log.Infof("fleet client certificate expires at %s, renewing it", current.NotAfter.UTC())
+
+	key, csr, err := r.pendingRequest(ctx, &stored, current)
+	if err != nil {
+		return err
+	}
+
+	certPEM, err := r.issuer.Issue(ctx, csr, clientCfg)
+	if errors.Is(err, ErrPending) {
+		r.log.Infof("waiting for the renewed fleet client certificate, checking again in %s", r.cfg.CheckInterval)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	renewed, err := validateRenewed(certPEM, key, current)
+	if err != nil {
+		return err
+	}
+
+	stored = storedCertificate{
+		Certificate: string(certPEM),
+		Key:         stored.PendingKey,
+	}
+	if err := r.save(ctx, stored); err != nil {
+		return fmt.Errorf("failed to store the renewed fleet client certificate in the vault: %w", err)
+	}
+
+	r.mx.Lock()
+	r.stored = stored
+	newCfg := r.apply(r.base)
+	setters := r.setters
+	r.mx.Unlock()
+
+	fleetClient, err := client.NewAuthWithConfig(r.log, r.apiKey, newCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create the fleet client with the renewed certificate: %w", err)
+	}
+	for _, cs := range setters {
+		cs.SetClient(fleetClient)
+	}
+
+	if done, ok := r.issuer.(interface{ Done() error }); ok {
+		if err := done.Done(); err != nil {
+			r.log.Warnf("failed to clean up the certificate renewal files: %v", err)
+		}
+	}
+
+	r.log.Infof("fleet client certificate renewed, valid until %s", renewed.NotAfter.UTC())
+	return nil
+}
+
+// pendingRequest returns the key and the certificate signing request of the
+// renewal in progress, generating and storing new ones if there are none. The
+// request is stored, so the issuer gets the same request on every check until
+// the certificate is renewed.
+func (r *Renewer) pendingRequest(ctx context.Context, stored *storedCertificate, current *x509.Certificate) (crypto.Signer, []byte, error) {
+	key := parsePendingKey(stored.PendingKey)
+	if key == nil && stored.PendingKey != "" {
+		r.log.Warn("discarding the invalid pending fleet client certificate key")
+	}
+	if key != nil && csrMatchesKey([]byte(stored.PendingCSR), key) {
+		return key, []byte(stored.PendingCSR), nil
+	}
+
+	pending := *stored
+	if key == nil {
+		newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate the fleet client certificate key: %w", err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(newKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode the fleet client certificate key: %w", err)
+		}
+		key = newKey
+		pending.PendingKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	}
+
+	csr, err := createCSR(current, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	pending.PendingCSR = string(csr)
+	if err := r.save(ctx, pending); err != nil {
+		return nil, nil, fmt.Errorf("failed to store the fleet client certificate key in the vault: %w", err)
+	}
+
+	*stored = pending
+	r.mx.Lock()
+	r.stored.PendingKey = pending.PendingKey
+	r.stored.PendingCSR = pending.PendingCSR
+	r.mx.Unlock()
+	return key, csr, nil
+}
+
+// parsePendingKey returns the PEM encoded key of the renewal in progress, nil
+// if there is none or it's invalid.
+func parsePendingKey(keyPEM string) crypto.Signer {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil
+	}
+	signer, _ := key.(crypto.Signer)
+	return signer
+}
+
+// csrMatchesKey returns true when the PEM encoded certificate signing request
+// is valid and for the public key of key.
+func csrMatchesKey(csrPEM []byte, key crypto.Signer) bool {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return false
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil || csr.CheckSignature() != nil {
+		return false
+	}
+	pub, ok := csr.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(key.Public())
+}
+
+func (r *Renewer) save(ctx context.Context, stored storedCertificate) error {
+	b, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	v, err := vault.New(ctx, r.vaultOpts...)
+	if err != nil {
+		return fmt.Errorf("could not create new vault: %w", err)
+	}
+	defer v.Close()
+	return v.Set(ctx, VaultKey, b)
+}
+
+func load(ctx context.Context, vaultOpts []vault.OptionFunc) (storedCertificate, error) {
+	var stored storedCertificate
+
+	// open vault readonly, the certificate was never renewed if it doesn't exist
+	v, err := vault.New(ctx, append(vaultOpts, vault.WithReadonly(true))...)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stored, nil
+	}
+	if err != nil {
+		return stored, err
+	}
+	defer v.Close()
+
+	exists, err := v.Exists(ctx, VaultKey)
+	if err != nil || !exists {
+		return stored, err
+	}
+
+	b, err := v.Get(ctx, VaultKey)
+	if err != nil {
+		return stored, err
+	}
+	err = json.Unmarshal(b, &stored)
+	return stored, err
+}
+
+// currentCertificate returns the client certificate in use, nil if there is
+// none.
+func currentCertificate(log *logger.Logger, cfg remote.Config) (*x509.Certificate, error) {
+	if cfg.Transport.TLS == nil || cfg.Transport.TLS.Certificate.Certificate == "" {
+		return nil, nil
+	}
+
+	data, err := tlscommon.ReadPEMFile(log, cfg.Transport.TLS.Certificate.Certificate, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the fleet client certificate: %w", err)
+	}
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the fleet client certificate: %w", err)
+	}
+	return certs[0], nil
+}
+
+// createCSR returns the PEM encoded certificate signing request for a
+// certificate with the same subject as current.
+func createCSR(current *x509.Certificate, key crypto.Signer) ([]byte, error) {
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:        current.Subject,
+		DNSNames:       current.DNSNames,
+		EmailAddresses: current.EmailAddresses,
+		IPAddresses:    current.IPAddresses,
+		URIs:           current.URIs,
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the certificate signing request: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}), nil
+}
+
+// validateRenewed checks the renewed certificate matches the key of the
+// request and outlives the current certificate.
+func validateRenewed(certPEM []byte, key crypto.Signer, current *x509.Certificate) (*x509.Certificate, error) {
+	certs, err := parseCertificates(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the renewed fleet client certificate: %w", err)
+	}
+	renewed := certs[0]
+
+	pub, ok := renewed.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(key.Public()) {
+		return nil, errors.New("the renewed fleet client certificate does not match the certificate signing request key")
+	}
+	if !renewed.NotAfter.After(current.NotAfter) {
+		return nil, fmt.Errorf("the renewed fleet client certificate expires at %s, not after the current one", renewed.NotAfter.UTC())
+	}
+	return renewed, nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
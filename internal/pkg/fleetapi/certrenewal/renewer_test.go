@@ -0,0 +1,340 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package certrenewal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns a PEM encoded certificate for pub valid for validity.
+func (ca *testCA) issue(t *testing.T, subject pkix.Name, dnsNames []string, pub crypto.PublicKey, validity time.Duration) []byte {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, pub, ca.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// sign issues a certificate for a PEM encoded certificate signing request.
+func (ca *testCA) sign(t *testing.T, csrPEM []byte, validity time.Duration) []byte {
+	t.Helper()
+	block, _ := pem.Decode(csrPEM)
+	require.NotNil(t, block)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	require.NoError(t, err)
+	require.NoError(t, csr.CheckSignature())
+	return ca.issue(t, csr.Subject, csr.DNSNames, csr.PublicKey, validity)
+}
+
+// clientConfig returns a fleet client configuration using a client
+// certificate valid for validity.
+func clientConfig(t *testing.T, ca *testCA, validity time.Duration) remote.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	certPEM := ca.issue(t, pkix.Name{CommonName: "agent-1"}, []string{"agent-1.example.com"}, key.Public(), validity)
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+
+	cfg := remote.DefaultClientConfig()
+	cfg.Protocol = remote.ProtocolHTTPS
+	cfg.Host = "fleet.example.com:8220"
+	cfg.Transport.TLS = &tlscommon.Config{
+		Certificate: tlscommon.CertificateConfig{Certificate: certPath, Key: keyPath},
+	}
+	return cfg
+}
+
+type issuerFunc func(ctx context.Context, csr []byte, clientCfg remote.Config) ([]byte, error)
+
+func (f issuerFunc) Issue(ctx context.Context, csr []byte, clientCfg remote.Config) ([]byte, error) {
+	return f(ctx, csr, clientCfg)
+}
+
+type testSetter struct {
+	clients []client.Sender
+}
+
+func (s *testSetter) SetClient(c client.Sender) {
+	s.clients = append(s.clients, c)
+}
+
+func newTestRenewer(t *testing.T, vaultPath string, base remote.Config, issuer Issuer) *Renewer {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Enabled = true
+	cfg.DropDir = t.TempDir()
+
+	log, _ := loggertest.New("certrenewal")
+	r, err := New(context.Background(), log, cfg, "api-key", base,
+		vault.WithVaultPath(vaultPath),
+		vault.WithUnprivileged(true))
+	require.NoError(t, err)
+	r.issuer = issuer
+	return r
+}
+
+func TestRenewer(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "vault does not use NewGCMWithRandomNonce.")
+	ctx := context.Background()
+	ca := newTestCA(t)
+	vaultPath := filepath.Join(t.TempDir(), "vault")
+
+	t.Run("certificate not about to expire", func(t *testing.T) {
+		issued := false
+		r := newTestRenewer(t, vaultPath, clientConfig(t, ca, 90*24*time.Hour), issuerFunc(func(context.Context, []byte, remote.Config) ([]byte, error) {
+			issued = true
+			return nil, nil
+		}))
+
+		require.NoError(t, r.check(ctx))
+		assert.False(t, issued, "certificate should not be renewed")
+	})
+
+	t.Run("certificate about to expire", func(t *testing.T) {
+		base := clientConfig(t, ca, 24*time.Hour)
+		var csrs [][]byte
+		r := newTestRenewer(t, vaultPath, base, issuerFunc(func(_ context.Context, csr []byte, _ remote.Config) ([]byte, error) {
+			csrs = append(csrs, csr)
+			if len(csrs) == 1 {
+				return nil, ErrPending
+			}
+			return ca.sign(t, csr, 90*24*time.Hour), nil
+		}))
+		setter := &testSetter{}
+		r.AddSetter(setter)
+
+		// the renewed certificate is not available yet
+		require.NoError(t, r.check(ctx))
+		assert.Empty(t, setter.clients)
+		assert.Equal(t, base, r.ClientConfig(base), "the configured certificate is used until renewed")
+
+		require.NoError(t, r.check(ctx))
+		require.Len(t, csrs, 2)
+		assert.Equal(t, csrs[0], csrs[1], "the pending request should be reused")
+		require.Len(t, setter.clients, 1, "a new fleet client should be set")
+
+		renewedCfg := r.ClientConfig(base)
+		require.NotEqual(t, base, renewedCfg)
+		renewed, err := currentCertificate(r.log, renewedCfg)
+		require.NoError(t, err)
+		assert.Equal(t, "agent-1", renewed.Subject.CommonName)
+		assert.Equal(t, []string{"agent-1.example.com"}, renewed.DNSNames)
+		_, err = tlscommon.LoadCertificate(&renewedCfg.Transport.TLS.Certificate)
+		require.NoError(t, err, "renewed certificate and key should be usable")
+		assert.NotEqual(t, base.Transport.TLS.Certificate, renewedCfg.Transport.TLS.Certificate)
+		assert.Equal(t, base.Host, renewedCfg.Host)
+
+		// restored from the vault on restart
+		restarted := newTestRenewer(t, vaultPath, base, nil)
+		assert.Equal(t, renewedCfg, restarted.ClientConfig(base))
+		require.NoError(t, restarted.check(ctx), "the renewed certificate is not about to expire")
+	})
+}
+
+func TestRenewerWithFileIssuer(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "vault does not use NewGCMWithRandomNonce.")
+	ctx := context.Background()
+	ca := newTestCA(t)
+	vaultPath := filepath.Join(t.TempDir(), "vault")
+
+	base := clientConfig(t, ca, 24*time.Hour)
+	r := newTestRenewer(t, vaultPath, base, nil)
+	r.issuer = NewIssuer(r.cfg)
+	setter := &testSetter{}
+	r.AddSetter(setter)
+	csrPath := filepath.Join(r.cfg.DropDir, dropCSRFile)
+	certPath := filepath.Join(r.cfg.DropDir, dropCertFile)
+
+	require.NoError(t, r.check(ctx))
+	csr, err := os.ReadFile(csrPath)
+	require.NoError(t, err)
+
+	// the request is not regenerated while waiting for the certificate
+	require.NoError(t, r.check(ctx))
+	again, err := os.ReadFile(csrPath)
+	require.NoError(t, err)
+	assert.Equal(t, csr, again)
+
+	// nor after a restart
+	r = newTestRenewer(t, vaultPath, base, nil)
+	r.issuer = &fileIssuer{dir: filepath.Dir(csrPath)}
+	r.AddSetter(setter)
+	require.NoError(t, r.check(ctx))
+	again, err = os.ReadFile(csrPath)
+	require.NoError(t, err)
+	assert.Equal(t, csr, again)
+	assert.Empty(t, setter.clients)
+
+	require.NoError(t, os.WriteFile(certPath, ca.sign(t, csr, 90*24*time.Hour), 0o600))
+	require.NoError(t, r.check(ctx))
+	require.Len(t, setter.clients, 1, "a new fleet client should be set")
+	assert.NoFileExists(t, csrPath)
+	assert.NoFileExists(t, certPath)
+}
+
+func TestFileIssuer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "renewal")
+	issuer := NewIssuer(&Config{DropDir: dir})
+	csr := []byte("csr")
+
+	_, err := issuer.Issue(context.Background(), csr, remote.Config{})
+	require.ErrorIs(t, err, ErrPending)
+	written, err := os.ReadFile(filepath.Join(dir, dropCSRFile))
+	require.NoError(t, err)
+	assert.Equal(t, csr, written)
+
+	_, err = issuer.Issue(context.Background(), csr, remote.Config{})
+	require.ErrorIs(t, err, ErrPending, "certificate not dropped yet")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, dropCertFile), []byte("cert"), 0o600))
+	cert, err := issuer.Issue(context.Background(), csr, remote.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("cert"), cert)
+
+	// a new request discards the certificate dropped for the previous one
+	_, err = issuer.Issue(context.Background(), []byte("new csr"), remote.Config{})
+	require.ErrorIs(t, err, ErrPending)
+	assert.NoFileExists(t, filepath.Join(dir, dropCertFile))
+
+	require.NoError(t, issuer.(*fileIssuer).Done())
+	assert.NoFileExists(t, filepath.Join(dir, dropCSRFile))
+}
+
+func TestEndpointIssuer(t *testing.T) {
+	status := http.StatusAccepted
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/pkcs10", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "csr", string(body))
+
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte("cert"))
+		}
+	}))
+	defer s.Close()
+
+	issuer := NewIssuer(&Config{Endpoint: s.URL + "/renew"})
+	clientCfg := remote.DefaultClientConfig()
+
+	_, err := issuer.Issue(context.Background(), []byte("csr"), clientCfg)
+	require.ErrorIs(t, err, ErrPending)
+
+	status = http.StatusOK
+	cert, err := issuer.Issue(context.Background(), []byte("csr"), clientCfg)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("cert"), cert)
+
+	status = http.StatusForbidden
+	_, err = issuer.Issue(context.Background(), []byte("csr"), clientCfg)
+	assert.ErrorContains(t, err, "returned status 403")
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    func(c *Config)
+		errMsg string
+	}{
+		{name: "disabled", cfg: func(c *Config) {}},
+		{name: "endpoint", cfg: func(c *Config) { c.Enabled = true; c.Endpoint = "https://ca.example.com" }},
+		{name: "drop dir", cfg: func(c *Config) { c.Enabled = true; c.DropDir = "/tmp/renewal" }},
+		{
+			name:   "no source",
+			cfg:    func(c *Config) { c.Enabled = true },
+			errMsg: "requires either an endpoint or a drop_dir",
+		},
+		{
+			name:   "both sources",
+			cfg:    func(c *Config) { c.Enabled = true; c.Endpoint = "https://ca.example.com"; c.DropDir = "/tmp/renewal" },
+			errMsg: "either an endpoint or a drop_dir, not both",
+		},
+		{
+			name:   "invalid renew_before",
+			cfg:    func(c *Config) { c.Enabled = true; c.DropDir = "/tmp/renewal"; c.RenewBefore = 0 },
+			errMsg: "renew_before must be greater than 0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tc.cfg(cfg)
+			err := cfg.Validate()
+			if tc.errMsg != "" {
+				assert.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
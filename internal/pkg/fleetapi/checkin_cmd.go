@@ -37,6 +37,9 @@ type CheckinComponent struct {
 	Status  string        `json:"status"`
 	Message string        `json:"message"`
 	Units   []CheckinUnit `json:"units,omitempty"`
+	// RemovedUnits are the IDs of the units removed since the last checkin,
+	// only set on delta checkins.
+	RemovedUnits []string `json:"removed_units,omitempty"`
 }
 
 // CheckinRequest consists of multiple events reported to fleet ui.
@@ -47,6 +50,24 @@ type CheckinRequest struct {
 	Message        string             `json:"message"`    // V2 Agent message
 	Components     []CheckinComponent `json:"components"` // V2 Agent components
 	UpgradeDetails *details.Details   `json:"upgrade_details,omitempty"`
+	// ComponentsDelta is set when Components only contains the components,
+	// and the units within them, that changed since the last checkin.
+	ComponentsDelta bool `json:"components_delta,omitempty"`
+	// RemovedComponents are the IDs of the components removed since the last
+	// checkin, only set on delta checkins.
+	RemovedComponents []string `json:"removed_components,omitempty"`
+	// StatusHistory are the status transitions that happened while the agent
+	// could not check in, oldest first. Only sent when fleet-server accepts it.
+	StatusHistory []CheckinStatusTransition `json:"status_history,omitempty"`
+}
+
+// CheckinStatusTransition is a status of the agent and its components, recorded
+// when it changed while Fleet Server could not be reached.
+type CheckinStatusTransition struct {
+	Timestamp  time.Time          `json:"timestamp"`
+	Status     string             `json:"status"`
+	Message    string             `json:"message"`
+	Components []CheckinComponent `json:"components,omitempty"`
 }
 
 // SerializableEvent is a representation of the event to be send to the Fleet Server API via the checkin
@@ -72,9 +93,13 @@ func (e *CheckinRequest) Validate() error {
 // CheckinResponse is the response send back from the server which contains all the action that
 // need to be executed or proxy to running processes.
 type CheckinResponse struct {
-	AckToken     string  `json:"ack_token"`
-	Actions      Actions `json:"actions"`
-	FleetWarning string  `json:"-"`
+	AckToken string  `json:"ack_token"`
+	Actions  Actions `json:"actions"`
+	// ComponentsDelta is set when fleet-server accepts delta checkins.
+	ComponentsDelta bool `json:"components_delta,omitempty"`
+	// StatusHistory is set when fleet-server accepts the status history.
+	StatusHistory bool   `json:"status_history,omitempty"`
+	FleetWarning  string `json:"-"`
 }
 
 // Validate validates the response send from the server.
@@ -43,6 +43,8 @@ type Sender interface {
 const defaultFleetApiVersion = "2023-06-01"
 
 var baseRoundTrippers = func(rt http.RoundTripper) (http.RoundTripper, error) {
+	rt = NewGzipRequestRoundTripper(rt)
+
 	rt = NewFleetUserAgentRoundTripper(rt, release.Version())
 
 	rt = NewElasticApiVersionRoundTripper(rt, defaultFleetApiVersion)
@@ -75,6 +77,7 @@ func init() {
 // - Send the API Key on every HTTP request.
 // - Ensure a minimun version of fleet-server is required.
 // - Send the Fleet User Agent on every HTTP request.
+// - Compress the request bodies once fleet-server accepts it.
 func NewAuthWithConfig(log *logger.Logger, apiKey string, cfg remote.Config) (*remote.Client, error) {
 	return remote.NewWithConfig(log, cfg, func(rt http.RoundTripper) (http.RoundTripper, error) {
 		rt, err := baseRoundTrippers(rt)
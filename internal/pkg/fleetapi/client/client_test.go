@@ -5,6 +5,7 @@
 package client
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -320,3 +321,58 @@ func Test_CheckRemote(t *testing.T) {
 		})
 	}
 }
+
+func TestGzipRequestRoundTripper(t *testing.T) {
+	body := strings.Repeat(`{"id":"unit","status":"HEALTHY"}`, 100)
+
+	var acceptGzip, rejectGzip bool
+	var encodings []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		encodings = append(encodings, encoding)
+		if encoding == "gzip" && rejectGzip {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		reader := io.Reader(r.Body)
+		if encoding == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			reader = zr
+		}
+		got, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(got))
+
+		if acceptGzip {
+			w.Header().Set("Accept-Encoding", "gzip")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	rt := NewGzipRequestRoundTripper(http.DefaultTransport)
+	send := func() {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// not compressed until the server advertises it accepts it
+	send()
+	acceptGzip = true
+	send()
+	send()
+
+	// the server does not accept it anymore, the request is sent again uncompressed
+	acceptGzip = false
+	rejectGzip = true
+	send()
+	send()
+
+	assert.Equal(t, []string{"", "", "gzip", "gzip", "", ""}, encodings)
+}
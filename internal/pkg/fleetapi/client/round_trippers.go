@@ -5,8 +5,14 @@
 package client
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"sync/atomic"
 
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
 )
@@ -88,3 +94,91 @@ func (r *ElasticApiVersionRoundTripper) RoundTrip(req *http.Request) (*http.Resp
 func NewElasticApiVersionRoundTripper(inner http.RoundTripper, elasticApiVersion string) http.RoundTripper {
 	return &ElasticApiVersionRoundTripper{elasticApiVersion: elasticApiVersion, rt: inner}
 }
+
+// minGzipRequestSize is the smallest request body compressed by the
+// GzipRequestRoundTripper, smaller bodies aren't worth the overhead.
+const minGzipRequestSize = 1024
+
+// GzipRequestRoundTripper compresses the request bodies with gzip once the
+// server has advertised it accepts gzip encoded requests, through the
+// Accept-Encoding header of any of its responses (RFC 7694). If the server
+// later refuses an encoded request, compression is disabled and the request
+// is sent again uncompressed.
+type GzipRequestRoundTripper struct {
+	rt      http.RoundTripper
+	minSize int
+	enabled atomic.Bool
+}
+
+// RoundTrip compresses the request body when the server accepts it.
+func (r *GzipRequestRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !r.enabled.Load() || req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return r.send(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(body) < r.minSize {
+		return r.send(withBody(req, body))
+	}
+
+	compressed := new(bytes.Buffer)
+	zw := gzip.NewWriter(compressed)
+	if _, err := zw.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to compress request body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress request body: %w", err)
+	}
+
+	gzReq := withBody(req, compressed.Bytes())
+	gzReq.Header.Set("Content-Encoding", "gzip")
+	resp, err := r.send(gzReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		return resp, nil
+	}
+
+	// the server does not accept compressed requests anymore
+	r.enabled.Store(false)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return r.send(withBody(req, body))
+}
+
+func (r *GzipRequestRoundTripper) send(req *http.Request) (*http.Response, error) {
+	resp, err := r.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range resp.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(enc), "gzip") {
+				r.enabled.Store(true)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// withBody returns a shallow copy of req, with its own headers, sending body.
+func withBody(req *http.Request, body []byte) *http.Request {
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return out
+}
+
+// NewGzipRequestRoundTripper wraps an existing http.RoundTripper and
+// compresses the request bodies once the server accepts it.
+func NewGzipRequestRoundTripper(wrapped http.RoundTripper) http.RoundTripper {
+	return &GzipRequestRoundTripper{rt: wrapped, minSize: minGzipRequestSize}
+}
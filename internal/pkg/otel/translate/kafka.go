@@ -0,0 +1,166 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	oteltranslate "github.com/elastic/beats/v7/libbeat/otelbeat/oteltranslate"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+)
+
+// kafkaToOTelOptions holds the subset of the Beats kafka output configuration that can be expressed with the
+// kafka exporter.
+type kafkaToOTelOptions struct {
+	Hosts            []string          `config:"hosts" validate:"required"`
+	TLS              *tlscommon.Config `config:"ssl"`
+	Timeout          time.Duration     `config:"timeout" validate:"min=1"`
+	MaxMessageBytes  *int              `config:"max_message_bytes" validate:"min=1"`
+	RequiredACKs     *int              `config:"required_acks" validate:"min=-1"`
+	Compression      string            `config:"compression"`
+	CompressionLevel int               `config:"compression_level"`
+	Version          string            `config:"version"`
+	BulkMaxSize      int               `config:"bulk_max_size"`
+	MaxRetries       int               `config:"max_retries" validate:"min=-1,nonzero"`
+	ClientID         string            `config:"client_id"`
+	Username         string            `config:"username"`
+	Password         string            `config:"password"`
+	Topic            string            `config:"topic"`
+	Metadata         struct {
+		Retry struct {
+			Max     int           `config:"max" validate:"min=0"`
+			Backoff time.Duration `config:"backoff" validate:"min=0"`
+		} `config:"retry"`
+		RefreshFreq time.Duration `config:"refresh_frequency" validate:"min=0"`
+		Full        bool          `config:"full"`
+	} `config:"metadata"`
+	Backoff struct {
+		Init time.Duration `config:"init"`
+		Max  time.Duration `config:"max"`
+	} `config:"backoff"`
+	Sasl struct {
+		Mechanism string `config:"mechanism"`
+	} `config:"sasl"`
+}
+
+// defaultKafkaToOTelOptions mirrors the defaults of the Beats kafka output.
+func defaultKafkaToOTelOptions() kafkaToOTelOptions {
+	opts := kafkaToOTelOptions{
+		Timeout:          30 * time.Second,
+		Compression:      "gzip",
+		CompressionLevel: 4,
+		Version:          "2.1.0",
+		BulkMaxSize:      2048,
+		MaxRetries:       3,
+		ClientID:         "beats",
+	}
+	opts.Metadata.Retry.Max = 3
+	opts.Metadata.Retry.Backoff = 250 * time.Millisecond
+	opts.Metadata.RefreshFreq = 10 * time.Minute
+	opts.Backoff.Init = 1 * time.Second
+	opts.Backoff.Max = 60 * time.Second
+	return opts
+}
+
+// kafkaUnsupportedSettings lists kafka output settings that have no equivalent in the kafka exporter.
+var kafkaUnsupportedSettings = []string{"topics", "key", "partition", "headers", "kerberos", "codec"}
+
+// translateKafkaOutputToExporter translates a kafka output configuration to a kafka exporter configuration.
+func translateKafkaOutputToExporter(cfg *config.C) (map[string]any, error) {
+	for _, setting := range kafkaUnsupportedSettings {
+		if cfg.HasField(setting) {
+			return nil, fmt.Errorf("setting %s is currently not supported by the kafka exporter", setting)
+		}
+	}
+
+	kafkaCfg := defaultKafkaToOTelOptions()
+	if err := cfg.Unpack(&kafkaCfg); err != nil {
+		return nil, fmt.Errorf("failed unpacking config. %w", err)
+	}
+	if kafkaCfg.Topic == "" {
+		return nil, errors.New("setting topic is required")
+	}
+	if strings.Contains(kafkaCfg.Topic, "%{") {
+		return nil, fmt.Errorf("dynamic topic %q is currently not supported by the kafka exporter", kafkaCfg.Topic)
+	}
+
+	producerCfg := map[string]any{
+		"compression":        kafkaCfg.Compression, // compression
+		"flush_max_messages": kafkaCfg.BulkMaxSize, // bulk_max_size
+	}
+	if kafkaCfg.Compression == "gzip" {
+		producerCfg["compression_params"] = map[string]any{
+			"level": kafkaCfg.CompressionLevel, // compression_level
+		}
+	}
+	if kafkaCfg.MaxMessageBytes != nil {
+		producerCfg["max_message_bytes"] = *kafkaCfg.MaxMessageBytes // max_message_bytes
+	}
+	if kafkaCfg.RequiredACKs != nil {
+		producerCfg["required_acks"] = *kafkaCfg.RequiredACKs // required_acks
+	}
+
+	retryCfg := map[string]any{
+		"enabled":          true,
+		"initial_interval": kafkaCfg.Backoff.Init, // backoff.init
+		"max_interval":     kafkaCfg.Backoff.Max,  // backoff.max
+	}
+	if kafkaCfg.MaxRetries < 0 {
+		// max_retries: -1 retries forever
+		retryCfg["max_elapsed_time"] = 0
+	}
+
+	otelYAMLCfg := map[string]any{
+		"brokers":          kafkaCfg.Hosts,   // hosts
+		"protocol_version": kafkaCfg.Version, // version
+		"client_id":        kafkaCfg.ClientID,
+		"timeout":          kafkaCfg.Timeout,
+		"logs": map[string]any{
+			"topic": kafkaCfg.Topic,
+			// beats events are sent as the JSON encoded log body, which is what the raw encoding does for map bodies
+			"encoding": "raw",
+		},
+		"metadata": map[string]any{
+			"full":             kafkaCfg.Metadata.Full,        // metadata.full
+			"refresh_interval": kafkaCfg.Metadata.RefreshFreq, // metadata.refresh_frequency
+			"retry": map[string]any{
+				"max":     kafkaCfg.Metadata.Retry.Max,     // metadata.retry.max
+				"backoff": kafkaCfg.Metadata.Retry.Backoff, // metadata.retry.backoff
+			},
+		},
+		"producer":         producerCfg,
+		"retry_on_failure": retryCfg,
+	}
+
+	// Authentication
+	if kafkaCfg.Username != "" {
+		mechanism := kafkaCfg.Sasl.Mechanism
+		if mechanism == "" {
+			mechanism = "PLAIN"
+		}
+		otelYAMLCfg["auth"] = map[string]any{
+			"sasl": map[string]any{
+				"username":  kafkaCfg.Username,
+				"password":  kafkaCfg.Password,
+				"mechanism": strings.ToUpper(mechanism),
+			},
+		}
+	}
+
+	// convert ssl configuration
+	otelTLSConfig, err := oteltranslate.TLSCommonToOTel(kafkaCfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert SSL config into OTel: %w", err)
+	}
+	if otelTLSConfig != nil {
+		otelYAMLCfg["tls"] = otelTLSConfig
+	}
+
+	return otelYAMLCfg, nil
+}
@@ -0,0 +1,151 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package translate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+func TestTranslateKafkaOutputToExporter(t *testing.T) {
+	tests := []struct {
+		name          string
+		outputConfig  map[string]any
+		expected      map[string]any
+		expectedError string
+	}{
+		{
+			name: "defaults",
+			outputConfig: map[string]any{
+				"hosts": []any{"localhost:9092"},
+				"topic": "logs",
+			},
+			expected: map[string]any{
+				"brokers":          []string{"localhost:9092"},
+				"protocol_version": "2.1.0",
+				"client_id":        "beats",
+				"timeout":          30 * time.Second,
+				"logs": map[string]any{
+					"topic":    "logs",
+					"encoding": "raw",
+				},
+				"metadata": map[string]any{
+					"full":             false,
+					"refresh_interval": 10 * time.Minute,
+					"retry": map[string]any{
+						"max":     3,
+						"backoff": 250 * time.Millisecond,
+					},
+				},
+				"producer": map[string]any{
+					"compression":        "gzip",
+					"flush_max_messages": 2048,
+					"compression_params": map[string]any{
+						"level": 4,
+					},
+				},
+				"retry_on_failure": map[string]any{
+					"enabled":          true,
+					"initial_interval": 1 * time.Second,
+					"max_interval":     60 * time.Second,
+				},
+			},
+		},
+		{
+			name: "producer and auth settings",
+			outputConfig: map[string]any{
+				"hosts":             []any{"kafka-1:9092", "kafka-2:9092"},
+				"topic":             "logs",
+				"compression":       "snappy",
+				"required_acks":     -1,
+				"max_message_bytes": 1000000,
+				"max_retries":       -1,
+				"username":          "user",
+				"password":          "pass",
+				"sasl.mechanism":    "scram-sha-512",
+			},
+			expected: map[string]any{
+				"brokers":          []string{"kafka-1:9092", "kafka-2:9092"},
+				"protocol_version": "2.1.0",
+				"client_id":        "beats",
+				"timeout":          30 * time.Second,
+				"logs": map[string]any{
+					"topic":    "logs",
+					"encoding": "raw",
+				},
+				"metadata": map[string]any{
+					"full":             false,
+					"refresh_interval": 10 * time.Minute,
+					"retry": map[string]any{
+						"max":     3,
+						"backoff": 250 * time.Millisecond,
+					},
+				},
+				"producer": map[string]any{
+					"compression":        "snappy",
+					"flush_max_messages": 2048,
+					"max_message_bytes":  1000000,
+					"required_acks":      -1,
+				},
+				"retry_on_failure": map[string]any{
+					"enabled":          true,
+					"initial_interval": 1 * time.Second,
+					"max_interval":     60 * time.Second,
+					"max_elapsed_time": 0,
+				},
+				"auth": map[string]any{
+					"sasl": map[string]any{
+						"username":  "user",
+						"password":  "pass",
+						"mechanism": "SCRAM-SHA-512",
+					},
+				},
+			},
+		},
+		{
+			name: "missing topic",
+			outputConfig: map[string]any{
+				"hosts": []any{"localhost:9092"},
+			},
+			expectedError: "setting topic is required",
+		},
+		{
+			name: "dynamic topic",
+			outputConfig: map[string]any{
+				"hosts": []any{"localhost:9092"},
+				"topic": "%{[fields.topic]}",
+			},
+			expectedError: `dynamic topic "%{[fields.topic]}" is currently not supported by the kafka exporter`,
+		},
+		{
+			name: "partitioning",
+			outputConfig: map[string]any{
+				"hosts":          []any{"localhost:9092"},
+				"topic":          "logs",
+				"partition.hash": map[string]any{},
+			},
+			expectedError: "setting partition is currently not supported by the kafka exporter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.NewConfigFrom(tt.outputConfig)
+			require.NoError(t, err)
+			actual, err := translateKafkaOutputToExporter(cfg)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package translate
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	oteltranslate "github.com/elastic/beats/v7/libbeat/otelbeat/oteltranslate"
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+// logstashOTLPDefaultPort is the port of the Logstash OTLP input when a host doesn't specify one, the default port
+// of the OTLP gRPC receivers.
+const logstashOTLPDefaultPort = "4317"
+
+// logstashToOTelOptions holds the subset of the Beats logstash output configuration that can be expressed with the
+// loadbalancing exporter.
+type logstashToOTelOptions struct {
+	OTLP struct {
+		Enabled bool `config:"enabled"`
+	} `config:"otlp"`
+	Hosts            []string          `config:"hosts" validate:"required"`
+	LoadBalance      bool              `config:"loadbalance"`
+	Worker           int               `config:"worker" validate:"min=1"`
+	BulkMaxSize      int               `config:"bulk_max_size" validate:"min=1"`
+	Pipelining       int               `config:"pipelining" validate:"min=0"`
+	Timeout          time.Duration     `config:"timeout"`
+	CompressionLevel int               `config:"compression_level" validate:"min=0, max=9"`
+	MaxRetries       int               `config:"max_retries" validate:"min=-1"`
+	TLS              *tlscommon.Config `config:"ssl"`
+	ProxyURL         string            `config:"proxy_url"`
+	Backoff          struct {
+		Init time.Duration `config:"init"`
+		Max  time.Duration `config:"max"`
+	} `config:"backoff"`
+}
+
+// defaultLogstashToOTelOptions mirrors the defaults of the Beats logstash output.
+func defaultLogstashToOTelOptions() logstashToOTelOptions {
+	opts := logstashToOTelOptions{
+		Timeout:          30 * time.Second,
+		CompressionLevel: 3,
+		MaxRetries:       3,
+		Worker:           1,
+		BulkMaxSize:      2048,
+		Pipelining:       2,
+	}
+	opts.Backoff.Init = 1 * time.Second
+	opts.Backoff.Max = 60 * time.Second
+	return opts
+}
+
+// isLogstashOTLPEnabled returns true when the logstash output of the component opts in to sending the events with
+// OTLP. The Beats logstash output speaks the lumberjack protocol of the Logstash beats input, the events can only be
+// sent with OTLP to a Logstash running an OTLP input, the output stays on the process runtime otherwise.
+func isLogstashOTLPEnabled(comp *component.Component) bool {
+	for _, unit := range comp.Units {
+		if unit.Type != client.UnitTypeOutput || unit.Config == nil {
+			continue
+		}
+		cfg, err := config.NewConfigFrom(unit.Config.GetSource().AsMap())
+		if err != nil {
+			return false
+		}
+		enabled, err := cfg.Bool("otlp.enabled", -1)
+		return err == nil && enabled
+	}
+	return false
+}
+
+// translateLogstashOutputToExporter translates a logstash output configuration opting in with otlp.enabled to a
+// loadbalancing exporter configuration sending OTLP to the logstash hosts. Logstash must be running an OTLP input on
+// the configured hosts.
+//
+// The loadbalancing exporter is used even for a single host, so the exporter type doesn't depend on the number of
+// hosts. Without loadbalance, the Beats output only fails over between hosts; the exporter always spreads events
+// across all of them.
+func translateLogstashOutputToExporter(cfg *config.C) (map[string]any, error) {
+	lsCfg := defaultLogstashToOTelOptions()
+	if err := cfg.Unpack(&lsCfg); err != nil {
+		return nil, fmt.Errorf("failed unpacking config. %w", err)
+	}
+	if !lsCfg.OTLP.Enabled {
+		return nil, fmt.Errorf("the logstash output sends the events with OTLP only when otlp.enabled is set")
+	}
+	if lsCfg.ProxyURL != "" {
+		return nil, fmt.Errorf("setting proxy_url is currently not supported by the loadbalancing exporter")
+	}
+
+	hostnames := make([]string, 0, len(lsCfg.Hosts))
+	for _, host := range lsCfg.Hosts {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, logstashOTLPDefaultPort)
+		}
+		hostnames = append(hostnames, host)
+	}
+
+	// convert ssl configuration, the Beats output sends plain text unless ssl is configured
+	otelTLSConfig, err := oteltranslate.TLSCommonToOTel(lsCfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert SSL config into OTel: %w", err)
+	}
+	if otelTLSConfig == nil {
+		otelTLSConfig = map[string]any{"insecure": true}
+	}
+
+	compression := "none"
+	if lsCfg.CompressionLevel > 0 {
+		compression = "gzip" // compression_level
+	}
+
+	retryCfg := map[string]any{
+		"enabled":          lsCfg.MaxRetries != 0,
+		"initial_interval": lsCfg.Backoff.Init, // backoff.init
+		"max_interval":     lsCfg.Backoff.Max,  // backoff.max
+	}
+	if lsCfg.MaxRetries < 0 {
+		// max_retries: -1 retries forever
+		retryCfg["max_elapsed_time"] = 0
+	}
+
+	// the Beats output publishes up to pipelining batches of bulk_max_size events per worker and per host
+	numConsumers := lsCfg.Worker * len(hostnames)
+	queueCfg := map[string]any{
+		"enabled":       true,
+		"num_consumers": numConsumers, // worker
+		"sizer":         "items",
+		"queue_size":    numConsumers * max(lsCfg.Pipelining, 1) * lsCfg.BulkMaxSize, // pipelining
+		"batch": map[string]any{
+			"flush_timeout": 1 * time.Second,
+			"min_size":      0,
+			"max_size":      lsCfg.BulkMaxSize, // bulk_max_size
+		},
+	}
+
+	otelYAMLCfg := map[string]any{
+		"protocol": map[string]any{
+			"otlp": map[string]any{
+				"timeout":     lsCfg.Timeout, // timeout
+				"compression": compression,
+				"tls":         otelTLSConfig,
+			},
+		},
+		"resolver": map[string]any{
+			"static": map[string]any{
+				"hostnames": hostnames, // hosts
+			},
+		},
+		"retry_on_failure": retryCfg,
+		"sending_queue":    queueCfg,
+	}
+
+	return otelYAMLCfg, nil
+}
@@ -0,0 +1,142 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package translate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/config"
+)
+
+func TestTranslateLogstashOutputToExporter(t *testing.T) {
+	tests := []struct {
+		name          string
+		outputConfig  map[string]any
+		expected      map[string]any
+		expectedError string
+	}{
+		{
+			name: "defaults",
+			outputConfig: map[string]any{
+				"otlp.enabled": true,
+				"hosts":        []any{"logstash-1", "logstash-2:5045"},
+			},
+			expected: map[string]any{
+				"protocol": map[string]any{
+					"otlp": map[string]any{
+						"timeout":     30 * time.Second,
+						"compression": "gzip",
+						"tls": map[string]any{
+							"insecure": true,
+						},
+					},
+				},
+				"resolver": map[string]any{
+					"static": map[string]any{
+						"hostnames": []string{"logstash-1:4317", "logstash-2:5045"},
+					},
+				},
+				"retry_on_failure": map[string]any{
+					"enabled":          true,
+					"initial_interval": 1 * time.Second,
+					"max_interval":     60 * time.Second,
+				},
+				"sending_queue": map[string]any{
+					"enabled":       true,
+					"num_consumers": 2,
+					"sizer":         "items",
+					"queue_size":    2 * 2 * 2048,
+					"batch": map[string]any{
+						"flush_timeout": 1 * time.Second,
+						"min_size":      0,
+						"max_size":      2048,
+					},
+				},
+			},
+		},
+		{
+			name: "no compression and no retries",
+			outputConfig: map[string]any{
+				"otlp.enabled":      true,
+				"hosts":             []any{"logstash:5044"},
+				"worker":            4,
+				"bulk_max_size":     100,
+				"pipelining":        0,
+				"loadbalance":       true,
+				"compression_level": 0,
+				"max_retries":       0,
+				"timeout":           "10s",
+				"backoff.init":      "2s",
+				"backoff.max":       "30s",
+			},
+			expected: map[string]any{
+				"protocol": map[string]any{
+					"otlp": map[string]any{
+						"timeout":     10 * time.Second,
+						"compression": "none",
+						"tls": map[string]any{
+							"insecure": true,
+						},
+					},
+				},
+				"resolver": map[string]any{
+					"static": map[string]any{
+						"hostnames": []string{"logstash:5044"},
+					},
+				},
+				"retry_on_failure": map[string]any{
+					"enabled":          false,
+					"initial_interval": 2 * time.Second,
+					"max_interval":     30 * time.Second,
+				},
+				"sending_queue": map[string]any{
+					"enabled":       true,
+					"num_consumers": 4,
+					"sizer":         "items",
+					"queue_size":    4 * 100,
+					"batch": map[string]any{
+						"flush_timeout": 1 * time.Second,
+						"min_size":      0,
+						"max_size":      100,
+					},
+				},
+			},
+		},
+		{
+			name: "proxy",
+			outputConfig: map[string]any{
+				"otlp.enabled": true,
+				"hosts":        []any{"logstash:5044"},
+				"proxy_url":    "socks5://proxy:1080",
+			},
+			expectedError: "setting proxy_url is currently not supported by the loadbalancing exporter",
+		},
+		{
+			name: "otlp not enabled",
+			outputConfig: map[string]any{
+				"hosts": []any{"logstash:5044"},
+			},
+			expectedError: "the logstash output sends the events with OTLP only when otlp.enabled is set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.NewConfigFrom(tt.outputConfig)
+			require.NoError(t, err)
+			actual, err := translateLogstashOutputToExporter(cfg)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
@@ -38,13 +38,39 @@ type BeatMonitoringConfigGetter func(unitID, binary string) map[string]any
 type exporterConfigTranslationFunc func(*config.C) (map[string]any, error)
 
 var (
-	OtelSupportedOutputTypes         = []string{"elasticsearch"}
+	// OtelSupportedOutputTypes are the output types translated into an exporter. The logstash output is only
+	// translated when it opts in to sending the events with OTLP, see isLogstashOTLPEnabled.
+	OtelSupportedOutputTypes         = addNonFipsOutputTypes([]string{"elasticsearch", "logstash"})
 	OtelSupportedInputTypes          = []string{"filestream", "http/metrics", "beat/metrics", "system/metrics"}
-	configTranslationFuncForExporter = map[otelcomponent.Type]exporterConfigTranslationFunc{
+	configTranslationFuncForExporter = addNonFipsExporterTranslations(map[otelcomponent.Type]exporterConfigTranslationFunc{
 		otelcomponent.MustNewType("elasticsearch"): translateEsOutputToExporter,
+		otelcomponent.MustNewType("loadbalancing"): translateLogstashOutputToExporter,
+	})
+	// beatReceivers lists the beats that can run as receivers inside the Otel Collector. Components run by other
+	// beats, like auditbeat, heartbeat or packetbeat, stay on the process runtime until a receiver is available.
+	beatReceivers = map[string]beatReceiver{
+		"filebeat": {
+			receiverType:          otelcomponent.MustNewType(fbreceiver.Name),
+			inputsKey:             "inputs",
+			defaultDatastreamType: "logs",
+		},
+		"metricbeat": {
+			receiverType:          otelcomponent.MustNewType(mbreceiver.Name),
+			inputsKey:             "modules",
+			defaultDatastreamType: "metrics",
+		},
 	}
 )
 
+// beatReceiver describes how the components of a beat are translated into a beat receiver configuration.
+type beatReceiver struct {
+	receiverType otelcomponent.Type
+	// inputsKey is the key the unit inputs are placed under in the beat configuration.
+	inputsKey string
+	// defaultDatastreamType is needed to translate from the agent policy config format to the beats config format.
+	defaultDatastreamType string
+}
+
 // GetOtelConfig returns the Otel collector configuration for the given component model.
 // All added component and pipelines names are prefixed with OtelNamePrefix.
 // Unsupported components are quietly ignored.
@@ -76,8 +102,27 @@ func GetOtelConfig(
 
 // IsComponentOtelSupported checks if the given component can be run in an Otel Collector.
 func IsComponentOtelSupported(comp *component.Component) bool {
-	return slices.Contains(OtelSupportedOutputTypes, comp.OutputType) &&
-		slices.Contains(OtelSupportedInputTypes, comp.InputType)
+	return VerifyComponentIsOtelSupported(comp) == nil
+}
+
+// VerifyComponentIsOtelSupported returns an error explaining why the given component can't be run in an Otel
+// Collector, or nil if it can.
+func VerifyComponentIsOtelSupported(comp *component.Component) error {
+	if !slices.Contains(OtelSupportedOutputTypes, comp.OutputType) {
+		return fmt.Errorf("unsupported output type: %s", comp.OutputType)
+	}
+	if comp.OutputType == "logstash" && !isLogstashOTLPEnabled(comp) {
+		return fmt.Errorf("unsupported output type: logstash, otlp.enabled is not set")
+	}
+	if !slices.Contains(OtelSupportedInputTypes, comp.InputType) {
+		if beatName := getBeatNameForComponent(comp); beatName != "" {
+			if _, ok := beatReceivers[beatName]; !ok {
+				return fmt.Errorf("unsupported input type: %s, no receiver is available for %s", comp.InputType, beatName)
+			}
+		}
+		return fmt.Errorf("unsupported input type: %s", comp.InputType)
+	}
+	return nil
 }
 
 // getSupportedComponents returns components from the given model that can be run in an Otel Collector.
@@ -170,6 +215,7 @@ func getReceiversConfigForComponent(
 	if err != nil {
 		return nil, err
 	}
+	binaryName := getBeatNameForComponent(comp)
 
 	// get inputs for all the units
 	// we run a single receiver for each component to mirror what beats processes do
@@ -189,7 +235,6 @@ func getReceiversConfigForComponent(
 	// always safe. We should either ensure this is always the case, or have an explicit mapping.
 	beatName := strings.TrimSuffix(receiverType.String(), "receiver")
 	beatDataPath := filepath.Join(paths.Run(), comp.ID)
-	binaryName := getBeatNameForComponent(comp)
 	dataset := fmt.Sprintf("elastic_agent.%s", strings.ReplaceAll(strings.ReplaceAll(binaryName, "-", "_"), "/", "_"))
 
 	receiverConfig := map[string]any{
@@ -216,15 +261,8 @@ func getReceiversConfigForComponent(
 			},
 		},
 	}
-	switch beatName {
-	case "filebeat":
-		receiverConfig[beatName] = map[string]any{
-			"inputs": inputs,
-		}
-	case "metricbeat":
-		receiverConfig[beatName] = map[string]any{
-			"modules": inputs,
-		}
+	receiverConfig[beatName] = map[string]any{
+		beatReceivers[binaryName].inputsKey: inputs,
 	}
 	// add the output queue config if present
 	if outputQueueConfig != nil {
@@ -276,26 +314,19 @@ func getBeatNameForComponent(comp *component.Component) string {
 // getSignalForComponent returns the otel signal for the given component. Currently, this is always logs, even for
 // metricbeat.
 func getSignalForComponent(comp *component.Component) (pipeline.Signal, error) {
-	beatName := getBeatNameForComponent(comp)
-	switch beatName {
-	case "filebeat", "metricbeat":
-		return pipeline.SignalLogs, nil
-	default:
+	if _, ok := beatReceivers[getBeatNameForComponent(comp)]; !ok {
 		return pipeline.Signal{}, fmt.Errorf("unknown otel signal for input type: %s", comp.InputType)
 	}
+	return pipeline.SignalLogs, nil
 }
 
 // getReceiverTypeForComponent returns the receiver type for the given component.
 func getReceiverTypeForComponent(comp *component.Component) (otelcomponent.Type, error) {
-	beatName := getBeatNameForComponent(comp)
-	switch beatName {
-	case "filebeat":
-		return otelcomponent.MustNewType(fbreceiver.Name), nil
-	case "metricbeat":
-		return otelcomponent.MustNewType(mbreceiver.Name), nil
-	default:
+	receiver, ok := beatReceivers[getBeatNameForComponent(comp)]
+	if !ok {
 		return otelcomponent.Type{}, fmt.Errorf("unknown otel receiver type for input type: %s", comp.InputType)
 	}
+	return receiver.receiverType, nil
 }
 
 // getExporterTypeForComponent returns the exporter type for the given component.
@@ -303,6 +334,11 @@ func getExporterTypeForComponent(comp *component.Component) (otelcomponent.Type,
 	switch comp.OutputType {
 	case "elasticsearch":
 		return otelcomponent.MustNewType("elasticsearch"), nil
+	case "logstash":
+		// logstash hosts opting in are reached over OTLP, spread over all the configured hosts
+		return otelcomponent.MustNewType("loadbalancing"), nil
+	case "kafka":
+		return otelcomponent.MustNewType("kafka"), nil
 	default:
 		return otelcomponent.Type{}, fmt.Errorf("unknown otel exporter type for output type: %s", comp.OutputType)
 	}
@@ -384,15 +420,11 @@ func getInputsForUnit(unit component.Unit, info info.Agent, defaultDataStreamTyp
 // getDefaultDatastreamTypeForComponent returns the default datastream type for a given component.
 // This is needed to translate from the agent policy config format to the beats config format.
 func getDefaultDatastreamTypeForComponent(comp *component.Component) (string, error) {
-	beatName := getBeatNameForComponent(comp)
-	switch beatName {
-	case "filebeat":
-		return "logs", nil
-	case "metricbeat":
-		return "metrics", nil
-	default:
+	receiver, ok := beatReceivers[getBeatNameForComponent(comp)]
+	if !ok {
 		return "", fmt.Errorf("input type not supported by Otel: %s", comp.InputType)
 	}
+	return receiver.defaultDatastreamType, nil
 }
 
 // translateEsOutputToExporter translates an elasticsearch output configuration to an elasticsearch exporter configuration.
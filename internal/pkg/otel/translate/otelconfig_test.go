@@ -13,12 +13,14 @@ import (
 	"go.opentelemetry.io/collector/confmap"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/mapstr"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pipeline"
 
 	"github.com/elastic/elastic-agent/pkg/component"
@@ -660,3 +662,159 @@ func TestGetOtelConfig(t *testing.T) {
 }
 
 // TODO: Add unit tests for other config generation functions
+
+// outputTestComponent returns a filestream component sending its events to an output of the given type.
+func outputTestComponent(outputType string, outputConfig map[string]any) component.Component {
+	return component.Component{
+		ID:         "filestream-default",
+		InputType:  "filestream",
+		OutputType: outputType,
+		InputSpec: &component.InputRuntimeSpec{
+			BinaryName: "agentbeat",
+			Spec: component.InputSpec{
+				Command: &component.CommandSpec{
+					Args: []string{"filebeat"},
+				},
+			},
+		},
+		Units: []component.Unit{
+			{
+				ID:   "filestream-unit",
+				Type: client.UnitTypeInput,
+				Config: component.MustExpectedConfig(map[string]any{
+					"id":         "test",
+					"use_output": "default",
+					"streams": []any{
+						map[string]any{
+							"id":    "test-1",
+							"paths": []any{"/var/log/*.log"},
+						},
+					},
+				}),
+			},
+			{
+				ID:     "filestream-default",
+				Type:   client.UnitTypeOutput,
+				Config: component.MustExpectedConfig(outputConfig),
+			},
+		},
+	}
+}
+
+// assertOtelOutput checks that the component runs in the otel collector and exports its events with the exporter
+// translated from its output configuration.
+func assertOtelOutput(t *testing.T, comp component.Component, exporterID string, translate exporterConfigTranslationFunc) {
+	t.Helper()
+	require.True(t, IsComponentOtelSupported(&comp))
+
+	conf, err := GetOtelConfig(&component.Model{Components: []component.Component{comp}}, &info.AgentInfo{}, noBeatMonitoringConfig)
+	require.NoError(t, err)
+	require.NotNil(t, conf)
+
+	outputCfg, err := config.NewConfigFrom(comp.Units[1].Config.GetSource().AsMap())
+	require.NoError(t, err)
+	expectedExporter, err := translate(outputCfg)
+	require.NoError(t, err)
+
+	exporters, ok := conf.ToStringMap()["exporters"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{exporterID: expectedExporter}, exporters)
+	pipelines, ok := conf.Get("service::pipelines").(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, map[string][]string{
+		"exporters": {exporterID},
+		"receivers": {"filebeatreceiver/_agent-component/filestream-default"},
+	}, pipelines["logs/_agent-component/filestream-default"])
+}
+
+func noBeatMonitoringConfig(_, _ string) map[string]any {
+	return nil
+}
+
+func TestGetOtelConfigLogstashOutput(t *testing.T) {
+	comp := outputTestComponent("logstash", map[string]any{
+		"type":         "logstash",
+		"otlp.enabled": true,
+		"hosts":        []any{"logstash-1:4317", "logstash-2"},
+		"ssl": map[string]any{
+			"enabled": false,
+		},
+	})
+	assertOtelOutput(t, comp, "loadbalancing/_agent-component/default", translateLogstashOutputToExporter)
+
+	// settings the exporter can't express are reported when generating the configuration
+	comp = outputTestComponent("logstash", map[string]any{
+		"type":         "logstash",
+		"otlp.enabled": true,
+		"hosts":        []any{"logstash-1:4317"},
+		"proxy_url":    "socks5://proxy:1080",
+	})
+	assert.True(t, IsComponentOtelSupported(&comp))
+	_, err := GetOtelConfig(&component.Model{Components: []component.Component{comp}}, &info.AgentInfo{}, noBeatMonitoringConfig)
+	assert.ErrorContains(t, err, "proxy_url")
+
+	// without the opt-in the output keeps sending to the Logstash beats input from the process runtime
+	comp = outputTestComponent("logstash", map[string]any{
+		"type":  "logstash",
+		"hosts": []any{"logstash-1:5044"},
+	})
+	assert.EqualError(t, VerifyComponentIsOtelSupported(&comp), "unsupported output type: logstash, otlp.enabled is not set")
+	conf, err := GetOtelConfig(&component.Model{Components: []component.Component{comp}}, &info.AgentInfo{}, noBeatMonitoringConfig)
+	require.NoError(t, err)
+	assert.Nil(t, conf)
+}
+
+func TestIsComponentOtelSupportedOutputs(t *testing.T) {
+	for _, tc := range []struct {
+		outputType string
+		supported  bool
+	}{
+		{outputType: "elasticsearch", supported: true},
+		{outputType: "logstash", supported: false},
+		{outputType: "kafka", supported: kafkaOutputSupported},
+		{outputType: "redis", supported: false},
+	} {
+		t.Run(tc.outputType, func(t *testing.T) {
+			comp := outputTestComponent(tc.outputType, map[string]any{"type": tc.outputType})
+			assert.Equal(t, tc.supported, IsComponentOtelSupported(&comp))
+			if !tc.supported {
+				assert.ErrorContains(t, VerifyComponentIsOtelSupported(&comp), "unsupported output type: "+tc.outputType)
+			}
+		})
+	}
+}
+
+func TestIsComponentOtelSupportedBeats(t *testing.T) {
+	for _, tc := range []struct {
+		beatName  string
+		inputType string
+		reason    string
+	}{
+		{beatName: "filebeat", inputType: "filestream"},
+		{beatName: "metricbeat", inputType: "system/metrics"},
+		{beatName: "auditbeat", inputType: "audit/auditd", reason: "unsupported input type: audit/auditd, no receiver is available for auditbeat"},
+		{beatName: "packetbeat", inputType: "packet", reason: "unsupported input type: packet, no receiver is available for packetbeat"},
+		{beatName: "heartbeat", inputType: "synthetics/http", reason: "unsupported input type: synthetics/http, no receiver is available for heartbeat"},
+	} {
+		t.Run(tc.beatName, func(t *testing.T) {
+			comp := outputTestComponent("elasticsearch", map[string]any{
+				"type":  "elasticsearch",
+				"hosts": []any{"localhost:9200"},
+			})
+			comp.InputType = tc.inputType
+			comp.InputSpec.Spec.Command.Args = []string{tc.beatName}
+
+			err := VerifyComponentIsOtelSupported(&comp)
+			conf, confErr := GetOtelConfig(&component.Model{Components: []component.Component{comp}}, &info.AgentInfo{}, noBeatMonitoringConfig)
+			require.NoError(t, confErr)
+			if tc.reason == "" {
+				assert.NoError(t, err)
+				assert.NotNil(t, conf)
+				return
+			}
+			// the component stays on the process runtime, no collector configuration is generated for it
+			assert.EqualError(t, err, tc.reason)
+			assert.Nil(t, conf)
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build requirefips
+
+package translate
+
+import (
+	otelcomponent "go.opentelemetry.io/collector/component"
+)
+
+func addNonFipsOutputTypes(outputTypes []string) []string {
+	// the kafka exporter is not available in fips mode
+	return outputTypes
+}
+
+func addNonFipsExporterTranslations(funcs map[otelcomponent.Type]exporterConfigTranslationFunc) map[otelcomponent.Type]exporterConfigTranslationFunc {
+	// the kafka exporter is not available in fips mode
+	return funcs
+}
@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !requirefips
+
+package translate
+
+import (
+	otelcomponent "go.opentelemetry.io/collector/component"
+)
+
+func addNonFipsOutputTypes(outputTypes []string) []string {
+	return append(outputTypes, "kafka")
+}
+
+func addNonFipsExporterTranslations(funcs map[otelcomponent.Type]exporterConfigTranslationFunc) map[otelcomponent.Type]exporterConfigTranslationFunc {
+	funcs[otelcomponent.MustNewType("kafka")] = translateKafkaOutputToExporter
+	return funcs
+}
@@ -0,0 +1,22 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !requirefips
+
+package translate
+
+import (
+	"testing"
+)
+
+const kafkaOutputSupported = true
+
+func TestGetOtelConfigKafkaOutput(t *testing.T) {
+	comp := outputTestComponent("kafka", map[string]any{
+		"type":  "kafka",
+		"hosts": []any{"kafka-1:9092", "kafka-2:9092"},
+		"topic": "logs",
+	})
+	assertOtelOutput(t, comp, "kafka/_agent-component/default", translateKafkaOutputToExporter)
+}
@@ -6,14 +6,27 @@ package queue
 
 import (
 	"container/heap"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 )
 
+// finishedActionTTL is how long the completion or the failure of an action is remembered, an action depending on it
+// queued later than that waits for it to run again.
+const finishedActionTTL = 24 * time.Hour
+
+// ErrDependencyFailed is returned when an action depends on an action that failed.
+var ErrDependencyFailed = errors.New("dependency failed")
+
 // saver is the minimal interface needed for state storage.
 type saver interface {
 	SetQueue(a []fleetapi.ScheduledAction)
+	SetQueueScheduling(s Scheduling)
 	Save() error
 }
 
@@ -22,6 +35,7 @@ type item struct {
 	action   fleetapi.ScheduledAction
 	priority int64
 	index    int
+	schedule *Schedule
 }
 
 // queue uses the standard library's container/heap to implement a priority queue
@@ -29,9 +43,18 @@ type item struct {
 type queue []*item
 
 // ActionQueue is a priority queue with the ability to persist to disk.
+// Queued actions may recur on a cron-like schedule or wait for other actions
+// to complete, see AddWithSchedule.
+// It is safe for concurrent use, the actions may complete or fail after the
+// dispatch returned.
 type ActionQueue struct {
-	q *queue
-	s saver
+	mx sync.Mutex
+	q  *queue
+	s  saver
+
+	// completed and failed hold the time the recently finished actions completed or failed, keyed by action ID.
+	completed map[string]time.Time
+	failed    map[string]time.Time
 }
 
 // Len returns the length of the queue
@@ -91,47 +114,206 @@ func newQueue(actions []fleetapi.ScheduledAction) (*queue, error) {
 	return &q, nil
 }
 
-// NewActionQueue creates a new queue with the passed actions using the saver for state storage.
-func NewActionQueue(actions []fleetapi.ScheduledAction, s saver) (*ActionQueue, error) {
+// NewActionQueue creates a new queue with the passed actions and their scheduling state using the saver for state
+// storage.
+func NewActionQueue(actions []fleetapi.ScheduledAction, scheduling Scheduling, s saver) (*ActionQueue, error) {
 	q, err := newQueue(actions)
 	if err != nil {
 		return nil, err
 	}
-	return &ActionQueue{
-		q: q,
-		s: s,
-	}, nil
+	for _, item := range *q {
+		schedule, ok := scheduling.Schedules[item.action.ID()]
+		if !ok {
+			continue
+		}
+		item.schedule = &schedule
+		if !schedule.Next.IsZero() {
+			item.priority = schedule.Next.Unix()
+		}
+	}
+	heap.Init(q)
+
+	aq := &ActionQueue{
+		q:         q,
+		s:         s,
+		completed: maps.Clone(scheduling.Completed),
+		failed:    maps.Clone(scheduling.Failed),
+	}
+	aq.forgetFinished(time.Now())
+	return aq, nil
 }
 
 // Add will add an action to the queue with the associated priority.
 // The priority is meant to be the start-time of the action as a unix epoch time.
 // Complexity: O(log n)
 func (q *ActionQueue) Add(action fleetapi.ScheduledAction, priority int64) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	e := &item{
+		action:   action,
+		priority: priority,
+	}
+	heap.Push(q.q, e)
+}
+
+// AddWithSchedule will add an action to the queue with the associated priority and schedule.
+// A recurring action is queued again for its next occurrence every time it's dequeued, until the next occurrence is
+// after the action expiration. An action with dependencies stays in the queue until all the actions it depends on
+// are completed, see Complete. ErrDependencyFailed is returned, and the action isn't queued, when one of them has
+// failed, see Fail.
+// Complexity: O(log n)
+func (q *ActionQueue) AddWithSchedule(action fleetapi.ScheduledAction, priority int64, schedule Schedule) error {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	if err := schedule.validate(action.ID()); err != nil {
+		return fmt.Errorf("invalid schedule for action %s: %w", action.ID(), err)
+	}
+	for _, id := range schedule.DependsOn {
+		if _, ok := q.failed[id]; ok {
+			return fmt.Errorf("action %s depends on action %s: %w", action.ID(), id, ErrDependencyFailed)
+		}
+	}
 	e := &item{
 		action:   action,
 		priority: priority,
 	}
+	if !schedule.isZero() {
+		e.schedule = &schedule
+	}
 	heap.Push(q.q, e)
+	return nil
+}
+
+// Complete marks the action with the given ID as successfully completed, releasing the queued actions that depend on
+// it. The completion is remembered for the actions depending on it that are queued later.
+func (q *ActionQueue) Complete(actionID string) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	if q.completed == nil {
+		q.completed = make(map[string]time.Time)
+	}
+	q.completed[actionID] = time.Now()
+	delete(q.failed, actionID)
 }
 
-// DequeueActions will dequeue all actions that have a priority less then time.Now().
+// Fail marks the action with the given ID as failed. The queued actions depending on it, directly or not, can't run
+// anymore: they are removed from the queue and returned. The failure is remembered for the actions depending on it
+// that are queued later, see AddWithSchedule.
+// Complexity: O(n*log n)
+func (q *ActionQueue) Fail(actionID string) []fleetapi.ScheduledAction {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return q.fail(actionID)
+}
+
+// fail marks the action as failed and removes the actions depending on it, it must be called with the lock held.
+func (q *ActionQueue) fail(actionID string) []fleetapi.ScheduledAction {
+	if q.failed == nil {
+		q.failed = make(map[string]time.Time)
+	}
+	q.failed[actionID] = time.Now()
+	delete(q.completed, actionID)
+
+	var dependents []*item
+	for _, item := range *q.q {
+		if item.schedule != nil && slices.Contains(item.schedule.DependsOn, actionID) {
+			dependents = append(dependents, item)
+		}
+	}
+	for _, item := range dependents {
+		heap.Remove(q.q, item.index)
+	}
+	actions := make([]fleetapi.ScheduledAction, 0, len(dependents))
+	for _, item := range dependents {
+		actions = append(actions, item.action)
+		actions = append(actions, q.fail(item.action.ID())...)
+	}
+	return actions
+}
+
+// DequeueActions will dequeue all actions that have a priority less then time.Now() and whose dependencies are
+// completed. Recurring actions are queued again for their next occurrence. Actions still waiting for their
+// dependencies after their expiration are dequeued as well, for them to be reported as expired.
 // Complexity: O(n*log n)
 func (q *ActionQueue) DequeueActions() []fleetapi.ScheduledAction {
-	ts := time.Now().Unix()
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	now := time.Now()
+	ts := now.Unix()
 	actions := make([]fleetapi.ScheduledAction, 0)
+	var blocked, recurring []*item
 	for q.q.Len() != 0 {
 		if (*q.q)[0].priority > ts {
 			break
 		}
 		item := heap.Pop(q.q).(*item)
+		if !q.dependenciesCompleted(item) {
+			if exp, err := item.action.Expiration(); err == nil && now.After(exp) {
+				actions = append(actions, item.action)
+				continue
+			}
+			blocked = append(blocked, item)
+			continue
+		}
 		actions = append(actions, item.action)
+		if next := q.nextOccurrence(item, now); next != nil {
+			recurring = append(recurring, next)
+		}
+	}
+	for _, item := range blocked {
+		heap.Push(q.q, item)
+	}
+	for _, item := range recurring {
+		heap.Push(q.q, item)
 	}
 	return actions
 }
 
+// dependenciesCompleted returns true if all the actions the item depends on are completed.
+func (q *ActionQueue) dependenciesCompleted(i *item) bool {
+	if i.schedule == nil {
+		return true
+	}
+	for _, id := range i.schedule.DependsOn {
+		if _, ok := q.completed[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// nextOccurrence returns the item for the next occurrence of a recurring item after ts, nil is returned if the item
+// doesn't recur or the next occurrence is after the action expiration.
+func (q *ActionQueue) nextOccurrence(i *item, ts time.Time) *item {
+	if i.schedule == nil {
+		return nil
+	}
+	next := i.schedule.next(ts)
+	if next.IsZero() {
+		return nil
+	}
+	if exp, err := i.action.Expiration(); err == nil && next.After(exp) {
+		return nil
+	}
+	schedule := *i.schedule
+	schedule.Next = next
+	return &item{
+		action:   i.action,
+		priority: next.Unix(),
+		schedule: &schedule,
+	}
+}
+
 // Cancel will remove any actions in the queue with a matching actionID and return the number of entries cancelled.
 // Complexity: O(n*log n)
 func (q *ActionQueue) Cancel(actionID string) int {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
 	items := make([]*item, 0)
 	for _, item := range *q.q {
 		if item.action.ID() == actionID {
@@ -146,6 +328,13 @@ func (q *ActionQueue) Cancel(actionID string) int {
 
 // Actions returns all actions in the queue, item 0 is guaranteed to be the min, the rest may not be in sorted order.
 func (q *ActionQueue) Actions() []fleetapi.ScheduledAction {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return q.actions()
+}
+
+// actions returns all actions in the queue, it must be called with the lock held.
+func (q *ActionQueue) actions() []fleetapi.ScheduledAction {
 	actions := make([]fleetapi.ScheduledAction, q.q.Len())
 	for i, item := range *q.q {
 		actions[i] = item.action
@@ -155,6 +344,9 @@ func (q *ActionQueue) Actions() []fleetapi.ScheduledAction {
 
 // CancelType cancels all actions in the queue with a matching action type and returns the number of entries cancelled.
 func (q *ActionQueue) CancelType(actionType string) int {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
 	items := make([]*item, 0)
 	for _, item := range *q.q {
 		if item.action.Type() == actionType {
@@ -167,8 +359,51 @@ func (q *ActionQueue) CancelType(actionType string) int {
 	return len(items)
 }
 
+// Scheduling returns the scheduling state of the queued actions along with the recently finished actions.
+func (q *ActionQueue) Scheduling() Scheduling {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return q.scheduling()
+}
+
+// scheduling returns the scheduling state of the queue, it must be called with the lock held.
+func (q *ActionQueue) scheduling() Scheduling {
+	var scheduling Scheduling
+	for _, item := range *q.q {
+		if item.schedule == nil {
+			continue
+		}
+		if scheduling.Schedules == nil {
+			scheduling.Schedules = make(map[string]Schedule)
+		}
+		scheduling.Schedules[item.action.ID()] = *item.schedule
+	}
+	if len(q.completed) > 0 {
+		scheduling.Completed = maps.Clone(q.completed)
+	}
+	if len(q.failed) > 0 {
+		scheduling.Failed = maps.Clone(q.failed)
+	}
+	return scheduling
+}
+
+// forgetFinished forgets the actions that finished more than finishedActionTTL before ts.
+func (q *ActionQueue) forgetFinished(ts time.Time) {
+	expired := func(_ string, finished time.Time) bool {
+		return ts.Sub(finished) > finishedActionTTL
+	}
+	maps.DeleteFunc(q.completed, expired)
+	maps.DeleteFunc(q.failed, expired)
+}
+
 // Save persists the queue to disk.
 func (q *ActionQueue) Save() error {
-	q.s.SetQueue(q.Actions())
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	q.forgetFinished(time.Now())
+	scheduling := q.scheduling()
+	q.s.SetQueue(q.actions())
+	q.s.SetQueueScheduling(scheduling)
 	return q.s.Save()
 }
@@ -59,6 +59,10 @@ func (m *mockSaver) SetQueue(a []fleetapi.ScheduledAction) {
 	m.Called(a)
 }
 
+func (m *mockSaver) SetQueueScheduling(s Scheduling) {
+	m.Called(s)
+}
+
 func (m *mockSaver) Save() error {
 	args := m.Called()
 	return args.Error(0)
@@ -242,7 +246,7 @@ func Test_ActionQueue_DequeueActions(t *testing.T) {
 			index:    2,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		actions := aq.DequeueActions()
 
@@ -276,7 +280,7 @@ func Test_ActionQueue_DequeueActions(t *testing.T) {
 			index:    2,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		actions := aq.DequeueActions()
 
@@ -308,7 +312,7 @@ func Test_ActionQueue_DequeueActions(t *testing.T) {
 			index:    2,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		actions := aq.DequeueActions()
 
@@ -336,7 +340,7 @@ func Test_ActionQueue_DequeueActions(t *testing.T) {
 			index:    2,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		actions := aq.DequeueActions()
 		assert.Empty(t, actions)
@@ -365,7 +369,7 @@ func Test_ActionQueue_Cancel(t *testing.T) {
 
 	t.Run("empty queue", func(t *testing.T) {
 		q := &queue{}
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		n := aq.Cancel("test-1")
 		assert.Zero(t, n)
@@ -387,7 +391,7 @@ func Test_ActionQueue_Cancel(t *testing.T) {
 			index:    2,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		n := aq.Cancel("test-1")
 		assert.Equal(t, 1, n)
@@ -417,7 +421,7 @@ func Test_ActionQueue_Cancel(t *testing.T) {
 			index:    2,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		n := aq.Cancel("test-1")
 		assert.Equal(t, 2, n)
@@ -444,7 +448,7 @@ func Test_ActionQueue_Cancel(t *testing.T) {
 			index:    2,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		n := aq.Cancel("test-1")
 		assert.Equal(t, 3, n)
@@ -466,7 +470,7 @@ func Test_ActionQueue_Cancel(t *testing.T) {
 			index:    2,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		n := aq.Cancel("test-0")
 		assert.Zero(t, n)
@@ -488,7 +492,7 @@ func Test_ActionQueue_Cancel(t *testing.T) {
 func Test_ActionQueue_Actions(t *testing.T) {
 	t.Run("empty queue", func(t *testing.T) {
 		q := &queue{}
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 		actions := aq.Actions()
 		assert.Len(t, actions, 0)
 	})
@@ -514,7 +518,7 @@ func Test_ActionQueue_Actions(t *testing.T) {
 			index:    2,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		actions := aq.Actions()
 		assert.Len(t, actions, 3)
@@ -534,7 +538,7 @@ func Test_ActionQueue_CancelType(t *testing.T) {
 	a3.On("Type").Return("unknown")
 
 	t.Run("empty queue", func(t *testing.T) {
-		aq := &ActionQueue{&queue{}, &mockSaver{}}
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
 
 		n := aq.CancelType("upgrade")
 		assert.Equal(t, 0, n)
@@ -547,7 +551,7 @@ func Test_ActionQueue_CancelType(t *testing.T) {
 			index:    0,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		n := aq.CancelType("upgrade")
 		assert.Equal(t, 1, n)
@@ -560,7 +564,7 @@ func Test_ActionQueue_CancelType(t *testing.T) {
 			index:    0,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		n := aq.CancelType("upgrade")
 		assert.Equal(t, 0, n)
@@ -577,9 +581,202 @@ func Test_ActionQueue_CancelType(t *testing.T) {
 			index:    1,
 		}}
 		heap.Init(q)
-		aq := &ActionQueue{q, &mockSaver{}}
+		aq := &ActionQueue{q: q, s: &mockSaver{}}
 
 		n := aq.CancelType("upgrade")
 		assert.Equal(t, 2, n)
 	})
 }
+
+func Test_ActionQueue_AddWithSchedule(t *testing.T) {
+	a := &mockAction{}
+	a.On("ID").Return("test-1")
+
+	t.Run("invalid recurrence", func(t *testing.T) {
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+		err := aq.AddWithSchedule(a, 1, Schedule{Recurrence: "not a cron"})
+		assert.ErrorContains(t, err, "invalid recurrence")
+		assert.Zero(t, aq.q.Len())
+	})
+
+	t.Run("depends on itself", func(t *testing.T) {
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+		err := aq.AddWithSchedule(a, 1, Schedule{DependsOn: []string{"test-1"}})
+		assert.ErrorContains(t, err, "cannot depend on itself")
+		assert.Zero(t, aq.q.Len())
+	})
+
+	t.Run("empty schedule", func(t *testing.T) {
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+		require.NoError(t, aq.AddWithSchedule(a, 1, Schedule{}))
+		require.Equal(t, 1, aq.q.Len())
+		assert.Nil(t, (*aq.q)[0].schedule)
+	})
+
+	t.Run("recurrence and dependencies", func(t *testing.T) {
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+		schedule := Schedule{Recurrence: "@daily", DependsOn: []string{"test-2"}}
+		require.NoError(t, aq.AddWithSchedule(a, 1, schedule))
+		require.Equal(t, 1, aq.q.Len())
+		assert.Equal(t, &schedule, (*aq.q)[0].schedule)
+	})
+}
+
+func Test_ActionQueue_DequeueActions_Dependencies(t *testing.T) {
+	t.Run("action waits for its dependency", func(t *testing.T) {
+		a1 := &mockAction{}
+		a1.On("ID").Return("test-1")
+		a2 := &mockAction{}
+		a2.On("ID").Return("test-2")
+		a2.On("Expiration").Return(time.Time{}, fleetapi.ErrNoExpiration)
+
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+		aq.Add(a1, time.Now().Add(time.Hour).Unix())
+		require.NoError(t, aq.AddWithSchedule(a2, time.Now().Add(-time.Minute).Unix(), Schedule{DependsOn: []string{"test-1"}}))
+
+		actions := aq.DequeueActions()
+		assert.Empty(t, actions, "action must wait for its dependency")
+		assert.Equal(t, 2, aq.q.Len())
+
+		aq.Complete("test-other")
+		assert.Empty(t, aq.DequeueActions())
+
+		aq.Complete("test-1")
+		actions = aq.DequeueActions()
+		require.Len(t, actions, 1)
+		assert.Equal(t, "test-2", actions[0].ID())
+		assert.Equal(t, 1, aq.q.Len())
+	})
+
+	t.Run("dependency completed before the action is queued", func(t *testing.T) {
+		a := &mockAction{}
+		a.On("ID").Return("test-2")
+
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+		aq.Complete("test-1")
+		require.NoError(t, aq.AddWithSchedule(a, time.Now().Add(-time.Minute).Unix(), Schedule{DependsOn: []string{"test-1"}}))
+
+		actions := aq.DequeueActions()
+		require.Len(t, actions, 1)
+		assert.Equal(t, "test-2", actions[0].ID())
+	})
+
+	t.Run("action waiting for its dependency after its expiration is dequeued", func(t *testing.T) {
+		a := &mockAction{}
+		a.On("ID").Return("test-2")
+		a.On("Expiration").Return(time.Now().Add(-time.Second), nil)
+
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+		require.NoError(t, aq.AddWithSchedule(a, time.Now().Add(-time.Minute).Unix(), Schedule{DependsOn: []string{"test-1"}}))
+
+		actions := aq.DequeueActions()
+		require.Len(t, actions, 1)
+		assert.Equal(t, "test-2", actions[0].ID())
+		assert.Zero(t, aq.q.Len())
+	})
+}
+
+func Test_ActionQueue_Fail(t *testing.T) {
+	a1 := &mockAction{}
+	a1.On("ID").Return("test-1")
+	a2 := &mockAction{}
+	a2.On("ID").Return("test-2")
+	a3 := &mockAction{}
+	a3.On("ID").Return("test-3")
+	a4 := &mockAction{}
+	a4.On("ID").Return("test-4")
+
+	aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+	ts := time.Now().Add(time.Hour).Unix()
+	require.NoError(t, aq.AddWithSchedule(a2, ts, Schedule{DependsOn: []string{"test-1"}}))
+	require.NoError(t, aq.AddWithSchedule(a3, ts, Schedule{DependsOn: []string{"test-2"}}))
+	aq.Add(a4, ts)
+
+	removed := aq.Fail("test-1")
+	assert.ElementsMatch(t, []fleetapi.ScheduledAction{a2, a3}, removed, "direct and indirect dependents are removed")
+	assert.Equal(t, []fleetapi.ScheduledAction{a4}, aq.Actions())
+	assert.Contains(t, aq.failed, "test-2")
+	assert.Contains(t, aq.failed, "test-3")
+
+	err := aq.AddWithSchedule(a2, ts, Schedule{DependsOn: []string{"test-1"}})
+	assert.ErrorIs(t, err, ErrDependencyFailed, "dependents queued after the failure are refused")
+	assert.Equal(t, 1, aq.q.Len())
+
+	aq.Complete("test-1")
+	assert.NoError(t, aq.AddWithSchedule(a2, ts, Schedule{DependsOn: []string{"test-1"}}), "a later completion replaces the failure")
+}
+
+func Test_ActionQueue_DequeueActions_Recurrence(t *testing.T) {
+	t.Run("action is queued again for the next occurrence", func(t *testing.T) {
+		a := &mockAction{}
+		a.On("ID").Return("test-1")
+		a.On("Expiration").Return(time.Time{}, fleetapi.ErrNoExpiration)
+
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+		require.NoError(t, aq.AddWithSchedule(a, time.Now().Add(-time.Minute).Unix(), Schedule{Recurrence: "@hourly"}))
+
+		actions := aq.DequeueActions()
+		require.Len(t, actions, 1)
+		require.Equal(t, 1, aq.q.Len())
+
+		next := (*aq.q)[0]
+		assert.Equal(t, a, next.action)
+		assert.Greater(t, next.priority, time.Now().Unix())
+		assert.Equal(t, next.priority, next.schedule.Next.Unix())
+		assert.Zero(t, next.schedule.Next.Minute())
+		assert.Empty(t, aq.DequeueActions())
+	})
+
+	t.Run("action is not queued again after its expiration", func(t *testing.T) {
+		a := &mockAction{}
+		a.On("ID").Return("test-1")
+		a.On("Expiration").Return(time.Now().Add(time.Minute), nil)
+
+		aq := &ActionQueue{q: &queue{}, s: &mockSaver{}}
+		require.NoError(t, aq.AddWithSchedule(a, time.Now().Add(-time.Minute).Unix(), Schedule{Recurrence: "@yearly"}))
+
+		actions := aq.DequeueActions()
+		require.Len(t, actions, 1)
+		assert.Zero(t, aq.q.Len())
+	})
+}
+
+func Test_ActionQueue_Scheduling(t *testing.T) {
+	ts := time.Now().Add(-time.Minute)
+	next := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	a1 := &mockAction{}
+	a1.On("ID").Return("test-1")
+	a1.On("StartTime").Return(ts, nil)
+	a2 := &mockAction{}
+	a2.On("ID").Return("test-2")
+	a2.On("StartTime").Return(ts, nil)
+	a2.On("Expiration").Return(time.Time{}, fleetapi.ErrNoExpiration)
+
+	recently := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	long := time.Now().Add(-2 * finishedActionTTL)
+	scheduling := Scheduling{
+		Schedules: map[string]Schedule{
+			"test-1": {Recurrence: "@hourly", Next: next},
+			"test-2": {DependsOn: []string{"test-3", "test-4"}},
+		},
+		Completed: map[string]time.Time{"test-3": recently, "test-4": long},
+		Failed:    map[string]time.Time{"test-5": recently, "test-6": long},
+	}
+
+	s := &mockSaver{}
+	aq, err := NewActionQueue([]fleetapi.ScheduledAction{a1, a2}, scheduling, s)
+	require.NoError(t, err)
+
+	// the next occurrence of the recurring action takes precedence over its start time
+	assert.Empty(t, aq.DequeueActions(), "test-1 isn't due and the completion of test-4 is forgotten")
+
+	s.On("SetQueue", mock.Anything).Once()
+	s.On("SetQueueScheduling", Scheduling{
+		Schedules: scheduling.Schedules,
+		Completed: map[string]time.Time{"test-3": recently},
+		Failed:    map[string]time.Time{"test-5": recently},
+	}).Once()
+	s.On("Save").Return(nil).Once()
+	require.NoError(t, aq.Save())
+	s.AssertExpectations(t)
+}
@@ -0,0 +1,69 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package queue
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/cronexpr"
+)
+
+// Schedule controls when a queued action runs besides its start time.
+type Schedule struct {
+	// Recurrence is a cron expression, when set the action is queued again
+	// for its next occurrence every time it's dequeued.
+	Recurrence string `json:"recurrence,omitempty" yaml:"recurrence,omitempty"`
+	// DependsOn lists the IDs of the actions that must complete successfully
+	// before the action is dequeued.
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	// Next is the time of the next occurrence of a recurring action. Once set
+	// it takes precedence over the start time of the action.
+	Next time.Time `json:"next,omitzero" yaml:"next,omitempty"`
+}
+
+// Scheduling is the scheduling state of the queue that is persisted
+// alongside the queued actions.
+type Scheduling struct {
+	// Schedules holds the schedule of the queued actions keyed by action ID.
+	Schedules map[string]Schedule `json:"schedules,omitempty" yaml:"schedules,omitempty"`
+	// Completed holds the time the recently completed actions completed, keyed by action ID.
+	Completed map[string]time.Time `json:"completed,omitempty" yaml:"completed,omitempty"`
+	// Failed holds the time the recently failed actions failed, keyed by action ID.
+	Failed map[string]time.Time `json:"failed,omitempty" yaml:"failed,omitempty"`
+}
+
+// validate returns an error if the schedule can't be used for the action with
+// the given ID.
+func (s Schedule) validate(actionID string) error {
+	if s.Recurrence != "" {
+		if _, err := cronexpr.Parse(s.Recurrence); err != nil {
+			return fmt.Errorf("invalid recurrence %q: %w", s.Recurrence, err)
+		}
+	}
+	if slices.Contains(s.DependsOn, actionID) {
+		return fmt.Errorf("action %s cannot depend on itself", actionID)
+	}
+	return nil
+}
+
+// next returns the first occurrence of the recurrence after ts, the zero
+// time is returned if the action doesn't recur.
+func (s Schedule) next(ts time.Time) time.Time {
+	if s.Recurrence == "" {
+		return time.Time{}
+	}
+	expr, err := cronexpr.Parse(s.Recurrence)
+	if err != nil {
+		return time.Time{}
+	}
+	return expr.Next(ts)
+}
+
+// isZero returns true if the schedule has no effect on the action.
+func (s Schedule) isZero() bool {
+	return s.Recurrence == "" && len(s.DependsOn) == 0
+}
@@ -174,6 +174,9 @@ type Component struct {
 
 	// Component-level configuration
 	Component *proto.Component `yaml:"component,omitempty"`
+
+	// Resources the component process is limited to, nil when not limited.
+	Resources *limits.ResourceLimits `yaml:"resources,omitempty"`
 }
 
 func (c Component) MarshalYAML() (interface{}, error) {
@@ -322,6 +325,14 @@ func (r *RuntimeSpecs) ToComponents(
 }
 
 func unitForInput(input inputI, id string) Unit {
+	if input.renderErr != nil {
+		return Unit{
+			ID:       id,
+			Type:     client.UnitTypeInput,
+			LogLevel: input.logLevel,
+			Err:      input.renderErr,
+		}
+	}
 	cfg, cfgErr := ExpectedConfig(input.config)
 	return Unit{
 		ID:       id,
@@ -392,6 +403,7 @@ func (r *RuntimeSpecs) componentsForInputType(
 					RuntimeManager: runtimeManager,
 					Features:       featureFlags.AsProto(),
 					Component:      componentConfig.AsProto(),
+					Resources:      componentConfig.ResourceLimits(componentID, inputType),
 				})
 			}
 		}
@@ -421,6 +433,7 @@ func (r *RuntimeSpecs) componentsForInputType(
 					RuntimeManager: input.runtimeManager,
 					Features:       featureFlags.AsProto(),
 					Component:      componentConfig.AsProto(),
+					Resources:      componentConfig.ResourceLimits(componentID, inputType),
 				})
 			}
 		}
@@ -473,6 +486,11 @@ func (r *RuntimeSpecs) PolicyToComponents(
 	}
 	sort.Strings(outputKeys)
 
+	// get the component resource limits from the policy
+	resources, err := limits.ParseResources(policy)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse resource limits from policy: %w", err)
+	}
 	// get agent limits from the policy
 	limits, err := limits.Parse(policy)
 	if err != nil {
@@ -481,7 +499,8 @@ func (r *RuntimeSpecs) PolicyToComponents(
 	// for now it's a shared component configuration for all components
 	// subject to change in the future
 	componentConfig := &ComponentConfig{
-		Limits: ComponentLimits(*limits),
+		Limits:    ComponentLimits(*limits),
+		Resources: resources,
 	}
 
 	var components []Component
@@ -692,6 +711,13 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 			delete(input, runtimeManagerKey)
 		}
 
+		// an input that failed to render is reported as an error on its unit
+		var renderErr error
+		if renderErrRaw, ok := input[transpiler.RenderErrorKey]; ok {
+			renderErr = fmt.Errorf("rendering input failed: %v", renderErrRaw)
+			delete(input, transpiler.RenderErrorKey)
+		}
+
 		// Inject the top level fleet policy revision into each input configuration. This
 		// allows individual inputs (like endpoint) to detect policy changes more easily.
 		injectInputPolicyID(policy, input)
@@ -704,6 +730,7 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 			inputType:      t,
 			config:         input,
 			runtimeManager: runtimeManager,
+			renderErr:      renderErr,
 		})
 	}
 	if len(outputsMap) == 0 {
@@ -724,6 +751,10 @@ type inputI struct {
 	// - the "enabled", "use_output", and "log_level" keys are removed
 	// - the key "policy.revision" is set to the current fleet policy revision
 	config map[string]interface{}
+
+	// renderErr is set when a variable substitution of the input failed, the
+	// input is then reported in error on its unit.
+	renderErr error
 }
 
 type outputI struct {
@@ -743,27 +774,43 @@ type outputI struct {
 	inputs map[string][]inputI
 }
 
-// varsForPlatform sets the runtime variables that are available in the
-// input specification runtime checks. This function should always be
-// edited in sync with the documentation in specs/README.md.
-func varsForPlatform(platform PlatformDetail, defaultProvider string) (*transpiler.Vars, error) {
-	return transpiler.NewVars("", map[string]interface{}{
+// RuntimeFacts returns the facts about the running platform that the runtime
+// prevention conditions of the specifications are evaluated against. This
+// function should always be edited in sync with the documentation in
+// specs/README.md.
+func RuntimeFacts(platform PlatformDetail) map[string]interface{} {
+	return map[string]interface{}{
 		"install": map[string]interface{}{
 			"in_default": paths.ArePathsEqual(paths.Top(), paths.InstallPath(paths.DefaultBasePath)) || platform.IsInstalledViaExternalPkgMgr,
 		},
 		"runtime": map[string]interface{}{
-			"platform":    platform.String(),
-			"os":          platform.OS,
-			"arch":        platform.Arch,
-			"native_arch": platform.NativeArch,
-			"family":      platform.Family,
-			"major":       platform.Major,
-			"minor":       platform.Minor,
+			"platform":                  platform.String(),
+			"os":                        platform.OS,
+			"arch":                      platform.Arch,
+			"native_arch":               platform.NativeArch,
+			"family":                    platform.Family,
+			"major":                     platform.Major,
+			"minor":                     platform.Minor,
+			"kernel_version":            platform.Host.KernelVersion,
+			"kernel_major":              platform.Host.KernelMajor,
+			"kernel_minor":              platform.Host.KernelMinor,
+			"memory_total":              platform.Host.MemoryTotal,
+			"memory_available_at_start": platform.Host.MemoryAvailableAtStart,
+			"cgroup_version":            platform.Host.CgroupVersion,
+			"containerized":             platform.Host.Containerized || platform.OS == Container,
+			"container_runtime":         platform.Host.ContainerRuntime,
 		},
 		"user": map[string]interface{}{
-			"root": platform.User.Root,
+			"root":         platform.User.Root,
+			"unprivileged": platform.User.Unprivileged,
 		},
-	}, nil, defaultProvider)
+	}
+}
+
+// varsForPlatform sets the runtime variables that are available in the
+// input specification runtime checks.
+func varsForPlatform(platform PlatformDetail, defaultProvider string) (*transpiler.Vars, error) {
+	return transpiler.NewVars("", RuntimeFacts(platform), nil, defaultProvider)
 }
 
 func validateRuntimeChecks(
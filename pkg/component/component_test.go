@@ -21,6 +21,7 @@ import (
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/eql"
+	"github.com/elastic/elastic-agent/pkg/limits"
 	"github.com/elastic/go-ucfg"
 
 	"github.com/go-viper/mapstructure/v2"
@@ -706,6 +707,71 @@ func TestToComponents(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:     "Invalid: single input failed to render",
+			Platform: linuxAMD64Platform,
+			Policy: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":    "elasticsearch",
+						"enabled": true,
+					},
+				},
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"type":       "filestream",
+						"id":         "filestream-0",
+						"use_output": "default",
+						"enabled":    true,
+					},
+					map[string]interface{}{
+						"type":                    "filestream",
+						"id":                      "filestream-1",
+						"use_output":              "default",
+						"enabled":                 true,
+						transpiler.RenderErrorKey: "filter failed",
+					},
+				},
+			},
+			Result: []Component{
+				{
+					InputType:  "filestream",
+					OutputType: "elasticsearch",
+					ID:         "filestream-default",
+					InputSpec: &InputRuntimeSpec{
+						InputType:  "filestream",
+						BinaryName: "testbeat",
+						BinaryPath: filepath.Join("..", "..", "specs", "testbeat"),
+					},
+					Units: []Unit{
+						{
+							ID:       "filestream-default",
+							Type:     client.UnitTypeOutput,
+							LogLevel: defaultUnitLogLevel,
+							Config: MustExpectedConfig(map[string]interface{}{
+								"type": "elasticsearch",
+							}),
+						},
+						{
+							ID:       "filestream-default-filestream-0",
+							Type:     client.UnitTypeInput,
+							LogLevel: defaultUnitLogLevel,
+							Config: MustExpectedConfig(map[string]interface{}{
+								"type": "filestream",
+								"id":   "filestream-0",
+							}),
+						},
+						{
+							ID:       "filestream-default-filestream-1",
+							Type:     client.UnitTypeInput,
+							LogLevel: defaultUnitLogLevel,
+							Err:      fmt.Errorf("rendering input failed: %v", "filter failed"),
+						},
+					},
+					RuntimeManager: DefaultRuntimeManager,
+				},
+			},
+		},
 		{
 			Name:     "Invalid: single input failed to decode into config (isolated units)",
 			Platform: linuxAMD64Platform,
@@ -2551,16 +2617,25 @@ func TestPreventionsAreValid(t *testing.T) {
 			"in_default": true,
 		},
 		"runtime": map[string]interface{}{
-			"platform":    "platform",
-			"os":          "os",
-			"arch":        "arch",
-			"native_arch": "native_arch",
-			"family":      "family",
-			"major":       1,
-			"minor":       2,
+			"platform":                  "platform",
+			"os":                        "os",
+			"arch":                      "arch",
+			"native_arch":               "native_arch",
+			"family":                    "family",
+			"major":                     1,
+			"minor":                     2,
+			"kernel_version":            "6.8.0",
+			"kernel_major":              6,
+			"kernel_minor":              8,
+			"memory_total":              1024,
+			"memory_available_at_start": 512,
+			"cgroup_version":            2,
+			"containerized":             false,
+			"container_runtime":         "",
 		},
 		"user": map[string]interface{}{
-			"root": false,
+			"root":         false,
+			"unprivileged": false,
 		},
 	}, nil, "")
 	require.NoError(t, err)
@@ -3132,3 +3207,47 @@ func makeMapStructureErr(t *testing.T) error {
 	}
 	return mapstructure.Decode(data, &output)
 }
+
+func TestPolicyToComponentsResourceLimits(t *testing.T) {
+	policy := map[string]any{
+		"agent": map[string]any{
+			"limits": map[string]any{
+				"resources": map[string]any{
+					"filestream":  map[string]any{"cpu": 1, "memory": "512MB"},
+					"log-default": map[string]any{"open_files": 1024},
+				},
+			},
+		},
+		"outputs": map[string]any{
+			"default": map[string]any{"type": "elasticsearch", "enabled": true},
+		},
+		"inputs": []any{
+			map[string]any{"type": "filestream", "id": "filestream-0", "enabled": true},
+			map[string]any{"type": "log", "id": "log-0", "enabled": true},
+			map[string]any{"type": "system/metrics", "id": "system-0", "enabled": true},
+		},
+	}
+
+	linuxAMD64Platform := PlatformDetail{
+		Platform: Platform{
+			OS:   Linux,
+			Arch: AMD64,
+			GOOS: Linux,
+		},
+	}
+	runtime, err := LoadRuntimeSpecs(filepath.Join("..", "..", "specs"), linuxAMD64Platform, SkipBinaryCheck())
+	require.NoError(t, err)
+
+	result, err := runtime.PolicyToComponents(policy, logp.InfoLevel, nil)
+	require.NoError(t, err)
+
+	resources := make(map[string]*limits.ResourceLimits, len(result))
+	for _, comp := range result {
+		resources[comp.ID] = comp.Resources
+	}
+	assert.Equal(t, map[string]*limits.ResourceLimits{
+		"filestream-default":     {CPU: 1, Memory: "512MB"},
+		"log-default":            {OpenFiles: 1024},
+		"system/metrics-default": nil,
+	}, resources)
+}
@@ -52,6 +52,20 @@ func (c ComponentLimits) AsProto() *proto.ComponentLimits {
 
 type ComponentConfig struct {
 	Limits ComponentLimits
+	// Resources are the resource limits of the components keyed by component ID or input type.
+	Resources map[string]limits.ResourceLimits
+}
+
+// ResourceLimits returns the resource limits for a component, limits set for
+// the component ID take precedence over the ones set for its input type.
+func (c ComponentConfig) ResourceLimits(componentID, inputType string) *limits.ResourceLimits {
+	if r, ok := c.Resources[componentID]; ok && !r.IsZero() {
+		return &r
+	}
+	if r, ok := c.Resources[inputType]; ok && !r.IsZero() {
+		return &r
+	}
+	return nil
 }
 
 func (c ComponentConfig) AsProto() *proto.Component {
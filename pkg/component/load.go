@@ -257,6 +257,23 @@ func (r *RuntimeSpecs) GetInput(inputType string) (InputRuntimeSpec, error) {
 	return runtimeSpec, err
 }
 
+// Platform returns the details of the platform the specifications were loaded for.
+func (r *RuntimeSpecs) Platform() PlatformDetail {
+	return r.platform
+}
+
+// Preventions returns the reason why inputs are prevented from running on this
+// platform by their runtime checks, keyed by input type.
+func (r *RuntimeSpecs) Preventions() map[string]string {
+	preventions := make(map[string]string)
+	for inputType, spec := range r.inputSpecs {
+		if err := validateRuntimeChecks(&spec.Spec.Runtime, r.platform); err != nil {
+			preventions[inputType] = err.Error()
+		}
+	}
+	return preventions
+}
+
 // ServiceSpecs returns only the input specification that are based on the service runtime.
 func (r *RuntimeSpecs) ServiceSpecs() []InputRuntimeSpec {
 	var services []InputRuntimeSpec
@@ -7,11 +7,13 @@ package component
 import (
 	"fmt"
 	goruntime "runtime"
+	"strconv"
 	"strings"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install/pkgmgr"
 
 	"github.com/elastic/go-sysinfo"
+	"github.com/elastic/go-sysinfo/types"
 
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
@@ -105,6 +107,28 @@ func (p Platforms) Exists(platform string) bool {
 // UserDetail provides user specific information on the running platform.
 type UserDetail struct {
 	Root bool
+	// Unprivileged is true when the Elastic Agent is running in unprivileged mode.
+	Unprivileged bool
+}
+
+// HostDetail provides facts about the host the Elastic Agent is running on.
+type HostDetail struct {
+	KernelVersion string
+	KernelMajor   int
+	KernelMinor   int
+
+	// MemoryTotal and MemoryAvailableAtStart are in bytes, zero when unknown.
+	// MemoryAvailableAtStart is sampled once, when the platform details are loaded.
+	MemoryTotal            int
+	MemoryAvailableAtStart int
+
+	// CgroupVersion is 1 or 2 on Linux, zero when cgroups are not available.
+	CgroupVersion int
+
+	Containerized bool
+	// ContainerRuntime is the detected container runtime (docker, podman, containerd, cri-o,
+	// lxc or kubernetes), empty when not detected.
+	ContainerRuntime string
 }
 
 // PlatformDetail is platform that has more detail information about the running platform.
@@ -118,11 +142,20 @@ type PlatformDetail struct {
 
 	IsInstalledViaExternalPkgMgr bool
 	User                         UserDetail
+	Host                         HostDetail
 }
 
 // PlatformModifier can modify the platform details before the runtime specifications are loaded.
 type PlatformModifier func(detail PlatformDetail) PlatformDetail
 
+// WithUnprivileged returns a PlatformModifier that sets whether the Elastic Agent is running unprivileged.
+func WithUnprivileged(unprivileged bool) PlatformModifier {
+	return func(detail PlatformDetail) PlatformDetail {
+		detail.User.Unprivileged = unprivileged
+		return detail
+	}
+}
+
 // LoadPlatformDetail loads the platform details for the current system.
 func LoadPlatformDetail(modifiers ...PlatformModifier) (PlatformDetail, error) {
 	hasRoot, err := utils.HasRoot()
@@ -159,9 +192,51 @@ func LoadPlatformDetail(modifiers ...PlatformModifier) (PlatformDetail, error) {
 			Root: hasRoot,
 		},
 		IsInstalledViaExternalPkgMgr: pkgmgr.InstalledViaExternalPkgMgr(),
+		Host:                         loadHostDetail(info),
 	}
 	for _, modifier := range modifiers {
 		detail = modifier(detail)
 	}
 	return detail, nil
 }
+
+// loadHostDetail gathers the host facts, facts that cannot be determined are left empty.
+func loadHostDetail(info types.Host) HostDetail {
+	hostInfo := info.Info()
+	detail := HostDetail{
+		KernelVersion:    hostInfo.KernelVersion,
+		CgroupVersion:    cgroupVersion(),
+		ContainerRuntime: containerRuntime(),
+	}
+	detail.KernelMajor, detail.KernelMinor = parseKernelVersion(hostInfo.KernelVersion)
+	if hostInfo.Containerized != nil {
+		detail.Containerized = *hostInfo.Containerized
+	}
+	if detail.ContainerRuntime != "" {
+		detail.Containerized = true
+	}
+	if mem, err := info.Memory(); err == nil {
+		detail.MemoryTotal = int(mem.Total)                //nolint:gosec // memory size fits in an int on supported platforms
+		detail.MemoryAvailableAtStart = int(mem.Available) //nolint:gosec // memory size fits in an int on supported platforms
+	}
+	return detail
+}
+
+// parseKernelVersion returns the major and minor version of a kernel version
+// string like 6.8.0-45-generic, zero is returned for the parts that can't be parsed.
+func parseKernelVersion(version string) (major int, minor int) {
+	parts := strings.SplitN(version, ".", 3)
+	numbers := []*int{&major, &minor}
+	for i := 0; i < len(parts) && i < len(numbers); i++ {
+		digits := parts[i]
+		if end := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); end != -1 {
+			digits = digits[:end]
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			break
+		}
+		*numbers[i] = n
+	}
+	return major, minor
+}
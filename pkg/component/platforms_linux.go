@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package component
+
+import (
+	"os"
+	"strings"
+)
+
+const cgroupMountPath = "/sys/fs/cgroup"
+
+// cgroupVersion returns the version of the cgroup hierarchy, zero when cgroups are not mounted.
+func cgroupVersion() int {
+	if _, err := os.Stat(cgroupMountPath + "/cgroup.controllers"); err == nil {
+		// unified hierarchy
+		return 2
+	}
+	if _, err := os.Stat(cgroupMountPath); err == nil {
+		return 1
+	}
+	return 0
+}
+
+// containerRuntime detects the container runtime the Elastic Agent runs in,
+// empty when not running in a container or the runtime is unknown.
+func containerRuntime() string {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman"
+	}
+	// cgroup v1 paths include the runtime, with cgroup v2 namespaces they are just "/"
+	if data, err := os.ReadFile("/proc/1/cgroup"); err == nil {
+		cgroups := string(data)
+		for _, runtime := range []string{"docker", "containerd", "crio", "lxc"} {
+			if strings.Contains(cgroups, runtime) {
+				if runtime == "crio" {
+					return "cri-o"
+				}
+				return runtime
+			}
+		}
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+	return ""
+}
@@ -0,0 +1,17 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux
+
+package component
+
+// cgroupVersion always returns zero, cgroups are only available on Linux.
+func cgroupVersion() int {
+	return 0
+}
+
+// containerRuntime always returns an empty string, container runtimes are only detected on Linux.
+func containerRuntime() string {
+	return ""
+}
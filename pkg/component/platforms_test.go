@@ -15,3 +15,74 @@ func TestLoadPlatformDetail(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, platformDetail)
 }
+
+func TestParseKernelVersion(t *testing.T) {
+	testCases := []struct {
+		version string
+		major   int
+		minor   int
+	}{
+		{version: "6.8.0-45-generic", major: 6, minor: 8},
+		{version: "5.15.153.1-microsoft-standard-WSL2", major: 5, minor: 15},
+		{version: "4.18.0-553.el8_10.x86_64", major: 4, minor: 18},
+		{version: "10.0.20348.2700 (WinBuild.160101.0800)", major: 10, minor: 0},
+		{version: "23.6.0", major: 23, minor: 6},
+		{version: "6", major: 6, minor: 0},
+		{version: "", major: 0, minor: 0},
+		{version: "unknown", major: 0, minor: 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.version, func(t *testing.T) {
+			major, minor := parseKernelVersion(tc.version)
+			assert.Equal(t, tc.major, major)
+			assert.Equal(t, tc.minor, minor)
+		})
+	}
+}
+
+func TestWithUnprivileged(t *testing.T) {
+	platformDetail, err := LoadPlatformDetail(WithUnprivileged(true))
+	assert.NoError(t, err)
+	assert.True(t, platformDetail.User.Unprivileged)
+}
+
+func TestValidateRuntimeChecksHostFacts(t *testing.T) {
+	platform := PlatformDetail{
+		Platform: Platform{OS: Linux, Arch: AMD64, GOOS: Linux},
+		Host: HostDetail{
+			KernelVersion:          "4.18.0-553.el8_10.x86_64",
+			KernelMajor:            4,
+			KernelMinor:            18,
+			MemoryTotal:            2 << 30,
+			MemoryAvailableAtStart: 1 << 30,
+			CgroupVersion:          1,
+			ContainerRuntime:       "docker",
+		},
+		User: UserDetail{Unprivileged: true},
+	}
+
+	testCases := []struct {
+		name      string
+		condition string
+		prevented bool
+	}{
+		{name: "old kernel", condition: "${runtime.kernel_major} < 5", prevented: true},
+		{name: "new kernel", condition: "${runtime.kernel_major} > 5 or (${runtime.kernel_major} == 5 and ${runtime.kernel_minor} >= 10)", prevented: false},
+		{name: "low memory", condition: "${runtime.memory_available_at_start} < 2147483648", prevented: true},
+		{name: "cgroup v1", condition: "${runtime.cgroup_version} == 1", prevented: true},
+		{name: "container runtime", condition: "${runtime.containerized} == true and ${runtime.container_runtime} == 'podman'", prevented: false},
+		{name: "unprivileged", condition: "${user.unprivileged} == true", prevented: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRuntimeChecks(&RuntimeSpec{
+				Preventions: []RuntimePreventionSpec{{Condition: tc.condition, Message: "prevented"}},
+			}, platform)
+			if tc.prevented {
+				assert.ErrorContains(t, err, "prevented")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/core/process"
+	"github.com/elastic/elastic-agent/pkg/limits"
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
@@ -111,6 +112,13 @@ type commandRuntime struct {
 	lastCheckin    time.Time
 	missedCheckins int
 	restartBucket  *rate.Limiter
+
+	// limiter enforces the resource limits of the running process, limitsErr
+	// is set when they could not be applied and limitsBreach when the process
+	// breached them during the last check-in period.
+	limiter      resourceLimiter
+	limitsErr    string
+	limitsBreach string
 }
 
 // newCommandRuntime creates a new command runtime for the provided component.
@@ -181,6 +189,7 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 		case newComp := <-c.compCh:
 			c.current = newComp
 			c.syncLogLevels()
+			c.applyResourceLimits()
 
 			sendExpected := c.state.syncExpected(&newComp)
 			changed := c.state.syncUnits(&newComp)
@@ -249,6 +258,9 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 						c.missedCheckins++
 						c.log.Debugf("Last check-in was: %s, now is: %s. The diff %s is higher than allowed %s.", c.lastCheckin.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano), now.Sub(c.lastCheckin), checkinPeriod)
 					}
+					if c.limiter != nil {
+						c.limitsBreach = c.limiter.breach()
+					}
 					if c.missedCheckins == 0 {
 						c.compState(client.UnitStateHealthy)
 					} else if c.missedCheckins > 0 && c.missedCheckins < maxCheckinMisses {
@@ -337,7 +349,14 @@ func (c *commandRuntime) forceCompState(state client.UnitState, msg string) {
 // compState updates just the component state not all the units.
 func (c *commandRuntime) compState(state client.UnitState) {
 	msg := stateUnknownMessage
-	if state == client.UnitStateHealthy {
+	if state == client.UnitStateHealthy && (c.limitsBreach != "" || c.limitsErr != "") {
+		state = client.UnitStateDegraded
+		if c.limitsBreach != "" {
+			msg = fmt.Sprintf("Degraded: pid '%d' %s", c.proc.PID, c.limitsBreach)
+		} else {
+			msg = fmt.Sprintf("Degraded: pid '%d' %s", c.proc.PID, c.limitsErr)
+		}
+	} else if state == client.UnitStateHealthy {
 		msg = fmt.Sprintf("Healthy: communicating with pid '%d'", c.proc.PID)
 	} else if state == client.UnitStateDegraded {
 		if c.missedCheckins == 1 {
@@ -395,15 +414,29 @@ func (c *commandRuntime) start(comm Communicator) error {
 	c.lastCheckin = time.Time{}
 	c.missedCheckins = 0
 
+	cmdOpts := []process.CmdOption{attachOutErr(c.logStd, c.logErr), dirPath(workDir)}
+	c.prepareResourceLimits()
+	if c.limiter != nil {
+		if opt := c.limiter.cmdOption(); opt != nil {
+			cmdOpts = append(cmdOpts, opt)
+		}
+	}
+
 	proc, err := process.Start(path,
 		process.WithArgs(args),
 		process.WithEnv(env),
-		process.WithCmdOptions(attachOutErr(c.logStd, c.logErr), dirPath(workDir)))
+		process.WithCmdOptions(cmdOpts...))
 	if err != nil {
+		c.releaseResourceLimits()
 		return err
 	}
 
 	c.proc = proc
+	if c.limiter != nil {
+		if err := c.limiter.started(proc.PID); err != nil {
+			c.resourceLimitsFailed(err)
+		}
+	}
 	c.forceCompState(client.UnitStateStarting, fmt.Sprintf("Starting: spawned pid '%d'", c.proc.PID))
 	c.startWatcher(proc, comm)
 	return nil
@@ -460,14 +493,22 @@ func (c *commandRuntime) startWatcher(info *process.Info, comm Communicator) {
 }
 
 func (c *commandRuntime) handleProc(state *os.ProcessState) bool {
+	limitsBreach := c.releaseResourceLimits()
 	switch c.actionState {
 	case actionStart:
+		c.state.Restarts++
 		if c.restartBucket != nil && c.restartBucket.Allow() {
 			stopMsg := fmt.Sprintf("Suppressing FAILED state due to restart for '%d' exited with code '%d'", state.Pid(), state.ExitCode())
+			if limitsBreach != "" {
+				stopMsg += ": " + limitsBreach
+			}
 			c.forceCompState(client.UnitStateStopped, stopMsg)
 		} else {
 			// report failure only if bucket is full of restart events
 			stopMsg := fmt.Sprintf("Failed: pid '%d' exited with code '%d'", state.Pid(), state.ExitCode())
+			if limitsBreach != "" {
+				stopMsg += ": " + limitsBreach
+			}
 			c.forceCompState(client.UnitStateFailed, stopMsg)
 		}
 		return true
@@ -483,6 +524,64 @@ func (c *commandRuntime) handleProc(state *os.ProcessState) bool {
 	return false
 }
 
+// prepareResourceLimits prepares the enforcement of the resource limits of
+// the component on the process about to start, a failure to do so degrades the
+// component.
+func (c *commandRuntime) prepareResourceLimits() {
+	c.limitsErr = ""
+	limiter, err := newResourceLimiter(c.log, c.current.ID, c.resourceLimits())
+	if err != nil {
+		c.resourceLimitsFailed(err)
+		return
+	}
+	c.limiter = limiter
+}
+
+// applyResourceLimits enforces the resource limits of the component on its
+// running process, a failure to do so degrades the component.
+func (c *commandRuntime) applyResourceLimits() {
+	if c.proc == nil {
+		return
+	}
+	if c.limiter == nil {
+		// the limits couldn't be prepared when the process started
+		if c.resourceLimits().IsZero() {
+			c.limitsErr = ""
+		}
+		return
+	}
+	c.limitsErr = ""
+	if err := c.limiter.update(c.resourceLimits()); err != nil {
+		c.resourceLimitsFailed(err)
+	}
+}
+
+func (c *commandRuntime) resourceLimits() limits.ResourceLimits {
+	if c.current.Resources == nil {
+		return limits.ResourceLimits{}
+	}
+	return *c.current.Resources
+}
+
+func (c *commandRuntime) resourceLimitsFailed(err error) {
+	c.limitsErr = fmt.Sprintf("resource limits not applied: %s", err)
+	c.log.Warnf("Failed to apply resource limits to component %s: %s", c.current.ID, err)
+}
+
+// releaseResourceLimits cleans up the resource limits of an exited process and
+// returns the limit it breached, if any.
+func (c *commandRuntime) releaseResourceLimits() string {
+	c.limitsErr = ""
+	c.limitsBreach = ""
+	if c.limiter == nil {
+		return ""
+	}
+	breach := c.limiter.breach()
+	c.limiter.cleanup()
+	c.limiter = nil
+	return breach
+}
+
 func (c *commandRuntime) workDirPath() string {
 	return filepath.Join(paths.Run(), c.current.ID)
 }
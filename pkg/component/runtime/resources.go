@@ -0,0 +1,26 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"github.com/elastic/elastic-agent/pkg/core/process"
+	"github.com/elastic/elastic-agent/pkg/limits"
+)
+
+// resourceLimiter enforces the resource limits of a component process.
+type resourceLimiter interface {
+	// cmdOption returns the option starting the process with the limits
+	// enforced, nil when the process doesn't need one.
+	cmdOption() process.CmdOption
+	// started applies the limits to the started process with the given pid.
+	started(pid int) error
+	// update applies new resource limits to the running process.
+	update(res limits.ResourceLimits) error
+	// breach returns a description of the limit the process is breaching,
+	// empty when no limit is breached.
+	breach() string
+	// cleanup releases what was used to enforce the limits once the process exited.
+	cleanup()
+}
@@ -0,0 +1,408 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/core/process"
+	"github.com/elastic/elastic-agent/pkg/limits"
+)
+
+const (
+	// cpuPeriod is the cgroup CPU period in microseconds the CPU quota is computed for.
+	cpuPeriod = 100000
+
+	componentsCgroup = "components"
+	agentCgroup      = "agent"
+
+	// memoryRecoveryRatio is the ratio of the memory limit the usage must go
+	// under for a memory limit breach to end.
+	memoryRecoveryRatio = 0.9
+)
+
+var (
+	// cgroupMountPath is where the cgroup v2 unified hierarchy is mounted.
+	cgroupMountPath = "/sys/fs/cgroup"
+	// selfCgroupPath is the file describing the cgroup of the agent process.
+	selfCgroupPath = "/proc/self/cgroup"
+	// procPath is where the proc filesystem is mounted.
+	procPath = "/proc"
+	// systemdDelegate returns true when the Delegate property of the systemd
+	// unit is enabled.
+	systemdDelegate = unitDelegated
+)
+
+// cgroupLimiter enforces the CPU and memory limits of a component through a
+// cgroup v2 created under the cgroup of the agent, and the open files limit
+// through the process rlimit.
+type cgroupLimiter struct {
+	pid  int
+	path string
+	// dir is the cgroup directory the process is started in, it's only open
+	// until the process is started.
+	dir *os.File
+
+	limit    limits.ResourceLimits
+	memEvent map[string]uint64
+	breached string
+}
+
+// newResourceLimiter prepares the enforcement of the resource limits of a
+// component, the cgroup enforcing the CPU and memory limits is created before
+// the process starts directly in it.
+func newResourceLimiter(_ *logger.Logger, componentID string, res limits.ResourceLimits) (resourceLimiter, error) {
+	l := &cgroupLimiter{}
+	if res.CPU > 0 || res.Memory != "" {
+		path, err := createComponentCgroup(componentID)
+		if err != nil {
+			return nil, err
+		}
+		l.path = path
+	}
+	if err := l.update(res); err != nil {
+		l.cleanup()
+		return nil, err
+	}
+	if l.path != "" {
+		dir, err := os.Open(l.path)
+		if err != nil {
+			l.cleanup()
+			return nil, fmt.Errorf("failed to open cgroup %s: %w", l.path, err)
+		}
+		l.dir = dir
+		l.memEvent, _ = readMemoryEvents(l.path)
+	}
+	return l, nil
+}
+
+// cmdOption starts the process in the cgroup of the component, so it's
+// limited from its first instruction.
+func (l *cgroupLimiter) cmdOption() process.CmdOption {
+	if l.dir == nil {
+		return nil
+	}
+	return func(cmd *exec.Cmd) error {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(l.dir.Fd())
+		return nil
+	}
+}
+
+func (l *cgroupLimiter) started(pid int) error {
+	l.pid = pid
+	if l.dir != nil {
+		_ = l.dir.Close()
+		l.dir = nil
+	}
+	return l.setOpenFiles(l.limit.OpenFiles)
+}
+
+func (l *cgroupLimiter) update(res limits.ResourceLimits) error {
+	if (res.CPU > 0 || res.Memory != "") && l.path == "" {
+		// the cgroup is only created when the process starts
+		return errors.New("cpu and memory limits added to a running component apply on its next restart")
+	}
+	if l.path != "" {
+		cpuMax := fmt.Sprintf("max %d", cpuPeriod)
+		if res.CPU > 0 {
+			cpuMax = fmt.Sprintf("%d %d", int64(res.CPU*cpuPeriod), cpuPeriod)
+		}
+		if err := writeCgroupFile(l.path, "cpu.max", cpuMax); err != nil {
+			return fmt.Errorf("failed to set cpu limit: %w", err)
+		}
+
+		memory, err := res.MemoryBytes()
+		if err != nil {
+			return err
+		}
+		memoryMax := "max"
+		if memory > 0 {
+			memoryMax = strconv.FormatInt(memory, 10)
+		}
+		if err := writeCgroupFile(l.path, "memory.max", memoryMax); err != nil {
+			return fmt.Errorf("failed to set memory limit: %w", err)
+		}
+	}
+	if err := l.setOpenFiles(res.OpenFiles); err != nil {
+		return err
+	}
+	l.limit = res
+	return nil
+}
+
+// setOpenFiles sets the open files limit of the process once it's started.
+func (l *cgroupLimiter) setOpenFiles(openFiles uint64) error {
+	if openFiles == 0 || l.pid == 0 {
+		return nil
+	}
+	rlimit := unix.Rlimit{Cur: openFiles, Max: openFiles}
+	if err := unix.Prlimit(l.pid, unix.RLIMIT_NOFILE, &rlimit, nil); err != nil {
+		return fmt.Errorf("failed to set open files limit: %w", err)
+	}
+	return nil
+}
+
+// breach reports the memory limit as breached from the time the cgroup hits
+// it until the memory usage of the cgroup goes back under memoryRecoveryRatio
+// of the limit.
+func (l *cgroupLimiter) breach() string {
+	if l.path == "" {
+		return ""
+	}
+	events, err := readMemoryEvents(l.path)
+	if err != nil {
+		return l.breached
+	}
+	previous := l.memEvent
+	l.memEvent = events
+	switch {
+	case events["oom_kill"] > previous["oom_kill"]:
+		l.breached = fmt.Sprintf("memory limit of %s exceeded, killed by the OOM killer", l.limit.Memory)
+	case events["max"] > previous["max"]:
+		l.breached = fmt.Sprintf("memory limit of %s reached", l.limit.Memory)
+	case l.breached != "" && !l.memoryRecovered():
+		// still breached
+	default:
+		l.breached = ""
+	}
+	return l.breached
+}
+
+// memoryRecovered returns true when the memory usage of the cgroup is under
+// memoryRecoveryRatio of the limit.
+func (l *cgroupLimiter) memoryRecovered() bool {
+	limit, err := l.limit.MemoryBytes()
+	if err != nil || limit == 0 {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(l.path, "memory.current"))
+	if err != nil {
+		return true
+	}
+	current, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return true
+	}
+	return float64(current) < float64(limit)*memoryRecoveryRatio
+}
+
+func (l *cgroupLimiter) cleanup() {
+	if l.dir != nil {
+		_ = l.dir.Close()
+		l.dir = nil
+	}
+	if l.path != "" {
+		// only succeeds once all the processes in the cgroup exited
+		_ = os.Remove(l.path)
+	}
+}
+
+// createComponentCgroup creates the cgroup of a component under the cgroup of the agent.
+func createComponentCgroup(componentID string) (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupMountPath, "cgroup.controllers")); err != nil {
+		return "", errors.New("cpu and memory limits require cgroup v2")
+	}
+	parent, err := selfCgroup()
+	if err != nil {
+		return "", err
+	}
+	// the cgroups of the components are only managed in a cgroup delegated to
+	// the agent, the rest of the hierarchy belongs to the service manager
+	if err := checkDelegation(parent); err != nil {
+		return "", err
+	}
+
+	components := filepath.Join(parent, componentsCgroup)
+	if err := os.MkdirAll(components, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cgroup %s: %w", components, err)
+	}
+	if err := enableControllers(parent); err != nil {
+		return "", err
+	}
+	if err := enableControllers(components); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(components, strings.ReplaceAll(componentID, "/", "_"))
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cgroup %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// enableControllers enables the cpu and memory controllers for the children of a cgroup.
+func enableControllers(path string) error {
+	err := writeCgroupFile(path, "cgroup.subtree_control", "+cpu +memory")
+	if errors.Is(err, syscall.EBUSY) {
+		// cgroup v2 doesn't allow enabling controllers for the children of a
+		// cgroup that has processes, move them to a leaf cgroup first
+		if err := moveProcsToLeaf(path); err != nil {
+			return err
+		}
+		err = writeCgroupFile(path, "cgroup.subtree_control", "+cpu +memory")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to enable cpu and memory controllers for cgroup %s: %w", path, err)
+	}
+	return nil
+}
+
+// moveProcsToLeaf moves the processes of a cgroup to its agent leaf cgroup.
+// Nothing is moved when the cgroup holds processes that aren't the agent or
+// processes it started.
+func moveProcsToLeaf(path string) error {
+	procs, err := os.ReadFile(filepath.Join(path, "cgroup.procs"))
+	if err != nil {
+		return fmt.Errorf("failed to read processes of cgroup %s: %w", path, err)
+	}
+	pids := strings.Fields(string(procs))
+	for _, pid := range pids {
+		if !isAgentProcess(pid) {
+			return fmt.Errorf("cgroup %s holds the process with pid '%s' that isn't started by the agent", path, pid)
+		}
+	}
+
+	leaf := filepath.Join(path, agentCgroup)
+	if err := os.MkdirAll(leaf, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup %s: %w", leaf, err)
+	}
+	for _, pid := range pids {
+		// processes can exit while they're being moved
+		if err := writeCgroupFile(leaf, "cgroup.procs", pid); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return fmt.Errorf("failed to move pid '%s' to cgroup %s: %w", pid, leaf, err)
+		}
+	}
+	return nil
+}
+
+// checkDelegation returns an error when the cgroup of the agent isn't
+// delegated to it. The cgroup is delegated when systemd marks it as such, when
+// it's owned by the unprivileged user the agent runs as or when the Delegate
+// property of the systemd service of the agent is enabled. Either way the agent
+// must be able to enable controllers for its children.
+func checkDelegation(path string) error {
+	if err := unix.Access(filepath.Join(path, "cgroup.subtree_control"), unix.W_OK); err != nil {
+		return fmt.Errorf("cgroup %s is not delegated to the agent, its cgroup.subtree_control is not writable: %w", path, err)
+	}
+	for _, attr := range []string{"trusted.delegate", "user.delegate"} {
+		value := make([]byte, 1)
+		if n, err := unix.Getxattr(path, attr, value); err == nil && n == 1 && value[0] == '1' {
+			return nil
+		}
+	}
+	var stat unix.Stat_t
+	if euid := os.Geteuid(); euid != 0 && unix.Stat(path, &stat) == nil && int(stat.Uid) == euid {
+		return nil
+	}
+	if unit := filepath.Base(path); strings.HasSuffix(unit, ".service") && systemdDelegate(unit) {
+		return nil
+	}
+	return fmt.Errorf("cgroup %s is not delegated to the agent, set Delegate=yes on the service of the agent", path)
+}
+
+// unitDelegated returns true when the Delegate property of the systemd unit is enabled.
+func unitDelegated(unit string) bool {
+	out, err := exec.Command("systemctl", "show", "--property=Delegate", "--value", unit).Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(out)) == "yes"
+}
+
+// isAgentProcess returns true when the process is the agent or one of the
+// processes it started, directly or not.
+func isAgentProcess(pid string) bool {
+	self := os.Getpid()
+	for range 64 {
+		n, err := strconv.Atoi(pid)
+		if err != nil || n <= 1 {
+			return false
+		}
+		if n == self {
+			return true
+		}
+		pid, err = parentPid(n)
+		if err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// parentPid returns the pid of the parent of a process.
+func parentPid(pid int) (string, error) {
+	data, err := os.ReadFile(filepath.Join(procPath, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return "", err
+	}
+	// the command name can hold spaces and parentheses, the fields after it
+	// are the state and the parent pid
+	end := bytes.LastIndexByte(data, ')')
+	if end == -1 {
+		return "", fmt.Errorf("invalid stat of process %d", pid)
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 2 {
+		return "", fmt.Errorf("invalid stat of process %d", pid)
+	}
+	return fields[1], nil
+}
+
+// selfCgroup returns the path of the cgroup v2 of the agent process.
+func selfCgroup() (string, error) {
+	data, err := os.ReadFile(selfCgroupPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the cgroup of the agent: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// the unified hierarchy has the "0::<path>" format
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			path = strings.TrimSuffix(path, "/"+agentCgroup)
+			return filepath.Join(cgroupMountPath, path), nil
+		}
+	}
+	return "", errors.New("the agent is not running in a cgroup v2")
+}
+
+func readMemoryEvents(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(filepath.Join(path, "memory.events"))
+	if err != nil {
+		return nil, err
+	}
+	events := make(map[string]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64); err == nil {
+			events[key] = n
+		}
+	}
+	return events, nil
+}
+
+func writeCgroupFile(path, name, value string) error {
+	return os.WriteFile(filepath.Join(path, name), []byte(value), 0644)
+}
@@ -0,0 +1,171 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/pkg/limits"
+)
+
+// fakeCgroupTree points the limiter to a directory mimicking a cgroup v2
+// hierarchy with the agent running in /elastic-agent.service.
+func fakeCgroupTree(t *testing.T) string {
+	root := t.TempDir()
+	agentPath := filepath.Join(root, "elastic-agent.service")
+	require.NoError(t, os.MkdirAll(agentPath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("cpu memory"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(agentPath, "cgroup.subtree_control"), nil, 0644))
+
+	selfCgroup := filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, os.WriteFile(selfCgroup, []byte("0::/elastic-agent.service\n"), 0644))
+
+	origMount, origSelf, origDelegate := cgroupMountPath, selfCgroupPath, systemdDelegate
+	cgroupMountPath, selfCgroupPath = root, selfCgroup
+	systemdDelegate = func(unit string) bool {
+		return unit == "elastic-agent.service"
+	}
+	t.Cleanup(func() {
+		cgroupMountPath, selfCgroupPath, systemdDelegate = origMount, origSelf, origDelegate
+	})
+	return agentPath
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestCgroupLimiter(t *testing.T) {
+	agentPath := fakeCgroupTree(t)
+	componentPath := filepath.Join(agentPath, componentsCgroup, "beat_metrics-monitoring")
+
+	limiter, err := newResourceLimiter(nil, "beat/metrics-monitoring", limits.ResourceLimits{CPU: 1.5, Memory: "256MB"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "+cpu +memory", readFile(t, filepath.Join(agentPath, "cgroup.subtree_control")))
+	assert.Equal(t, "+cpu +memory", readFile(t, filepath.Join(agentPath, componentsCgroup, "cgroup.subtree_control")))
+	assert.Equal(t, "150000 100000", readFile(t, filepath.Join(componentPath, "cpu.max")))
+	assert.Equal(t, strconv.Itoa(256*1024*1024), readFile(t, filepath.Join(componentPath, "memory.max")))
+
+	// the process is started directly in the cgroup of the component
+	opt := limiter.cmdOption()
+	require.NotNil(t, opt)
+	cmd := exec.Command("true")
+	require.NoError(t, opt(cmd))
+	require.NotNil(t, cmd.SysProcAttr)
+	assert.True(t, cmd.SysProcAttr.UseCgroupFD)
+	dir, err := os.Stat(fmt.Sprintf("/proc/self/fd/%d", cmd.SysProcAttr.CgroupFD))
+	require.NoError(t, err)
+	expected, err := os.Stat(componentPath)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(expected, dir), "cgroup fd must refer to the cgroup of the component")
+	assert.NoFileExists(t, filepath.Join(componentPath, "cgroup.procs"), "the process must not be moved to the cgroup")
+
+	require.NoError(t, limiter.started(os.Getpid()))
+	assert.Nil(t, limiter.cmdOption(), "the cgroup is only open until the process starts")
+
+	// no memory events yet
+	assert.Empty(t, limiter.breach())
+
+	memoryEvents := filepath.Join(componentPath, "memory.events")
+	memoryCurrent := filepath.Join(componentPath, "memory.current")
+	require.NoError(t, os.WriteFile(memoryEvents, []byte("low 0\nhigh 0\nmax 3\noom 0\noom_kill 0\n"), 0644))
+	require.NoError(t, os.WriteFile(memoryCurrent, []byte(strconv.Itoa(256*1024*1024)), 0644))
+	assert.Equal(t, "memory limit of 256MB reached", limiter.breach())
+	assert.Equal(t, "memory limit of 256MB reached", limiter.breach(), "breach lasts while the memory usage is at the limit")
+
+	require.NoError(t, os.WriteFile(memoryCurrent, []byte(strconv.Itoa(128*1024*1024)), 0644))
+	assert.Empty(t, limiter.breach(), "breach ends once the memory usage goes down")
+
+	require.NoError(t, os.WriteFile(memoryEvents, []byte("low 0\nhigh 0\nmax 4\noom 1\noom_kill 1\n"), 0644))
+	assert.Equal(t, "memory limit of 256MB exceeded, killed by the OOM killer", limiter.breach())
+
+	// removing the limits resets them to max
+	require.NoError(t, limiter.update(limits.ResourceLimits{}))
+	assert.Equal(t, "max 100000", readFile(t, filepath.Join(componentPath, "cpu.max")))
+	assert.Equal(t, "max", readFile(t, filepath.Join(componentPath, "memory.max")))
+}
+
+func TestCgroupLimiterOpenFilesOnly(t *testing.T) {
+	agentPath := fakeCgroupTree(t)
+
+	var current unix.Rlimit
+	require.NoError(t, unix.Getrlimit(unix.RLIMIT_NOFILE, &current))
+
+	limiter, err := newResourceLimiter(nil, "filestream-default", limits.ResourceLimits{OpenFiles: current.Cur})
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(agentPath, componentsCgroup), "no cgroup needed for open files")
+	assert.Nil(t, limiter.cmdOption())
+	require.NoError(t, limiter.started(os.Getpid()))
+
+	err = limiter.update(limits.ResourceLimits{OpenFiles: current.Cur, Memory: "1GB"})
+	assert.ErrorContains(t, err, "apply on its next restart")
+}
+
+func TestSelfCgroupStripsAgentLeaf(t *testing.T) {
+	agentPath := fakeCgroupTree(t)
+	require.NoError(t, os.WriteFile(selfCgroupPath, []byte("0::/elastic-agent.service/agent\n"), 0644))
+
+	path, err := selfCgroup()
+	require.NoError(t, err)
+	assert.Equal(t, agentPath, path)
+}
+
+func TestCgroupLimiterWithoutDelegation(t *testing.T) {
+	agentPath := fakeCgroupTree(t)
+	systemdDelegate = func(string) bool { return false }
+	if os.Geteuid() != 0 {
+		// a cgroup owned by the unprivileged agent user is delegated
+		t.Skip("the cgroup of the test is owned by the user running it")
+	}
+
+	_, err := newResourceLimiter(nil, "filestream-default", limits.ResourceLimits{Memory: "1GB"})
+	assert.ErrorContains(t, err, "is not delegated to the agent")
+	assert.NoDirExists(t, filepath.Join(agentPath, componentsCgroup), "no cgroup is created without delegation")
+	assert.Empty(t, readFile(t, filepath.Join(agentPath, "cgroup.subtree_control")), "no controller is enabled without delegation")
+}
+
+func TestMoveProcsToLeaf(t *testing.T) {
+	agentPath := fakeCgroupTree(t)
+
+	cmd := exec.Command("sleep", "60")
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	t.Run("processes of the agent are moved", func(t *testing.T) {
+		procs := fmt.Sprintf("%d\n%d\n", os.Getpid(), cmd.Process.Pid)
+		require.NoError(t, os.WriteFile(filepath.Join(agentPath, "cgroup.procs"), []byte(procs), 0644))
+
+		require.NoError(t, moveProcsToLeaf(agentPath))
+		// the fake cgroup.procs file only keeps the last pid written
+		assert.Equal(t, strconv.Itoa(cmd.Process.Pid), readFile(t, filepath.Join(agentPath, agentCgroup, "cgroup.procs")))
+	})
+
+	t.Run("nothing is moved when another process is in the cgroup", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(filepath.Join(agentPath, agentCgroup)))
+		procs := fmt.Sprintf("%d\n%d\n", os.Getpid(), os.Getppid())
+		require.NoError(t, os.WriteFile(filepath.Join(agentPath, "cgroup.procs"), []byte(procs), 0644))
+
+		err := moveProcsToLeaf(agentPath)
+		assert.ErrorContains(t, err, "isn't started by the agent")
+		assert.NoDirExists(t, filepath.Join(agentPath, agentCgroup))
+	})
+}
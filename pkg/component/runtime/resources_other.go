@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux
+
+package runtime
+
+import (
+	"sync"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/core/process"
+	"github.com/elastic/elastic-agent/pkg/limits"
+)
+
+// unsupportedLimitsWarning logs that the resource limits are ignored once for all the components.
+var unsupportedLimitsWarning sync.Once
+
+// noopLimiter ignores the resource limits, they are only enforced on Linux.
+type noopLimiter struct {
+	log *logger.Logger
+}
+
+// newResourceLimiter returns a limiter ignoring the resource limits, they are only enforced on Linux.
+func newResourceLimiter(log *logger.Logger, _ string, res limits.ResourceLimits) (resourceLimiter, error) {
+	l := &noopLimiter{log: log}
+	return l, l.update(res)
+}
+
+func (l *noopLimiter) cmdOption() process.CmdOption {
+	return nil
+}
+
+func (l *noopLimiter) started(_ int) error {
+	return nil
+}
+
+func (l *noopLimiter) update(res limits.ResourceLimits) error {
+	if !res.IsZero() {
+		unsupportedLimitsWarning.Do(func() {
+			l.log.Warn("Resource limits are only enforced on Linux, the resource limits of the components are ignored")
+		})
+	}
+	return nil
+}
+
+func (l *noopLimiter) breach() string {
+	return ""
+}
+
+func (l *noopLimiter) cleanup() {}
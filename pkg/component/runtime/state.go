@@ -80,6 +80,10 @@ type ComponentState struct {
 	// of the endpoint service. If you need the PID for beats, use the coordinator/communicator
 	Pid uint64
 
+	// Restarts is the number of times the process of the component exited
+	// while it was expected to be running.
+	Restarts uint64 `yaml:"restarts,omitempty"`
+
 	// internal
 	expectedUnits map[ComponentUnitKey]expectedUnitState
 
@@ -140,6 +140,13 @@ type AgentStateInfo struct {
 	IsManaged    bool   `json:"is_managed" yaml:"is_managed"`
 }
 
+// UpgradeCheck is the outcome of a check performed by an upgrade dry run.
+type UpgradeCheck struct {
+	Name    string `json:"name" yaml:"name"`
+	Passed  bool   `json:"passed" yaml:"passed"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
 // AgentState is the current state of the Elastic Agent.
 type AgentState struct {
 	Info           AgentStateInfo         `json:"info" yaml:"info"`
@@ -206,6 +213,12 @@ type Client interface {
 	Restart(ctx context.Context) error
 	// Upgrade triggers upgrade of the current running daemon.
 	Upgrade(ctx context.Context, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string) (string, error)
+	// UpgradeDryRun runs the checks of an upgrade of the current running daemon without upgrading it.
+	UpgradeDryRun(ctx context.Context, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string) ([]UpgradeCheck, error)
+	// Rollback switches the current running daemon to a previous version retained on disk.
+	Rollback(ctx context.Context, version string) (string, error)
+	// RotateKey replaces the key the current running daemon encrypts its configuration and state with.
+	RotateKey(ctx context.Context) (uint32, error)
 	// DiagnosticAgent gathers diagnostics information for the running Elastic Agent.
 	DiagnosticAgent(ctx context.Context, additionalDiags []AdditionalMetrics) ([]DiagnosticFileResult, error)
 	// DiagnosticUnits gathers diagnostics information from specific units (or all if non are provided).
@@ -352,6 +365,60 @@ func (c *client) Upgrade(ctx context.Context, version string, sourceURI string,
 	return res.Version, nil
 }
 
+// UpgradeDryRun runs the checks of an upgrade of the current running daemon without upgrading it.
+// The checks stop at the first failure, a failed check is not reported as an error.
+func (c *client) UpgradeDryRun(ctx context.Context, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string) ([]UpgradeCheck, error) {
+	res, err := c.client.UpgradeDryRun(ctx, &cproto.UpgradeRequest{
+		Version:        version,
+		SourceURI:      sourceURI,
+		SkipVerify:     skipVerify,
+		PgpBytes:       pgpBytes,
+		SkipDefaultPgp: skipDefaultPgp,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status == cproto.ActionStatus_FAILURE && len(res.Checks) == 0 {
+		return nil, errors.New(res.Error)
+	}
+	checks := make([]UpgradeCheck, 0, len(res.Checks))
+	for _, check := range res.Checks {
+		checks = append(checks, UpgradeCheck{
+			Name:    check.Name,
+			Passed:  check.Passed,
+			Message: check.Message,
+		})
+	}
+	return checks, nil
+}
+
+// Rollback switches the current running daemon to a previous version retained on disk.
+func (c *client) Rollback(ctx context.Context, version string) (string, error) {
+	res, err := c.client.Rollback(ctx, &cproto.RollbackRequest{
+		Version: version,
+	})
+	if err != nil {
+		return "", err
+	}
+	if res.Status == cproto.ActionStatus_FAILURE {
+		return "", errors.New(res.Error)
+	}
+	return res.Version, nil
+}
+
+// RotateKey replaces the key the current running daemon encrypts its configuration and state with, it returns
+// the version of the new key.
+func (c *client) RotateKey(ctx context.Context) (uint32, error) {
+	res, err := c.client.RotateKey(ctx, &cproto.RotateKeyRequest{})
+	if err != nil {
+		return 0, err
+	}
+	if res.Status == cproto.ActionStatus_FAILURE {
+		return 0, errors.New(res.Error)
+	}
+	return res.Version, nil
+}
+
 // DiagnosticAgent gathers diagnostics information for the running Elastic Agent.
 func (c *client) DiagnosticAgent(ctx context.Context, additionalMetrics []AdditionalMetrics) ([]DiagnosticFileResult, error) {
 	resp, err := c.client.DiagnosticAgent(ctx, &cproto.DiagnosticAgentRequest{AdditionalMetrics: additionalMetrics})
@@ -29,6 +29,9 @@ const (
 	ElasticAgentControl_StateWatch_FullMethodName           = "/cproto.ElasticAgentControl/StateWatch"
 	ElasticAgentControl_Restart_FullMethodName              = "/cproto.ElasticAgentControl/Restart"
 	ElasticAgentControl_Upgrade_FullMethodName              = "/cproto.ElasticAgentControl/Upgrade"
+	ElasticAgentControl_UpgradeDryRun_FullMethodName        = "/cproto.ElasticAgentControl/UpgradeDryRun"
+	ElasticAgentControl_Rollback_FullMethodName             = "/cproto.ElasticAgentControl/Rollback"
+	ElasticAgentControl_RotateKey_FullMethodName            = "/cproto.ElasticAgentControl/RotateKey"
 	ElasticAgentControl_DiagnosticAgent_FullMethodName      = "/cproto.ElasticAgentControl/DiagnosticAgent"
 	ElasticAgentControl_DiagnosticUnits_FullMethodName      = "/cproto.ElasticAgentControl/DiagnosticUnits"
 	ElasticAgentControl_DiagnosticComponents_FullMethodName = "/cproto.ElasticAgentControl/DiagnosticComponents"
@@ -52,6 +55,13 @@ type ElasticAgentControlClient interface {
 	Restart(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RestartResponse, error)
 	// Upgrade starts the upgrade process of Elastic Agent.
 	Upgrade(ctx context.Context, in *UpgradeRequest, opts ...grpc.CallOption) (*UpgradeResponse, error)
+	// UpgradeDryRun runs every check of the upgrade process, including downloading, verifying and
+	// unpacking the package, without upgrading Elastic Agent.
+	UpgradeDryRun(ctx context.Context, in *UpgradeRequest, opts ...grpc.CallOption) (*UpgradeDryRunResponse, error)
+	// Rollback switches the Elastic Agent to a previous version retained on disk and restarts it.
+	Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackResponse, error)
+	// RotateKey replaces the key the Elastic Agent encrypts its configuration and state with.
+	RotateKey(ctx context.Context, in *RotateKeyRequest, opts ...grpc.CallOption) (*RotateKeyResponse, error)
 	// Gather diagnostic information for the running Elastic Agent.
 	DiagnosticAgent(ctx context.Context, in *DiagnosticAgentRequest, opts ...grpc.CallOption) (*DiagnosticAgentResponse, error)
 	// Gather diagnostic information for the running units.
@@ -134,6 +144,36 @@ func (c *elasticAgentControlClient) Upgrade(ctx context.Context, in *UpgradeRequ
 	return out, nil
 }
 
+func (c *elasticAgentControlClient) UpgradeDryRun(ctx context.Context, in *UpgradeRequest, opts ...grpc.CallOption) (*UpgradeDryRunResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpgradeDryRunResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_UpgradeDryRun_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) Rollback(ctx context.Context, in *RollbackRequest, opts ...grpc.CallOption) (*RollbackResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RollbackResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_Rollback_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) RotateKey(ctx context.Context, in *RotateKeyRequest, opts ...grpc.CallOption) (*RotateKeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RotateKeyResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_RotateKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *elasticAgentControlClient) DiagnosticAgent(ctx context.Context, in *DiagnosticAgentRequest, opts ...grpc.CallOption) (*DiagnosticAgentResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(DiagnosticAgentResponse)
@@ -209,6 +249,13 @@ type ElasticAgentControlServer interface {
 	Restart(context.Context, *Empty) (*RestartResponse, error)
 	// Upgrade starts the upgrade process of Elastic Agent.
 	Upgrade(context.Context, *UpgradeRequest) (*UpgradeResponse, error)
+	// UpgradeDryRun runs every check of the upgrade process, including downloading, verifying and
+	// unpacking the package, without upgrading Elastic Agent.
+	UpgradeDryRun(context.Context, *UpgradeRequest) (*UpgradeDryRunResponse, error)
+	// Rollback switches the Elastic Agent to a previous version retained on disk and restarts it.
+	Rollback(context.Context, *RollbackRequest) (*RollbackResponse, error)
+	// RotateKey replaces the key the Elastic Agent encrypts its configuration and state with.
+	RotateKey(context.Context, *RotateKeyRequest) (*RotateKeyResponse, error)
 	// Gather diagnostic information for the running Elastic Agent.
 	DiagnosticAgent(context.Context, *DiagnosticAgentRequest) (*DiagnosticAgentResponse, error)
 	// Gather diagnostic information for the running units.
@@ -247,6 +294,15 @@ func (UnimplementedElasticAgentControlServer) Restart(context.Context, *Empty) (
 func (UnimplementedElasticAgentControlServer) Upgrade(context.Context, *UpgradeRequest) (*UpgradeResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Upgrade not implemented")
 }
+func (UnimplementedElasticAgentControlServer) UpgradeDryRun(context.Context, *UpgradeRequest) (*UpgradeDryRunResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpgradeDryRun not implemented")
+}
+func (UnimplementedElasticAgentControlServer) Rollback(context.Context, *RollbackRequest) (*RollbackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Rollback not implemented")
+}
+func (UnimplementedElasticAgentControlServer) RotateKey(context.Context, *RotateKeyRequest) (*RotateKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateKey not implemented")
+}
 func (UnimplementedElasticAgentControlServer) DiagnosticAgent(context.Context, *DiagnosticAgentRequest) (*DiagnosticAgentResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DiagnosticAgent not implemented")
 }
@@ -363,6 +419,60 @@ func _ElasticAgentControl_Upgrade_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ElasticAgentControl_UpgradeDryRun_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpgradeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).UpgradeDryRun(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_UpgradeDryRun_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).UpgradeDryRun(ctx, req.(*UpgradeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_Rollback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).Rollback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_Rollback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).Rollback(ctx, req.(*RollbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_RotateKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).RotateKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_RotateKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).RotateKey(ctx, req.(*RotateKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ElasticAgentControl_DiagnosticAgent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DiagnosticAgentRequest)
 	if err := dec(in); err != nil {
@@ -444,6 +554,18 @@ var ElasticAgentControl_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Upgrade",
 			Handler:    _ElasticAgentControl_Upgrade_Handler,
 		},
+		{
+			MethodName: "UpgradeDryRun",
+			Handler:    _ElasticAgentControl_UpgradeDryRun_Handler,
+		},
+		{
+			MethodName: "Rollback",
+			Handler:    _ElasticAgentControl_Rollback_Handler,
+		},
+		{
+			MethodName: "RotateKey",
+			Handler:    _ElasticAgentControl_RotateKey_Handler,
+		},
 		{
 			MethodName: "DiagnosticAgent",
 			Handler:    _ElasticAgentControl_DiagnosticAgent_Handler,
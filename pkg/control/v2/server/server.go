@@ -188,6 +188,61 @@ func (s *Server) Upgrade(ctx context.Context, request *cproto.UpgradeRequest) (*
 	}, nil
 }
 
+// UpgradeDryRun runs the checks of the upgrade operation without upgrading.
+func (s *Server) UpgradeDryRun(ctx context.Context, request *cproto.UpgradeRequest) (*cproto.UpgradeDryRunResponse, error) {
+	report := s.coord.DryRunUpgrade(ctx, request.Version, request.SourceURI, request.SkipVerify, request.SkipDefaultPgp, request.PgpBytes...)
+
+	res := &cproto.UpgradeDryRunResponse{
+		Status:  cproto.ActionStatus_SUCCESS,
+		Version: request.Version,
+		Checks:  make([]*cproto.UpgradeCheck, 0, len(report.Checks)),
+	}
+	for _, check := range report.Checks {
+		res.Checks = append(res.Checks, &cproto.UpgradeCheck{
+			Name:    check.Name,
+			Passed:  check.Passed,
+			Message: check.Message,
+		})
+		if !check.Passed {
+			res.Status = cproto.ActionStatus_FAILURE
+			res.Error = fmt.Sprintf("upgrade check %s failed: %s", check.Name, check.Message)
+		}
+	}
+	return res, nil
+}
+
+// Rollback switches the Elastic Agent to a previous version retained on disk.
+func (s *Server) Rollback(ctx context.Context, request *cproto.RollbackRequest) (*cproto.RollbackResponse, error) {
+	err := s.coord.Rollback(ctx, request.Version, nil)
+	if err != nil {
+		//nolint:nilerr // ignore the error, return a failure rollback response
+		return &cproto.RollbackResponse{
+			Status: cproto.ActionStatus_FAILURE,
+			Error:  err.Error(),
+		}, nil
+	}
+	return &cproto.RollbackResponse{
+		Status:  cproto.ActionStatus_SUCCESS,
+		Version: request.Version,
+	}, nil
+}
+
+// RotateKey replaces the key the Elastic Agent encrypts its configuration and state with.
+func (s *Server) RotateKey(ctx context.Context, _ *cproto.RotateKeyRequest) (*cproto.RotateKeyResponse, error) {
+	version, err := s.coord.RotateKey(ctx)
+	if err != nil {
+		//nolint:nilerr // ignore the error, return a failure rotate key response
+		return &cproto.RotateKeyResponse{
+			Status: cproto.ActionStatus_FAILURE,
+			Error:  err.Error(),
+		}, nil
+	}
+	return &cproto.RotateKeyResponse{
+		Status:  cproto.ActionStatus_SUCCESS,
+		Version: version,
+	}, nil
+}
+
 // DiagnosticAgent returns diagnostic information for this running Elastic Agent.
 func (s *Server) DiagnosticAgent(ctx context.Context, req *cproto.DiagnosticAgentRequest) (*cproto.DiagnosticAgentResponse, error) {
 	res := make([]*cproto.DiagnosticFileResult, 0, len(s.diagHooks))
@@ -0,0 +1,29 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package eql allows the embedders of Elastic Agent to add functions to the EQL expressions of the
+// conditions of the agent policy.
+package eql
+
+import (
+	"github.com/elastic/elastic-agent/internal/pkg/eql"
+)
+
+// Function is a function called while an expression is evaluated, the function is responsible for
+// checking the arity and converting the types of its arguments.
+type Function = eql.Function
+
+// RegisterFunction adds a function that can be called from the EQL expressions. It returns an error
+// if the name isn't a valid function name or if a function with the same name is already registered,
+// built-in functions can't be replaced.
+func RegisterFunction(name string, fn Function) error {
+	return eql.RegisterFunction(name, fn)
+}
+
+// RegisterTimeDependentFunction adds a function that can be called from the EQL expressions and
+// returns a different result over time for the same arguments. The conditions calling it are
+// evaluated again periodically.
+func RegisterTimeDependentFunction(name string, fn Function) error {
+	return eql.RegisterTimeDependentFunction(name, fn)
+}
@@ -0,0 +1,35 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package eql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/eql"
+)
+
+func TestRegisterFunction(t *testing.T) {
+	err := RegisterFunction("embedderAnswer", func(args []interface{}) (interface{}, error) {
+		return 42, nil
+	})
+	require.NoError(t, err)
+	err = RegisterTimeDependentFunction("embedderClock", func(args []interface{}) (interface{}, error) {
+		return 1, nil
+	})
+	require.NoError(t, err)
+
+	r, err := eql.Eval("embedderAnswer() == 42 and embedderClock() == 1", nil, true)
+	require.NoError(t, err)
+	assert.True(t, r)
+
+	e, err := eql.New("embedderClock() == 1")
+	require.NoError(t, err)
+	assert.True(t, e.IsTimeDependent())
+
+	assert.Error(t, RegisterFunction("now", func(args []interface{}) (interface{}, error) { return nil, nil }))
+}
@@ -103,6 +103,21 @@ func RemoveLimitsOnChangeCallback(id string) {
 // policy can be a *config.Config, config.Config or anything config.NewConfigFrom
 // can work with. If policy is nil, Parse is a no-op.
 func Parse(policy any) (*LimitsConfig, error) {
+	c, err := toConfig(policy)
+	if err != nil || c == nil {
+		return nil, err
+	}
+
+	parsedConfig := rootConfig{}
+	if err := c.UnpackTo(&parsedConfig); err != nil {
+		return nil, fmt.Errorf("could not unpack limits config: %w", err)
+	}
+
+	return &parsedConfig.Agent.Limits, nil
+}
+
+// toConfig converts a policy to a *config.Config, nil is returned for a nil policy.
+func toConfig(policy any) (*config.Config, error) {
 	if policy == nil {
 		return nil, nil
 	}
@@ -123,16 +138,7 @@ func Parse(policy any) (*LimitsConfig, error) {
 		}
 	}
 
-	if c == nil {
-		return nil, nil
-	}
-
-	parsedConfig := rootConfig{}
-	if err := c.UnpackTo(&parsedConfig); err != nil {
-		return nil, fmt.Errorf("could not unpack limits config: %w", err)
-	}
-
-	return &parsedConfig.Agent.Limits, nil
+	return c, nil
 }
 
 // Apply receives a config and applies it. If c is nil, Apply is a no-op.
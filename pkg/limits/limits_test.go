@@ -96,3 +96,68 @@ func TestAddLimitsOnChangeCallback(t *testing.T) {
 		require.False(t, called, "callback must not be called")
 	})
 }
+
+func TestParseResources(t *testing.T) {
+	cases := []struct {
+		name   string
+		policy string
+		exp    map[string]ResourceLimits
+		expErr string
+	}{
+		{
+			name:   "no resources",
+			policy: `agent.limits.go_max_procs: 2`,
+		},
+		{
+			name: "resources per component and input type",
+			policy: `
+agent.limits.resources:
+  filestream:
+    cpu: 0.5
+    memory: 512MB
+  filestream-default:
+    open_files: 4096
+`,
+			exp: map[string]ResourceLimits{
+				"filestream":         {CPU: 0.5, Memory: "512MB"},
+				"filestream-default": {OpenFiles: 4096},
+			},
+		},
+		{
+			name: "invalid memory",
+			policy: `
+agent.limits.resources.filestream.memory: lots
+`,
+			expErr: `invalid memory "lots"`,
+		},
+		{
+			name: "negative cpu",
+			policy: `
+agent.limits.resources.filestream.cpu: -1
+`,
+			expErr: "cpu cannot be negative accessing 'agent.limits.resources.filestream'",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resources, err := ParseResources(config.MustNewConfigFrom(tc.policy))
+			if tc.expErr != "" {
+				require.ErrorContains(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, resources)
+		})
+	}
+}
+
+func TestResourceLimitsMemoryBytes(t *testing.T) {
+	b, err := ResourceLimits{Memory: "512MB"}.MemoryBytes()
+	require.NoError(t, err)
+	require.Equal(t, int64(512*1024*1024), b)
+
+	b, err = ResourceLimits{}.MemoryBytes()
+	require.NoError(t, err)
+	require.Zero(t, b)
+}
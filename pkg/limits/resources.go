@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package limits
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/go-units"
+)
+
+// ResourceLimits are the resources a component process is allowed to use.
+// A zero value means the resource is not limited.
+type ResourceLimits struct {
+	// CPU is the number of CPUs worth of time the component can use, fractions are allowed.
+	CPU float64 `yaml:"cpu,omitempty" config:"cpu" json:"cpu,omitempty"`
+	// Memory is the maximum amount of memory the component can use, e.g. 512MB or 2GB.
+	Memory string `yaml:"memory,omitempty" config:"memory" json:"memory,omitempty"`
+	// OpenFiles is the maximum number of file descriptors the component can open.
+	OpenFiles uint64 `yaml:"open_files,omitempty" config:"open_files" json:"open_files,omitempty"`
+}
+
+type resourcesRootConfig struct {
+	Agent struct {
+		Limits struct {
+			Resources map[string]ResourceLimits `config:"resources"`
+		} `config:"limits"`
+	} `config:"agent"`
+}
+
+// Validate returns an error if the resource limits are invalid, it's called when unpacking the configuration.
+func (r ResourceLimits) Validate() error {
+	if r.CPU < 0 {
+		return errors.New("cpu cannot be negative")
+	}
+	if _, err := r.MemoryBytes(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MemoryBytes returns the memory limit in bytes, zero when memory is not limited.
+func (r ResourceLimits) MemoryBytes() (int64, error) {
+	if r.Memory == "" {
+		return 0, nil
+	}
+	b, err := units.RAMInBytes(r.Memory)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory %q: %w", r.Memory, err)
+	}
+	if b <= 0 {
+		return 0, fmt.Errorf("invalid memory %q: must be greater than zero", r.Memory)
+	}
+	return b, nil
+}
+
+// IsZero returns true when no resource is limited.
+func (r ResourceLimits) IsZero() bool {
+	return r == ResourceLimits{}
+}
+
+// ParseResources receives a policy, parses and returns the resource limits of
+// the components keyed by component ID or input type, from the
+// agent.limits.resources section. If policy is nil, ParseResources is a no-op.
+func ParseResources(policy any) (map[string]ResourceLimits, error) {
+	c, err := toConfig(policy)
+	if err != nil || c == nil {
+		return nil, err
+	}
+
+	parsedConfig := resourcesRootConfig{}
+	if err := c.UnpackTo(&parsedConfig); err != nil {
+		return nil, fmt.Errorf("could not unpack resource limits config: %w", err)
+	}
+
+	return parsedConfig.Agent.Limits.Resources, nil
+}
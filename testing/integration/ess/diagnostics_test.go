@@ -49,6 +49,7 @@ var diagnosticsFiles = []string{
 	"mutex.pprof.gz",
 	"otel.yaml",
 	"otel-merged.yaml",
+	"otel-unsupported-components.yaml",
 	"pre-config.yaml",
 	"local-config.yaml",
 	"state.yaml",
@@ -423,6 +423,119 @@ func (_c *Client_Restart_Call) RunAndReturn(run func(context.Context) error) *Cl
 	return _c
 }
 
+// Rollback provides a mock function with given fields: ctx, version
+func (_m *Client) Rollback(ctx context.Context, version string) (string, error) {
+	ret := _m.Called(ctx, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Rollback")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, version)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_Rollback_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Rollback'
+type Client_Rollback_Call struct {
+	*mock.Call
+}
+
+// Rollback is a helper method to define mock.On call
+//   - ctx context.Context
+//   - version string
+func (_e *Client_Expecter) Rollback(ctx interface{}, version interface{}) *Client_Rollback_Call {
+	return &Client_Rollback_Call{Call: _e.mock.On("Rollback", ctx, version)}
+}
+
+func (_c *Client_Rollback_Call) Run(run func(ctx context.Context, version string)) *Client_Rollback_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Client_Rollback_Call) Return(_a0 string, _a1 error) *Client_Rollback_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_Rollback_Call) RunAndReturn(run func(context.Context, string) (string, error)) *Client_Rollback_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RotateKey provides a mock function with given fields: ctx
+func (_m *Client) RotateKey(ctx context.Context) (uint32, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateKey")
+	}
+
+	var r0 uint32
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (uint32, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) uint32); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_RotateKey_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RotateKey'
+type Client_RotateKey_Call struct {
+	*mock.Call
+}
+
+// RotateKey is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Client_Expecter) RotateKey(ctx interface{}) *Client_RotateKey_Call {
+	return &Client_RotateKey_Call{Call: _e.mock.On("RotateKey", ctx)}
+}
+
+func (_c *Client_RotateKey_Call) Run(run func(ctx context.Context)) *Client_RotateKey_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Client_RotateKey_Call) Return(_a0 uint32, _a1 error) *Client_RotateKey_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_RotateKey_Call) RunAndReturn(run func(context.Context) (uint32, error)) *Client_RotateKey_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // State provides a mock function with given fields: ctx
 func (_m *Client) State(ctx context.Context) (*client.AgentState, error) {
 	ret := _m.Called(ctx)
@@ -614,6 +727,83 @@ func (_c *Client_Upgrade_Call) RunAndReturn(run func(context.Context, string, st
 	return _c
 }
 
+// UpgradeDryRun provides a mock function with given fields: ctx, version, sourceURI, skipVerify, skipDefaultPgp, pgpBytes
+func (_m *Client) UpgradeDryRun(ctx context.Context, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string) ([]client.UpgradeCheck, error) {
+	_va := make([]interface{}, len(pgpBytes))
+	for _i := range pgpBytes {
+		_va[_i] = pgpBytes[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, version, sourceURI, skipVerify, skipDefaultPgp)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpgradeDryRun")
+	}
+
+	var r0 []client.UpgradeCheck
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool, bool, ...string) ([]client.UpgradeCheck, error)); ok {
+		return rf(ctx, version, sourceURI, skipVerify, skipDefaultPgp, pgpBytes...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool, bool, ...string) []client.UpgradeCheck); ok {
+		r0 = rf(ctx, version, sourceURI, skipVerify, skipDefaultPgp, pgpBytes...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]client.UpgradeCheck)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, bool, bool, ...string) error); ok {
+		r1 = rf(ctx, version, sourceURI, skipVerify, skipDefaultPgp, pgpBytes...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_UpgradeDryRun_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpgradeDryRun'
+type Client_UpgradeDryRun_Call struct {
+	*mock.Call
+}
+
+// UpgradeDryRun is a helper method to define mock.On call
+//   - ctx context.Context
+//   - version string
+//   - sourceURI string
+//   - skipVerify bool
+//   - skipDefaultPgp bool
+//   - pgpBytes ...string
+func (_e *Client_Expecter) UpgradeDryRun(ctx interface{}, version interface{}, sourceURI interface{}, skipVerify interface{}, skipDefaultPgp interface{}, pgpBytes ...interface{}) *Client_UpgradeDryRun_Call {
+	return &Client_UpgradeDryRun_Call{Call: _e.mock.On("UpgradeDryRun",
+		append([]interface{}{ctx, version, sourceURI, skipVerify, skipDefaultPgp}, pgpBytes...)...)}
+}
+
+func (_c *Client_UpgradeDryRun_Call) Run(run func(ctx context.Context, version string, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string)) *Client_UpgradeDryRun_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]string, len(args)-5)
+		for i, a := range args[5:] {
+			if a != nil {
+				variadicArgs[i] = a.(string)
+			}
+		}
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(bool), args[4].(bool), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *Client_UpgradeDryRun_Call) Return(_a0 []client.UpgradeCheck, _a1 error) *Client_UpgradeDryRun_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_UpgradeDryRun_Call) RunAndReturn(run func(context.Context, string, string, bool, bool, ...string) ([]client.UpgradeCheck, error)) *Client_UpgradeDryRun_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Version provides a mock function with given fields: ctx
 func (_m *Client) Version(ctx context.Context) (client.Version, error) {
 	ret := _m.Called(ctx)